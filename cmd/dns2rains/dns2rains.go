@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/zonefile"
+	"github.com/spf13/cobra"
+)
+
+var zone string
+var context string
+var outputPath string
+var assertions bool
+
+var rootCmd = &cobra.Command{
+	Use:   "dns2rains PATH",
+	Short: "dns2rains converts a DNS master file to a RAINS zonefile",
+	Long: `	dns2rains converts the DNS master file at PATH to a RAINS zonefile. Records
+	which cannot be expressed in RAINS are reported together with their line
+	number on stderr.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		z, unmappable, err := zonefile.IO{}.LoadMasterFile(args[0], zone, context)
+		if err != nil {
+			log.Fatalf("Error: was not able to convert master file: %v", err)
+		}
+		for _, r := range unmappable {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", r)
+		}
+		sections := []section.Section{z}
+		if assertions {
+			sections = nil
+			for _, a := range z.Content {
+				a.SubjectZone = z.SubjectZone
+				a.Context = z.Context
+				sections = append(sections, a)
+			}
+		}
+		encoding := zonefile.IO{}.Encode(sections)
+		if outputPath == "" {
+			fmt.Println(encoding)
+			return
+		}
+		if err := ioutil.WriteFile(outputPath, []byte(encoding), 0600); err != nil {
+			log.Fatalf("Error: was not able to write zonefile: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.Flags().StringVarP(&zone, "zone", "z", "", "The zone described by the master file. "+
+		"Relative names are completed with it until an $ORIGIN entry changes the origin.")
+	rootCmd.Flags().StringVarP(&context, "context", "c", ".", "The context of the zone.")
+	rootCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Path to which the zonefile is "+
+		"written. If empty, it is written to stdout.")
+	rootCmd.Flags().BoolVarP(&assertions, "assertions", "a", false, "If set, the records are "+
+		"converted to separate assertions instead of a zone.")
+	rootCmd.MarkFlagRequired("zone")
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/connection"
+	"github.com/netsec-ethz/rains/internal/pkg/libresolve"
+	"github.com/netsec-ethz/rains/internal/pkg/rains2dns"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+//udpScheme prefixes an IP address which is reached over plain UDP instead of TLS over TCP.
+const udpScheme = "udp://"
+
+var config = rains2dns.DefaultConfig()
+var listeners listenersFlag
+var forwarders addressesFlag
+var rootNameServers addressesFlag
+var rootZonePublicKeyPath string
+var maxConnections int
+var maxConcurrentQueries int
+var maxRecursiveCount int
+var maxTTL time.Duration
+var selectionPolicy selectionPolicyFlag
+var backoff time.Duration
+var maxBackoff time.Duration
+
+var rootCmd = &cobra.Command{
+	Use:   "rains2dns [PATH]",
+	Short: "rains2dns answers DNS queries with information from RAINS",
+	Long: `	rains2dns is a gateway for legacy applications which only speak DNS. It
+	accepts DNS queries over UDP and TCP, looks them up in RAINS and translates
+	the answers to DNS records. If no PATH to a config file is provided, the
+	default config is used.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 {
+			var err error
+			if config, err = rains2dns.LoadConfig(args[0]); err != nil {
+				log.Fatalf("Error: was not able to load config file: %v", err)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.Flags().Var(&listeners, "listeners", "A list of addresses on which DNS queries are "+
+		"accepted over UDP and TCP and the RAINS context in which they are looked up. The format is "+
+		"elem(,elem) where elem := address,contextName")
+	rootCmd.Flags().Var(&forwarders, "forwarders", "A list of RAINS servers to which queries are "+
+		"forwarded. If empty, queries are resolved recursively starting at the root name servers.")
+	rootCmd.Flags().Var(&rootNameServers, "rootNameServers", "A list of RAINS root name servers "+
+		"at which recursive lookups start.")
+	rootCmd.Flags().StringVar(&rootZonePublicKeyPath, "rootZonePublicKeyPath",
+		"data/keys/rootDelegationAssertion.gob", "Path to the file storing the root zone public key.")
+	rootCmd.Flags().IntVar(&maxConnections, "maxConnections", 10, "The maximum number of "+
+		"connections to RAINS servers kept open.")
+	rootCmd.Flags().IntVar(&maxConcurrentQueries, "maxConcurrentQueries", 100, "The maximum "+
+		"number of queries received over UDP which are looked up at the same time.")
+	rootCmd.Flags().IntVar(&maxRecursiveCount, "maxRecursiveCount", 50, "The maximum number of "+
+		"referrals followed in a recursive lookup.")
+	rootCmd.Flags().DurationVar(&maxTTL, "maxTTL", time.Hour, "The maximum TTL of a DNS record. "+
+		"Records are otherwise valid as long as the signatures of the corresponding RAINS section.")
+	rootCmd.Flags().Var(&selectionPolicy, "selectionPolicy", "Determines in which order the "+
+		"forwarders or root name servers are contacted. Possible values are Failover, RoundRobin, "+
+		"Random and LowestRTT.")
+	rootCmd.Flags().DurationVar(&backoff, "backoff", time.Second, "The amount of time a forwarder "+
+		"or root name server is avoided after a failed query. It doubles with each consecutive "+
+		"failure.")
+	rootCmd.Flags().DurationVar(&maxBackoff, "maxBackoff", 5*time.Minute, "The maximum amount of "+
+		"time a forwarder or root name server is avoided after failed queries.")
+}
+
+func main() {
+	h := log15.CallerFileHandler(log15.StdoutHandler)
+	log15.Root().SetHandler(log15.LvlFilterHandler(log15.LvlInfo, h))
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+	if !rootCmd.Flag("help").Changed {
+		updateConfig(&config)
+		mode := libresolve.Recursive
+		if len(config.Forwarders) > 0 {
+			mode = libresolve.Forward
+		}
+		resolver, err := libresolve.New(addrs(config.RootNameServers), addrs(config.Forwarders),
+			config.RootZonePublicKeyPath, mode, nil, config.MaxConnections,
+			util.MaxCacheValidity{
+				AssertionValidity: config.MaxTTL,
+				ShardValidity:     config.MaxTTL,
+				PshardValidity:    config.MaxTTL,
+				ZoneValidity:      config.MaxTTL,
+			}, config.MaxRecursiveCount)
+		if err != nil {
+			log.Fatalf("Error: Unable to initialize resolver: %v", err)
+		}
+		resolver.Policy = config.SelectionPolicy
+		resolver.Backoff = config.Backoff
+		resolver.MaxBackoff = config.MaxBackoff
+		gateway := rains2dns.New(config, resolver)
+		if err := gateway.Start(); err != nil {
+			log.Fatalf("Error: Unable to start gateway: %v", err)
+		}
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+		gateway.Shutdown()
+	}
+}
+
+//updateConfig overrides config with the provided cmd line flags
+func updateConfig(config *rains2dns.Config) {
+	if rootCmd.Flag("listeners").Changed {
+		config.Listeners = listeners.value
+	}
+	if rootCmd.Flag("forwarders").Changed {
+		config.Forwarders = forwarders.value
+	}
+	if rootCmd.Flag("rootNameServers").Changed {
+		config.RootNameServers = rootNameServers.value
+	}
+	if rootCmd.Flag("rootZonePublicKeyPath").Changed {
+		config.RootZonePublicKeyPath = rootZonePublicKeyPath
+	}
+	if rootCmd.Flag("maxConnections").Changed {
+		config.MaxConnections = maxConnections
+	}
+	if rootCmd.Flag("maxConcurrentQueries").Changed {
+		config.MaxConcurrentQueries = maxConcurrentQueries
+	}
+	if rootCmd.Flag("maxRecursiveCount").Changed {
+		config.MaxRecursiveCount = maxRecursiveCount
+	}
+	if rootCmd.Flag("maxTTL").Changed {
+		config.MaxTTL = maxTTL
+	}
+	if rootCmd.Flag("selectionPolicy").Changed {
+		config.SelectionPolicy = selectionPolicy.value
+	}
+	if rootCmd.Flag("backoff").Changed {
+		config.Backoff = backoff
+	}
+	if rootCmd.Flag("maxBackoff").Changed {
+		config.MaxBackoff = maxBackoff
+	}
+}
+
+func addrs(infos []connection.Info) []net.Addr {
+	var result []net.Addr
+	for _, info := range infos {
+		result = append(result, info.Addr)
+	}
+	return result
+}
+
+type listenersFlag struct {
+	set   bool
+	value []rains2dns.ListenerConfig
+}
+
+func (i *listenersFlag) String() string {
+	if i.set {
+		return fmt.Sprintf("%v", i.value)
+	}
+	return "[{127.0.0.1:5353 .}]" //default
+}
+
+func (i *listenersFlag) Set(value string) error {
+	values := strings.Split(value, ",")
+	if len(values)%2 != 0 {
+		return errors.New("Error: amount of address and context values is not the same")
+	}
+	i.set = true
+	for j := 0; j < len(values); j += 2 {
+		i.value = append(i.value, rains2dns.ListenerConfig{Address: values[j], Context: values[j+1]})
+	}
+	return nil
+}
+
+func (i *listenersFlag) Type() string {
+	return "[]rains2dns.ListenerConfig"
+}
+
+type addressesFlag struct {
+	set   bool
+	value []connection.Info
+}
+
+func (i *addressesFlag) String() string {
+	if i.set {
+		return fmt.Sprintf("%v", i.value)
+	}
+	return "[]" //default
+}
+
+func (i *addressesFlag) Set(value string) error {
+	for _, addr := range strings.Split(value, ",") {
+		if strings.HasPrefix(addr, udpScheme) {
+			udpAddr, err := net.ResolveUDPAddr("udp", strings.TrimPrefix(addr, udpScheme))
+			if err != nil {
+				return err
+			}
+			i.value = append(i.value, connection.Info{Type: connection.UDP, Addr: udpAddr})
+		} else if tcpAddr, err := net.ResolveTCPAddr("tcp", addr); err == nil {
+			i.value = append(i.value, connection.Info{Type: connection.TCP, Addr: tcpAddr})
+		} else {
+			return err
+		}
+	}
+	i.set = true
+	return nil
+}
+
+func (i *addressesFlag) Type() string {
+	return "[]net.Addr"
+}
+
+type selectionPolicyFlag struct {
+	set   bool
+	value libresolve.SelectionPolicy
+}
+
+func (i *selectionPolicyFlag) String() string {
+	if i.set {
+		return i.value.String()
+	}
+	return libresolve.Failover.String() //default
+}
+
+func (i *selectionPolicyFlag) Set(value string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := i.value.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	i.set = true
+	return nil
+}
+
+func (i *selectionPolicyFlag) Type() string {
+	return "selectionPolicy"
+}
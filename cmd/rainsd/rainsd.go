@@ -1,11 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/inconshreveable/log15"
@@ -18,22 +22,42 @@ import (
 )
 
 var config = rainsd.DefaultConfig()
+var configPath string
 var id string
 var rootZonePublicKeyPath string
 var assertionCheckPointInterval time.Duration
 var negAssertionCheckPointInterval time.Duration
 var zoneKeyCheckPointInterval time.Duration
 var checkPointPath string
+var checkPointEncoding checkPointEncodingFlag
+var checkPointGenerations int
 var preLoadCaches bool
+var controlSocket string
+var metricsAddr string
+var shutdownDeadline time.Duration
+var logLevel string
 
 //switchboard
 var serverAddress addressFlag
-var rootServerAddress addressFlag
+var rootServerAddresses addressesFlag
+var rootServerPolicy selectionPolicyFlag
+var rootServerBackoff time.Duration
+var rootServerMaxBackoff time.Duration
 var maxConnections int
+var maxMessageSize int
 var keepAlivePeriod time.Duration
 var tcpTimeout time.Duration
 var tlsCertificateFile string
 var tlsPrivateKeyFile string
+var peerBlacklist []string
+var infraKeyPath string
+var infraKeyNames map[string]string
+var rejectUnsignedMessages bool
+var heartbeatInterval time.Duration
+var maxMissedHeartbeats int
+var responseRateLimit float64
+var responseRateBurst int
+var responseRateLimitSlip int
 
 // SCION specific settings
 var dispatcherSock string
@@ -48,6 +72,12 @@ var normalWorkerCount int
 var notificationWorkerCount int
 var capabilitiesCacheSize int
 var capabilities string
+var zoneBlacklist []string
+var queryRateLimit float64
+var queryRateBurst int
+var assertionRateLimit float64
+var assertionRateBurst int
+var maxQueuedPerSender int
 
 //verify
 var zoneKeyCacheSize int
@@ -55,15 +85,21 @@ var zoneKeyCacheWarnSize int
 var maxPublicKeysPerZone int
 var pendingKeyCacheSize int
 var delegationQueryValidity time.Duration
+var delegationQueryRetries int
+var backupServers addressesFlag
 var reapZoneKeyCacheInterval time.Duration
 var reapPendingKeyCacheInterval time.Duration
 
 //engine
 var assertionCacheSize int
 var negativeAssertionCacheSize int
+var addressCacheSize int
+var assertionStorePath string
 var pendingQueryCacheSize int
+var answerCoalescingDeadline time.Duration
 var queryValidity time.Duration
 var authorities authoritiesFlag
+var intermediaryFor authoritiesFlag
 var maxAssertionValidity time.Duration
 var maxShardValidity time.Duration
 var maxPshardValidity time.Duration
@@ -71,14 +107,18 @@ var maxZoneValidity time.Duration
 var reapAssertionCacheInterval time.Duration
 var reapNegAssertionCacheInterval time.Duration
 var reapPendingQCacheInterval time.Duration
+var inconsistencyPolicy inconsistencyPolicyFlag
+var staleGracePeriod time.Duration
+var minLastHopAnswerSize bool
+var enforceNameset bool
 var maxRecurseDepth int
 
 var rootCmd = &cobra.Command{
 	Use:   "rainsd [PATH]",
 	Short: "rainsd is an implementation of a RAINS server",
 	Long: `	This program implements a RAINS server which serves requests over the RAINS protocol.
-	The server can be configured to support the first two modes of operation, authority
-	service and query service. The third one is not yet implemented.
+	The server can be configured to support all three modes of operation, authority
+	service, query service and intermediary service.
 
 	* authority service -- the server acts on behalf of an authority to ensure
 	 properly signed assertions are available to the system,
@@ -98,7 +138,8 @@ var rootCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 1 {
 			var err error
-			if config, err = rainsd.LoadConfig(args[0]); err != nil {
+			configPath = args[0]
+			if config, err = rainsd.LoadConfig(configPath); err != nil {
 				log.Fatalf("Error: was not able to load config file: %v", err)
 			}
 		}
@@ -109,7 +150,18 @@ func init() {
 	rootCmd.Flags().Var(&serverAddress, "serverAddress", "The network address of this server.")
 	rootCmd.Flags().Var(&authorities, "authorities", "A list of contexts and zones for which this server "+
 		"is authoritative. The format is elem(,elem)* where elem := zoneName,contextName")
-	rootCmd.Flags().Var(&rootServerAddress, "rootServerAddress", "The root name server address")
+	rootCmd.Flags().Var(&intermediaryFor, "intermediaryFor", "A list of contexts and zones for which "+
+		"this server acts as an intermediary. The format is elem(,elem)* where elem := "+
+		"zoneName,contextName")
+	rootCmd.Flags().Var(&rootServerAddresses, "rootServerAddress", "The root name server address. "+
+		"The flag can be repeated to specify several root name servers.")
+	rootCmd.Flags().Var(&rootServerPolicy, "rootServerPolicy", "Determines in which order the "+
+		"root name servers are contacted. Possible values are Failover, RoundRobin, Random and LowestRTT.")
+	rootCmd.Flags().DurationVar(&rootServerBackoff, "rootServerBackoff", time.Second, "The amount "+
+		"of time a root name server is avoided after a failed query. It doubles with each "+
+		"consecutive failure.")
+	rootCmd.Flags().DurationVar(&rootServerMaxBackoff, "rootServerMaxBackoff", 5*time.Minute,
+		"The maximum amount of time a root name server is avoided after failed queries.")
 	rootCmd.Flags().StringVar(&id, "id", "", "Server id")
 	rootCmd.Flags().StringVar(&rootZonePublicKeyPath, "rootZonePublicKeyPath", "data/keys/rootDelegationAssertion.gob", "Path to the "+
 		"file storing the RAINS' root zone public key.")
@@ -121,11 +173,27 @@ func init() {
 		"seconds after which a checkpoint of the zone key cache is performed.")
 	rootCmd.Flags().StringVar(&checkPointPath, "checkPointPath", "data/checkpoint/resolver/", "Path where the server's "+
 		"checkpoint information is stored.")
+	rootCmd.Flags().Var(&checkPointEncoding, "checkPointEncoding", "Determines the format of the "+
+		"checkpoint files. Possible values are GobCheckPoint, CBORCheckPoint and ZonefileCheckPoint.")
+	rootCmd.Flags().IntVar(&checkPointGenerations, "checkPointGenerations", 2, "The number of "+
+		"previous checkpoint files which are kept next to the current one.")
 	rootCmd.Flags().BoolVar(&preLoadCaches, "preLoadCaches", false, "If true, the assertion, negative assertion, "+
 		"and zone key cache are pre-loaded from the checkpoint files in CheckPointPath at start up.")
+	rootCmd.Flags().StringVar(&controlSocket, "controlSocket", "", "Path of the unix socket over which "+
+		"the server can be controlled at runtime. Only the user running the server can access it. If "+
+		"empty, no control socket is opened.")
+	rootCmd.Flags().StringVar(&metricsAddr, "metricsAddr", "", "Address (host:port) on which the "+
+		"server's metrics are served over http in the Prometheus text format. If empty, no metrics are served.")
+	rootCmd.Flags().DurationVar(&shutdownDeadline, "shutdownDeadline", 10*time.Second, "The maximum "+
+		"amount of time the server waits for its input queues and pending queries to drain when it "+
+		"receives SIGTERM.")
+	rootCmd.Flags().StringVar(&logLevel, "logLevel", "info", "The level of log messages written to "+
+		"stdout. Possible values are crit, error, warn, info and debug.")
 
 	//switchboard
 	rootCmd.Flags().IntVar(&maxConnections, "maxConnections", 10000, "The maximum number of allowed active connections.")
+	rootCmd.Flags().IntVar(&maxMessageSize, "maxMessageSize", 0, "The maximum size in bytes of an "+
+		"incoming message. Larger messages are rejected. If 0, the size is not limited.")
 	rootCmd.Flags().DurationVar(&keepAlivePeriod, "keepAlivePeriod", time.Minute, "How long to keep idle connections open.")
 	rootCmd.Flags().DurationVar(&tcpTimeout, "tcpTimeout", 5*time.Minute, "TCPTimeout is the maximum amount of "+
 		"time a dial will wait for a tcp connect to complete.")
@@ -133,6 +201,28 @@ func init() {
 		"certificate file proving the server's identity.")
 	rootCmd.Flags().StringVar(&tlsPrivateKeyFile, "tlsPrivateKeyFile", "data/cert/server.key", "The path to the server's tls "+
 		"private key file proving the server's identity.")
+	rootCmd.Flags().StringSliceVar(&peerBlacklist, "peerBlacklist", nil, "A list of IP or SCION "+
+		"addresses from which no traffic is accepted.")
+	rootCmd.Flags().StringVar(&infraKeyPath, "infraKeyPath", "", "Path to a directory storing the "+
+		"infrastructure private keys with which outgoing messages are signed. If empty, messages are not signed.")
+	rootCmd.Flags().StringToStringVar(&infraKeyNames, "infraKeyNames", nil, "A list of peer IP "+
+		"addresses and the names of the assertions holding their infrastructure keys. The format is "+
+		"elem(,elem)* where elem := address=name")
+	rootCmd.Flags().BoolVar(&rejectUnsignedMessages, "rejectUnsignedMessages", false, "If true, "+
+		"unsigned messages from peers listed in infraKeyNames are dropped.")
+	rootCmd.Flags().DurationVar(&heartbeatInterval, "heartbeatInterval", 0, "The time after which a "+
+		"heartbeat is sent on a connection on which nothing has been received. If 0, no heartbeats "+
+		"are sent.")
+	rootCmd.Flags().IntVar(&maxMissedHeartbeats, "maxMissedHeartbeats", 3, "The number of "+
+		"unanswered heartbeats after which a connection is closed.")
+	rootCmd.Flags().Float64Var(&responseRateLimit, "responseRateLimit", 0, "The maximum number of "+
+		"responses per second sent over SCION or UDP to a client network. If 0, responses are not "+
+		"limited.")
+	rootCmd.Flags().IntVar(&responseRateBurst, "responseRateBurst", 20, "The maximum number of "+
+		"responses sent to a client network in a burst.")
+	rootCmd.Flags().IntVar(&responseRateLimitSlip, "responseRateLimitSlip", 2, "Every nth "+
+		"suppressed response is replaced by a rate limited notification. If 0, suppressed responses "+
+		"are always dropped.")
 
 	// SCION specific settings
 	rootCmd.Flags().StringVar(&dispatcherSock, "dispatcherSock", "/run/shm/dispatcher/default.sock", "Path to the dispatcher socket.")
@@ -147,6 +237,18 @@ func init() {
 	rootCmd.Flags().IntVar(&notificationWorkerCount, "notificationWorkerCount", 1, "Number of workers on the notification queue.")
 	rootCmd.Flags().IntVar(&capabilitiesCacheSize, "capabilitiesCacheSize", 10, "Maximum number of elements in the capabilities cache.")
 	rootCmd.Flags().StringVar(&capabilities, "capabilities", "urn:x-rains:tlssrv", "A list of capabilities this server supports.")
+	rootCmd.Flags().StringSliceVar(&zoneBlacklist, "zoneBlacklist", nil, "A list of zones whose "+
+		"sections are dropped.")
+	rootCmd.Flags().Float64Var(&queryRateLimit, "queryRateLimit", 0, "The maximum number of queries "+
+		"per second accepted from a client. If 0, queries are not limited.")
+	rootCmd.Flags().IntVar(&queryRateBurst, "queryRateBurst", 50, "The maximum number of queries "+
+		"accepted from a client in a burst.")
+	rootCmd.Flags().Float64Var(&assertionRateLimit, "assertionRateLimit", 0, "The maximum number of "+
+		"messages containing sections per second accepted from a client. If 0, they are not limited.")
+	rootCmd.Flags().IntVar(&assertionRateBurst, "assertionRateBurst", 20, "The maximum number of "+
+		"messages containing sections accepted from a client in a burst.")
+	rootCmd.Flags().IntVar(&maxQueuedPerSender, "maxQueuedPerSender", 0, "The maximum number of "+
+		"messages of a single sender in the normal queue. If 0, the queue is not shared fairly.")
 
 	//verify
 	rootCmd.Flags().IntVar(&zoneKeyCacheSize, "zoneKeyCacheSize", 1000, "The maximum number of entries in the zone key cache.")
@@ -156,6 +258,11 @@ func init() {
 	rootCmd.Flags().IntVar(&pendingKeyCacheSize, "pendingKeyCacheSize", 100, "The maximum number of entries in the pending key cache.")
 	rootCmd.Flags().DurationVar(&delegationQueryValidity, "delegationQueryValidity", time.Second, "The amount of seconds in the "+
 		"future when delegation queries are set to expire.")
+	rootCmd.Flags().IntVar(&delegationQueryRetries, "delegationQueryRetries", 1, "The number of times "+
+		"unanswered delegation queries are resent before the backup servers are queried.")
+	rootCmd.Flags().Var(&backupServers, "backupServers", "The address of a server to which "+
+		"delegation queries are sent when the original destination did not answer them. Can be "+
+		"specified several times. The servers are queried in the given order.")
 	rootCmd.Flags().DurationVar(&reapZoneKeyCacheInterval, "reapZoneKeyCacheInterval", 15*time.Minute, "The time interval to wait "+
 		"between removing expired entries from the zone key cache.")
 	rootCmd.Flags().DurationVar(&reapPendingKeyCacheInterval, "reapPendingKeyCacheInterval", 15*time.Minute, "The time interval to wait "+
@@ -166,8 +273,17 @@ func init() {
 		"assertion cache.")
 	rootCmd.Flags().IntVar(&negativeAssertionCacheSize, "negativeAssertionCacheSize", 1000, "The maximum number of entries in the "+
 		"negative assertion cache.")
+	rootCmd.Flags().IntVar(&addressCacheSize, "addressCacheSize", 1000, "The maximum number of "+
+		"address assertions and address zones in the address cache.")
+	rootCmd.Flags().StringVar(&assertionStorePath, "assertionStorePath", "", "Path of the folder in which "+
+		"assertions, shards, pshards and zones are stored on disk instead of in memory. If empty, they "+
+		"are only kept in memory.")
 	rootCmd.Flags().IntVar(&pendingQueryCacheSize, "pendingQueryCacheSize", 1000, " The maximum number of entries in the "+
 		"pending query cache.")
+	rootCmd.Flags().DurationVar(&answerCoalescingDeadline, "answerCoalescingDeadline", 0, "The amount "+
+		"of time answers to a forwarded query are gathered after the last one arrived before they "+
+		"are sent back together, but at most until the query expires. Zero sends each answer right "+
+		"away.")
 	rootCmd.Flags().DurationVar(&queryValidity, "queryValidity", time.Second, "The amount of seconds in the "+
 		"future when a query is set to expire.")
 	rootCmd.Flags().DurationVar(&maxAssertionValidity, "maxAssertionValidity", 3*time.Hour, "contains the maximum number "+
@@ -188,6 +304,19 @@ func init() {
 		"wait between removing expired entries from the negative assertion cache.")
 	rootCmd.Flags().DurationVar(&reapPendingQCacheInterval, "reapPendingQCacheInterval", 15*time.Minute, "The time interval to "+
 		"wait between removing expired entries from the pending query cache.")
+	rootCmd.Flags().Var(&inconsistencyPolicy, "inconsistencyPolicy", "Determines which cached sections are "+
+		"removed when a received section contradicts them. Possible values are KeepCached, "+
+		"EvictConflicting and EvictZone. A received section which is newer than all sections it "+
+		"contradicts or which belongs to a zone the server is authoritative for replaces them.")
+	rootCmd.Flags().DurationVar(&staleGracePeriod, "staleGracePeriod", 0, "The amount of time expired "+
+		"sections are kept in the caches to answer queries containing the expired assertions ok "+
+		"query option. Zero disables serving expired sections.")
+	rootCmd.Flags().BoolVar(&minLastHopAnswerSize, "minLastHopAnswerSize", false, "If true, negative "+
+		"answers to all queries consist of the smallest cached shard, pshard or zone as if the "+
+		"queries contained the minimize last hop answer size query option.")
+	rootCmd.Flags().BoolVar(&enforceNameset, "enforceNameset", false, "If true, received "+
+		"assertions, shards and zones containing an assertion whose subject name is not matched by "+
+		"the nameset of its zone are dropped.")
 	rootCmd.Flags().IntVar(&maxRecurseDepth, "maxrecurse", 50, "Recursive resolver maximum depth (max. depth of recursive stack)")
 }
 
@@ -206,7 +335,10 @@ func main() {
 		} else {
 			log.Println("Starting server")
 		}
-		rootNameServers := []net.Addr{rootServerAddress.value.Addr}
+		rootNameServers := []net.Addr{}
+		for _, info := range rootServerAddresses.value {
+			rootNameServers = append(rootNameServers, info.Addr)
+		}
 		// maxRecurseCount = 50 means the recursion will abort if called to itself more than 50 times
 		resolver, err := libresolve.New(rootNameServers, nil, server.Config().RootZonePublicKeyPath,
 			libresolve.Recursive, server.Addr(), maxConnections, server.Config().MaxCacheValidity,
@@ -215,11 +347,27 @@ func main() {
 			log.Fatalf("Error: Unable to initialize recursive resolver: %v", err.Error())
 			return
 		}
+		resolver.Policy = rootServerPolicy.value
+		resolver.Backoff = rootServerBackoff
+		resolver.MaxBackoff = rootServerMaxBackoff
 		server.SetResolver(resolver)
+		server.SetConfigLoader(loadConfig)
 		log.Println("Server successfully initialized")
-		go server.Start(false, id)
-		handleUserInput()
-		server.Shutdown()
+		go server.Start(id)
+		sigterm := make(chan os.Signal, 1)
+		signal.Notify(sigterm, syscall.SIGTERM)
+		go reloadOnSighup(server)
+		quit := make(chan bool)
+		go func() {
+			handleUserInput()
+			quit <- true
+		}()
+		select {
+		case <-quit:
+			server.Shutdown()
+		case <-sigterm:
+			server.GracefulShutdown(server.Config().ShutdownDeadline)
+		}
 	}
 }
 
@@ -240,15 +388,36 @@ func updateConfig(config *rainsd.Config) {
 	if rootCmd.Flag("checkPointPath").Changed {
 		config.CheckPointPath = checkPointPath
 	}
+	if rootCmd.Flag("checkPointEncoding").Changed {
+		config.CheckPointEncoding = checkPointEncoding.value
+	}
+	if rootCmd.Flag("checkPointGenerations").Changed {
+		config.CheckPointGenerations = checkPointGenerations
+	}
 	if rootCmd.Flag("preLoadCaches").Changed {
 		config.PreLoadCaches = preLoadCaches
 	}
+	if rootCmd.Flag("controlSocket").Changed {
+		config.ControlSocket = controlSocket
+	}
+	if rootCmd.Flag("metricsAddr").Changed {
+		config.MetricsAddr = metricsAddr
+	}
+	if rootCmd.Flag("shutdownDeadline").Changed {
+		config.ShutdownDeadline = shutdownDeadline
+	}
+	if rootCmd.Flag("logLevel").Changed {
+		config.LogLevel = logLevel
+	}
 	if rootCmd.Flag("serverAddress").Changed {
 		config.ServerAddress = serverAddress.value
 	}
 	if rootCmd.Flag("maxConnections").Changed {
 		config.MaxConnections = maxConnections
 	}
+	if rootCmd.Flag("maxMessageSize").Changed {
+		config.MaxMessageSize = maxMessageSize
+	}
 	if rootCmd.Flag("keepAlivePeriod").Changed {
 		config.KeepAlivePeriod = keepAlivePeriod
 	}
@@ -261,6 +430,33 @@ func updateConfig(config *rainsd.Config) {
 	if rootCmd.Flag("tlsPrivateKeyFile").Changed {
 		config.TLSPrivateKeyFile = tlsPrivateKeyFile
 	}
+	if rootCmd.Flag("peerBlacklist").Changed {
+		config.PeerBlacklist = peerBlacklist
+	}
+	if rootCmd.Flag("infraKeyPath").Changed {
+		config.InfraKeyPath = infraKeyPath
+	}
+	if rootCmd.Flag("infraKeyNames").Changed {
+		config.InfraKeyNames = infraKeyNames
+	}
+	if rootCmd.Flag("rejectUnsignedMessages").Changed {
+		config.RejectUnsignedMessages = rejectUnsignedMessages
+	}
+	if rootCmd.Flag("heartbeatInterval").Changed {
+		config.HeartbeatInterval = heartbeatInterval
+	}
+	if rootCmd.Flag("maxMissedHeartbeats").Changed {
+		config.MaxMissedHeartbeats = maxMissedHeartbeats
+	}
+	if rootCmd.Flag("responseRateLimit").Changed {
+		config.ResponseRateLimit = responseRateLimit
+	}
+	if rootCmd.Flag("responseRateBurst").Changed {
+		config.ResponseRateBurst = responseRateBurst
+	}
+	if rootCmd.Flag("responseRateLimitSlip").Changed {
+		config.ResponseRateLimitSlip = responseRateLimitSlip
+	}
 	if rootCmd.Flag("dispatcherSock").Changed {
 		config.DispatcherSock = dispatcherSock
 	}
@@ -291,6 +487,24 @@ func updateConfig(config *rainsd.Config) {
 	if rootCmd.Flag("capabilities").Changed {
 		config.Capabilities = []message.Capability{message.Capability(capabilities)}
 	}
+	if rootCmd.Flag("zoneBlacklist").Changed {
+		config.ZoneBlacklist = zoneBlacklist
+	}
+	if rootCmd.Flag("queryRateLimit").Changed {
+		config.QueryRateLimit = queryRateLimit
+	}
+	if rootCmd.Flag("queryRateBurst").Changed {
+		config.QueryRateBurst = queryRateBurst
+	}
+	if rootCmd.Flag("assertionRateLimit").Changed {
+		config.AssertionRateLimit = assertionRateLimit
+	}
+	if rootCmd.Flag("assertionRateBurst").Changed {
+		config.AssertionRateBurst = assertionRateBurst
+	}
+	if rootCmd.Flag("maxQueuedPerSender").Changed {
+		config.MaxQueuedPerSender = maxQueuedPerSender
+	}
 	if rootCmd.Flag("zoneKeyCacheSize").Changed {
 		config.ZoneKeyCacheSize = zoneKeyCacheSize
 	}
@@ -306,6 +520,12 @@ func updateConfig(config *rainsd.Config) {
 	if rootCmd.Flag("delegationQueryValidity").Changed {
 		config.DelegationQueryValidity = delegationQueryValidity
 	}
+	if rootCmd.Flag("delegationQueryRetries").Changed {
+		config.DelegationQueryRetries = delegationQueryRetries
+	}
+	if rootCmd.Flag("backupServers").Changed {
+		config.BackupServers = backupServers.value
+	}
 	if rootCmd.Flag("reapZoneKeyCacheInterval").Changed {
 		config.ReapZoneKeyCacheInterval = reapZoneKeyCacheInterval
 	}
@@ -318,12 +538,24 @@ func updateConfig(config *rainsd.Config) {
 	if rootCmd.Flag("negativeAssertionCacheSize").Changed {
 		config.NegativeAssertionCacheSize = negativeAssertionCacheSize
 	}
+	if rootCmd.Flag("addressCacheSize").Changed {
+		config.AddressCacheSize = addressCacheSize
+	}
+	if rootCmd.Flag("assertionStorePath").Changed {
+		config.AssertionStorePath = assertionStorePath
+	}
 	if rootCmd.Flag("pendingQueryCacheSize").Changed {
 		config.PendingQueryCacheSize = pendingQueryCacheSize
 	}
+	if rootCmd.Flag("answerCoalescingDeadline").Changed {
+		config.AnswerCoalescingDeadline = answerCoalescingDeadline
+	}
 	if rootCmd.Flag("authorities").Changed {
 		config.Authorities = authorities.value
 	}
+	if rootCmd.Flag("intermediaryFor").Changed {
+		config.IntermediaryFor = intermediaryFor.value
+	}
 	if rootCmd.Flag("maxAssertionValidity").Changed {
 		config.MaxCacheValidity.AssertionValidity = maxAssertionValidity
 	}
@@ -345,6 +577,48 @@ func updateConfig(config *rainsd.Config) {
 	if rootCmd.Flag("reapPendingQCacheInterval").Changed {
 		config.ReapPendingQCacheInterval = reapPendingQCacheInterval
 	}
+	if rootCmd.Flag("inconsistencyPolicy").Changed {
+		config.InconsistencyPolicy = inconsistencyPolicy.value
+	}
+	if rootCmd.Flag("staleGracePeriod").Changed {
+		config.StaleGracePeriod = staleGracePeriod
+	}
+	if rootCmd.Flag("minLastHopAnswerSize").Changed {
+		config.MinLastHopAnswerSize = minLastHopAnswerSize
+	}
+	if rootCmd.Flag("enforceNameset").Changed {
+		config.EnforceNameset = enforceNameset
+	}
+}
+
+//loadConfig returns the config stored at configPath, or the default config if no path has been
+//provided, overridden by the provided cmd line flags.
+func loadConfig() (rainsd.Config, error) {
+	conf := rainsd.DefaultConfig()
+	if configPath != "" {
+		var err error
+		if conf, err = rainsd.LoadConfig(configPath); err != nil {
+			return rainsd.Config{}, err
+		}
+	}
+	updateConfig(&conf)
+	return conf, nil
+}
+
+//reloadOnSighup reloads the configuration of server each time SIGHUP is received.
+func reloadOnSighup(server *rainsd.Server) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	for range sighup {
+		restart, err := server.ReloadConfig()
+		if err != nil {
+			log.Printf("Error: Was not able to reload config: %v", err)
+		} else if len(restart) > 0 {
+			log.Printf("Config reloaded. Restart required for: %s", strings.Join(restart, " "))
+		} else {
+			log.Println("Config reloaded")
+		}
+	}
 }
 
 func handleUserInput() {
@@ -359,6 +633,9 @@ func handleUserInput() {
 	}
 }
 
+//udpScheme prefixes an IP address which is reached over plain UDP instead of TLS over TCP.
+const udpScheme = "udp://"
+
 type addressFlag struct {
 	set   bool
 	value connection.Info
@@ -366,6 +643,9 @@ type addressFlag struct {
 
 func (i *addressFlag) String() string {
 	if i.set {
+		if i.value.Type == connection.UDP {
+			return udpScheme + i.value.Addr.String()
+		}
 		return i.value.Addr.String()
 	}
 	return "127.0.0.1:55553" //default
@@ -374,6 +654,11 @@ func (i *addressFlag) String() string {
 func (i *addressFlag) Set(value string) (err error) {
 	i.set = true
 	i.value = connection.Info{}
+	if strings.HasPrefix(value, udpScheme) {
+		i.value.Addr, err = net.ResolveUDPAddr("udp", strings.TrimPrefix(value, udpScheme))
+		i.value.Type = connection.UDP
+		return err
+	}
 	i.value.Addr, err = net.ResolveTCPAddr("", value)
 	if err != nil { // Not an IP address
 		i.value.Addr, err = snet.AddrFromString(value)
@@ -390,6 +675,32 @@ func (i *addressFlag) Type() string {
 	return "net.Addr"
 }
 
+type addressesFlag struct {
+	value []connection.Info
+}
+
+func (i *addressesFlag) String() string {
+	addrs := []string{}
+	for _, info := range i.value {
+		addr := addressFlag{set: true, value: info}
+		addrs = append(addrs, addr.String())
+	}
+	return fmt.Sprintf("%v", addrs)
+}
+
+func (i *addressesFlag) Set(value string) error {
+	addr := addressFlag{}
+	if err := addr.Set(value); err != nil {
+		return err
+	}
+	i.value = append(i.value, addr.value)
+	return nil
+}
+
+func (i *addressesFlag) Type() string {
+	return "[]net.Addr"
+}
+
 type authoritiesFlag struct {
 	set   bool
 	value []rainsd.ZoneContext
@@ -418,3 +729,87 @@ func (i *authoritiesFlag) Set(value string) error {
 func (i *authoritiesFlag) Type() string {
 	return "[]zoneContext"
 }
+
+type checkPointEncodingFlag struct {
+	set   bool
+	value rainsd.CheckPointEncoding
+}
+
+func (i *checkPointEncodingFlag) String() string {
+	if i.set {
+		return i.value.String()
+	}
+	return rainsd.GobCheckPoint.String() //default
+}
+
+func (i *checkPointEncodingFlag) Set(value string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := i.value.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	i.set = true
+	return nil
+}
+
+func (i *checkPointEncodingFlag) Type() string {
+	return "checkPointEncoding"
+}
+
+type inconsistencyPolicyFlag struct {
+	set   bool
+	value rainsd.InconsistencyPolicy
+}
+
+func (i *inconsistencyPolicyFlag) String() string {
+	if i.set {
+		return i.value.String()
+	}
+	return rainsd.EvictConflicting.String() //default
+}
+
+func (i *inconsistencyPolicyFlag) Set(value string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := i.value.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	i.set = true
+	return nil
+}
+
+func (i *inconsistencyPolicyFlag) Type() string {
+	return "inconsistencyPolicy"
+}
+
+type selectionPolicyFlag struct {
+	set   bool
+	value libresolve.SelectionPolicy
+}
+
+func (i *selectionPolicyFlag) String() string {
+	if i.set {
+		return i.value.String()
+	}
+	return libresolve.Failover.String() //default
+}
+
+func (i *selectionPolicyFlag) Set(value string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := i.value.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	i.set = true
+	return nil
+}
+
+func (i *selectionPolicyFlag) Type() string {
+	return "selectionPolicy"
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
 	"github.com/netsec-ethz/rains/internal/pkg/object"
 	"github.com/netsec-ethz/rains/internal/pkg/query"
 	"github.com/netsec-ethz/rains/internal/pkg/token"
@@ -32,6 +34,10 @@ var insecureTLS = flag.BoolP("insecureTLS", "i", false,
 	"when set it does not check the validity of the server's TLS certificate. (default false)")
 var tok = flag.StringP("token", "t", "",
 	"specifies a token to be used in the query instead of using a randomly generated one.")
+var udp = flag.BoolP("udp", "u", false,
+	"when set the query is sent over plain UDP instead of TLS over TCP. (default false)")
+var reverse = flag.BoolP("reverse", "x", false,
+	"when set the name argument is an address or address prefix and a reverse lookup is performed. (default false)")
 
 //SCION settings
 var dispatcherSock = flag.String("dispatcherSock", "/run/shm/dispatcher/default.sock",
@@ -58,6 +64,8 @@ var maxAF = flag.BoolP("maxAF", "9", false, "Query option: Maximize answer fresh
 func init() {
 	flag.CommandLine.SortFlags = false
 	flag.Lookup("insecureTLS").NoOptDefVal = "true"
+	flag.Lookup("udp").NoOptDefVal = "true"
+	flag.Lookup("reverse").NoOptDefVal = "true"
 	flag.Lookup("minEE").NoOptDefVal = "true"
 	flag.Lookup("minAS").NoOptDefVal = "true"
 	flag.Lookup("minIL").NoOptDefVal = "true"
@@ -93,7 +101,11 @@ func main() {
 	serverAddr, err := snet.AddrFromString(fmt.Sprintf("%s:%d", server, *port))
 	if err != nil {
 		// was not a valid SCION address, try to parse it as a regular IP address
-		serverAddr, err = net.ResolveTCPAddr("", fmt.Sprintf("%s:%d", server, *port))
+		if *udp {
+			serverAddr, err = net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", server, *port))
+		} else {
+			serverAddr, err = net.ResolveTCPAddr("", fmt.Sprintf("%s:%d", server, *port))
+		}
 		if err != nil {
 			log.Fatalf("Error: serverAddr or port malformed: %v", err)
 		}
@@ -138,7 +150,20 @@ func main() {
 		}
 	}
 
-	msg := util.NewQueryMessage(name, *context, *expires, types, parseAllQueryOptions(), t)
+	var msg message.Message
+	if *reverse {
+		subjectAddr, err := address.Parse(name)
+		if err != nil {
+			log.Fatalf("Error: malformed address: %v", err)
+		}
+		if len(types) == 0 {
+			types = []object.Type{object.OTName}
+		}
+		msg = util.NewAddressQueryMessage(subjectAddr, *context, *expires, types,
+			parseAllQueryOptions(), t)
+	} else {
+		msg = util.NewQueryMessage(name, *context, *expires, types, parseAllQueryOptions(), t)
+	}
 
 	answerMsg, err := util.SendQuery(msg, serverAddr, time.Second)
 	if err != nil {
@@ -21,6 +21,7 @@ var config = publisher.DefaultConfig()
 var zonefilePath string
 var authServers addressesFlag
 var privateKeyPath string
+var infraKeyPath string
 var doSharding bool
 var keepShards bool
 var nofAssertionsPerShard int
@@ -45,9 +46,11 @@ var sortShards bool
 var sortZone bool
 var sigNotExpired bool
 var checkStringFields bool
+var checkNameset bool
 var doSigning bool
 var maxZoneSize int
 var outputPath string
+var masterFilePath string
 var doPublish bool
 
 var rootCmd = &cobra.Command{
@@ -79,6 +82,9 @@ func init() {
 	rootCmd.Flags().StringVar(&zonefilePath, "zonefilePath", "data/zonefiles/zf.txt", "Path to the zonefile")
 	rootCmd.Flags().StringVar(&privateKeyPath, "privateKeyPath", "data/keys/key_sec.pem", "Path to a file storing the private keys. "+
 		"Each line contains a key phase as integer and a private key encoded in hexadecimal separated by a space.")
+	rootCmd.Flags().StringVar(&infraKeyPath, "infraKeyPath", "", "Path to a directory storing the private "+
+		"infrastructure keys with which messages sent to the authoritative servers are signed. If empty, "+
+		"messages are not signed. (default \"\")")
 	rootCmd.Flags().BoolVar(&doSharding, "doSharding", true, "If set to true, all assertions in the zonefile "+
 		"are grouped into pshards based on keepPshards, nofAssertionsPerPshard, bFAlgo, BFHash, and "+
 		"bloomFilterSize parameters.")
@@ -129,7 +135,8 @@ func init() {
 		"signature lifetimes are uniformly spread out. (default 1 minute)")
 	rootCmd.Flags().BoolVar(&doConsistencyCheck, "doConsistencyCheck", true, "Performs all consistency checks "+
 		"if set to true. The check involves: sorting shards, sorting zones, checking that no signature "+
-		"is expired, and that all string fields contain no protocol keywords.")
+		"is expired, that all string fields contain no protocol keywords, and that all subject names "+
+		"are in the zone's nameset.")
 	rootCmd.Flags().BoolVar(&sortShards, "sortShards", false, "If set to true, makes sure that the assertions "+
 		"withing the shard are sorted.")
 	rootCmd.Flags().BoolVar(&sortZone, "sortZone", false, "If set to true, makes sure that the assertions "+
@@ -138,6 +145,9 @@ func init() {
 		"have a validUntil time in the future")
 	rootCmd.Flags().BoolVar(&checkStringFields, "checkStringFields", false, "If set to true, checks that none "+
 		"of the assertions' text fields contain protocol keywords.")
+	rootCmd.Flags().BoolVar(&checkNameset, "checkNameset", false, "If set to true, checks that the "+
+		"subject names of all assertions are matched by the nameset expressions published in the "+
+		"zone's assertions with subject name @.")
 	rootCmd.Flags().BoolVar(&doSigning, "doSigning", true, "If set to true, all sections with signature meta "+
 		"data are signed.")
 	rootCmd.Flags().IntVar(&maxZoneSize, "maxZoneSize", 60000, "this option only has an effect when doSigning is "+
@@ -145,6 +155,8 @@ func init() {
 		"not the zone itself.")
 	rootCmd.Flags().StringVar(&outputPath, "outputPath", "", "If not an empty string, a zonefile with the signed "+
 		"sections is generated and stored at the provided path. (default \"\")")
+	rootCmd.Flags().StringVar(&masterFilePath, "masterFilePath", "", "If not an empty string, the zone "+
+		"is exported as a DNS master file and stored at the provided path. (default \"\")")
 	rootCmd.Flags().BoolVar(&doPublish, "doPublish", true, "If set to true, sends the signed sections to all "+
 		"authoritative rains servers. If the zone is smaller than the maximum allowed size, the zone is "+
 		"sent. Otherwise, the zone section's content is sent separately such that the maximum message "+
@@ -176,6 +188,9 @@ func updateConfig(config *publisher.Config) {
 	if rootCmd.Flag("privateKeyPath").Changed {
 		config.PrivateKeyPath = privateKeyPath
 	}
+	if rootCmd.Flag("infraKeyPath").Changed {
+		config.InfraKeyPath = infraKeyPath
+	}
 	if rootCmd.Flag("keepShards").Changed {
 		config.ShardingConf.KeepShards = keepShards
 	}
@@ -248,6 +263,9 @@ func updateConfig(config *publisher.Config) {
 	if rootCmd.Flag("checkStringFields").Changed {
 		config.ConsistencyConf.CheckStringFields = checkStringFields
 	}
+	if rootCmd.Flag("checkNameset").Changed {
+		config.ConsistencyConf.CheckNameset = checkNameset
+	}
 	if rootCmd.Flag("doSigning").Changed {
 		config.DoSigning = doSigning
 	}
@@ -257,6 +275,9 @@ func updateConfig(config *publisher.Config) {
 	if rootCmd.Flag("outputPath").Changed {
 		config.OutputPath = outputPath
 	}
+	if rootCmd.Flag("masterFilePath").Changed {
+		config.MasterFilePath = masterFilePath
+	}
 	if rootCmd.Flag("doPublish").Changed {
 		config.DoPublish = doPublish
 	}
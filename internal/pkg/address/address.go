@@ -0,0 +1,198 @@
+//address provides the subject of address assertions, address zones and address queries which are
+//used for reverse lookups.
+
+package address
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	cbor "github.com/britram/borat"
+	"github.com/scionproto/scion/go/lib/addr"
+
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+)
+
+//Prefix is an IPv4 or IPv6 address prefix. If IA is not empty, the prefix is located in the SCION
+//AS identified by IA. A host address is a prefix with full length.
+type Prefix struct {
+	IA  string
+	Net *net.IPNet
+}
+
+//Parse returns the prefix represented by s. The prefix is either of the form 192.0.2.0/24 or
+//1-ff00:0:110,[192.0.2.0/24]. If the prefix length is omitted, s is a host address.
+func Parse(s string) (Prefix, error) {
+	p := Prefix{}
+	if i := strings.Index(s, ",["); i >= 0 && strings.HasSuffix(s, "]") {
+		ia, err := addr.IAFromString(s[:i])
+		if err != nil {
+			return Prefix{}, fmt.Errorf("malformed ISD-AS in address %s: %v", s, err)
+		}
+		p.IA = ia.String()
+		s = s[i+2 : len(s)-1]
+	}
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return Prefix{}, fmt.Errorf("malformed address: %s", s)
+		}
+		if strings.Contains(s, ":") {
+			s += "/128"
+		} else {
+			s += "/32"
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return Prefix{}, fmt.Errorf("malformed address prefix: %v", err)
+	}
+	if len(ipNet.IP) == net.IPv6len && ipNet.IP.To4() != nil {
+		//The textual representation of an IPv4-mapped IPv6 address is ambiguous.
+		return Prefix{}, fmt.Errorf("IPv4-mapped IPv6 addresses are not supported: %s", s)
+	}
+	p.Net = ipNet
+	return p, nil
+}
+
+//String returns the prefix in the format accepted by Parse.
+func (p Prefix) String() string {
+	if p.Net == nil {
+		return ""
+	}
+	if p.IA != "" {
+		return fmt.Sprintf("%s,[%s]", p.IA, p.Net)
+	}
+	return p.Net.String()
+}
+
+//Type returns the object type of the addresses contained in p.
+func (p Prefix) Type() object.Type {
+	switch {
+	case p.IsIPv4() && p.IA == "":
+		return object.OTIP4Addr
+	case p.IA == "":
+		return object.OTIP6Addr
+	case p.IsIPv4():
+		return object.OTScionAddr4
+	default:
+		return object.OTScionAddr6
+	}
+}
+
+//IsIPv4 returns true if p is an IPv4 prefix.
+func (p Prefix) IsIPv4() bool {
+	return p.Net != nil && len(p.Net.IP) == net.IPv4len
+}
+
+//Len returns the prefix length of p in bits.
+func (p Prefix) Len() int {
+	if p.Net == nil {
+		return 0
+	}
+	ones, _ := p.Net.Mask.Size()
+	return ones
+}
+
+//Contains returns true if all addresses of q are also contained in p.
+func (p Prefix) Contains(q Prefix) bool {
+	if p.Net == nil || q.Net == nil || p.IA != q.IA || p.IsIPv4() != q.IsIPv4() {
+		return false
+	}
+	return p.Len() <= q.Len() && p.Net.Contains(q.Net.IP)
+}
+
+//Truncate returns the prefix of length n containing p. It returns p if n is not shorter than p.
+func (p Prefix) Truncate(n int) Prefix {
+	if p.Net == nil || n < 0 || n >= p.Len() {
+		return p
+	}
+	mask := net.CIDRMask(n, 8*len(p.Net.IP))
+	return Prefix{IA: p.IA, Net: &net.IPNet{IP: p.Net.IP.Mask(mask), Mask: mask}}
+}
+
+//CompareTo compares two prefixes and returns 0 if they are equal, 1 if p is greater than q and -1
+//if p is smaller than q
+func (p Prefix) CompareTo(q Prefix) int {
+	if p.IA < q.IA {
+		return -1
+	} else if p.IA > q.IA {
+		return 1
+	} else if p.Net == nil || q.Net == nil {
+		if p.Net == q.Net {
+			return 0
+		} else if p.Net == nil {
+			return -1
+		}
+		return 1
+	} else if len(p.Net.IP) < len(q.Net.IP) {
+		return -1
+	} else if len(p.Net.IP) > len(q.Net.IP) {
+		return 1
+	} else if c := bytes.Compare(p.Net.IP, q.Net.IP); c != 0 {
+		return c
+	} else if p.Len() < q.Len() {
+		return -1
+	} else if p.Len() > q.Len() {
+		return 1
+	}
+	return 0
+}
+
+// MarshalCBOR implements the CBORMarshaler interface. A prefix is encoded as an array containing
+// its type, its length, its address and in case of a SCION prefix its ISD-AS.
+func (p Prefix) MarshalCBOR(w *cbor.CBORWriter) error {
+	if p.Net == nil {
+		return errors.New("address prefix is not set")
+	}
+	res := []interface{}{int(p.Type()), p.Len(), []byte(p.Net.IP)}
+	if p.IA != "" {
+		res = append(res, p.IA)
+	}
+	return w.WriteArray(res)
+}
+
+//UnmarshalArray takes in a CBOR decoded array and populates p.
+func (p *Prefix) UnmarshalArray(in []interface{}) error {
+	if len(in) < 3 {
+		return errors.New("cbor address prefix encoding must contain a type, length and address")
+	}
+	t, ok := in[0].(int)
+	if !ok {
+		return errors.New("cbor address prefix encoding of the type should be an int")
+	}
+	length, ok := in[1].(int)
+	if !ok {
+		return errors.New("cbor address prefix encoding of the length should be an int")
+	}
+	ip, ok := in[2].([]byte)
+	if !ok {
+		return errors.New("cbor address prefix encoding of the address should be a byte array")
+	}
+	p.IA = ""
+	switch object.Type(t) {
+	case object.OTIP4Addr, object.OTIP6Addr:
+	case object.OTScionAddr4, object.OTScionAddr6:
+		if len(in) < 4 {
+			return errors.New("cbor scion address prefix encoding does not contain an ISD-AS")
+		}
+		if p.IA, ok = in[3].(string); !ok {
+			return errors.New("cbor address prefix encoding of the ISD-AS should be a string")
+		}
+	default:
+		return fmt.Errorf("unsupported address prefix type: %d", t)
+	}
+	bits := 8 * net.IPv6len
+	if object.Type(t) == object.OTIP4Addr || object.Type(t) == object.OTScionAddr4 {
+		bits = 8 * net.IPv4len
+	}
+	if len(ip) != bits/8 || length < 0 || length > bits {
+		return fmt.Errorf("malformed cbor address prefix: length=%d address=%v", length, ip)
+	}
+	mask := net.CIDRMask(length, bits)
+	p.Net = &net.IPNet{IP: net.IP(ip).Mask(mask), Mask: mask}
+	return nil
+}
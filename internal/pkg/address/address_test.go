@@ -0,0 +1,145 @@
+package address
+
+import (
+	"bytes"
+	"testing"
+
+	cbor "github.com/britram/borat"
+
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+)
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		input   string
+		want    string
+		objType object.Type
+		length  int
+	}{
+		{"192.0.2.0/24", "192.0.2.0/24", object.OTIP4Addr, 24},
+		{"192.0.2.77/24", "192.0.2.0/24", object.OTIP4Addr, 24},
+		{"192.0.2.1", "192.0.2.1/32", object.OTIP4Addr, 32},
+		{"2001:db8::/32", "2001:db8::/32", object.OTIP6Addr, 32},
+		{"2001:db8::1", "2001:db8::1/128", object.OTIP6Addr, 128},
+		{"1-ff00:0:110,[192.0.2.0/24]", "1-ff00:0:110,[192.0.2.0/24]", object.OTScionAddr4, 24},
+		{"1-ff00:0:110,[2001:db8::1]", "1-ff00:0:110,[2001:db8::1/128]", object.OTScionAddr6, 128},
+	}
+	for i, test := range tests {
+		p, err := Parse(test.input)
+		if err != nil {
+			t.Fatalf("%d: Was not able to parse %s: %v", i, test.input, err)
+		}
+		if p.String() != test.want || p.Type() != test.objType || p.Len() != test.length {
+			t.Errorf("%d: Wrong prefix. expected=%s,%v,%d actual=%s,%v,%d", i, test.want,
+				test.objType, test.length, p, p.Type(), p.Len())
+		}
+	}
+	for i, input := range []string{"", "192.0.2", "192.0.2.0/33", "example.com", "::ffff:192.0.2.1",
+		"ff00:0:110,[192.0.2.0]",
+		"1-ff00:0:110,[192.0.2.0"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("%d: Malformed prefix was accepted: %s", i, input)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	var tests = []struct {
+		p, q string
+		want bool
+	}{
+		{"192.0.2.0/24", "192.0.2.1", true},
+		{"192.0.2.0/24", "192.0.2.0/24", true},
+		{"192.0.2.0/24", "192.0.2.0/23", false},
+		{"192.0.2.0/24", "198.51.100.1", false},
+		{"0.0.0.0/0", "198.51.100.1", true},
+		{"::/0", "198.51.100.1", false},
+		{"2001:db8::/32", "2001:db8::1", true},
+		{"192.0.2.0/24", "1-ff00:0:110,[192.0.2.1]", false},
+		{"1-ff00:0:110,[192.0.2.0/24]", "1-ff00:0:110,[192.0.2.1]", true},
+		{"1-ff00:0:110,[192.0.2.0/24]", "1-ff00:0:111,[192.0.2.1]", false},
+	}
+	for i, test := range tests {
+		p, _ := Parse(test.p)
+		q, _ := Parse(test.q)
+		if p.Contains(q) != test.want {
+			t.Errorf("%d: %s.Contains(%s) expected=%t actual=%t", i, p, q, test.want, p.Contains(q))
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	var tests = []struct {
+		p    string
+		n    int
+		want string
+	}{
+		{"192.0.2.77", 24, "192.0.2.0/24"},
+		{"192.0.2.77", 0, "0.0.0.0/0"},
+		{"192.0.2.0/24", 32, "192.0.2.0/24"},
+		{"2001:db8::1", 32, "2001:db8::/32"},
+		{"1-ff00:0:110,[192.0.2.77]", 25, "1-ff00:0:110,[192.0.2.0/25]"},
+	}
+	for i, test := range tests {
+		p, _ := Parse(test.p)
+		if p.Truncate(test.n).String() != test.want {
+			t.Errorf("%d: Wrong truncated prefix. expected=%s actual=%s", i, test.want,
+				p.Truncate(test.n))
+		}
+	}
+}
+
+func TestCompareTo(t *testing.T) {
+	sorted := []string{"192.0.2.0/23", "192.0.2.0/24", "192.0.2.1", "198.51.100.0/24",
+		"2001:db8::/32", "1-ff00:0:110,[192.0.2.0/24]"}
+	for i := 0; i < len(sorted); i++ {
+		for j := 0; j < len(sorted); j++ {
+			p, _ := Parse(sorted[i])
+			q, _ := Parse(sorted[j])
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if p.CompareTo(q) != want {
+				t.Errorf("%s.CompareTo(%s) expected=%d actual=%d", p, q, want, p.CompareTo(q))
+			}
+		}
+	}
+}
+
+func TestCBOR(t *testing.T) {
+	for i, input := range []string{"192.0.2.0/24", "2001:db8::1", "1-ff00:0:110,[192.0.2.0/24]",
+		"1-ff00:0:110,[2001:db8::/48]", "0.0.0.0/0"} {
+		p, _ := Parse(input)
+		encoding := new(bytes.Buffer)
+		if err := cbor.NewCBORWriter(encoding).WriteIntMap(map[int]interface{}{5: p}); err != nil {
+			t.Fatalf("%d: Was not able to marshal %s: %v", i, p, err)
+		}
+		m, err := cbor.NewCBORReader(encoding).ReadIntMapUntagged()
+		if err != nil {
+			t.Fatalf("%d: Was not able to read map: %v", i, err)
+		}
+		var decoded Prefix
+		if err := decoded.UnmarshalArray(m[5].([]interface{})); err != nil {
+			t.Fatalf("%d: Was not able to unmarshal %s: %v", i, p, err)
+		}
+		if decoded.CompareTo(p) != 0 {
+			t.Errorf("%d: Wrong decoded prefix. expected=%s actual=%s", i, p, decoded)
+		}
+	}
+	var tests = [][]interface{}{
+		{int(object.OTIP4Addr), 24},
+		{int(object.OTIP4Addr), 33, []byte{192, 0, 2, 0}},
+		{int(object.OTIP6Addr), 24, []byte{192, 0, 2, 0}},
+		{int(object.OTScionAddr4), 24, []byte{192, 0, 2, 0}},
+		{int(object.OTName), 24, []byte{192, 0, 2, 0}},
+	}
+	for i, test := range tests {
+		var p Prefix
+		if err := p.UnmarshalArray(test); err == nil {
+			t.Errorf("%d: Malformed encoding was accepted: %v", i, test)
+		}
+	}
+}
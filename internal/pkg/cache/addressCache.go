@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/datastructures/safeCounter"
+	"github.com/netsec-ethz/rains/internal/pkg/lruCache"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+//addressCacheValue is the value stored in the AddressImpl.cache. It holds all address assertions
+//and address zones of a prefix and context.
+type addressCacheValue struct {
+	assertions map[string]addressExpiration //section.Hash -> addressExpiration
+	zones      map[string]addressExpiration //section.Hash -> addressExpiration
+	cacheKey   string
+	deleted    bool
+	//mux protects deleted, assertions and zones from simultaneous access.
+	mux sync.RWMutex
+}
+
+type addressExpiration struct {
+	section    section.WithSig
+	expiration int64
+}
+
+/*
+ * address cache implementation
+ * Address assertions and address zones are stored by their prefix and context. A lookup tries all
+ * prefixes containing the subject address from the longest to the shortest one.
+ */
+type AddressImpl struct {
+	cache   *lruCache.Cache
+	counter *safeCounter.Counter
+}
+
+func NewAddress(maxSize int) *AddressImpl {
+	return &AddressImpl{
+		cache:   lruCache.New(),
+		counter: safeCounter.New(maxSize),
+	}
+}
+
+//addressCacheMapKey returns the key for AddressImpl.cache based on the prefix and context
+func addressCacheMapKey(prefix address.Prefix, context string) string {
+	return fmt.Sprintf("%s %s", prefix, context)
+}
+
+//AddAssertion adds an address assertion together with an expiration time (number of seconds since
+//01.01.1970) to the cache. It returns false if the cache is full and an element was removed
+//according to least recently used strategy.
+func (c *AddressImpl) AddAssertion(a *section.AddressAssertion, expiration int64,
+	isInternal bool) bool {
+	return c.add(a, a.SubjectAddr, a.Context, expiration, isInternal, false)
+}
+
+//AddZone adds an address zone together with an expiration time (number of seconds since
+//01.01.1970) to the cache. It returns false if the cache is full and an element was removed
+//according to least recently used strategy.
+func (c *AddressImpl) AddZone(z *section.AddressZone, expiration int64, isInternal bool) bool {
+	return c.add(z, z.SubjectAddr, z.Context, expiration, isInternal, true)
+}
+
+func (c *AddressImpl) add(s section.WithSig, prefix address.Prefix, context string,
+	expiration int64, isInternal, isZone bool) bool {
+	key := addressCacheMapKey(prefix, context)
+	cacheValue := addressCacheValue{
+		assertions: make(map[string]addressExpiration),
+		zones:      make(map[string]addressExpiration),
+		cacheKey:   key,
+	}
+	v, _ := c.cache.GetOrAdd(key, &cacheValue, isInternal)
+	value := v.(*addressCacheValue)
+	value.mux.Lock()
+	if value.deleted {
+		value.mux.Unlock()
+		return c.add(s, prefix, context, expiration, isInternal, isZone)
+	}
+	sections := value.assertions
+	if isZone {
+		sections = value.zones
+	}
+	isFull := false
+	if _, ok := sections[s.Hash()]; !ok {
+		sections[s.Hash()] = addressExpiration{section: s, expiration: expiration}
+		isFull = c.counter.Inc()
+	}
+	value.mux.Unlock()
+	c.removeLeastRecentlyUsed()
+	return !isFull
+}
+
+//removeLeastRecentlyUsed removes elements according to lru strategy until the cache is not full.
+func (c *AddressImpl) removeLeastRecentlyUsed() {
+	for c.counter.IsFull() {
+		key, value := c.cache.GetLeastRecentlyUsed()
+		if value == nil {
+			break
+		}
+		v := value.(*addressCacheValue)
+		v.mux.Lock()
+		if v.deleted {
+			v.mux.Unlock()
+			continue
+		}
+		v.deleted = true
+		c.cache.Remove(key)
+		c.counter.Sub(len(v.assertions) + len(v.zones))
+		v.mux.Unlock()
+	}
+}
+
+//Get returns true and the cached sections with the longest prefix containing subjectAddr in
+//context. Address assertions are only returned if they contain an object of one of types. If
+//there is no such address assertion for a prefix, the address zones of the prefix are returned.
+//Otherwise nil and false is returned.
+func (c *AddressImpl) Get(subjectAddr address.Prefix, context string, types []object.Type) (
+	[]section.WithSig, bool) {
+	if subjectAddr.Net == nil {
+		return nil, false
+	}
+	for n := subjectAddr.Len(); n >= 0; n-- {
+		v, ok := c.cache.Get(addressCacheMapKey(subjectAddr.Truncate(n), context))
+		if !ok {
+			continue
+		}
+		value := v.(*addressCacheValue)
+		value.mux.RLock()
+		if value.deleted {
+			value.mux.RUnlock()
+			continue
+		}
+		var sections []section.WithSig
+		for _, av := range value.assertions {
+			if containsType(av.section.(*section.AddressAssertion), types) {
+				sections = append(sections, av.section)
+			}
+		}
+		if len(sections) == 0 {
+			for _, av := range value.zones {
+				sections = append(sections, av.section)
+			}
+		}
+		value.mux.RUnlock()
+		if len(sections) > 0 {
+			return sections, true
+		}
+	}
+	return nil, false
+}
+
+//containsType returns true if a contains an object of one of types or if types is empty.
+func containsType(a *section.AddressAssertion, types []object.Type) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, o := range a.Content {
+		for _, t := range types {
+			if o.Type == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//RemoveExpiredValues goes through the cache and removes all expired address assertions and
+//address zones.
+func (c *AddressImpl) RemoveExpiredValues() {
+	for _, v := range c.cache.GetAll() {
+		value := v.(*addressCacheValue)
+		deleteCount := 0
+		value.mux.Lock()
+		if value.deleted {
+			value.mux.Unlock()
+			continue
+		}
+		for _, sections := range []map[string]addressExpiration{value.assertions, value.zones} {
+			for key, va := range sections {
+				if va.expiration < time.Now().Unix() {
+					delete(sections, key)
+					deleteCount++
+				}
+			}
+		}
+		if len(value.assertions) == 0 && len(value.zones) == 0 {
+			value.deleted = true
+			c.cache.Remove(value.cacheKey)
+		}
+		value.mux.Unlock()
+		c.counter.Sub(deleteCount)
+	}
+}
+
+//Checkpoint returns all cached address assertions and address zones
+func (c *AddressImpl) Checkpoint() (sections []section.Section) {
+	for _, e := range c.cache.GetAll() {
+		value := e.(*addressCacheValue)
+		value.mux.RLock()
+		if !value.deleted {
+			for _, v := range value.assertions {
+				sections = append(sections, v.section)
+			}
+			for _, v := range value.zones {
+				sections = append(sections, v.section)
+			}
+		}
+		value.mux.RUnlock()
+	}
+	return
+}
+
+//Resize sets the maximum number of address assertions and address zones in the cache to maxSize.
+//If the cache holds more elements, they are removed according to least recently used strategy.
+func (c *AddressImpl) Resize(maxSize int) {
+	if c.counter.SetMaxCount(maxSize) {
+		c.removeLeastRecentlyUsed()
+	}
+}
+
+//Len returns the number of elements in the cache.
+func (c *AddressImpl) Len() int {
+	return c.counter.Value()
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+func TestAddressCache(t *testing.T) {
+	c := NewAddress(4)
+	exp := time.Now().Add(time.Hour).Unix()
+	zone := getAddressZone("192.0.2.0/24", "192.0.2.1")
+	deleg := getAddressAssertion("192.0.2.0/24", object.OTDelegation)
+	host := getAddressAssertion("192.0.2.7", object.OTName)
+	if !c.AddZone(zone, exp, false) || !c.AddAssertion(deleg, exp, false) ||
+		!c.AddAssertion(host, exp, false) || c.Len() != 3 {
+		t.Fatalf("Sections were not added to cache. len=%d", c.Len())
+	}
+	var tests = []struct {
+		subject string
+		context string
+		types   []object.Type
+		want    section.WithSig
+	}{
+		{"192.0.2.7", ".", []object.Type{object.OTName}, host},
+		{"192.0.2.7", ".", nil, host},
+		{"192.0.2.8", ".", []object.Type{object.OTName}, zone},
+		{"192.0.2.8", ".", []object.Type{object.OTDelegation}, deleg},
+		{"192.0.2.0/25", ".", []object.Type{object.OTDelegation}, deleg},
+		{"192.0.2.7", "other", []object.Type{object.OTName}, nil},
+		{"192.0.3.7", ".", []object.Type{object.OTName}, nil},
+		{"1-ff00:0:110,[192.0.2.7]", ".", []object.Type{object.OTName}, nil},
+	}
+	for i, test := range tests {
+		subject, _ := address.Parse(test.subject)
+		sections, ok := c.Get(subject, test.context, test.types)
+		if test.want == nil {
+			if ok {
+				t.Errorf("%d: Unexpected sections returned: %v", i, sections)
+			}
+		} else if !ok || len(sections) != 1 || sections[0] != test.want {
+			t.Errorf("%d: Wrong sections. expected=%v actual=%v", i, test.want, sections)
+		}
+	}
+	if len(c.Checkpoint()) != 3 {
+		t.Errorf("Wrong checkpoint length. expected=3 actual=%d", len(c.Checkpoint()))
+	}
+	//The least recently used entry is the one of host. The cache holds less than maxSize elements.
+	c.Resize(3)
+	if c.Len() != 2 {
+		t.Errorf("Resize did not remove elements. expected=2 actual=%d", c.Len())
+	}
+	if sections, ok := c.Get(host.SubjectAddr, ".", []object.Type{object.OTName}); !ok ||
+		sections[0] != zone {
+		t.Errorf("Wrong sections after resize. expected=%v actual=%v", zone, sections)
+	}
+	c.Resize(4)
+	c.AddAssertion(getAddressAssertion("198.51.100.1", object.OTName), time.Now().Unix()-1,
+		false)
+	c.RemoveExpiredValues()
+	if c.Len() != 2 {
+		t.Errorf("Expired element was not removed. expected=2 actual=%d", c.Len())
+	}
+}
+
+func getAddressAssertion(subject string, t object.Type) *section.AddressAssertion {
+	prefix, _ := address.Parse(subject)
+	a := &section.AddressAssertion{SubjectAddr: prefix, Context: "."}
+	switch t {
+	case object.OTName:
+		a.Content = []object.Object{{Type: object.OTName, Value: object.Name{Name: "example.com.",
+			Types: []object.Type{object.OTIP4Addr}}}}
+	case object.OTDelegation:
+		a.Content = []object.Object{{Type: object.OTDelegation, Value: getExampleDelgations(
+			"ch")[0].Content[0].Value}}
+	}
+	return a
+}
+
+func getAddressZone(subject, content string) *section.AddressZone {
+	prefix, _ := address.Parse(subject)
+	a := getAddressAssertion(content, object.OTName)
+	a.Context = ""
+	return &section.AddressZone{SubjectAddr: prefix, Context: ".",
+		Content: []*section.AddressAssertion{a}}
+}
@@ -77,7 +77,7 @@ func assertionCacheMapKeyFQDN(fqdn, context string, oType object.Type) string {
 
 //Add adds an assertion together with an expiration time (number of seconds since 01.01.1970) to
 //the cache. It returns false if the cache is full and an element was removed according to least
-//recently used strategy. It also adds the shard to the consistency cache.
+//recently used strategy. It also adds the assertion to the zone index.
 func (c *AssertionImpl) Add(a *section.Assertion, expiration int64, isInternal bool) bool {
 	isFull := false
 	for _, o := range a.Content {
@@ -107,7 +107,12 @@ func (c *AssertionImpl) Add(a *section.Assertion, expiration int64, isInternal b
 		}
 		value.mux.Unlock()
 	}
-	//Remove elements according to lru strategy
+	c.removeLeastRecentlyUsed()
+	return !isFull
+}
+
+//removeLeastRecentlyUsed removes elements according to lru strategy until the cache is not full.
+func (c *AssertionImpl) removeLeastRecentlyUsed() {
 	for c.counter.IsFull() {
 		key, value := c.cache.GetLeastRecentlyUsed()
 		if value == nil {
@@ -132,7 +137,14 @@ func (c *AssertionImpl) Add(a *section.Assertion, expiration int64, isInternal b
 		c.counter.Sub(len(v.assertions))
 		v.mux.Unlock()
 	}
-	return !isFull
+}
+
+//Resize sets the maximum number of assertions in the cache to maxSize. If the cache holds more
+//assertions, elements are removed according to least recently used strategy.
+func (c *AssertionImpl) Resize(maxSize int) {
+	if c.counter.SetMaxCount(maxSize) {
+		c.removeLeastRecentlyUsed()
+	}
 }
 
 // zoneHierarchy returns a slice of domain names upto the root to try and find a match in the cache.
@@ -186,8 +198,39 @@ func (c *AssertionImpl) Get(fqdn, context string, objType object.Type, strict bo
 	return assertions, len(assertions) > 0
 }
 
+//GetInRange returns true and all assertions of subjectZone and context whose subject name is
+//within interval if there exist some. Otherwise nil and false is returned.
+func (c *AssertionImpl) GetInRange(subjectZone, context string, interval section.Interval) (
+	[]*section.Assertion, bool) {
+	set, ok := c.zoneMap.Get(subjectZone)
+	if !ok {
+		return nil, false
+	}
+	var assertions []*section.Assertion
+	seen := make(map[string]bool)
+	for _, key := range set.(*safeHashMap.Map).GetAllKeys() {
+		v, ok := c.cache.Get(key)
+		if !ok {
+			continue
+		}
+		value := v.(*assertionCacheValue)
+		value.mux.RLock()
+		if !value.deleted {
+			for hash, av := range value.assertions {
+				if !seen[hash] && av.assertion.Context == context &&
+					section.Intersect(interval, av.assertion) {
+					seen[hash] = true
+					assertions = append(assertions, av.assertion)
+				}
+			}
+		}
+		value.mux.RUnlock()
+	}
+	return assertions, len(assertions) > 0
+}
+
 //RemoveExpiredValues goes through the cache and removes all expired assertions from the
-//assertionCache and the consistency cache.
+//assertionCache and the zone index.
 func (c *AssertionImpl) RemoveExpiredValues() {
 	for _, v := range c.cache.GetAll() {
 		value := v.(*assertionCacheValue)
@@ -218,7 +261,36 @@ func (c *AssertionImpl) RemoveExpiredValues() {
 	}
 }
 
-//RemoveZone deletes all assertions in the assertionCache and consistencyCache of the given zone.
+//Remove deletes assertion from the assertionCache and the zone index.
+func (c *AssertionImpl) Remove(a *section.Assertion) {
+	hash := a.Hash()
+	for _, o := range a.Content {
+		key := assertionCacheMapKey(a.SubjectName, a.SubjectZone, a.Context, o.Type)
+		v, ok := c.cache.Get(key)
+		if !ok {
+			continue
+		}
+		value := v.(*assertionCacheValue)
+		value.mux.Lock()
+		if _, ok := value.assertions[hash]; ok && !value.deleted {
+			delete(value.assertions, hash)
+			c.mux.Lock()
+			c.entriesPerAssertionMap[hash]--
+			c.mux.Unlock()
+			c.counter.Dec()
+			if len(value.assertions) == 0 {
+				value.deleted = true
+				c.cache.Remove(value.cacheKey)
+				if set, ok := c.zoneMap.Get(value.zone); ok {
+					set.(*safeHashMap.Map).Remove(value.cacheKey)
+				}
+			}
+		}
+		value.mux.Unlock()
+	}
+}
+
+//RemoveZone deletes all assertions in the assertionCache and the zone index of the given zone.
 func (c *AssertionImpl) RemoveZone(zone string) {
 	if set, ok := c.zoneMap.Remove(zone); ok {
 		for _, key := range set.(*safeHashMap.Map).GetAllKeys() {
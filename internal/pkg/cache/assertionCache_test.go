@@ -10,6 +10,7 @@ import (
 	"github.com/netsec-ethz/rains/internal/pkg/datastructures/safeHashMap"
 	"github.com/netsec-ethz/rains/internal/pkg/lruCache"
 	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
 )
 
 func TestAssertionCache(t *testing.T) {
@@ -117,6 +118,35 @@ func TestAssertionCache(t *testing.T) {
 	}
 }
 
+func TestAssertionGetInRangeAndRemove(t *testing.T) {
+	c := NewAssertion(10)
+	assertions := getAssertions()
+	for _, a := range assertions {
+		a.Content = []object.Object{object.Object{Type: object.OTIP4Addr, Value: "192.0.2.0"}}
+		c.Add(a, time.Now().Add(time.Hour).Unix(), false)
+	}
+	a, ok := c.GetInRange("ch", ".", getShards()[0])
+	if !ok || len(a) != 1 || a[0] != assertions[0] {
+		t.Errorf("Wrong assertions in range [a,c] expected=%v actual=%v", assertions[0], a)
+	}
+	a, ok = c.GetInRange("ch", ".", section.TotalInterval{})
+	if !ok || len(a) != 2 {
+		t.Errorf("Wrong number of assertions in total interval expected=2 actual=%d", len(a))
+	}
+	if a, ok = c.GetInRange("org", "test-cch", getShards()[1]); ok || len(a) != 0 {
+		t.Errorf("Context is not considered actual=%v", a)
+	}
+	c.Remove(assertions[0])
+	if a, ok = c.GetInRange("ch", ".", getShards()[0]); ok || c.Len() != 3 {
+		t.Errorf("Assertion was not removed len=%d actual=%v", c.Len(), a)
+	}
+	//removing an assertion which is not cached has no effect
+	c.Remove(assertions[0])
+	if c.Len() != 3 {
+		t.Errorf("Removing a non cached assertion changed the cache size len=%d", c.Len())
+	}
+}
+
 func TestAssertionCheckpoint(t *testing.T) {
 	var tests = []struct {
 		input Assertion
@@ -153,3 +183,22 @@ func TestAssertionCheckpoint(t *testing.T) {
 		}
 	}
 }
+
+func TestAssertionResize(t *testing.T) {
+	c := NewAssertion(10)
+	delegationsCH := getExampleDelgations("ch")
+	delegationsORG := getExampleDelgations("org")
+	c.Add(delegationsCH[0], time.Now().Add(time.Hour).Unix(), false)
+	c.Add(delegationsORG[0], time.Now().Add(time.Hour).Unix(), true)
+	c.Resize(20)
+	if c.Len() != 2 {
+		t.Errorf("entries were removed when growing the cache. actual=%d", c.Len())
+	}
+	c.Resize(1)
+	if c.Len() != 1 {
+		t.Errorf("least recently used entry was not removed. actual=%d", c.Len())
+	}
+	if _, ok := c.Get(delegationsORG[0].FQDN(), ".", delegationsORG[0].Content[0].Type, true); !ok {
+		t.Error("internal entry was removed")
+	}
+}
@@ -1,7 +1,6 @@
 package cache
 
 import (
-	"crypto/sha256"
 	"fmt"
 	"sort"
 
@@ -24,24 +23,20 @@ func NewCapability(maxSize int) *CapabilityImpl {
 		capabilityMap: lruCache.New(),
 		counter:       safeCounter.New(maxSize),
 	}
-	cache.capabilityMap.GetOrAdd("e5365a09be554ae55b855f15264dbc837b04f5831daeb321359e18cdabab5745",
-		[]message.Capability{message.TLSOverTCP}, true)
-	cache.capabilityMap.GetOrAdd("76be8b528d0075f7aae98d6fa57a6d3c83ae480a8469e668d7b0af968995ac71",
-		[]message.Capability{message.NoCapability}, false)
+	tlsOverTCP := []message.Capability{message.TLSOverTCP}
+	noCapability := []message.Capability{message.NoCapability}
+	cache.capabilityMap.GetOrAdd(message.CapabilityHash(tlsOverTCP), tlsOverTCP, true)
+	cache.capabilityMap.GetOrAdd(message.CapabilityHash(noCapability), noCapability, false)
 	cache.counter.Add(2)
 	return cache
 }
 
+//Add stores capabilities under the hash returned by message.CapabilityHash.
 func (c *CapabilityImpl) Add(capabilities []message.Capability) {
-	//FIXME CFE take a SHA-256 hash of the CBOR byte stream derived from normalizing such an array by sorting it in lexicographically increasing order,
-	//then serializing it and add it to the cache
-	sort.Slice(capabilities, func(i, j int) bool { return capabilities[i] < capabilities[j] })
-	cs := []byte{}
-	for _, c := range capabilities {
-		cs = append(cs, []byte(c)...)
-	}
-	hash := sha256.Sum256(cs)
-	_, ok := c.capabilityMap.GetOrAdd(string(hash[:]), capabilities, false)
+	caps := make([]message.Capability, len(capabilities))
+	copy(caps, capabilities)
+	sort.Slice(caps, func(i, j int) bool { return caps[i] < caps[j] })
+	_, ok := c.capabilityMap.GetOrAdd(message.CapabilityHash(caps), caps, false)
 	//handle full cache
 	if ok && c.counter.Inc() {
 		for {
@@ -65,6 +60,12 @@ func (c *CapabilityImpl) Get(hash []byte) ([]message.Capability, bool) {
 	return nil, false
 }
 
+//Resize sets the maximum number of capability lists in the cache to maxSize. Surplus lists are
+//removed when the next list is added.
+func (c *CapabilityImpl) Resize(maxSize int) {
+	c.counter.SetMaxCount(maxSize)
+}
+
 func (c *CapabilityImpl) Len() int {
 	return c.counter.Value()
 }
@@ -4,31 +4,20 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/netsec-ethz/rains/internal/pkg/datastructures/safeCounter"
-	"github.com/netsec-ethz/rains/internal/pkg/lruCache"
 	"github.com/netsec-ethz/rains/internal/pkg/message"
 )
 
 func TestCapabilityCache(t *testing.T) {
-	//TODO CFE remove these manually added entries once there is a working add implementation
-	cache := lruCache.New()
-	cache.GetOrAdd("e5365a09be554ae55b855f15264dbc837b04f5831daeb321359e18cdabab5745",
-		[]message.Capability{message.TLSOverTCP}, true)
-	cache.GetOrAdd("76be8b528d0075f7aae98d6fa57a6d3c83ae480a8469e668d7b0af968995ac71",
-		[]message.Capability{message.NoCapability}, false)
-	counter := safeCounter.New(10)
-	counter.Add(2)
 	var tests = []struct {
 		input Capability
 	}{
-		{&CapabilityImpl{capabilityMap: cache, counter: counter}},
+		{NewCapability(4)},
 	}
 	for i, test := range tests {
 		c := test.input
 		if c.Len() != 2 {
 			t.Error("init size is incorrect", "size", c.Len())
 		}
-		//TODO CFE test add when it is correctly implemented.
 		caps, ok := c.Get([]byte("e5365a09be554ae55b855f15264dbc837b04f5831daeb321359e18cdabab5745"))
 		if !ok {
 			t.Errorf("%d: Get did not returned contained element.", i)
@@ -36,12 +25,37 @@ func TestCapabilityCache(t *testing.T) {
 		if !reflect.DeepEqual(caps, []message.Capability{message.TLSOverTCP}) {
 			t.Errorf("%d: Returned element is wrong", i)
 		}
-		caps, ok = c.Get([]byte("76be8b528d0075f7aae98d6fa57a6d3c83ae480a8469e668d7b0af968995ac71"))
+		caps, ok = c.Get([]byte(message.CapabilityHash([]message.Capability{message.NoCapability})))
 		if !ok {
 			t.Errorf("%d: Get did not returned contained element.", i)
 		}
 		if !reflect.DeepEqual(caps, []message.Capability{message.NoCapability}) {
 			t.Errorf("%d: Returned element is wrong", i)
 		}
+		//add capability list in unsorted order
+		list := []message.Capability{message.TLSOverTCP, message.Capability("urn:x-rains:test")}
+		c.Add(list)
+		if c.Len() != 3 {
+			t.Errorf("%d: Add did not increase size, size=%d", i, c.Len())
+		}
+		caps, ok = c.Get([]byte(message.CapabilityHash(list)))
+		if !ok {
+			t.Errorf("%d: Get did not return added element.", i)
+		}
+		if !reflect.DeepEqual(caps, []message.Capability{message.Capability("urn:x-rains:test"),
+			message.TLSOverTCP}) {
+			t.Errorf("%d: Returned element is not sorted, caps=%v", i, caps)
+		}
+		if list[0] != message.TLSOverTCP {
+			t.Errorf("%d: Add modified the input list", i)
+		}
+		//full cache evicts the least recently used non internal entry
+		c.Add([]message.Capability{message.Capability("urn:x-rains:test2")})
+		if c.Len() != 3 {
+			t.Errorf("%d: Size after eviction is incorrect, size=%d", i, c.Len())
+		}
+		if _, ok = c.Get([]byte(message.CapabilityHash([]message.Capability{message.TLSOverTCP}))); !ok {
+			t.Errorf("%d: Internal element was evicted", i)
+		}
 	}
 }
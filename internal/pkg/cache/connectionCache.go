@@ -4,16 +4,25 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/netsec-ethz/rains/internal/pkg/datastructures/safeCounter"
 	"github.com/netsec-ethz/rains/internal/pkg/lruCache"
 	"github.com/netsec-ethz/rains/internal/pkg/message"
 )
 
+//liveness contains the time when a message was last received on a connection and the number of
+//heartbeats sent on it since then.
+type liveness struct {
+	lastSeen time.Time
+	missed   int
+}
+
 //connCacheValue is the value pointed to by the hash map in the ConnectionImpl
 type connCacheValue struct {
 	connections  []net.Conn
 	capabilities []message.Capability
+	liveness     map[net.Conn]*liveness
 
 	mux sync.RWMutex
 	//set to true if the pointer to this element is removed from the hash map
@@ -41,11 +50,12 @@ func networkAddr(addr net.Addr) string {
 
 //AddConnection adds conn to the cache. If the cache is full the least recently used connection is removed.
 func (c *ConnectionImpl) AddConnection(conn net.Conn) {
-	v := &connCacheValue{connections: []net.Conn{}}
+	v := &connCacheValue{connections: []net.Conn{}, liveness: make(map[net.Conn]*liveness)}
 	e, _ := c.cache.GetOrAdd(networkAddr(conn.RemoteAddr()), v, false)
 	value := e.(*connCacheValue)
 	value.mux.Lock()
 	value.connections = append(value.connections, conn)
+	value.liveness[conn] = &liveness{lastSeen: time.Now()}
 	value.mux.Unlock()
 	if c.counter.Inc() {
 		//cache is full, remove all connections from the least recently used destination
@@ -131,6 +141,7 @@ func (c *ConnectionImpl) CloseAndRemoveConnection(conn net.Conn) {
 				for i, connection := range v.connections {
 					if connection == conn {
 						v.connections = append((v.connections)[:i], (v.connections)[i+1:]...)
+						delete(v.liveness, conn)
 						c.counter.Dec()
 					}
 				}
@@ -180,6 +191,81 @@ func (c *ConnectionImpl) CloseAndRemoveAllConnections() {
 	}
 }
 
+//MarkSeen records that a message has been received on conn and resets its number of missed
+//heartbeats.
+func (c *ConnectionImpl) MarkSeen(conn net.Conn) {
+	if e, ok := c.cache.Get(networkAddr(conn.RemoteAddr())); ok {
+		v := e.(*connCacheValue)
+		v.mux.Lock()
+		defer v.mux.Unlock()
+		if l, ok := v.liveness[conn]; ok {
+			l.lastSeen = time.Now()
+			l.missed = 0
+		}
+	}
+}
+
+//Probe closes and removes all connections on which nothing has been received although maxMissed
+//heartbeats have been sent on them and returns them as evicted. It returns all other connections
+//on which nothing has been received during idle as probe and counts a sent heartbeat for each of
+//them.
+func (c *ConnectionImpl) Probe(idle time.Duration, maxMissed int) (probe, evicted []net.Conn) {
+	probe, evicted = []net.Conn{}, []net.Conn{}
+	now := time.Now()
+	for _, e := range c.cache.GetAll() {
+		v := e.(*connCacheValue)
+		v.mux.Lock()
+		if !v.deleted {
+			for _, conn := range v.connections {
+				l, ok := v.liveness[conn]
+				if !ok || now.Sub(l.lastSeen) < idle {
+					continue
+				}
+				if l.missed >= maxMissed {
+					evicted = append(evicted, conn)
+				} else {
+					l.missed++
+					probe = append(probe, conn)
+				}
+			}
+		}
+		v.mux.Unlock()
+	}
+	for _, conn := range evicted {
+		c.CloseAndRemoveConnection(conn)
+	}
+	return probe, evicted
+}
+
+//Info returns the remote address, the time when a message was last received and the number of
+//missed heartbeats of all cached connections.
+func (c *ConnectionImpl) Info() []ConnectionInfo {
+	infos := []ConnectionInfo{}
+	for _, e := range c.cache.GetAll() {
+		v := e.(*connCacheValue)
+		v.mux.RLock()
+		if !v.deleted {
+			for _, conn := range v.connections {
+				if l, ok := v.liveness[conn]; ok {
+					infos = append(infos, ConnectionInfo{
+						RemoteAddr:       conn.RemoteAddr(),
+						LastSeen:         l.lastSeen,
+						MissedHeartbeats: l.missed,
+					})
+				}
+			}
+		}
+		v.mux.RUnlock()
+	}
+	return infos
+}
+
+//Resize sets the maximum number of connections in the cache to maxSize. Surplus connections are
+//removed when the next connection is added.
+func (c *ConnectionImpl) Resize(maxSize int) {
+	c.counter.SetMaxCount(maxSize)
+}
+
 func (c *ConnectionImpl) Len() int {
 	return c.counter.Value()
 }
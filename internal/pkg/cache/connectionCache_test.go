@@ -94,6 +94,44 @@ func TestConnectionCache(t *testing.T) {
 	}
 }
 
+func TestConnectionLiveness(t *testing.T) {
+	c := NewConnection(10)
+	conn1, _ := net.Pipe()
+	conn2, _ := net.Pipe()
+	c.AddConnection(conn1)
+	c.AddConnection(conn2)
+	if probe, evicted := c.Probe(time.Hour, 2); len(probe) != 0 || len(evicted) != 0 {
+		t.Errorf("Active connections were probed or evicted: %v %v", probe, evicted)
+	}
+	for i := 1; i <= 2; i++ {
+		if probe, _ := c.Probe(0, 2); len(probe) != 2 {
+			t.Fatalf("%d: expected both idle connections to be probed, got %d", i, len(probe))
+		}
+		for _, info := range c.Info() {
+			if info.MissedHeartbeats != i {
+				t.Errorf("%d: wrong number of missed heartbeats. expected=%d actual=%d", i, i,
+					info.MissedHeartbeats)
+			}
+		}
+	}
+	before := time.Now()
+	c.MarkSeen(conn2)
+	probe, evicted := c.Probe(0, 2)
+	if len(probe) != 1 || probe[0] != conn2 {
+		t.Errorf("Expected only the seen connection to be probed, got %v", probe)
+	}
+	if len(evicted) != 1 || evicted[0] != conn1 {
+		t.Errorf("Expected the other connection to be evicted, got %v", evicted)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Connection which missed all heartbeats was not removed. Len=%d", c.Len())
+	}
+	infos := c.Info()
+	if len(infos) != 1 || infos[0].LastSeen.Before(before) || infos[0].MissedHeartbeats != 1 {
+		t.Errorf("Wrong connection info: %v", infos)
+	}
+}
+
 func mockServer(tcpAddr string, t *testing.T) {
 	ln, err := net.Listen("tcp", tcpAddr)
 	if err != nil {
@@ -0,0 +1,368 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/cbor"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+const (
+	//recordHeaderSize is the size of the length and the checksum preceding each record.
+	recordHeaderSize = 8
+	//minCompactionSize is the number of bytes occupied by removed records after which a disk log is
+	//compacted if they also make up more than half of the file.
+	minCompactionSize = 1 << 20
+
+	recordPut    byte = 0
+	recordRemove byte = 1
+)
+
+var errLogClosed = errors.New("disk log is closed")
+
+//diskEntry is the in memory index entry of a section stored in a diskLog.
+type diskEntry struct {
+	offset     int64
+	size       int64
+	expiration int64
+}
+
+/*
+ * diskLog is an append only file of sections. Each record is prefixed with its length and a crc32
+ * checksum such that a partially written record at the end of the file is detected and discarded
+ * when the log is opened. Removals are recorded as tombstones. Only the offsets of the live records
+ * are kept in memory, the sections themselves are read from disk on demand. The file is rewritten
+ * without removed records once they make up more than half of it.
+ */
+type diskLog struct {
+	path    string
+	file    *os.File
+	size    int64                //offset at which the next record is appended
+	dead    int64                //number of bytes occupied by removed and overwritten records
+	dirty   bool                 //true if records have been appended since the last sync
+	entries map[string]diskEntry //section.Hash -> diskEntry
+	//mux protects all fields from simultaneous access.
+	mux sync.RWMutex
+}
+
+//openDiskLog opens or creates the log at path and replays it. add is called for each section which
+//has not been removed yet together with its hash, remove for each hash whose section has been removed
+//by a later record.
+func openDiskLog(path string, add func(s section.WithSigForward, hash string),
+	remove func(hash string)) (*diskLog, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l := &diskLog{path: path, file: file, entries: make(map[string]diskEntry)}
+	reader := bufio.NewReader(file)
+	for {
+		data, err := readRecord(reader)
+		if err != nil {
+			if err != io.EOF {
+				log.Warn("Discarding corrupted end of disk log", "path", path, "offset", l.size,
+					"error", err)
+			}
+			break
+		}
+		size := int64(recordHeaderSize + len(data))
+		switch data[0] {
+		case recordPut:
+			s, expiration, err := decodePutRecord(data)
+			if err != nil {
+				log.Warn("Discarding undecodable record of disk log", "path", path, "offset", l.size,
+					"error", err)
+				l.dead += size
+				break
+			}
+			hash := s.Hash()
+			if old, ok := l.entries[hash]; ok {
+				l.dead += old.size
+			} else {
+				add(s, hash)
+			}
+			l.entries[hash] = diskEntry{offset: l.size, size: size, expiration: expiration}
+		case recordRemove:
+			hash := string(data[1:])
+			if old, ok := l.entries[hash]; ok {
+				l.dead += old.size
+				delete(l.entries, hash)
+				remove(hash)
+			}
+			l.dead += size
+		default:
+			l.dead += size
+		}
+		l.size += size
+	}
+	if err := file.Truncate(l.size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+//readRecord returns the payload of the next record in r.
+func readRecord(r io.Reader) ([]byte, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header[:4]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:]) || len(data) == 0 {
+		return nil, errors.New("record checksum mismatch")
+	}
+	return data, nil
+}
+
+//encodeRecord returns data prefixed with its length and checksum.
+func encodeRecord(data []byte) []byte {
+	record := make([]byte, recordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[4:recordHeaderSize], crc32.ChecksumIEEE(data))
+	copy(record[recordHeaderSize:], data)
+	return record
+}
+
+//encodePutRecord returns the payload of a record storing s. The validity of s is stored alongside
+//as it is not part of its encoding.
+func encodePutRecord(s section.WithSigForward, expiration int64) ([]byte, error) {
+	buf := bytes.NewBuffer([]byte{recordPut})
+	for _, v := range []int64{expiration, s.ValidSince(), s.ValidUntil()} {
+		binary.Write(buf, binary.BigEndian, v)
+	}
+	if err := cbor.NewWriter(buf).Marshal(&message.Message{Content: []section.Section{s}}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+//decodePutRecord returns the section and expiration stored in data.
+func decodePutRecord(data []byte) (section.WithSigForward, int64, error) {
+	r := bytes.NewReader(data[1:])
+	values := make([]int64, 3)
+	if err := binary.Read(r, binary.BigEndian, values); err != nil {
+		return nil, 0, err
+	}
+	msg := message.Message{}
+	if err := cbor.NewReader(r).Unmarshal(&msg); err != nil {
+		return nil, 0, err
+	}
+	if len(msg.Content) != 1 {
+		return nil, 0, errors.New("record does not contain exactly one section")
+	}
+	s, ok := msg.Content[0].(section.WithSigForward)
+	if !ok {
+		return nil, 0, errors.New("record does not contain a section with signatures")
+	}
+	s.SetValidSince(values[1])
+	s.SetValidUntil(values[2])
+	return s, values[0], nil
+}
+
+//append writes data as a new record at the end of the log and returns its offset and size.
+func (l *diskLog) append(data []byte) (int64, int64, error) {
+	if l.file == nil {
+		return 0, 0, errLogClosed
+	}
+	record := encodeRecord(data)
+	if _, err := l.file.WriteAt(record, l.size); err != nil {
+		return 0, 0, err
+	}
+	offset := l.size
+	l.size += int64(len(record))
+	l.dirty = true
+	return offset, int64(len(record)), nil
+}
+
+//put stores s with the given expiration time. It returns false if s was already stored.
+func (l *diskLog) put(s section.WithSigForward, hash string, expiration int64) (bool, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if _, ok := l.entries[hash]; ok {
+		return false, nil
+	}
+	data, err := encodePutRecord(s, expiration)
+	if err != nil {
+		return false, err
+	}
+	offset, size, err := l.append(data)
+	if err != nil {
+		return false, err
+	}
+	l.entries[hash] = diskEntry{offset: offset, size: size, expiration: expiration}
+	return true, nil
+}
+
+//remove records that the section with hash has been removed. It returns false if no such section
+//is stored.
+func (l *diskLog) remove(hash string) (bool, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	entry, ok := l.entries[hash]
+	if !ok {
+		return false, nil
+	}
+	_, size, err := l.append(append([]byte{recordRemove}, hash...))
+	if err != nil {
+		return false, err
+	}
+	delete(l.entries, hash)
+	l.dead += entry.size + size
+	return true, nil
+}
+
+//get reads the section with hash from disk.
+func (l *diskLog) get(hash string) (section.WithSigForward, bool) {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	entry, ok := l.entries[hash]
+	if !ok || l.file == nil {
+		return nil, false
+	}
+	data, err := readRecord(io.NewSectionReader(l.file, entry.offset, entry.size))
+	if err == nil {
+		var s section.WithSigForward
+		if s, _, err = decodePutRecord(data); err == nil {
+			return s, true
+		}
+	}
+	log.Error("Was not able to read section from disk log", "path", l.path, "offset", entry.offset,
+		"error", err)
+	return nil, false
+}
+
+//expired returns the hashes of all sections whose expiration time is before now.
+func (l *diskLog) expired(now int64) []string {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	hashes := []string{}
+	for hash, entry := range l.entries {
+		if entry.expiration < now {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+//hashes returns the hashes of all stored sections.
+func (l *diskLog) hashes() []string {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	hashes := make([]string, 0, len(l.entries))
+	for hash := range l.entries {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+//compact rewrites the log without removed records if they make up more than half of the file. The
+//new file is synced before it atomically replaces the old one. The directory is synced afterwards
+//such that the replacement survives a crash.
+func (l *diskLog) compact() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.file == nil || l.dead < minCompactionSize || 2*l.dead < l.size {
+		return nil
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(l.path), filepath.Base(l.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]diskEntry)
+	w := bufio.NewWriter(tmp)
+	var size int64
+	for hash, entry := range l.entries {
+		record := make([]byte, entry.size)
+		if _, err = l.file.ReadAt(record, entry.offset); err != nil {
+			break
+		}
+		if _, err = w.Write(record); err != nil {
+			break
+		}
+		entries[hash] = diskEntry{offset: size, size: entry.size, expiration: entry.expiration}
+		size += entry.size
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	l.file.Close()
+	l.file, l.entries, l.size, l.dead, l.dirty = tmp, entries, size, 0, false
+	if err := syncDir(filepath.Dir(l.path)); err != nil {
+		log.Warn("Was not able to sync directory of disk log", "path", l.path, "error", err)
+	}
+	log.Info("Compacted disk log", "path", l.path, "size", size)
+	return nil
+}
+
+//sync commits the log to disk if records have been appended since the last sync.
+func (l *diskLog) sync() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.file == nil {
+		return errLogClosed
+	}
+	if !l.dirty {
+		return nil
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+//syncDir commits the directory entries of path to disk.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+//close syncs and closes the log. All subsequent modifications fail.
+func (l *diskLog) close() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.file == nil {
+		return errLogClosed
+	}
+	err := l.file.Sync()
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	l.file = nil
+	return err
+}
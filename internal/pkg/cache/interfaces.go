@@ -2,7 +2,9 @@ package cache
 
 import (
 	"net"
+	"time"
 
+	"github.com/netsec-ethz/rains/internal/pkg/address"
 	"github.com/netsec-ethz/rains/internal/pkg/keys"
 	"github.com/netsec-ethz/rains/internal/pkg/message"
 	"github.com/netsec-ethz/rains/internal/pkg/object"
@@ -33,10 +35,31 @@ type Connection interface {
 	CloseAndRemoveConnections(addr net.Addr)
 	//CloseAndRemoveAllConnections closes and removes all cached connections
 	CloseAndRemoveAllConnections()
+	//MarkSeen records that a message has been received on conn and resets its number of missed
+	//heartbeats.
+	MarkSeen(conn net.Conn)
+	//Probe closes and removes all connections on which nothing has been received although
+	//maxMissed heartbeats have been sent on them and returns them as evicted. It returns all
+	//other connections on which nothing has been received during idle as probe and counts a sent
+	//heartbeat for each of them.
+	Probe(idle time.Duration, maxMissed int) (probe, evicted []net.Conn)
+	//Info returns the remote address, the time when a message was last received and the number
+	//of missed heartbeats of all cached connections.
+	Info() []ConnectionInfo
+	//Resize sets the maximum number of connections in the cache to maxSize. Surplus connections
+	//are removed when the next connection is added.
+	Resize(maxSize int)
 	//Len returns the number of connections currently in the cache.
 	Len() int
 }
 
+//ConnectionInfo describes the liveness of a cached connection.
+type ConnectionInfo struct {
+	RemoteAddr       net.Addr
+	LastSeen         time.Time //time when a message was last received on the connection
+	MissedHeartbeats int       //number of heartbeats sent since then
+}
+
 //Capability stores a mapping from a hash of a capability list to a pointer of the list.
 type Capability interface {
 	//Add normalizes and serializes capabilities and then calculates a sha256 hash over it. It then
@@ -46,6 +69,9 @@ type Capability interface {
 	//Get returns true and a pointer to the capability list from which the hash was taken if
 	//present, otherwise false and nil.
 	Get(hash []byte) ([]message.Capability, bool)
+	//Resize sets the maximum number of capability lists in the cache to maxSize. Surplus lists
+	//are removed when the next list is added.
+	Resize(maxSize int)
 	//Len returns the number of elements currently in the cache.
 	Len() int
 }
@@ -67,8 +93,14 @@ type ZonePublicKey interface {
 		keys.PublicKey, *section.Assertion, bool)
 	//RemoveExpiredKeys deletes all expired public keys from the cache.
 	RemoveExpiredKeys()
+	//RemoveZone deletes all public keys of zone and context from the cache.
+	RemoveZone(zone, context string)
 	//Checkpoint returns all cached assertions
 	Checkpoint() []section.Section
+	//Resize sets the maximum number of public keys, the number of public keys after which Add
+	//returns false, and the number of public keys per zone after which a message is logged. Surplus
+	//public keys are removed when the next public key is added.
+	Resize(maxSize, warnSize, maxKeysPerZone int)
 	//Len returns the number of public keys currently in the cache.
 	Len() int
 }
@@ -80,11 +112,19 @@ type PendingKey interface {
 	//GetAndRemove returns util.MsgSectionSender which corresponds to token and true, and deletes it from
 	//the cache. False is returned if no util.MsgSectionSender matched token.
 	GetAndRemove(t token.Token) (util.MsgSectionSender, bool)
+	//Retry moves the entry with token oldToken to newToken, updates its expiration time to the one
+	//of the resent query and increments the entry's retry counter. It returns the entry's
+	//util.MsgSectionSender, the number of retries including this one and true. False is returned if
+	//no entry matched oldToken.
+	Retry(oldToken, newToken token.Token, expiration int64) (util.MsgSectionSender, int, bool)
 	//ContainsToken returns true if t is cached
 	ContainsToken(t token.Token) bool
 	//RemoveExpiredValues deletes all expired entries. It logs the host's addr which was not able to
 	//respond in time.
 	RemoveExpiredValues()
+	//Resize sets the maximum number of sections in the cache to maxSize. No entries are removed,
+	//but new ones are dropped as long as the cache holds maxSize or more sections.
+	Resize(maxSize int)
 	//Len returns the number of sections in the cache
 	Len() int
 }
@@ -97,8 +137,27 @@ type PendingQuery interface {
 	//GetAndRemove returns all util.MsgSectionSenders which correspond to token and delete them from the
 	//cache.
 	GetAndRemove(t token.Token) []util.MsgSectionSender
-	//RemoveExpiredValues deletes all expired entries.
-	RemoveExpiredValues()
+	//AddAnswer adds sections to the answer gathered for the entry with token t and sets the entry's
+	//deadline to deadline but at most to the entry's expiration. It returns the entry's deadline or
+	//the zero time if t is not cached. The second return value is true if sections are the first
+	//answer added for t.
+	AddAnswer(t token.Token, sections []section.Section, deadline time.Time) (time.Time, bool)
+	//GetAndRemoveAnswer returns all util.MsgSectionSenders which correspond to token t together
+	//with the gathered answer and deletes them from the cache if the entry's deadline has passed.
+	//Otherwise, only the entry's deadline is returned. The returned deadline is zero if t is not
+	//cached or no answer has been added for it.
+	GetAndRemoveAnswer(t token.Token) ([]util.MsgSectionSender, []section.Section, time.Time)
+	//GetAndRemoveAll returns all util.MsgSectionSenders in the cache and deletes them.
+	GetAndRemoveAll() []util.MsgSectionSender
+	//ContainsToken returns true if t is cached
+	ContainsToken(t token.Token) bool
+	//RemoveExpiredValues deletes all expired entries. For each querier of a deleted entry for which
+	//an answer has been gathered, it returns a util.MsgSectionSender containing the gathered
+	//answer.
+	RemoveExpiredValues() []util.MsgSectionSender
+	//Resize sets the maximum number of sections in the cache to maxSize. No entries are removed,
+	//but new ones are dropped as long as the cache holds maxSize or more sections.
+	Resize(maxSize int)
 	//Len returns the number of sections in the cache
 	Len() int
 }
@@ -107,20 +166,29 @@ type PendingQuery interface {
 type Assertion interface {
 	//Add adds an assertion together with an expiration time (number of seconds since 01.01.1970) to
 	//the cache. It returns false if the cache is full and a non internal element has been removed
-	//according to some strategy. It also adds assertion to the consistency cache.
+	//according to some strategy. It also adds assertion to the zone index used for consistency
+	//checks.
 	Add(assertion *section.Assertion, expiration int64, isInternal bool) bool
 	//Get returns true and a set of assertions matching the given key if there exist some. Otherwise
 	//nil and false is returned. If strict is set only an exact match for the provided FQDN is returned
 	// otherwise a search up the domain name hiearchy is performed.
 	Get(fqdn, context string, objType object.Type, strict bool) ([]*section.Assertion, bool)
+	//GetInRange returns true and all assertions of subjectZone and context whose subject name is
+	//within interval if there exist some. Otherwise nil and false is returned.
+	GetInRange(subjectZone, context string, interval section.Interval) ([]*section.Assertion, bool)
 	//RemoveExpiredValues goes through the cache and removes all expired assertions from the
-	//assertionCache and the consistency cache.
+	//assertionCache and the zone index.
 	RemoveExpiredValues()
-	//RemoveZone deletes all assertions in the assertionCache and consistencyCache of the given
+	//Remove deletes assertion from the assertionCache and the zone index.
+	Remove(assertion *section.Assertion)
+	//RemoveZone deletes all assertions in the assertionCache and the zone index of the given
 	//zone.
 	RemoveZone(zone string)
 	//Checkpoint returns all cached assertions
 	Checkpoint() []section.Section
+	//Resize sets the maximum number of assertions in the cache to maxSize. If the cache holds more
+	//assertions, non internal elements are removed according to some strategy.
+	Resize(maxSize int)
 	//Len returns the number of elements in the cache.
 	Len() int
 }
@@ -128,28 +196,62 @@ type Assertion interface {
 type NegativeAssertion interface {
 	//Add adds shard together with an expiration time (number of seconds since 01.01.1970) to
 	//the cache. It returns false if the cache is full and a non internal element has been removed
-	//according to some strategy. It also adds shard to the consistency cache.
+	//according to some strategy. It also adds shard to the zone index used for consistency checks.
 	AddShard(shard *section.Shard, expiration int64, isInternal bool) bool
 	//Add adds pshard together with an expiration time (number of seconds since 01.01.1970) to
 	//the cache. It returns false if the cache is full and a non internal element has been removed
-	//according to some strategy. It also adds shard to the consistency cache.
+	//according to some strategy. It also adds pshard to the zone index used for consistency checks.
 	AddPshard(pshard *section.Pshard, expiration int64, isInternal bool) bool
 	//Add adds zone together with an expiration time (number of seconds since 01.01.1970) to
 	//the cache. It returns false if the cache is full and a non internal element has been removed
-	//according to some strategy. It also adds zone to the consistency cache.
+	//according to some strategy. It also adds zone to the zone index used for consistency checks.
 	AddZone(zone *section.Zone, expiration int64, isInternal bool) bool
 	//Get returns true and a set of shards and zones matching subjectZone and context and overlap
 	//with interval if there exist some. When context is the empty string, a random context is
 	//chosen. Otherwise nil and false is returned.
 	Get(subjectZone, context string, interval section.Interval) ([]section.WithSigForward, bool)
 	//RemoveExpiredValues goes through the cache and removes all expired shards and zones from the
-	//assertionCache and the consistency cache.
+	//negAssertionCache and the zone index.
 	RemoveExpiredValues()
-	//RemoveZone deletes all shards and zones in the assertionCache and consistencyCache of the
+	//Remove deletes the shard, pshard or zone s from the negAssertionCache and the zone index.
+	Remove(s section.WithSigForward)
+	//RemoveZone deletes all shards and zones in the negAssertionCache and the zone index of the
 	//given subjectZone.
 	RemoveZone(subjectZone string)
 	//Checkpoint returns all cached negative assertions
 	Checkpoint() []section.Section
+	//Resize sets the maximum number of shards, pshards and zones in the cache to maxSize. If the
+	//cache holds more elements, non internal elements are removed according to some strategy.
+	Resize(maxSize int)
+	//Len returns the number of elements in the cache.
+	Len() int
+}
+
+//Address is used to store address assertions and address zones and to look them up by the longest
+//prefix containing an address.
+type Address interface {
+	//AddAssertion adds an address assertion together with an expiration time (number of seconds
+	//since 01.01.1970) to the cache. It returns false if the cache is full and a non internal
+	//element has been removed according to some strategy.
+	AddAssertion(a *section.AddressAssertion, expiration int64, isInternal bool) bool
+	//AddZone adds an address zone together with an expiration time (number of seconds since
+	//01.01.1970) to the cache. It returns false if the cache is full and a non internal element
+	//has been removed according to some strategy.
+	AddZone(z *section.AddressZone, expiration int64, isInternal bool) bool
+	//Get returns true and the cached sections with the longest prefix containing subjectAddr in
+	//context. Address assertions are only returned if they contain an object of one of types or
+	//if types is empty. If there is no such address assertion for a prefix, the address zones of
+	//the prefix are returned. Otherwise nil and false is returned.
+	Get(subjectAddr address.Prefix, context string, types []object.Type) ([]section.WithSig, bool)
+	//RemoveExpiredValues goes through the cache and removes all expired address assertions and
+	//address zones.
+	RemoveExpiredValues()
+	//Checkpoint returns all cached address assertions and address zones
+	Checkpoint() []section.Section
+	//Resize sets the maximum number of address assertions and address zones in the cache to
+	//maxSize. If the cache holds more elements, non internal elements are removed according to
+	//some strategy.
+	Resize(maxSize int)
 	//Len returns the number of elements in the cache.
 	Len() int
 }
@@ -47,21 +47,21 @@ func NewNegAssertion(maxSize int) *NegAssertionImpl {
 
 //Add adds a shard together with an expiration time (number of seconds since 01.01.1970) to
 //the cache. It returns false if the cache is full and an element was removed according to least
-//recently used strategy. It also adds shard to the consistency cache.
+//recently used strategy. It also adds shard to the zone index.
 func (c *NegAssertionImpl) AddShard(shard *section.Shard, expiration int64, isInternal bool) bool {
 	return add(c, shard, expiration, isInternal)
 }
 
 //Add adds a pshard together with an expiration time (number of seconds since 01.01.1970) to
 //the cache. It returns false if the cache is full and an element was removed according to least
-//recently used strategy. It also adds shard to the consistency cache.
+//recently used strategy. It also adds pshard to the zone index.
 func (c *NegAssertionImpl) AddPshard(pshard *section.Pshard, expiration int64, isInternal bool) bool {
 	return add(c, pshard, expiration, isInternal)
 }
 
 //Add adds a zone together with an expiration time (number of seconds since 01.01.1970) to
 //the cache. It returns false if the cache is full and an element was removed according to least
-//recently used strategy. It also adds zone to the consistency cache.
+//recently used strategy. It also adds zone to the zone index.
 func (c *NegAssertionImpl) AddZone(zone *section.Zone, expiration int64, isInternal bool) bool {
 	return add(c, zone, expiration, isInternal)
 }
@@ -93,7 +93,12 @@ func add(c *NegAssertionImpl, s section.WithSigForward, expiration int64, isInte
 		isFull = c.counter.Inc()
 	}
 	value.mux.Unlock()
-	//Remove elements according to lru strategy
+	c.removeLeastRecentlyUsed()
+	return !isFull
+}
+
+//removeLeastRecentlyUsed removes elements according to lru strategy until the cache is not full.
+func (c *NegAssertionImpl) removeLeastRecentlyUsed() {
 	for c.counter.IsFull() {
 		key, value := c.cache.GetLeastRecentlyUsed()
 		if value == nil {
@@ -113,7 +118,6 @@ func add(c *NegAssertionImpl, s section.WithSigForward, expiration int64, isInte
 		c.counter.Sub(len(v.sections))
 		v.mux.Unlock()
 	}
-	return !isFull
 }
 
 //Get returns true and a set of assertions matching the given key if there exist some. Otherwise
@@ -167,7 +171,30 @@ func (c *NegAssertionImpl) RemoveExpiredValues() {
 	}
 }
 
-//RemoveZone deletes all shards and zones in the assertionCache and consistencyCache of the given
+//Remove deletes the shard, pshard or zone s from the negAssertionCache and the zone index.
+func (c *NegAssertionImpl) Remove(s section.WithSigForward) {
+	v, ok := c.cache.Get(zoneCtxKey(s.GetSubjectZone(), s.GetContext()))
+	if !ok {
+		return
+	}
+	value := v.(*negAssertionCacheValue)
+	value.mux.Lock()
+	defer value.mux.Unlock()
+	if _, ok := value.sections[s.Hash()]; !ok || value.deleted {
+		return
+	}
+	delete(value.sections, s.Hash())
+	c.counter.Dec()
+	if len(value.sections) == 0 {
+		value.deleted = true
+		c.cache.Remove(value.cacheKey)
+		if set, ok := c.zoneMap.Get(value.zone); ok {
+			set.(*safeHashMap.Map).Remove(value.cacheKey)
+		}
+	}
+}
+
+//RemoveZone deletes all shards and zones in the negAssertionCache and the zone index of the given
 //subjectZone.
 func (c *NegAssertionImpl) RemoveZone(zone string) {
 	if set, ok := c.zoneMap.Remove(zone); ok {
@@ -204,6 +231,14 @@ func (c *NegAssertionImpl) Checkpoint() (sections []section.Section) {
 	return
 }
 
+//Resize sets the maximum number of shards, pshards and zones in the cache to maxSize. If the cache
+//holds more elements, they are removed according to least recently used strategy.
+func (c *NegAssertionImpl) Resize(maxSize int) {
+	if c.counter.SetMaxCount(maxSize) {
+		c.removeLeastRecentlyUsed()
+	}
+}
+
 //Len returns the number of elements in the cache.
 func (c *NegAssertionImpl) Len() int {
 	return c.counter.Value()
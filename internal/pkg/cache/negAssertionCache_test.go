@@ -93,6 +93,30 @@ func TestNegAssertionCache(t *testing.T) {
 	}
 }
 
+func TestNegAssertionRemove(t *testing.T) {
+	c := NewNegAssertion(10)
+	shards := getShards()
+	zones := getZones()
+	c.AddShard(shards[0], time.Now().Add(time.Hour).Unix(), false)
+	c.AddShard(shards[2], time.Now().Add(time.Hour).Unix(), false)
+	c.AddZone(zones[0], time.Now().Add(time.Hour).Unix(), false)
+	c.Remove(shards[0])
+	s, ok := c.Get("ch", ".", section.TotalInterval{})
+	if !ok || len(s) != 2 || c.Len() != 2 {
+		t.Errorf("Shard was not removed len=%d actual=%v", c.Len(), s)
+	}
+	//removing a section which is not cached has no effect
+	c.Remove(shards[1])
+	if c.Len() != 2 {
+		t.Errorf("Removing a non cached section changed the cache size len=%d", c.Len())
+	}
+	c.Remove(shards[2])
+	c.Remove(zones[0])
+	if s, ok := c.Get("ch", ".", section.TotalInterval{}); ok || c.Len() != 0 {
+		t.Errorf("Sections were not removed len=%d actual=%v", c.Len(), s)
+	}
+}
+
 func TestNegAssertionCheckpoint(t *testing.T) {
 	var tests = []struct {
 		input Assertion
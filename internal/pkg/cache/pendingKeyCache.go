@@ -15,6 +15,8 @@ type pkcValue struct {
 	mss util.MsgSectionSender
 	//expiration contains the expiration value of the forwarded query
 	expiration int64
+	//retries contains the number of times the query has been resent
+	retries int
 }
 
 type PendingKeyImpl struct {
@@ -56,6 +58,27 @@ func (c *PendingKeyImpl) GetAndRemove(t token.Token) (util.MsgSectionSender, boo
 	return util.MsgSectionSender{}, false
 }
 
+//Retry moves the entry with token oldToken to newToken, updates its expiration time to the one of
+//the resent query and increments the entry's retry counter. It returns the entry's
+//util.MsgSectionSender, the number of retries including this one and true. False is returned if no
+//entry matched oldToken.
+func (c *PendingKeyImpl) Retry(oldToken, newToken token.Token, expiration int64) (
+	util.MsgSectionSender, int, bool) {
+	val, present := c.tokenMap.Remove(oldToken.String())
+	if !present {
+		return util.MsgSectionSender{}, 0, false
+	}
+	value := val.(pkcValue)
+	value.expiration = expiration
+	value.retries++
+	if ok := c.tokenMap.Add(newToken.String(), value); !ok {
+		c.counter.Dec()
+		log.Warn("Token already in key cache. Random source of Token generator no random enough?")
+		return util.MsgSectionSender{}, 0, false
+	}
+	return value.mss, value.retries, true
+}
+
 //ContainsToken returns true if t is cached
 func (c *PendingKeyImpl) ContainsToken(t token.Token) bool {
 	_, present := c.tokenMap.Get(t.String())
@@ -78,6 +101,12 @@ func (c *PendingKeyImpl) RemoveExpiredValues() {
 	}
 }
 
+//Resize sets the maximum number of sections in the cache to maxSize. No entries are removed, but new
+//ones are dropped as long as the cache holds maxSize or more sections.
+func (c *PendingKeyImpl) Resize(maxSize int) {
+	c.counter.SetMaxCount(maxSize)
+}
+
 //Len returns the number of sections in the cache
 func (c *PendingKeyImpl) Len() int {
 	return c.tokenMap.Len()
@@ -53,6 +53,24 @@ func TestPendingKeyCache(t *testing.T) {
 			!reflect.DeepEqual(v, mss[2]) {
 			t.Error("mss[2] should be returned for this token")
 		}
+		//Test c.Retry()
+		newToken := token.New()
+		c.Add(mss[0], mss[0].Token, time.Now().Add(time.Hour).Unix())
+		if v, retries, ok := c.Retry(mss[0].Token, newToken, time.Now().Add(time.Hour).Unix()); !ok ||
+			retries != 1 || c.Len() != 1 || !reflect.DeepEqual(v, mss[0]) {
+			t.Errorf("mss[0] was not moved to the new token. retries=%d", retries)
+		}
+		if c.ContainsToken(mss[0].Token) || !c.ContainsToken(newToken) {
+			t.Error("entry is not stored under the new token")
+		}
+		if _, retries, ok := c.Retry(newToken, mss[0].Token, time.Now().Add(time.Hour).Unix()); !ok ||
+			retries != 2 {
+			t.Errorf("retry counter was not incremented. retries=%d", retries)
+		}
+		if _, _, ok := c.Retry(newToken, token.New(), time.Now().Add(time.Hour).Unix()); ok {
+			t.Error("entry with unknown token was retried")
+		}
+		c.GetAndRemove(mss[0].Token)
 		//Test c.RemoveExpiredValues()
 		c.Add(mss[0], mss[0].Token, time.Now().Add(time.Hour).Unix())
 		c.Add(mss[2], mss[2].Token, time.Now().Add(-time.Hour).Unix())
@@ -16,9 +16,12 @@ import (
 )
 
 //pqcValue contains sectionSender objets waiting for a query answer to arrive until expiration.
+//Answer sections arriving before deadline are gathered in answer.
 type pqcValue struct {
 	sss        []util.MsgSectionSender
 	expiration int64
+	answer     []section.Section
+	deadline   time.Time
 }
 
 //pqcKey returns a unique string representation of sections. Sections MUST only contain queries
@@ -108,21 +111,107 @@ func (c *PendingQueryImpl) GetAndRemove(t token.Token) []util.MsgSectionSender {
 	return nil
 }
 
-//RemoveExpiredValues deletes all expired entries.
-func (c *PendingQueryImpl) RemoveExpiredValues() {
+//AddAnswer adds sections to the answer gathered for the entry with token t and sets the entry's
+//deadline to deadline but at most to the entry's expiration. It returns the entry's deadline or the
+//zero time if t is not cached. The second return value is true if sections are the first answer
+//added for t.
+func (c *PendingQueryImpl) AddAnswer(t token.Token, sections []section.Section,
+	deadline time.Time) (time.Time, bool) {
+	c.tmux.Lock()
+	defer c.tmux.Unlock()
+	val, present := c.tokenMap[t]
+	if !present {
+		return time.Time{}, false
+	}
+	isFirst := val.deadline.IsZero()
+	val.answer = append(val.answer, sections...)
+	val.deadline = deadline
+	if expiration := time.Unix(val.expiration, 0); deadline.After(expiration) {
+		val.deadline = expiration
+	}
+	return val.deadline, isFirst
+}
+
+//GetAndRemoveAnswer returns all util.MsgSectionSenders which correspond to token t together with
+//the gathered answer and deletes them from the cache if the entry's deadline has passed. Otherwise,
+//only the entry's deadline is returned. The returned deadline is zero if t is not cached or no
+//answer has been added for it.
+func (c *PendingQueryImpl) GetAndRemoveAnswer(t token.Token) ([]util.MsgSectionSender,
+	[]section.Section, time.Time) {
+	c.qmux.Lock()
+	c.tmux.Lock()
+	defer c.qmux.Unlock()
+	defer c.tmux.Unlock()
+
+	val, present := c.tokenMap[t]
+	if !present || val.deadline.IsZero() {
+		return nil, nil, time.Time{}
+	}
+	if time.Now().Before(val.deadline) {
+		return nil, nil, val.deadline
+	}
+	delete(c.tokenMap, t)
+	key, _ := pqcKey(val.sss[0].Sections) //error case is catched in Add method.
+	delete(c.queryMap, key)               //all sss have the same pqcKey
+	c.counter.Sub(len(val.sss))
+	return val.sss, val.answer, val.deadline
+}
+
+//GetAndRemoveAll returns all util.MsgSectionSenders in the cache and deletes them.
+func (c *PendingQueryImpl) GetAndRemoveAll() []util.MsgSectionSender {
 	c.qmux.Lock()
 	c.tmux.Lock()
 	defer c.qmux.Unlock()
 	defer c.tmux.Unlock()
 
+	result := []util.MsgSectionSender{}
+	for _, v := range c.tokenMap {
+		result = append(result, v.sss...)
+		c.counter.Sub(len(v.sss))
+	}
+	c.tokenMap = make(map[token.Token]*pqcValue)
+	c.queryMap = make(map[string]token.Token)
+	return result
+}
+
+//ContainsToken returns true if t is cached
+func (c *PendingQueryImpl) ContainsToken(t token.Token) bool {
+	c.tmux.Lock()
+	defer c.tmux.Unlock()
+	_, present := c.tokenMap[t]
+	return present
+}
+
+//RemoveExpiredValues deletes all expired entries. For each querier of a deleted entry for which an
+//answer has been gathered, it returns a util.MsgSectionSender containing the gathered answer.
+func (c *PendingQueryImpl) RemoveExpiredValues() []util.MsgSectionSender {
+	c.qmux.Lock()
+	c.tmux.Lock()
+	defer c.qmux.Unlock()
+	defer c.tmux.Unlock()
+
+	var answers []util.MsgSectionSender
 	for k, v := range c.tokenMap {
 		if v.expiration < time.Now().Unix() {
+			if len(v.answer) > 0 {
+				for _, ss := range v.sss {
+					answers = append(answers, util.MsgSectionSender{Sender: ss.Sender,
+						Sections: v.answer, Token: ss.Token})
+				}
+			}
 			delete(c.tokenMap, k)
 			key, _ := pqcKey(v.sss[0].Sections) //error case is catched in Add method.
 			delete(c.queryMap, key)             //all sss have the same pqcKey
 			c.counter.Sub(len(v.sss))
 		}
 	}
+	return answers
+}
+
+//Resize sets the maximum number of sections in the cache to maxSize. No entries are removed, but new
+//ones are dropped as long as the cache holds maxSize or more sections.
+func (c *PendingQueryImpl) Resize(maxSize int) {
+	c.counter.SetMaxCount(maxSize)
 }
 
 //Len returns the number of sections in the cache
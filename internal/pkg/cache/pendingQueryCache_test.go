@@ -34,6 +34,10 @@ func TestPendingQueryCache(t *testing.T) {
 		if ok := c.Add(mss[2], mss[2].Token, time.Now().Add(time.Hour).Unix()); !ok || c.Len() != 3 {
 			t.Error("mss[2] was not added to the cache")
 		}
+		//Test c.ContainsToken()
+		if !c.ContainsToken(mss[0].Token) || c.ContainsToken(mss[1].Token) {
+			t.Error("wrong tokens are contained in the cache")
+		}
 		//Test c.GetAndRemove()
 		if v := c.GetAndRemove(mss[1].Token); len(v) != 0 || c.Len() != 3 {
 			t.Error("token should not be part of the cache")
@@ -46,6 +50,9 @@ func TestPendingQueryCache(t *testing.T) {
 			!reflect.DeepEqual(v[1], mss[1]) || c.Len() != 0 {
 			t.Error("mss[0] and mss[1] should be returned for this token")
 		}
+		if c.ContainsToken(mss[0].Token) {
+			t.Error("removed token is still contained in the cache")
+		}
 		//Test c.RemoveExpiredValues()
 		c.Add(mss[0], mss[0].Token, time.Now().Add(time.Hour).Unix())
 		c.Add(mss[2], mss[2].Token, time.Now().Add(-time.Hour).Unix())
@@ -80,3 +87,77 @@ func TestPendingQueryCache(t *testing.T) {
 		}
 	}
 }
+
+func TestPendingQueryCacheAnswer(t *testing.T) {
+	mss, _ := getQueries()
+	c := NewPendingQuery(3)
+	c.Add(mss[0], mss[0].Token, time.Now().Add(time.Hour).Unix())
+	c.Add(mss[1], mss[1].Token, time.Now().Add(time.Hour).Unix())
+	a1 := &section.Assertion{SubjectName: "a"}
+	a2 := &section.Assertion{SubjectName: "b"}
+	if d, _ := c.AddAnswer(mss[2].Token, []section.Section{a1}, time.Now()); !d.IsZero() {
+		t.Error("answer was added to a token which is not cached")
+	}
+	if sss, answer, deadline := c.GetAndRemoveAnswer(mss[0].Token); sss != nil || answer != nil ||
+		!deadline.IsZero() {
+		t.Error("answer returned before one was added")
+	}
+	deadline := time.Now().Add(time.Minute)
+	if d, isFirst := c.AddAnswer(mss[0].Token, []section.Section{a1}, deadline); !d.Equal(deadline) ||
+		!isFirst {
+		t.Errorf("first answer was not added correctly. deadline=%v isFirst=%t", d, isFirst)
+	}
+	//The deadline does not exceed the query's expiration.
+	if d, _ := c.AddAnswer(mss[0].Token, nil, time.Now().Add(2*time.Hour)); d.After(
+		time.Now().Add(time.Hour)) {
+		t.Errorf("deadline was not capped at the expiration. deadline=%v", d)
+	}
+	if sss, _, deadline := c.GetAndRemoveAnswer(mss[0].Token); sss != nil || deadline.IsZero() ||
+		c.Len() != 2 {
+		t.Error("answer returned before deadline passed")
+	}
+	if d, isFirst := c.AddAnswer(mss[0].Token, []section.Section{a2},
+		time.Now().Add(-time.Second)); d.IsZero() || isFirst {
+		t.Errorf("second answer was not added correctly. deadline=%v isFirst=%t", d, isFirst)
+	}
+	sss, answer, _ := c.GetAndRemoveAnswer(mss[0].Token)
+	if len(sss) != 2 || !reflect.DeepEqual(sss[0], mss[0]) || !reflect.DeepEqual(sss[1], mss[1]) {
+		t.Errorf("wrong section senders returned. actual=%v", sss)
+	}
+	if !reflect.DeepEqual(answer, []section.Section{a1, a2}) {
+		t.Errorf("answers were not gathered. actual=%v", answer)
+	}
+	if c.Len() != 0 || c.ContainsToken(mss[0].Token) {
+		t.Error("entry was not removed after its deadline passed")
+	}
+
+	//The answers gathered for a reaped entry are returned.
+	c.Add(mss[0], mss[0].Token, time.Now().Add(-time.Hour).Unix())
+	c.Add(mss[2], mss[2].Token, time.Now().Add(-time.Hour).Unix())
+	c.AddAnswer(mss[0].Token, []section.Section{a1}, time.Now().Add(time.Minute))
+	answers := c.RemoveExpiredValues()
+	if len(answers) != 1 || answers[0].Token != mss[0].Token ||
+		!reflect.DeepEqual(answers[0].Sections, []section.Section{a1}) || c.Len() != 0 {
+		t.Errorf("gathered answer of reaped entry was not returned. actual=%v", answers)
+	}
+}
+
+func TestPendingQueryCacheGetAndRemoveAll(t *testing.T) {
+	mss, _ := getQueries()
+	c := NewPendingQuery(4)
+	if sss := c.GetAndRemoveAll(); len(sss) != 0 {
+		t.Errorf("empty cache returned section senders. actual=%v", sss)
+	}
+	c.Add(mss[0], mss[0].Token, time.Now().Add(time.Hour).Unix())
+	c.Add(mss[1], mss[1].Token, time.Now().Add(time.Hour).Unix())
+	c.Add(mss[2], mss[2].Token, time.Now().Add(time.Hour).Unix())
+	if sss := c.GetAndRemoveAll(); len(sss) != 3 {
+		t.Errorf("wrong number of section senders returned. expected=3 actual=%d", len(sss))
+	}
+	if c.Len() != 0 || c.ContainsToken(mss[0].Token) || c.ContainsToken(mss[2].Token) {
+		t.Error("entries were not removed")
+	}
+	if ok := c.Add(mss[0], mss[0].Token, time.Now().Add(time.Hour).Unix()); !ok {
+		t.Error("query was not added as new entry after all entries were removed")
+	}
+}
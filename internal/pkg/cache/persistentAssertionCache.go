@@ -0,0 +1,102 @@
+package cache
+
+import (
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+/*
+ * persistent assertion cache implementation
+ * It stores all assertions in a disk log such that they survive a restart and are not bounded by
+ * the available memory. Only an index from the assertion cache map keys and the zones to the
+ * hashes of the stored assertions is kept in memory. Assertions are never evicted. Once the cache
+ * holds maxSize assertions, only internal assertions are added.
+ */
+type PersistentAssertionImpl struct {
+	*persistentCache
+}
+
+//NewPersistentAssertion opens the disk log at path or creates it if it does not exist and returns
+//a cache containing all assertions stored in it.
+func NewPersistentAssertion(path string, maxSize int) (*PersistentAssertionImpl, error) {
+	c, err := openPersistentCache(path, maxSize, "assertion", assertionIndexKeys)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Opened persistent assertion cache", "path", path, "assertions", c.Len())
+	return &PersistentAssertionImpl{c}, nil
+}
+
+//assertionIndexKeys returns the assertionCacheMapKey of each object type of s if it is an
+//assertion.
+func assertionIndexKeys(s section.WithSigForward) ([]string, bool) {
+	a, ok := s.(*section.Assertion)
+	if !ok {
+		return nil, false
+	}
+	var keys []string
+	for _, o := range a.Content {
+		keys = append(keys, assertionCacheMapKey(a.SubjectName, a.SubjectZone, a.Context, o.Type))
+	}
+	return keys, true
+}
+
+//Add writes an assertion together with an expiration time (number of seconds since 01.01.1970) to
+//disk and adds it to the index. It returns false if the cache is full. In this case, the assertion
+//is only added if it is internal.
+func (c *PersistentAssertionImpl) Add(a *section.Assertion, expiration int64,
+	isInternal bool) bool {
+	return c.add(a, expiration, isInternal)
+}
+
+//Get returns true and a set of assertions matching the given key if there exist some. Otherwise
+//nil and false is returned.
+// If strict is true then only a direct match for the provided FQDN is looked up.
+// Otherwise, a search up the domain name hierarchy is performed to get the topmost match.
+func (c *PersistentAssertionImpl) Get(fqdn, context string, objType object.Type, strict bool) (
+	[]*section.Assertion, bool) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	names := []string{fqdn}
+	if !strict {
+		names = zoneHierarchy(fqdn)
+	}
+	for _, name := range names {
+		if hashes, ok := c.keyIndex[assertionCacheMapKeyFQDN(name, context, objType)]; ok {
+			assertions := c.readAssertions(hashes)
+			return assertions, len(assertions) > 0
+		}
+	}
+	return nil, false
+}
+
+//readAssertions returns the assertions with the given hashes from disk.
+func (c *PersistentAssertionImpl) readAssertions(hashes map[string]bool) []*section.Assertion {
+	var assertions []*section.Assertion
+	for _, s := range c.read(hashes) {
+		assertions = append(assertions, s.(*section.Assertion))
+	}
+	return assertions
+}
+
+//GetInRange returns true and all assertions of subjectZone and context whose subject name is
+//within interval if there exist some. Otherwise nil and false is returned.
+func (c *PersistentAssertionImpl) GetInRange(subjectZone, context string,
+	interval section.Interval) ([]*section.Assertion, bool) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	hashes := make(map[string]bool)
+	for hash := range c.zoneMap[subjectZone] {
+		if meta := c.metaData[hash]; meta.context == context && section.Intersect(interval, meta) {
+			hashes[hash] = true
+		}
+	}
+	assertions := c.readAssertions(hashes)
+	return assertions, len(assertions) > 0
+}
+
+//Remove deletes assertion from disk and the index.
+func (c *PersistentAssertionImpl) Remove(a *section.Assertion) {
+	c.removeHash(a.Hash())
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+func TestPersistentAssertionCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "persistentAssertion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	logPath := path.Join(dir, "assertions.log")
+	c, err := NewPersistentAssertion(logPath, 10)
+	if err != nil {
+		t.Fatalf("Was not able to create cache: %v", err)
+	}
+	delegations := getExampleDelgations("ch")
+	ip := &section.Assertion{SubjectName: "www", SubjectZone: "ch", Context: ".",
+		Content: []object.Object{{Type: object.OTIP4Addr, Value: "192.0.2.1"}}}
+	expiration := time.Now().Add(time.Hour).Unix()
+	if !c.Add(delegations[0], expiration, true) || !c.Add(ip, expiration, false) || c.Len() != 2 {
+		t.Fatalf("Assertions were not added to cache len=%d", c.Len())
+	}
+	c.Add(ip, expiration, false)
+	if c.Len() != 2 {
+		t.Errorf("Assertion was added twice len=%d", c.Len())
+	}
+	c.Close()
+
+	//Content survives a restart
+	c, err = NewPersistentAssertion(logPath, 10)
+	if err != nil {
+		t.Fatalf("Was not able to reopen cache: %v", err)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Wrong number of assertions after reopening expected=2 actual=%d", c.Len())
+	}
+	a, ok := c.Get("ch.", ".", object.OTDelegation, true)
+	if !ok || len(a) != 1 || a[0].Hash() != delegations[0].Hash() ||
+		a[0].ValidUntil() != delegations[0].ValidUntil() {
+		t.Errorf("Wrong delegation from cache expected=%v actual=%v", delegations[0], a)
+	}
+	a, ok = c.Get("www.ch", ".", object.OTIP4Addr, true)
+	if !ok || len(a) != 1 || a[0].Hash() != ip.Hash() {
+		t.Errorf("Wrong assertion from cache expected=%v actual=%v", ip, a)
+	}
+	if a, ok := c.Get("www.ch", ".", object.OTIP6Addr, true); ok {
+		t.Errorf("Assertion of wrong type returned %v", a)
+	}
+	a, ok = c.GetInRange("ch", ".", section.StringInterval{Name: "www"})
+	if !ok || len(a) != 1 || a[0].Hash() != ip.Hash() {
+		t.Errorf("Wrong assertion in range expected=%v actual=%v", ip, a)
+	}
+	if a, ok := c.GetInRange("ch", ".", section.StringInterval{Name: "abc"}); ok {
+		t.Errorf("Assertion outside of range returned %v", a)
+	}
+
+	//Removals survive a restart and a torn record at the end is discarded
+	c.Remove(a[0])
+	c.Close()
+	f, _ := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0600)
+	f.Write([]byte{0, 0, 1, 0, 1, 2})
+	f.Close()
+	c, err = NewPersistentAssertion(logPath, 10)
+	if err != nil {
+		t.Fatalf("Was not able to reopen cache: %v", err)
+	}
+	if _, ok := c.Get("www.ch", ".", object.OTIP4Addr, true); ok || c.Len() != 1 {
+		t.Errorf("Removed assertion is still cached len=%d", c.Len())
+	}
+	if !c.Add(ip, time.Now().Add(-time.Hour).Unix(), false) || c.Len() != 2 {
+		t.Errorf("Assertion was not added after discarding torn record len=%d", c.Len())
+	}
+	c.RemoveExpiredValues()
+	if c.Len() != 1 || len(c.Checkpoint()) != 1 {
+		t.Errorf("Expired assertion was not removed len=%d", c.Len())
+	}
+	c.RemoveZone(".")
+	if c.Len() != 0 {
+		t.Errorf("Zone was not removed len=%d", c.Len())
+	}
+	c.Close()
+}
+
+func TestPersistentAssertionFull(t *testing.T) {
+	dir, err := ioutil.TempDir("", "persistentAssertion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := NewPersistentAssertion(path.Join(dir, "assertions.log"), 1)
+	if err != nil {
+		t.Fatalf("Was not able to create cache: %v", err)
+	}
+	defer c.Close()
+	delegations := getExampleDelgations("ch")
+	expiration := time.Now().Add(time.Hour).Unix()
+	c.Add(delegations[0], expiration, false)
+	if c.Add(delegations[1], expiration, false) || c.Len() != 1 {
+		t.Errorf("External assertion was added to full cache len=%d", c.Len())
+	}
+	if c.Add(delegations[1], expiration, true) || c.Len() != 2 {
+		t.Errorf("Internal assertion was not added to full cache len=%d", c.Len())
+	}
+	c.Resize(10)
+	if !c.Add(delegations[2], expiration, false) || c.Len() != 3 {
+		t.Errorf("External assertion was not added to resized cache len=%d", c.Len())
+	}
+}
+
+func TestPersistentAssertionSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "persistentAssertion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := NewPersistentAssertion(path.Join(dir, "assertions.log"), 10)
+	if err != nil {
+		t.Fatalf("Was not able to create cache: %v", err)
+	}
+	delegations := getExampleDelgations("ch")
+	c.Add(delegations[0], time.Now().Add(time.Hour).Unix(), false)
+	if !c.log.dirty {
+		t.Error("Added assertion is not marked as unsynced")
+	}
+	if err := c.Sync(); err != nil || c.log.dirty {
+		t.Errorf("Log was not synced err=%v", err)
+	}
+	c.Add(delegations[1], time.Now().Add(-time.Hour).Unix(), false)
+	c.RemoveExpiredValues()
+	if c.log.dirty || c.Len() != 1 {
+		t.Errorf("Log was not synced after removing expired assertions len=%d", c.Len())
+	}
+	c.Close()
+	if err := c.Sync(); err == nil {
+		t.Error("Closed log was synced")
+	}
+}
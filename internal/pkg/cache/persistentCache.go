@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/datastructures/safeCounter"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+//persistentMetaData is the in memory information about a section stored on disk which is needed to
+//answer lookups without reading the section.
+type persistentMetaData struct {
+	keys    []string //index keys under which the section is found
+	zone    string
+	context string
+	begin   string
+	end     string
+}
+
+//Begin returns the beginning of the range covered by the section.
+func (m persistentMetaData) Begin() string {
+	return m.begin
+}
+
+//End returns the end of the range covered by the section.
+func (m persistentMetaData) End() string {
+	return m.end
+}
+
+/*
+ * persistentCache contains the parts shared by the persistent assertion and negative assertion
+ * cache. It stores sections in a disk log and keeps an index from lookup keys and zones to the
+ * hashes of the stored sections in memory. Sections are never evicted. Once the cache holds maxSize
+ * sections, only internal ones are added.
+ */
+type persistentCache struct {
+	log      *diskLog
+	counter  *safeCounter.Counter
+	keyIndex map[string]map[string]bool //index key -> set of section.Hash
+	zoneMap  map[string]map[string]bool //zone -> set of section.Hash
+	metaData map[string]persistentMetaData
+	//indexKeys returns the keys under which s is indexed and false if s is not supported.
+	indexKeys func(s section.WithSigForward) ([]string, bool)
+	//kind describes the stored sections in log messages.
+	kind string
+	//mux protects the index from simultaneous access.
+	mux sync.RWMutex
+}
+
+//openPersistentCache opens the disk log at path or creates it if it does not exist and returns a
+//cache indexing all sections stored in it under the keys returned by indexKeys.
+func openPersistentCache(path string, maxSize int, kind string,
+	indexKeys func(section.WithSigForward) ([]string, bool)) (*persistentCache, error) {
+	c := &persistentCache{
+		counter:   safeCounter.New(maxSize),
+		keyIndex:  make(map[string]map[string]bool),
+		zoneMap:   make(map[string]map[string]bool),
+		metaData:  make(map[string]persistentMetaData),
+		indexKeys: indexKeys,
+		kind:      kind,
+	}
+	var err error
+	c.log, err = openDiskLog(path, c.index, c.unindex)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+//index adds s to the in memory index.
+func (c *persistentCache) index(s section.WithSigForward, hash string) {
+	keys, ok := c.indexKeys(s)
+	if !ok {
+		log.Warn("Ignoring unsupported section in disk log", "type", fmt.Sprintf("%T", s))
+		return
+	}
+	meta := persistentMetaData{
+		keys:    keys,
+		zone:    s.GetSubjectZone(),
+		context: s.GetContext(),
+		begin:   s.Begin(),
+		end:     s.End(),
+	}
+	for _, key := range keys {
+		if _, ok := c.keyIndex[key]; !ok {
+			c.keyIndex[key] = make(map[string]bool)
+		}
+		c.keyIndex[key][hash] = true
+	}
+	if _, ok := c.zoneMap[meta.zone]; !ok {
+		c.zoneMap[meta.zone] = make(map[string]bool)
+	}
+	c.zoneMap[meta.zone][hash] = true
+	c.metaData[hash] = meta
+	c.counter.Inc()
+}
+
+//unindex removes the section with hash from the in memory index.
+func (c *persistentCache) unindex(hash string) {
+	meta, ok := c.metaData[hash]
+	if !ok {
+		return
+	}
+	for _, key := range meta.keys {
+		delete(c.keyIndex[key], hash)
+		if len(c.keyIndex[key]) == 0 {
+			delete(c.keyIndex, key)
+		}
+	}
+	delete(c.zoneMap[meta.zone], hash)
+	if len(c.zoneMap[meta.zone]) == 0 {
+		delete(c.zoneMap, meta.zone)
+	}
+	delete(c.metaData, hash)
+	c.counter.Dec()
+}
+
+//add writes s together with an expiration time (number of seconds since 01.01.1970) to disk and
+//adds it to the index. It returns false if the cache is full. In this case, s is only added if it
+//is internal.
+func (c *persistentCache) add(s section.WithSigForward, expiration int64, isInternal bool) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	isFull := c.counter.IsFull()
+	if isFull && !isInternal {
+		return false
+	}
+	hash := s.Hash()
+	added, err := c.log.put(s, hash, expiration)
+	if err != nil {
+		log.Error("Was not able to store "+c.kind, "section", s, "error", err)
+		return false
+	}
+	if added {
+		c.index(s, hash)
+	}
+	return !isFull
+}
+
+//read returns the sections with the given hashes from disk.
+func (c *persistentCache) read(hashes map[string]bool) []section.WithSigForward {
+	var secs []section.WithSigForward
+	for hash := range hashes {
+		if s, ok := c.log.get(hash); ok {
+			secs = append(secs, s)
+		}
+	}
+	return secs
+}
+
+//RemoveExpiredValues removes all expired sections from disk and the index. Afterwards, the disk
+//log is synced and compacted if necessary.
+func (c *persistentCache) RemoveExpiredValues() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, hash := range c.log.expired(time.Now().Unix()) {
+		c.remove(hash)
+	}
+	if err := c.log.compact(); err != nil {
+		log.Error("Was not able to compact persistent "+c.kind+" cache", "error", err)
+	}
+	if err := c.log.sync(); err != nil {
+		log.Error("Was not able to sync persistent "+c.kind+" cache", "error", err)
+	}
+}
+
+//remove deletes the section with hash from disk and the index.
+func (c *persistentCache) remove(hash string) {
+	if _, err := c.log.remove(hash); err != nil {
+		log.Error("Was not able to remove "+c.kind, "error", err)
+		return
+	}
+	c.unindex(hash)
+}
+
+//removeHash deletes the section with hash from disk and the index.
+func (c *persistentCache) removeHash(hash string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.remove(hash)
+}
+
+//RemoveZone deletes all sections of the given zone from disk and the index.
+func (c *persistentCache) RemoveZone(zone string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for hash := range c.zoneMap[zone] {
+		c.remove(hash)
+	}
+}
+
+//Checkpoint returns all stored sections
+func (c *persistentCache) Checkpoint() (sections []section.Section) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	for _, hash := range c.log.hashes() {
+		if s, ok := c.log.get(hash); ok {
+			sections = append(sections, s)
+		}
+	}
+	return
+}
+
+//Sync commits all sections added or removed since the last sync to disk.
+func (c *persistentCache) Sync() error {
+	return c.log.sync()
+}
+
+//Resize sets the maximum number of sections in the cache to maxSize. No sections are removed, but
+//only internal ones are added as long as the cache holds maxSize or more sections.
+func (c *persistentCache) Resize(maxSize int) {
+	c.counter.SetMaxCount(maxSize)
+}
+
+//Len returns the number of elements in the cache.
+func (c *persistentCache) Len() int {
+	return c.counter.Value()
+}
+
+//Close syncs and closes the disk log. The cache must not be used afterwards.
+func (c *persistentCache) Close() error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.log.close()
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+/*
+ * persistent negative assertion cache implementation
+ * It stores all shards, pshards and zones in a disk log such that they survive a restart and are
+ * not bounded by the available memory. Only an index from zone and context to the hashes and
+ * ranges of the stored sections is kept in memory. Sections are never evicted. Once the cache holds
+ * maxSize sections, only internal ones are added.
+ */
+type PersistentNegAssertionImpl struct {
+	*persistentCache
+}
+
+//NewPersistentNegAssertion opens the disk log at path or creates it if it does not exist and
+//returns a cache containing all shards, pshards and zones stored in it.
+func NewPersistentNegAssertion(path string, maxSize int) (*PersistentNegAssertionImpl, error) {
+	c, err := openPersistentCache(path, maxSize, "negative assertion", negAssertionIndexKeys)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Opened persistent negative assertion cache", "path", path, "sections", c.Len())
+	return &PersistentNegAssertionImpl{c}, nil
+}
+
+//negAssertionIndexKeys returns the zoneCtxKey of s if it is a shard, pshard or zone.
+func negAssertionIndexKeys(s section.WithSigForward) ([]string, bool) {
+	switch s.(type) {
+	case *section.Shard, *section.Pshard, *section.Zone:
+		return []string{zoneCtxKey(s.GetSubjectZone(), s.GetContext())}, true
+	}
+	return nil, false
+}
+
+//AddShard writes a shard together with an expiration time (number of seconds since 01.01.1970) to
+//disk and adds it to the index. It returns false if the cache is full. In this case, the shard is
+//only added if it is internal.
+func (c *PersistentNegAssertionImpl) AddShard(shard *section.Shard, expiration int64,
+	isInternal bool) bool {
+	return c.add(shard, expiration, isInternal)
+}
+
+//AddPshard writes a pshard together with an expiration time (number of seconds since 01.01.1970)
+//to disk and adds it to the index. It returns false if the cache is full. In this case, the pshard
+//is only added if it is internal.
+func (c *PersistentNegAssertionImpl) AddPshard(pshard *section.Pshard, expiration int64,
+	isInternal bool) bool {
+	return c.add(pshard, expiration, isInternal)
+}
+
+//AddZone writes a zone together with an expiration time (number of seconds since 01.01.1970) to
+//disk and adds it to the index. It returns false if the cache is full. In this case, the zone is
+//only added if it is internal.
+func (c *PersistentNegAssertionImpl) AddZone(zone *section.Zone, expiration int64,
+	isInternal bool) bool {
+	return c.add(zone, expiration, isInternal)
+}
+
+//Get returns true and all shards, pshards and zones of zone and context which overlap with
+//interval if there exist some. Otherwise nil and false is returned.
+func (c *PersistentNegAssertionImpl) Get(zone, context string, interval section.Interval) (
+	[]section.WithSigForward, bool) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	hashes := make(map[string]bool)
+	for hash := range c.keyIndex[zoneCtxKey(zone, context)] {
+		if section.Intersect(c.metaData[hash], interval) {
+			hashes[hash] = true
+		}
+	}
+	secs := c.read(hashes)
+	return secs, len(secs) > 0
+}
+
+//Remove deletes the shard, pshard or zone s from disk and the index.
+func (c *PersistentNegAssertionImpl) Remove(s section.WithSigForward) {
+	c.removeHash(s.Hash())
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/signature"
+)
+
+func TestPersistentNegAssertionCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "persistentNegAssertion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	logPath := path.Join(dir, "negAssertions.log")
+	c, err := NewPersistentNegAssertion(logPath, 10)
+	if err != nil {
+		t.Fatalf("Was not able to create cache: %v", err)
+	}
+	shards := getShards()
+	zones := getZones()
+	sig := signature.Sig{Data: []byte("signature")}
+	shards[0].AddSig(sig)
+	shards[2].AddSig(sig)
+	zones[0].AddSig(sig)
+	expiration := time.Now().Add(time.Hour).Unix()
+	c.AddShard(shards[0], expiration, true)
+	c.AddShard(shards[2], expiration, false)
+	c.AddZone(zones[0], time.Now().Add(-time.Hour).Unix(), false)
+	if c.Len() != 3 {
+		t.Fatalf("Sections were not added to cache len=%d", c.Len())
+	}
+	c.Close()
+
+	//Content survives a restart
+	c, err = NewPersistentNegAssertion(logPath, 10)
+	if err != nil {
+		t.Fatalf("Was not able to reopen cache: %v", err)
+	}
+	defer c.Close()
+	if s, ok := c.Get("ch", ".", section.TotalInterval{}); !ok || len(s) != 3 || c.Len() != 3 {
+		t.Errorf("Wrong sections after reopening len=%d actual=%v", c.Len(), s)
+	}
+	s, ok := c.Get("ch", ".", section.StringInterval{Name: "d"})
+	if !ok || len(s) != 2 {
+		t.Errorf("Wrong sections in range actual=%v", s)
+	}
+	for _, sec := range s {
+		if sec.Hash() != shards[2].Hash() && sec.Hash() != zones[0].Hash() {
+			t.Errorf("Section outside of range returned %v", sec)
+		}
+	}
+	c.RemoveExpiredValues()
+	if s, ok := c.Get("ch", ".", section.TotalInterval{}); !ok || len(s) != 2 || c.Len() != 2 {
+		t.Errorf("Expired zone was not removed len=%d actual=%v", c.Len(), s)
+	}
+	c.Remove(shards[0])
+	if s, ok := c.Get("ch", ".", section.StringInterval{Name: "b"}); ok || c.Len() != 1 {
+		t.Errorf("Shard was not removed len=%d actual=%v", c.Len(), s)
+	}
+	c.RemoveZone("ch")
+	if s, ok := c.Get("ch", ".", section.TotalInterval{}); ok || c.Len() != 0 {
+		t.Errorf("Zone was not removed len=%d actual=%v", c.Len(), s)
+	}
+}
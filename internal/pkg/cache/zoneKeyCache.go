@@ -133,6 +133,8 @@ func (c *ZoneKeyImpl) Add(assertion *section.Assertion, publicKey keys.PublicKey
 			}
 		}
 	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
 	return c.counter.Value() < c.warnSize
 }
 
@@ -183,6 +185,31 @@ func (c *ZoneKeyImpl) RemoveExpiredKeys() {
 	}
 }
 
+//RemoveZone deletes all public keys of zone and context from the cache.
+func (c *ZoneKeyImpl) RemoveZone(zone, context string) {
+	for _, value := range c.cache.GetAll() {
+		val := value.(*zoneKeyCacheValue)
+		if val.zone != zone || val.context != context {
+			continue
+		}
+		val.mux.Lock() //This lock makes sure that no add methods are interfering while deleting
+		//the pointer to this entry.
+		if !val.deleted {
+			val.deleted = true
+			for _, key := range val.publicKeys.GetAllKeys() {
+				if _, ok := val.publicKeys.Remove(key); ok {
+					c.counter.Dec()
+					c.mux.Lock()
+					c.keysPerContextZone[val.getContextZone()]--
+					c.mux.Unlock()
+				}
+			}
+			c.cache.Remove(val.getCacheKey())
+		}
+		val.mux.Unlock()
+	}
+}
+
 //Checkpoint returns all cached assertions
 func (c *ZoneKeyImpl) Checkpoint() (assertions []section.Section) {
 	entries := c.cache.GetAll()
@@ -195,6 +222,17 @@ func (c *ZoneKeyImpl) Checkpoint() (assertions []section.Section) {
 	return
 }
 
+//Resize sets the maximum number of public keys, the number of public keys after which Add returns
+//false, and the number of public keys per zone after which a message is logged. Surplus public keys
+//are removed when the next public key is added.
+func (c *ZoneKeyImpl) Resize(maxSize, warnSize, maxKeysPerZone int) {
+	c.counter.SetMaxCount(maxSize)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.warnSize = warnSize
+	c.maxPublicKeysPerZone = maxKeysPerZone
+}
+
 //Len returns the number of public keys currently in the cache.
 func (c *ZoneKeyImpl) Len() int {
 	return c.counter.Value()
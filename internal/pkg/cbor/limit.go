@@ -0,0 +1,130 @@
+package cbor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/britram/borat"
+)
+
+//ErrMessageTooLarge is returned by a LimitedReader when the encoding of an item exceeds its limit.
+var ErrMessageTooLarge = errors.New("encoded message exceeds the maximum size")
+
+var errIndefiniteLength = errors.New("indefinite length items are not supported")
+
+/*
+ * LimitedReader decodes items of bounded size from a stream. It first reads the complete encoding
+ * of the next item into memory by following the headers of the item and its nested items. Reading
+ * is aborted as soon as the encoding exceeds the limit, such that a peer cannot make the reader
+ * allocate or buffer an unbounded amount of memory by announcing or sending a large item.
+ */
+type LimitedReader struct {
+	in    *bufio.Reader
+	limit uint64
+	buf   bytes.Buffer
+}
+
+//NewLimitedReader returns a reader which decodes items of at most limit bytes from in. If limit is
+//not positive, the size of items is not limited. The returned reader buffers in, thus in must not
+//be read by anyone else afterwards.
+func NewLimitedReader(in io.Reader, limit int) *LimitedReader {
+	r := &LimitedReader{in: bufio.NewReader(in), limit: math.MaxUint64}
+	if limit > 0 {
+		r.limit = uint64(limit)
+	}
+	return r
+}
+
+//Unmarshal reads the next item and decodes it into x. It returns io.EOF if in is exhausted before
+//the item starts and ErrMessageTooLarge if the item's encoding is larger than the limit. In the
+//latter case, the position in the stream is lost and no further items can be read.
+func (r *LimitedReader) Unmarshal(x interface{}) error {
+	r.buf.Reset()
+	if err := r.readItem(); err != nil {
+		return err
+	}
+	return borat.NewCBORReader(bytes.NewReader(r.buf.Bytes())).Unmarshal(x)
+}
+
+//readItem copies the encoding of the next item to r.buf.
+func (r *LimitedReader) readItem() error {
+	//pending is the number of items which still have to be read. Each item is at least one byte
+	//long.
+	pending := uint64(1)
+	for pending > 0 {
+		pending--
+		head, err := r.in.ReadByte()
+		if err != nil {
+			if err == io.EOF && r.buf.Len() > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if uint64(r.buf.Len()) >= r.limit {
+			return ErrMessageTooLarge
+		}
+		r.buf.WriteByte(head)
+		major, info := head>>5, head&0x1f
+		arg := uint64(info)
+		switch {
+		case info < 24:
+		case info <= 27:
+			n := uint64(1) << (info - 24)
+			if err := r.copy(n); err != nil {
+				return err
+			}
+			arg = 0
+			for _, b := range r.buf.Bytes()[uint64(r.buf.Len())-n:] {
+				arg = arg<<8 | uint64(b)
+			}
+		default:
+			return errIndefiniteLength
+		}
+		switch major {
+		case 2, 3: //byte and text string
+			if err := r.copy(arg); err != nil {
+				return err
+			}
+		case 4: //array
+			pending, err = r.add(pending, arg)
+		case 5: //map
+			if pending, err = r.add(pending, arg); err == nil {
+				pending, err = r.add(pending, arg)
+			}
+		case 6: //tag
+			pending, err = r.add(pending, 1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//add returns pending + n. It returns ErrMessageTooLarge if the pending items do not fit into the
+//limit anymore.
+func (r *LimitedReader) add(pending, n uint64) (uint64, error) {
+	remaining := r.limit - uint64(r.buf.Len())
+	if n > remaining || pending+n > remaining {
+		return 0, ErrMessageTooLarge
+	}
+	return pending + n, nil
+}
+
+//copy appends the next n bytes of the stream to r.buf. It returns ErrMessageTooLarge without
+//reading anything if they do not fit into the limit.
+func (r *LimitedReader) copy(n uint64) error {
+	if n > r.limit-uint64(r.buf.Len()) {
+		return ErrMessageTooLarge
+	}
+	if _, err := io.CopyN(&r.buf, r.in, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
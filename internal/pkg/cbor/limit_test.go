@@ -0,0 +1,126 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+//countingReader counts the number of bytes read from in.
+type countingReader struct {
+	in io.Reader
+	n  int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.in.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestLimitedReaderReadItem(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    []byte
+		limit    int
+		buffered int //number of bytes in r.buf after readItem returned
+		err      error
+	}{
+		{"small uint", []byte{0x17}, 1, 1, nil},
+		{"uint", []byte{0x19, 0x03, 0xe8}, 3, 3, nil},
+		{"negative int", []byte{0x38, 0x63}, 2, 2, nil},
+		{"nested arrays", []byte{0x82, 0x01, 0x82, 0x02, 0x81, 0x03}, 6, 6, nil},
+		{"map with nested items", []byte{0xa2, 0x01, 0x81, 0x02, 0x61, 'a', 0xa1, 0x03, 0x04}, 9, 9,
+			nil},
+		{"tag", []byte{0xc1, 0x1a, 0x5b, 0xc1, 0x2c, 0x00}, 6, 6, nil},
+		{"nested tags", []byte{0xd8, 0x20, 0xc1, 0x63, 'a', 'b', 'c'}, 7, 7, nil},
+		{"float16", []byte{0xf9, 0x3e, 0x00}, 3, 3, nil},
+		{"float32", []byte{0xfa, 0x47, 0xc3, 0x50, 0x00}, 5, 5, nil},
+		{"float64", []byte{0xfb, 0x3f, 0xf1, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}, 9, 9, nil},
+		{"simple values", []byte{0x83, 0xf4, 0xf5, 0xf6}, 4, 4, nil},
+		{"array of floats", []byte{0x82, 0xf9, 0x3c, 0x00, 0xf9, 0x7c, 0x00}, 7, 7, nil},
+		{"indefinite array", []byte{0x9f, 0x01, 0xff}, 10, 1, errIndefiniteLength},
+		{"indefinite map", []byte{0xbf, 0x01, 0x02, 0xff}, 10, 1, errIndefiniteLength},
+		{"indefinite text string", []byte{0x7f, 0x61, 'a', 0xff}, 10, 1, errIndefiniteLength},
+		{"indefinite nested array", []byte{0x81, 0x9f, 0xff}, 10, 2, errIndefiniteLength},
+		{"break", []byte{0xff}, 10, 1, errIndefiniteLength},
+		{"string at limit", []byte{0x63, 'a', 'b', 'c'}, 4, 4, nil},
+		{"string at limit+1", []byte{0x64, 'a', 'b', 'c', 'd'}, 4, 1, ErrMessageTooLarge},
+		{"array at limit", []byte{0x83, 0x01, 0x02, 0x03}, 4, 4, nil},
+		{"array at limit+1", []byte{0x84, 0x01, 0x02, 0x03, 0x04}, 4, 1, ErrMessageTooLarge},
+		{"nested array at limit+1", []byte{0x82, 0x01, 0x82, 0x02, 0x03}, 4, 3,
+			ErrMessageTooLarge},
+		{"map at limit+1", []byte{0xa2, 0x01, 0x02, 0x03, 0x04}, 4, 1, ErrMessageTooLarge},
+		{"float at limit+1", []byte{0x81, 0xfb, 0, 0, 0, 0, 0, 0, 0, 0}, 9, 2, ErrMessageTooLarge},
+		{"uint header at limit+1", []byte{0x1b, 0, 0, 0, 0, 0, 0, 0, 1}, 8, 1, ErrMessageTooLarge},
+		{"string header announcing more than limit", []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0xff, 0xff, 0xff}, 100, 9, ErrMessageTooLarge},
+		{"array header announcing more than limit", []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0xff, 0xff, 0xff}, 100, 9, ErrMessageTooLarge},
+		{"map header announcing more than limit", []byte{0xbb, 0x7f, 0xff, 0xff, 0xff, 0xff,
+			0xff, 0xff, 0xff}, 100, 9, ErrMessageTooLarge},
+		{"following item is not read", []byte{0x01, 0x02}, 1, 1, nil},
+		{"unlimited", []byte{0x5a, 0x00, 0x01, 0x00, 0x00}, 0, 5, io.ErrUnexpectedEOF},
+		{"truncated string", []byte{0x63, 'a'}, 10, 2, io.ErrUnexpectedEOF},
+		{"truncated array", []byte{0x82, 0x01}, 10, 2, io.ErrUnexpectedEOF},
+		{"empty stream", []byte{}, 10, 0, io.EOF},
+	}
+	for _, test := range tests {
+		r := NewLimitedReader(bytes.NewReader(test.input), test.limit)
+		err := r.readItem()
+		if err != test.err {
+			t.Errorf("%s: wrong error. expected=%v actual=%v", test.name, test.err, err)
+		}
+		if r.buf.Len() != test.buffered {
+			t.Errorf("%s: wrong number of buffered bytes. expected=%d actual=%d", test.name,
+				test.buffered, r.buf.Len())
+		}
+		if err == nil && !bytes.Equal(r.buf.Bytes(), test.input[:test.buffered]) {
+			t.Errorf("%s: wrong encoding buffered. expected=%x actual=%x", test.name,
+				test.input[:test.buffered], r.buf.Bytes())
+		}
+	}
+}
+
+func TestLimitedReaderTooLarge(t *testing.T) {
+	payload := make([]byte, 1<<20)
+	var tests = []struct {
+		name   string
+		header []byte
+		limit  int
+	}{
+		{"string at limit+1", []byte{0x5a, 0x00, 0x10, 0x00, 0x00}, len(payload) + 4},
+		{"header announcing more than limit", []byte{0x5a, 0x00, 0x10, 0x00, 0x00}, 1000},
+		{"array header announcing more than limit", []byte{0x9a, 0x00, 0x10, 0x00, 0x00}, 1000},
+	}
+	for _, test := range tests {
+		in := &countingReader{in: io.MultiReader(bytes.NewReader(test.header),
+			bytes.NewReader(payload))}
+		r := NewLimitedReader(in, test.limit)
+		if err := r.readItem(); err != ErrMessageTooLarge {
+			t.Errorf("%s: wrong error. expected=%v actual=%v", test.name, ErrMessageTooLarge, err)
+		}
+		if r.buf.Len() != len(test.header) {
+			t.Errorf("%s: content was buffered. expected=%d actual=%d", test.name,
+				len(test.header), r.buf.Len())
+		}
+		if in.n >= len(payload) {
+			t.Errorf("%s: the whole message was read. read=%d", test.name, in.n)
+		}
+	}
+}
+
+func TestLimitedReaderStream(t *testing.T) {
+	input := []byte{0x82, 0x01, 0x02, 0x63, 'a', 'b', 'c', 0x18, 0x64}
+	r := NewLimitedReader(bytes.NewReader(input), 4)
+	for i, want := range [][]byte{input[:3], input[3:7], input[7:]} {
+		r.buf.Reset()
+		if err := r.readItem(); err != nil || !bytes.Equal(r.buf.Bytes(), want) {
+			t.Errorf("%d: wrong item. expected=%x actual=%x error=%v", i, want, r.buf.Bytes(), err)
+		}
+	}
+	r.buf.Reset()
+	if err := r.readItem(); err != io.EOF {
+		t.Errorf("wrong error at the end of the stream. expected=%v actual=%v", io.EOF, err)
+	}
+}
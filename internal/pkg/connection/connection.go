@@ -66,6 +66,19 @@ func UnmarshalNetAddr(data []byte) (Type, net.Addr, error) {
 		if err = json.Unmarshal(addrData, &value); err != nil {
 			return -1, nil, err
 		}
+	case "UDP":
+		value = reflect.New(reflect.TypeOf(net.UDPAddr{})).Interface()
+		t = UDP
+		if _, ok := m["UDPAddr"]; !ok {
+			return -1, nil, errors.New("UDPAddr key not found in JSON config")
+		}
+		addrData, err := json.Marshal(m["UDPAddr"])
+		if err != nil {
+			return -1, nil, err
+		}
+		if err = json.Unmarshal(addrData, &value); err != nil {
+			return -1, nil, err
+		}
 	case "SCION":
 		if _, ok := m["SCIONAddr"]; !ok {
 			return -1, nil, errors.New("local address is required for SCION")
@@ -98,6 +111,7 @@ type Type int
 const (
 	TCP Type = iota + 1
 	SCION
+	UDP
 )
 
 //CreateConnection returns a newly created connection with connInfo or an error
@@ -105,6 +119,8 @@ func CreateConnection(addr net.Addr) (conn net.Conn, err error) {
 	switch addr.(type) {
 	case *net.TCPAddr:
 		return tls.Dial(addr.Network(), addr.String(), &tls.Config{InsecureSkipVerify: true})
+	case *net.UDPAddr:
+		return net.DialUDP(addr.Network(), nil, addr.(*net.UDPAddr))
 	case *snet.Addr:
 		addr := addr.(*snet.Addr)
 		rawIA, err := ioutil.ReadFile(fmt.Sprintf("%s/gen/ia", os.Getenv("SC")))
@@ -181,6 +197,18 @@ func Listen(conn net.Conn, tok token.Token, done chan<- message.Message, ec chan
 			}
 			return
 		}
+	case *net.UDPAddr:
+		//Each datagram contains exactly one message.
+		buf := make([]byte, MaxUDPPacketBytes)
+		n, err := conn.Read(buf)
+		if err != nil {
+			ec <- fmt.Errorf("failed to read datagram: %v", err)
+			return
+		}
+		if err := cbor.NewReader(bytes.NewReader(buf[:n])).Unmarshal(&msg); err != nil {
+			ec <- fmt.Errorf("failed to unmarshal CBOR: %v", err)
+			return
+		}
 	case *snet.Addr:
 		buf := make([]byte, MaxUDPPacketBytes)
 		n, _, err := conn.(snet.Conn).ReadFromSCION(buf)
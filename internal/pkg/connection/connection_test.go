@@ -0,0 +1,38 @@
+package connection
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalNetAddr(t *testing.T) {
+	var tests = []struct {
+		data  string
+		t     Type
+		addr  net.Addr
+		valid bool
+	}{
+		{`{"Type":"TCP","TCPAddr":{"IP":"192.0.2.1","Port":55553,"Zone":""}}`, TCP,
+			&net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 55553}, true},
+		{`{"Type":"UDP","UDPAddr":{"IP":"192.0.2.1","Port":53,"Zone":""}}`, UDP,
+			&net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 53}, true},
+		{`{"Type":"UDP","UDPAddr":{"IP":"2001:db8::1","Port":53,"Zone":""}}`, UDP,
+			&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 53}, true},
+		{`{"Type":"UDP","TCPAddr":{"IP":"192.0.2.1","Port":53,"Zone":""}}`, -1, nil, false},
+		{`{"Type":"UDP","UDPAddr":{"IP":"192.0.2.1","Port":"53"}}`, -1, nil, false},
+		{`{"Type":"Unknown"}`, -1, nil, false},
+		{`not json`, -1, nil, false},
+	}
+	for _, test := range tests {
+		typ, addr, err := UnmarshalNetAddr([]byte(test.data))
+		if (err == nil) != test.valid {
+			t.Errorf("%s: wrong error. expected valid=%t actual=%v", test.data, test.valid, err)
+			continue
+		}
+		if typ != test.t || !reflect.DeepEqual(addr, test.addr) {
+			t.Errorf("%s: wrong address. expected=%v,%v actual=%v,%v", test.data, test.t, test.addr,
+				typ, addr)
+		}
+	}
+}
@@ -67,6 +67,14 @@ func (m *Counter) Info() (int, int) {
 	return m.count, m.maxCount
 }
 
+//SetMaxCount sets maxCount to maxCount. It returns true if count >= maxCount.
+func (m *Counter) SetMaxCount(maxCount int) bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.maxCount = maxCount
+	return m.count >= m.maxCount
+}
+
 //IsFull returns true if count is larger or equal to maxCount.
 func (m *Counter) IsFull() bool {
 	return m.count >= m.maxCount
@@ -182,3 +182,14 @@ func TestString(t *testing.T) {
 		t.Errorf("string representation of the counter wrong.")
 	}
 }
+
+func TestSetMaxCount(t *testing.T) {
+	counter := New(5)
+	counter.count = 3
+	if counter.SetMaxCount(4) || counter.maxCount != 4 {
+		t.Errorf("counter is not full after increasing maxCount. %v", counter)
+	}
+	if !counter.SetMaxCount(2) || counter.maxCount != 2 || counter.count != 3 {
+		t.Errorf("counter is full after decreasing maxCount. %v", counter)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -56,6 +57,33 @@ func LoadPublicKeys(keyPath string) ([]*pem.Block, error) {
 	return output, nil
 }
 
+//LoadPrivateKeys reads all unencrypted private keys stored in the directory at keyPath in pem format
+//and returns a map from PublicKeyID to the corresponding private key data.
+func LoadPrivateKeys(keyPath string) (map[keys.PublicKeyID]interface{}, error) {
+	output := make(map[keys.PublicKeyID]interface{})
+	files, err := ioutil.ReadDir(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("Was not able to read directory: %v", err)
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), SecSuffix) {
+			keyPem, err := DecryptKey(keyPath, f.Name(), "")
+			if err != nil {
+				return nil, fmt.Errorf("Was not able to decrypt key: %v", err)
+			}
+			keyID, pkey, err := PemToKeyID(keyPem)
+			if err != nil {
+				return nil, fmt.Errorf("Was not able to decode pem encoded private key: %v", err)
+			}
+			if _, ok := output[keyID]; ok {
+				return nil, errors.New("Two keys for the same key meta data are not allowed")
+			}
+			output[keyID] = pkey
+		}
+	}
+	return output, nil
+}
+
 func loadPemBlock(folder, name string) (*pem.Block, error) {
 	data, err := ioutil.ReadFile(path.Join(folder, name))
 	if err != nil {
@@ -76,8 +76,15 @@ type Resolver struct {
 	Connections       cache.Connection
 	MaxCacheValidity  util.MaxCacheValidity
 	MaxRecursiveCount int
-	sendQuery         querySender
-	handleAnswer      answerHandler
+	//Policy determines in which order forwarders and root name servers are contacted.
+	Policy SelectionPolicy
+	//Backoff is the duration a forwarder or root name server is avoided after a failed query. It
+	//doubles with each consecutive failure up to MaxBackoff.
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	upstreams    upstreams
+	sendQuery    querySender
+	handleAnswer answerHandler
 }
 
 //New creates a resolver with the given parameters and default settings
@@ -94,25 +101,36 @@ func New(rootNS, forwarders []net.Addr, rootKeyPath string, mode ResolutionMode,
 		Connections:       cache.NewConnection(maxConn),
 		MaxCacheValidity:  maxCacheValidity,
 		MaxRecursiveCount: maxRecursiveCount,
+		Policy:            Failover,
+		Backoff:           defaultBackoff,
+		MaxBackoff:        defaultMaxBackoff,
 		// now the pointers to functions
 		sendQuery:    util.SendQuery,
 		handleAnswer: handleAnswer,
 	}
-	// load the root zone public key and store it as a delegation:
+	if err := r.LoadRootKey(rootKeyPath); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//LoadRootKey loads the root zone public key from rootKeyPath and stores it as a delegation. It
+//replaces the previously loaded root zone public key.
+func (r *Resolver) LoadRootKey(rootKeyPath string) error {
 	a := new(section.Assertion)
 	err := util.Load(rootKeyPath, a)
 	if err != nil {
 		log.Warn("Failed to load root zone public key", "err", err)
-		return nil, err
+		return err
 	}
 	since, until := util.GetOverlapValidityForSignatures(a.AllSigs())
-	a.UpdateValidity(since, until, maxCacheValidity.AssertionValidity)
+	a.UpdateValidity(since, until, r.MaxCacheValidity.AssertionValidity)
 	pk := a.Content[0].Value.(keys.PublicKey)
 	pk.ValidSince = a.ValidSince()
 	pk.ValidUntil = a.ValidUntil()
 	a.Content[0].Value = pk
 	r.Delegations.Add(a.FQDN(), a)
-	return r, nil
+	return nil
 }
 
 //ClientLookup forwards the query to the specified forwarders or performs a recursive lookup starting at
@@ -147,6 +165,34 @@ func (r *Resolver) ServerLookup(query *query.Name, addr net.Addr, token token.To
 		log.Error("Query failed", "query failure", err)
 		return
 	}
+	r.sendAnswer(msg, addr, token)
+}
+
+//SupportsAddressLookup returns true if the resolver is able to look up address queries. Reverse
+//lookups are only forwarded but not resolved recursively.
+func (r *Resolver) SupportsAddressLookup() bool {
+	return r.Mode == Forward
+}
+
+//ServerAddressLookup forwards the address query to the specified forwarders and sends the received
+//information to addr. The caller must check with SupportsAddressLookup that r is able to look up
+//address queries.
+func (r *Resolver) ServerAddressLookup(query *query.Address, addr net.Addr, token token.Token) {
+	log.Info("recResolver received address query", "query", query, "token", token)
+	if !r.SupportsAddressLookup() {
+		log.Warn("Address queries are only supported in forwarding mode", "mode", r.Mode)
+		return
+	}
+	msg, err := r.forwardQuery(query)
+	if err != nil {
+		log.Error("Query failed", "query failure", err)
+		return
+	}
+	r.sendAnswer(msg, addr, token)
+}
+
+//sendAnswer sends msg with token to addr.
+func (r *Resolver) sendAnswer(msg *message.Message, addr net.Addr, token token.Token) {
 	msg.Token = token
 	if conn, ok := r.Connections.GetConnection(addr); ok {
 		log.Info("recResolver answers query", "answer", msg, "token", token, "conn",
@@ -176,7 +222,7 @@ func (r *Resolver) createConnAndWrite(addr net.Addr, msg *message.Message) {
 			log.Error("failed to marshal message", err)
 			r.Connections.CloseAndRemoveConnections(addr)
 		}
-	case *snet.Addr:
+	case *snet.Addr, *net.UDPAddr:
 		encoding := new(bytes.Buffer)
 		if err := cbor.NewWriter(encoding).Marshal(&msg); err != nil {
 			log.Error("failed to marshal message to conn:", err)
@@ -187,13 +233,14 @@ func (r *Resolver) createConnAndWrite(addr net.Addr, msg *message.Message) {
 	}
 }
 
-func (r *Resolver) forwardQuery(q *query.Name) (*message.Message, error) {
+//forwardQuery sends q to the forwarders until one of them answers.
+func (r *Resolver) forwardQuery(q section.Section) (*message.Message, error) {
 	if len(r.Forwarders) == 0 {
 		return nil, errors.New("forwarders must be specified to use this mode")
 	}
-	for _, forwarder := range r.Forwarders {
+	for _, forwarder := range r.upstreams.order(r.Forwarders, r.Policy) {
 		msg := message.Message{Token: token.New(), Content: []section.Section{q}}
-		answer, err := r.sendQuery(msg, forwarder, r.DialTimeout*time.Millisecond)
+		answer, err := r.queryUpstream(msg, forwarder)
 		if err == nil {
 			return &answer, nil
 		}
@@ -201,6 +248,27 @@ func (r *Resolver) forwardQuery(q *query.Name) (*message.Message, error) {
 	return nil, fmt.Errorf("could not connect to any of the specified resolver: %v", r.Forwarders)
 }
 
+//queryUpstream sends msg to the forwarder or root name server addr and records the outcome in the
+//upstream's health state.
+func (r *Resolver) queryUpstream(msg message.Message, addr net.Addr) (message.Message, error) {
+	start := time.Now()
+	answer, err := r.sendQuery(msg, addr, r.DialTimeout*time.Millisecond)
+	if err != nil {
+		backoff, maxBackoff := r.Backoff, r.MaxBackoff
+		if backoff == 0 {
+			backoff = defaultBackoff
+		}
+		if maxBackoff == 0 {
+			maxBackoff = defaultMaxBackoff
+		}
+		r.upstreams.failed(addr, backoff, maxBackoff)
+		log.Debug("Query to upstream failed", "upstream", addr, "error", err)
+	} else {
+		r.upstreams.succeeded(addr, time.Since(start))
+	}
+	return answer, err
+}
+
 // recursiveResolve starts at the root and follows delegations until it receives an answer.
 // It aborts if called more than "recurseCount" times recursively.
 func (r *Resolver) recursiveResolve(q *query.Name, recurseCount int) (*message.Message, error) {
@@ -218,24 +286,46 @@ func (r *Resolver) recursiveResolve(q *query.Name, recurseCount int) (*message.M
 		}
 	}
 	//Start recursive lookup
-	for _, root := range r.RootNameServers {
+	minimize := q.ContainsOption(query.QOMinInfoLeakage)
+	for _, root := range r.upstreams.order(r.RootNameServers, r.Policy) {
 		log.Debug("connecting to root server", "serverAddr", root, "query", q)
 		addr := root
+		nofLabels := 1
 		for {
-			msg := message.Message{Token: token.New(), Content: []section.Section{q}}
-			answer, err := r.sendQuery(msg, addr, r.DialTimeout*time.Millisecond)
+			sq := q
+			if minimize {
+				sq = minimizedQuery(q, nofLabels)
+			}
+			msg := message.Message{Token: token.New(), Content: []section.Section{sq}}
+			var answer message.Message
+			var err error
+			if addr == root {
+				answer, err = r.queryUpstream(msg, addr)
+			} else {
+				answer, err = r.sendQuery(msg, addr, r.DialTimeout*time.Millisecond)
+			}
 			if err != nil || len(answer.Content) == 0 {
 				log.Debug("error in send query", "err", err)
+				if sq != q {
+					//The authority might not answer queries for names of its own zone. Fall back
+					//to the full query.
+					nofLabels = len(labels(q.Name))
+					continue
+				}
 				break
 			}
-			log.Info("recursive resolver rcv answer", "answer", answer, "query", q)
-			isFinal, isRedir, redirMap, srvMap, ipMap, nameMap := r.handleAnswer(r, answer, q, recurseCount)
+			log.Info("recursive resolver rcv answer", "answer", answer, "query", sq)
+			isFinal, isRedir, redirMap, srvMap, ipMap, nameMap := r.handleAnswer(r, answer, sq, recurseCount)
 			log.Info("handling answer in recursive lookup", "serverAddr", addr, "isFinal",
 				isFinal, "isRedir", isRedir, "redirMap", redirMap, "srvMap", srvMap, "ipMap", ipMap,
 				"nameMap", nameMap)
-			if isFinal {
+			if isFinal && sq != q {
+				//the authority answered the minimized name itself, ask it for one more label.
+				nofLabels++
+			} else if isFinal {
 				return &answer, nil
 			} else if isRedir {
+				nofLabels++
 				for _, name := range redirMap {
 					addr, err = r.handleRedirect(name, srvMap, ipMap, nameMap, AllowedRedirectTypes)
 					if err == nil {
@@ -253,6 +343,27 @@ func (r *Resolver) recursiveResolve(q *query.Name, recurseCount int) (*message.M
 		q.String())
 }
 
+//minimizedQuery returns a copy of q asking only for the last nofLabels labels of q's name. q itself
+//is returned if its name does not have more labels.
+func minimizedQuery(q *query.Name, nofLabels int) *query.Name {
+	l := labels(q.Name)
+	if nofLabels >= len(l) {
+		return q
+	}
+	mq := *q
+	mq.Name = strings.Join(l[len(l)-nofLabels:], ".") + "."
+	return &mq
+}
+
+//labels returns the labels of the fully qualified domain name name.
+func labels(name string) []string {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return nil
+	}
+	return strings.Split(name, ".")
+}
+
 // handleAnswer stores delegation assertions in the delegationCache. It informs the caller if msg
 // answers q. It also returns if the msg contains a redirect assertion which indicates that
 // another lookup must be performed. Information that is relevant for the next lookup are returned in
@@ -464,6 +575,17 @@ func (r *Resolver) answerDelegQueries(conn net.Conn) {
 				r.Connections.CloseAndRemoveConnection(conn)
 				breaking = true
 			}
+		case *net.UDPAddr:
+			n, err := conn.Read(buf)
+			if err != nil {
+				log.Warn("Failed to read datagram", "err", err)
+				breaking = true
+				break
+			}
+			if err := cbor.NewReader(bytes.NewReader(buf[:n])).Unmarshal(&msg); err != nil {
+				log.Warn("failed to unmarshal CBOR", "err", err)
+				breaking = true
+			}
 		case *snet.Addr:
 			n, _, err := conn.(snet.Conn).ReadFromSCION(buf)
 			if err != nil {
@@ -491,7 +613,7 @@ func (r *Resolver) answerDelegQueries(conn net.Conn) {
 				r.Connections.CloseAndRemoveConnection(conn)
 				breaking = true
 			}
-		case *snet.Addr:
+		case *snet.Addr, *net.UDPAddr:
 			encoding := new(bytes.Buffer)
 			if err := cbor.NewWriter(encoding).Marshal(&msg); err != nil {
 				log.Error("failed to marshal message to conn", err)
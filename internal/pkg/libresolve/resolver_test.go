@@ -1,7 +1,12 @@
 package libresolve
 
 import (
+	"errors"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -14,6 +19,8 @@ import (
 
 	"github.com/netsec-ethz/rains/internal/pkg/cache"
 	"github.com/netsec-ethz/rains/internal/pkg/datastructures/safeHashMap"
+	"github.com/netsec-ethz/rains/internal/pkg/keyManager"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
 	"github.com/netsec-ethz/rains/internal/pkg/util"
 )
 
@@ -94,3 +101,206 @@ func TestRecursiveResolveQueriesRoot(t *testing.T) {
 		t.Fatalf("Should have contacted 1 root server, but did it %d times", numberOfMessagesSent)
 	}
 }
+
+func TestRecursiveResolveMinInfoLeakage(t *testing.T) {
+	resolver := newResolver()
+	resolver.RootNameServers = []net.Addr{&net.TCPAddr{IP: net.IPv4(127, 0, 0, 11), Port: 5022}}
+	sentNames := []string{}
+	resolver.sendQuery = func(msg message.Message, addr net.Addr, timeout time.Duration) (message.Message, error) {
+		sentNames = append(sentNames, msg.Content[0].(*query.Name).Name)
+		return message.Message{Content: []section.Section{&section.Assertion{}}}, nil
+	}
+	resolver.handleAnswer = func(r *Resolver, msg message.Message, q *query.Name, recurseCount int) (
+		isFinal bool, isRedir bool, redirMap map[string]string, srvMap map[string]object.ServiceInfo,
+		ipMap map[string]string, nameMap map[string]object.Name) {
+		if q.Name == "www.ethz.ch." {
+			isFinal = true
+			return
+		}
+		isRedir = true
+		redirMap = map[string]string{q.Name: "ns." + q.Name}
+		ipMap = map[string]string{"ns." + q.Name: "127.0.0.1"}
+		return
+	}
+	q := newQuery()
+	q.Name = "www.ethz.ch."
+	q.Options = []query.Option{query.QOMinInfoLeakage}
+	if _, err := resolver.recursiveResolve(q, 0); err != nil {
+		t.Fatalf("The call to recursiveResolve finished with an error: %v", err)
+	}
+	expected := []string{"ch.", "ethz.ch.", "www.ethz.ch."}
+	if !reflect.DeepEqual(sentNames, expected) {
+		t.Errorf("Wrong names sent upstream. expected=%v, actual=%v", expected, sentNames)
+	}
+	//without the option the full name is sent to every authority
+	sentNames = []string{}
+	q.Options = []query.Option{}
+	if _, err := resolver.recursiveResolve(q, 0); err != nil {
+		t.Fatalf("The call to recursiveResolve finished with an error: %v", err)
+	}
+	if !reflect.DeepEqual(sentNames, []string{"www.ethz.ch."}) {
+		t.Errorf("Wrong names sent upstream. expected=%v, actual=%v", []string{"www.ethz.ch."},
+			sentNames)
+	}
+}
+
+func TestMinimizedQuery(t *testing.T) {
+	var tests = []struct {
+		name      string
+		nofLabels int
+		want      string
+	}{
+		{"www.ethz.ch.", 1, "ch."},
+		{"www.ethz.ch.", 2, "ethz.ch."},
+		{"www.ethz.ch.", 3, "www.ethz.ch."},
+		{"www.ethz.ch.", 4, "www.ethz.ch."},
+		{".", 1, "."},
+	}
+	for i, test := range tests {
+		q := newQuery()
+		q.Name = test.name
+		if mq := minimizedQuery(q, test.nofLabels); mq.Name != test.want {
+			t.Errorf("%d: Wrong minimized name. expected=%s, actual=%s", i, test.want, mq.Name)
+		}
+		if q.Name != test.name {
+			t.Errorf("%d: minimizedQuery modified the original query", i)
+		}
+	}
+}
+
+func TestForwardQueryBackoff(t *testing.T) {
+	dead := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5022}
+	alive := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 5022}
+	resolver := newResolver()
+	resolver.Forwarders = []net.Addr{dead, alive}
+	contacted := []net.Addr{}
+	resolver.sendQuery = func(msg message.Message, addr net.Addr, timeout time.Duration) (message.Message, error) {
+		contacted = append(contacted, addr)
+		if addr == dead {
+			return message.Message{}, errors.New("connection refused")
+		}
+		return message.Message{}, nil
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.forwardQuery(newQuery()); err != nil {
+			t.Fatalf("The call to forwardQuery finished with an error: %v", err)
+		}
+	}
+	//the dead forwarder is in backoff for the second query and thus contacted last
+	expected := []net.Addr{dead, alive, alive}
+	if !reflect.DeepEqual(contacted, expected) {
+		t.Errorf("Wrong forwarders contacted. expected=%v, actual=%v", expected, contacted)
+	}
+}
+
+func TestForwardAddressQuery(t *testing.T) {
+	resolver := newResolver()
+	resolver.Forwarders = []net.Addr{&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5022}}
+	var sent message.Message
+	resolver.sendQuery = func(msg message.Message, addr net.Addr, timeout time.Duration) (message.Message, error) {
+		sent = msg
+		return message.Message{}, nil
+	}
+	q := &query.Address{Context: ".", Types: []object.Type{object.OTName}}
+	if _, err := resolver.forwardQuery(q); err != nil {
+		t.Fatalf("The call to forwardQuery finished with an error: %v", err)
+	}
+	if len(sent.Content) != 1 || sent.Content[0] != q {
+		t.Errorf("Wrong query forwarded. expected=%v, actual=%v", q, sent.Content)
+	}
+	for mode, want := range map[ResolutionMode]bool{Forward: true, Recursive: false} {
+		resolver.Mode = mode
+		if resolver.SupportsAddressLookup() != want {
+			t.Errorf("Wrong address lookup support in mode %v. expected=%t", mode, want)
+		}
+	}
+}
+
+func TestUpstreamsOrder(t *testing.T) {
+	a := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5022}
+	b := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 5022}
+	c := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 3), Port: 5022}
+	addrs := []net.Addr{a, b, c}
+	var tests = []struct {
+		policy   SelectionPolicy
+		prepare  func(u *upstreams)
+		expected [][]net.Addr
+	}{
+		{Failover, func(u *upstreams) {}, [][]net.Addr{{a, b, c}, {a, b, c}}},
+		{RoundRobin, func(u *upstreams) {}, [][]net.Addr{{a, b, c}, {b, c, a}, {c, a, b}, {a, b, c}}},
+		{LowestRTT, func(u *upstreams) {
+			u.succeeded(a, 30*time.Millisecond)
+			u.succeeded(b, 10*time.Millisecond)
+			u.succeeded(c, 20*time.Millisecond)
+		}, [][]net.Addr{{b, c, a}}},
+		{LowestRTT, func(u *upstreams) {
+			u.succeeded(a, 30*time.Millisecond)
+			u.succeeded(b, 10*time.Millisecond)
+		}, [][]net.Addr{{c, b, a}}},
+		{Failover, func(u *upstreams) {
+			u.failed(a, time.Minute, time.Hour)
+			u.failed(b, time.Minute, time.Hour)
+		}, [][]net.Addr{{c, a, b}}},
+		{Failover, func(u *upstreams) {
+			u.failed(a, time.Minute, time.Hour)
+			u.succeeded(a, time.Millisecond)
+		}, [][]net.Addr{{a, b, c}}},
+	}
+	for i, test := range tests {
+		u := &upstreams{}
+		test.prepare(u)
+		for j, expected := range test.expected {
+			if ordered := u.order(addrs, test.policy); !reflect.DeepEqual(ordered, expected) {
+				t.Errorf("%d.%d: Wrong order. expected=%v, actual=%v", i, j, expected, ordered)
+			}
+		}
+	}
+}
+
+func TestUpstreamsFailedBackoff(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5022}
+	u := &upstreams{}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second,
+		5 * time.Second}
+	for i, backoff := range expected {
+		u.failed(addr, time.Second, 5*time.Second)
+		remaining := time.Until(u.state(addr).backoffUntil)
+		if remaining > backoff || remaining < backoff-time.Second/2 {
+			t.Errorf("%d: Wrong backoff. expected=%v, actual=%v", i, backoff, remaining)
+		}
+	}
+}
+
+func TestLoadRootKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rootKey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r := newResolver()
+	for _, name := range []string{"old", "new"} {
+		if err := keyManager.GenerateKey(dir, name, "root key", "ed25519", "pwd", 0); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name+".gob")
+		if err := keyManager.SelfSignedDelegation(filepath.Join(dir, name), path, "pwd", ".", ".",
+			time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.LoadRootKey(path); err != nil {
+			t.Fatalf("%s: was not able to load root key: %v", name, err)
+		}
+		want := new(section.Assertion)
+		if err := util.Load(path, want); err != nil {
+			t.Fatal(err)
+		}
+		a, ok := r.Delegations.Get(".")
+		if !ok || !reflect.DeepEqual(a.(*section.Assertion).Content[0].Value.(keys.PublicKey).Key,
+			want.Content[0].Value.(keys.PublicKey).Key) {
+			t.Errorf("%s: root delegation was not replaced. actual=%v", name, a)
+		}
+	}
+	if err := r.LoadRootKey(filepath.Join(dir, "missing.gob")); err == nil {
+		t.Error("no error returned for missing root key")
+	}
+}
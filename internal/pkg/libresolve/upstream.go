@@ -0,0 +1,129 @@
+package libresolve
+
+import (
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+//SelectionPolicy determines in which order a resolver contacts its forwarders or root name servers.
+type SelectionPolicy int
+
+//go:generate stringer -type=SelectionPolicy
+//go:generate jsonenums -type=SelectionPolicy
+const (
+	//Failover contacts the upstreams in the configured order.
+	Failover SelectionPolicy = iota
+	//RoundRobin starts at the next upstream for each lookup.
+	RoundRobin
+	//Random contacts the upstreams in a random order.
+	Random
+	//LowestRTT contacts the upstreams with the lowest measured round trip time first. Upstreams
+	//without a measurement are contacted before all others.
+	LowestRTT
+)
+
+const (
+	defaultBackoff    = time.Second
+	defaultMaxBackoff = 5 * time.Minute
+	//rttWeight is the weight of a new sample in the smoothed round trip time.
+	rttWeight = 0.125
+)
+
+//upstreamState contains the health information about one upstream.
+type upstreamState struct {
+	//failures is the number of consecutive failed queries.
+	failures int
+	//backoffUntil is the time until which the upstream is considered unavailable.
+	backoffUntil time.Time
+	//rtt is the smoothed round trip time of successful queries. It is zero if there was none.
+	rtt time.Duration
+}
+
+//upstreams keeps track of the health of a resolver's forwarders and root name servers. It is safe
+//for concurrent use.
+type upstreams struct {
+	mutex  sync.Mutex
+	states map[string]*upstreamState
+	//next is the index of the first upstream of the next lookup for the round-robin policy.
+	next int
+}
+
+//state returns the state of addr. It must only be called while holding the mutex.
+func (u *upstreams) state(addr net.Addr) *upstreamState {
+	if u.states == nil {
+		u.states = make(map[string]*upstreamState)
+	}
+	key := addr.Network() + " " + addr.String()
+	if _, ok := u.states[key]; !ok {
+		u.states[key] = &upstreamState{}
+	}
+	return u.states[key]
+}
+
+//order returns addrs in the order in which they should be contacted according to policy.
+//Upstreams in backoff are moved to the end such that they are only contacted when all others
+//failed.
+func (u *upstreams) order(addrs []net.Addr, policy SelectionPolicy) []net.Addr {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	ordered := make([]net.Addr, len(addrs))
+	copy(ordered, addrs)
+	switch policy {
+	case RoundRobin:
+		if len(ordered) > 0 {
+			start := u.next % len(ordered)
+			ordered = append(ordered[start:], ordered[:start]...)
+			u.next = start + 1
+		}
+	case Random:
+		rand.Shuffle(len(ordered), func(i, j int) { ordered[i], ordered[j] = ordered[j], ordered[i] })
+	case LowestRTT:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return u.state(ordered[i]).rtt < u.state(ordered[j]).rtt
+		})
+	}
+	now := time.Now()
+	available := []net.Addr{}
+	backedOff := []net.Addr{}
+	for _, addr := range ordered {
+		if u.state(addr).backoffUntil.After(now) {
+			backedOff = append(backedOff, addr)
+		} else {
+			available = append(available, addr)
+		}
+	}
+	return append(available, backedOff...)
+}
+
+//succeeded records a successful query to addr which took rtt.
+func (u *upstreams) succeeded(addr net.Addr, rtt time.Duration) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	s := u.state(addr)
+	s.failures = 0
+	s.backoffUntil = time.Time{}
+	if s.rtt == 0 {
+		s.rtt = rtt
+	} else {
+		s.rtt = time.Duration((1-rttWeight)*float64(s.rtt) + rttWeight*float64(rtt))
+	}
+}
+
+//failed records a failed query to addr. The upstream is put in backoff for an exponentially
+//growing duration starting at backoff and bounded by maxBackoff.
+func (u *upstreams) failed(addr net.Addr, backoff, maxBackoff time.Duration) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	s := u.state(addr)
+	s.failures++
+	for i := 1; i < s.failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	s.backoffUntil = time.Now().Add(backoff)
+}
@@ -1,8 +1,12 @@
 package message
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"sort"
 
 	cbor "github.com/britram/borat"
 
@@ -122,6 +126,24 @@ func (rm *Message) UnmarshalCBOR(r *cbor.CBORReader) error {
 				return err
 			}
 			rm.Content = append(rm.Content, q)
+		case 6:
+			a := &section.AddressAssertion{}
+			if err := a.UnmarshalMap(val); err != nil {
+				return err
+			}
+			rm.Content = append(rm.Content, a)
+		case 7:
+			z := &section.AddressZone{}
+			if err := z.UnmarshalMap(val); err != nil {
+				return err
+			}
+			rm.Content = append(rm.Content, z)
+		case 8:
+			q := &query.Address{}
+			if err := q.UnmarshalMap(val); err != nil {
+				return err
+			}
+			rm.Content = append(rm.Content, q)
 		case 23:
 			n := &section.Notification{}
 			if err := n.UnmarshalMap(val); err != nil {
@@ -167,6 +189,12 @@ func (rm *Message) MarshalCBOR(w *cbor.CBORWriter) error {
 			msgsect = append(msgsect, [2]interface{}{4, sect})
 		case *query.Name:
 			msgsect = append(msgsect, [2]interface{}{5, sect})
+		case *section.AddressAssertion:
+			msgsect = append(msgsect, [2]interface{}{6, sect})
+		case *section.AddressZone:
+			msgsect = append(msgsect, [2]interface{}{7, sect})
+		case *query.Address:
+			msgsect = append(msgsect, [2]interface{}{8, sect})
 		case *section.Notification:
 			msgsect = append(msgsect, [2]interface{}{23, sect})
 		default:
@@ -185,4 +213,20 @@ const (
 	NoCapability Capability = "urn:x-rains:nocapability"
 	//TLSOverTCP is used when the server listens for tls over tcp connections
 	TLSOverTCP Capability = "urn:x-rains:tlssrv"
+	//UDPSrv is used when the server listens for messages over plain UDP, one message per datagram
+	UDPSrv Capability = "urn:x-rains:udpsrv"
 )
+
+//CapabilityHash returns the hex encoded sha256 hash of the CBOR encoded capability list after it
+//has been sorted in lexicographically increasing order. caps is not modified.
+func CapabilityHash(caps []Capability) string {
+	cs := make([]string, len(caps))
+	for i, c := range caps {
+		cs[i] = string(c)
+	}
+	sort.Strings(cs)
+	encoding := new(bytes.Buffer)
+	cbor.NewCBORWriter(encoding).WriteStringArray(cs)
+	hash := sha256.Sum256(encoding.Bytes())
+	return hex.EncodeToString(hash[:])
+}
@@ -120,6 +120,30 @@ func CheckMessage(m1, m2 Message, t *testing.T) {
 				continue
 			}
 			t.Errorf("Types at position %d of Content slice are different", i)
+		case *section.AddressAssertion:
+			if s2, ok := m2.Content[i].(*section.AddressAssertion); ok {
+				if s1.CompareTo(s2) != 0 {
+					t.Fatalf("Address assertions are not equal q1=%s q2=%s", s1, s2)
+				}
+				continue
+			}
+			t.Errorf("Types at position %d of Content slice are different", i)
+		case *section.AddressZone:
+			if s2, ok := m2.Content[i].(*section.AddressZone); ok {
+				if s1.CompareTo(s2) != 0 {
+					t.Fatalf("Address zones are not equal q1=%s q2=%s", s1, s2)
+				}
+				continue
+			}
+			t.Errorf("Types at position %d of Content slice are different", i)
+		case *query.Address:
+			if s2, ok := m2.Content[i].(*query.Address); ok {
+				if s1.CompareTo(s2) != 0 {
+					t.Fatalf("Address queries are not equal q1=%s q2=%s", s1, s2)
+				}
+				continue
+			}
+			t.Errorf("Types at position %d of Content slice are different", i)
 		case *section.Notification:
 			if s2, ok := m2.Content[i].(*section.Notification); ok {
 				if s1.CompareTo(s2) != 0 {
@@ -133,3 +157,25 @@ func CheckMessage(m1, m2 Message, t *testing.T) {
 		}
 	}
 }
+
+func TestCapabilityHash(t *testing.T) {
+	var tests = []struct {
+		input []Capability
+		want  string
+	}{
+		{[]Capability{TLSOverTCP}, "e5365a09be554ae55b855f15264dbc837b04f5831daeb321359e18cdabab5745"},
+		{[]Capability{NoCapability}, "efa1ab5d8a9573c4976b25c667d93dd9a57a554cd9f275dd20fc3139f58ee3e8"},
+	}
+	for i, test := range tests {
+		if hash := CapabilityHash(test.input); hash != test.want {
+			t.Errorf("%d: Wrong capability hash, expected=%s, actual=%s", i, test.want, hash)
+		}
+	}
+	caps := []Capability{TLSOverTCP, NoCapability}
+	if CapabilityHash(caps) != CapabilityHash([]Capability{NoCapability, TLSOverTCP}) {
+		t.Error("Capability hash depends on the order of the capabilities")
+	}
+	if caps[0] != TLSOverTCP {
+		t.Error("CapabilityHash modified its input")
+	}
+}
@@ -1,6 +1,7 @@
 package message
 
 import (
+	"github.com/netsec-ethz/rains/internal/pkg/address"
 	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
 	"github.com/netsec-ethz/rains/internal/pkg/keys"
 	"github.com/netsec-ethz/rains/internal/pkg/object"
@@ -75,6 +76,37 @@ func GetMessage() Message {
 		Types:      []object.Type{object.OTIP4Addr},
 	}
 
+	subjectAddr, _ := address.Parse("192.0.2.0/24")
+	addrAssertion := &section.AddressAssertion{
+		SubjectAddr: subjectAddr,
+		Context:     globalContext,
+		Content: []object.Object{
+			object.Object{Type: object.OTName, Value: object.Name{Name: testDomain,
+				Types: []object.Type{object.OTIP4Addr}}},
+			object.Object{Type: object.OTRegistrant, Value: "abuse@example.com"},
+		},
+		Signatures: []signature.Sig{sig},
+	}
+
+	hostAddr, _ := address.Parse("192.0.2.1")
+	addrZone := &section.AddressZone{
+		SubjectAddr: subjectAddr,
+		Context:     globalContext,
+		Content: []*section.AddressAssertion{&section.AddressAssertion{
+			SubjectAddr: hostAddr,
+			Content:     addrAssertion.Content,
+		}},
+		Signatures: []signature.Sig{sig},
+	}
+
+	addrQuery := &query.Address{
+		SubjectAddr: hostAddr,
+		Context:     globalContext,
+		Expiration:  159159,
+		Options:     []query.Option{query.QOMinE2ELatency},
+		Types:       []object.Type{object.OTName},
+	}
+
 	notification := &section.Notification{
 		Token: token.New(),
 		Type:  section.NTNoAssertionsExist,
@@ -89,6 +121,9 @@ func GetMessage() Message {
 			q,
 			notification,
 			pshard,
+			addrAssertion,
+			addrZone,
+			addrQuery,
 		},
 		Token:        token.New(),
 		Capabilities: []Capability{Capability("Test"), Capability("Yes!")},
@@ -0,0 +1,673 @@
+//nameset compiles nameset expressions into matchers. A nameset expression describes the set of
+//subject names which are allowed in a zone. It is a modified POSIX Extended Regular Expression
+//which is always matched against a whole subject name:
+//
+//  - Literal characters match themselves. The metacharacters . [ ] ( ) * + ? { } | & ! ^ $ \ are
+//    matched literally when preceded by a backslash. Whitespace is ignored.
+//  - . matches any character and a bracket expression such as [a-z0-9-] or [^_] matches a single
+//    character out of (not out of) a set of characters. Within a bracket expression, [:class:]
+//    adds all characters of a POSIX character class (e.g. alpha, digit, alnum, lower), of a
+//    Unicode general category (e.g. L, Lu, Ll, Nd) or of a Unicode script (e.g. Latin, Greek,
+//    Han) to the set. POSIX character classes only contain ASCII characters.
+//  - The quantifiers *, +, ?, {m}, {m,} and {m,n} repeat the preceding expression. They are used
+//    to express length limits. Bounds must not be larger than 255.
+//  - Expressions are combined with the boolean operators | (union), & (intersection) and the
+//    prefix ! (complement). Concatenation binds tighter than !, ! binds tighter than & and &
+//    binds tighter than |. Parentheses group expressions.
+//  - ^ and $ match the empty string at the beginning and the end of the name, respectively.
+//
+//E.g. the expression [[:Ll:][:Nd:]-]{1,63} & ![-].* & !xn--.* allows all names consisting of one
+//to 63 lowercase letters, digits and hyphens which neither start with a hyphen nor with xn--.
+
+package nameset
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+)
+
+//maxRepeat is the largest bound allowed in a quantifier.
+const maxRepeat = 255
+
+//Matcher is a compiled nameset expression.
+type Matcher struct {
+	expr     string
+	root     node
+	nofNodes int
+}
+
+//Compile parses expr and returns a matcher accepting exactly the names described by expr.
+func Compile(expr string) (*Matcher, error) {
+	p := &parser{input: []rune(expr)}
+	root, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("malformed nameset expression %q: %v", expr, err)
+	}
+	return &Matcher{expr: expr, root: root, nofNodes: p.nofNodes}, nil
+}
+
+//MustCompile is like Compile but panics if expr is malformed.
+func MustCompile(expr string) *Matcher {
+	m, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+//Intersect returns a matcher accepting all names which are accepted by all matchers. It returns nil
+//if matchers is empty.
+func Intersect(matchers ...*Matcher) *Matcher {
+	if len(matchers) == 0 {
+		return nil
+	}
+	m := matchers[0]
+	for _, m2 := range matchers[1:] {
+		//node ids of m2 are shifted such that each node of the result has a unique id.
+		m = &Matcher{
+			expr: fmt.Sprintf("(%s) & (%s)", m.expr, m2.expr),
+			root: &and{id: m.nofNodes + m2.nofNodes, left: m.root,
+				right: shift(m2.root, m.nofNodes)},
+			nofNodes: m.nofNodes + m2.nofNodes + 1,
+		}
+	}
+	return m
+}
+
+//FromObjects compiles the expressions of all nameset objects in objs. It returns a matcher
+//accepting the names which are matched by all of them or nil if objs contains no nameset object.
+func FromObjects(objs []object.Object) (*Matcher, error) {
+	var matchers []*Matcher
+	for _, o := range objs {
+		if o.Type != object.OTNameset {
+			continue
+		}
+		expr, ok := o.Value.(object.NamesetExpr)
+		if !ok {
+			return nil, fmt.Errorf("nameset object value is not a NamesetExpr but %T", o.Value)
+		}
+		m, err := Compile(string(expr))
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return Intersect(matchers...), nil
+}
+
+//Match returns true if name is in the set of names described by m's expression.
+func (m *Matcher) Match(name string) bool {
+	e := &evaluation{name: []rune(name), memo: make([][]positions, m.nofNodes)}
+	return e.ends(m.root, 0)[len(e.name)]
+}
+
+//String returns the expression from which m was compiled.
+func (m *Matcher) String() string {
+	return m.expr
+}
+
+//positions is a set of positions in a name. positions[i] is true if i is in the set.
+type positions []bool
+
+//evaluation holds the state of a single Match call. memo[id][start] caches the end positions of
+//the node with id id when it is evaluated at position start.
+type evaluation struct {
+	name []rune
+	memo [][]positions
+}
+
+//ends returns the set of positions at which a match of n starting at position start can end.
+func (e *evaluation) ends(n node, start int) positions {
+	if e.memo[n.nodeID()] == nil {
+		e.memo[n.nodeID()] = make([]positions, len(e.name)+1)
+	}
+	if res := e.memo[n.nodeID()][start]; res != nil {
+		return res
+	}
+	res := n.ends(e, start)
+	e.memo[n.nodeID()][start] = res
+	return res
+}
+
+//node is an element of a compiled nameset expression.
+type node interface {
+	nodeID() int
+	//ends returns the set of positions at which a match of the node starting at position start
+	//can end.
+	ends(e *evaluation, start int) positions
+}
+
+type charSet struct {
+	id      int
+	negated bool
+	match   func(r rune) bool
+}
+
+func (n *charSet) nodeID() int { return n.id }
+
+func (n *charSet) ends(e *evaluation, start int) positions {
+	res := make(positions, len(e.name)+1)
+	if start < len(e.name) && n.match(e.name[start]) != n.negated {
+		res[start+1] = true
+	}
+	return res
+}
+
+type anchor struct {
+	id    int
+	atEnd bool
+}
+
+func (n *anchor) nodeID() int { return n.id }
+
+func (n *anchor) ends(e *evaluation, start int) positions {
+	res := make(positions, len(e.name)+1)
+	if (start == 0 && !n.atEnd) || (start == len(e.name) && n.atEnd) {
+		res[start] = true
+	}
+	return res
+}
+
+type empty struct {
+	id int
+}
+
+func (n *empty) nodeID() int { return n.id }
+
+func (n *empty) ends(e *evaluation, start int) positions {
+	res := make(positions, len(e.name)+1)
+	res[start] = true
+	return res
+}
+
+type concat struct {
+	id          int
+	left, right node
+}
+
+func (n *concat) nodeID() int { return n.id }
+
+func (n *concat) ends(e *evaluation, start int) positions {
+	res := make(positions, len(e.name)+1)
+	for i, ok := range e.ends(n.left, start) {
+		if ok {
+			res.add(e.ends(n.right, i))
+		}
+	}
+	return res
+}
+
+type or struct {
+	id          int
+	left, right node
+}
+
+func (n *or) nodeID() int { return n.id }
+
+func (n *or) ends(e *evaluation, start int) positions {
+	res := make(positions, len(e.name)+1)
+	res.add(e.ends(n.left, start))
+	res.add(e.ends(n.right, start))
+	return res
+}
+
+type and struct {
+	id          int
+	left, right node
+}
+
+func (n *and) nodeID() int { return n.id }
+
+func (n *and) ends(e *evaluation, start int) positions {
+	res := make(positions, len(e.name)+1)
+	right := e.ends(n.right, start)
+	for i, ok := range e.ends(n.left, start) {
+		res[i] = ok && right[i]
+	}
+	return res
+}
+
+type not struct {
+	id   int
+	expr node
+}
+
+func (n *not) nodeID() int { return n.id }
+
+func (n *not) ends(e *evaluation, start int) positions {
+	res := make(positions, len(e.name)+1)
+	for i, ok := range e.ends(n.expr, start) {
+		res[i] = i >= start && !ok
+	}
+	return res
+}
+
+type repeat struct {
+	id       int
+	expr     node
+	min, max int //max is -1 if there is no upper bound
+}
+
+func (n *repeat) nodeID() int { return n.id }
+
+func (n *repeat) ends(e *evaluation, start int) positions {
+	res := make(positions, len(e.name)+1)
+	current := make(positions, len(e.name)+1)
+	current[start] = true
+	for i := 0; ; i++ {
+		if i >= n.min {
+			if n.max < 0 && res.contains(current) {
+				//all positions reachable from current have already been added
+				break
+			}
+			res.add(current)
+		}
+		if i == n.max {
+			break
+		}
+		next := make(positions, len(e.name)+1)
+		for j, ok := range current {
+			if ok {
+				next.add(e.ends(n.expr, j))
+			}
+		}
+		current = next
+	}
+	return res
+}
+
+//add adds all positions of q to p.
+func (p positions) add(q positions) {
+	for i, ok := range q {
+		if ok {
+			p[i] = true
+		}
+	}
+}
+
+//contains returns true if all positions of q are in p.
+func (p positions) contains(q positions) bool {
+	for i, ok := range q {
+		if ok && !p[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//shift returns a copy of n where offset is added to the id of each node.
+func shift(n node, offset int) node {
+	switch n := n.(type) {
+	case *charSet:
+		return &charSet{id: n.id + offset, negated: n.negated, match: n.match}
+	case *anchor:
+		return &anchor{id: n.id + offset, atEnd: n.atEnd}
+	case *empty:
+		return &empty{id: n.id + offset}
+	case *concat:
+		return &concat{id: n.id + offset, left: shift(n.left, offset), right: shift(n.right, offset)}
+	case *or:
+		return &or{id: n.id + offset, left: shift(n.left, offset), right: shift(n.right, offset)}
+	case *and:
+		return &and{id: n.id + offset, left: shift(n.left, offset), right: shift(n.right, offset)}
+	case *not:
+		return &not{id: n.id + offset, expr: shift(n.expr, offset)}
+	case *repeat:
+		return &repeat{id: n.id + offset, expr: shift(n.expr, offset), min: n.min, max: n.max}
+	}
+	panic(fmt.Sprintf("unknown nameset node type %T", n))
+}
+
+//parser is a recursive descent parser for nameset expressions. The grammar is:
+//
+//	union         = intersection { "|" intersection }
+//	intersection  = complement { "&" complement }
+//	complement    = "!" complement | concatenation
+//	concatenation = quantified { quantified }
+//	quantified    = atom { "*" | "+" | "?" | "{" bounds "}" }
+//	atom          = "(" union ")" | "[" bracket "]" | "." | "^" | "$" | "\" char | char
+type parser struct {
+	input    []rune
+	pos      int
+	nofNodes int
+}
+
+func (p *parser) parse() (node, error) {
+	p.skipSpace()
+	if p.pos == len(p.input) {
+		return nil, fmt.Errorf("expression is empty")
+	}
+	n, err := p.union()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.input) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.input[p.pos], p.pos)
+	}
+	return n, nil
+}
+
+//newID returns a new unique node id.
+func (p *parser) newID() int {
+	p.nofNodes++
+	return p.nofNodes - 1
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+//peek returns the next non whitespace character and false if there is none.
+func (p *parser) peek() (rune, bool) {
+	p.skipSpace()
+	if p.pos == len(p.input) {
+		return 0, false
+	}
+	return p.input[p.pos], true
+}
+
+func (p *parser) union() (node, error) {
+	left, err := p.intersection()
+	if err != nil {
+		return nil, err
+	}
+	for r, ok := p.peek(); ok && r == '|'; r, ok = p.peek() {
+		p.pos++
+		right, err := p.intersection()
+		if err != nil {
+			return nil, err
+		}
+		left = &or{id: p.newID(), left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) intersection() (node, error) {
+	left, err := p.complement()
+	if err != nil {
+		return nil, err
+	}
+	for r, ok := p.peek(); ok && r == '&'; r, ok = p.peek() {
+		p.pos++
+		right, err := p.complement()
+		if err != nil {
+			return nil, err
+		}
+		left = &and{id: p.newID(), left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) concatenation() (node, error) {
+	var left node
+	for r, ok := p.peek(); ok && !strings.ContainsRune("|&)", r); r, ok = p.peek() {
+		right, err := p.quantified()
+		if err != nil {
+			return nil, err
+		}
+		if left == nil {
+			left = right
+		} else {
+			left = &concat{id: p.newID(), left: left, right: right}
+		}
+	}
+	if left == nil {
+		return nil, fmt.Errorf("missing expression at offset %d", p.pos)
+	}
+	return left, nil
+}
+
+func (p *parser) complement() (node, error) {
+	if r, ok := p.peek(); ok && r == '!' {
+		p.pos++
+		if r, ok := p.peek(); !ok || strings.ContainsRune("|&)", r) {
+			return nil, fmt.Errorf("missing expression after ! at offset %d", p.pos)
+		}
+		n, err := p.complement()
+		if err != nil {
+			return nil, err
+		}
+		return &not{id: p.newID(), expr: n}, nil
+	}
+	return p.concatenation()
+}
+
+func (p *parser) quantified() (node, error) {
+	n, err := p.atom()
+	if err != nil {
+		return nil, err
+	}
+	for r, ok := p.peek(); ok && strings.ContainsRune("*+?{", r); r, ok = p.peek() {
+		p.pos++
+		min, max := 0, -1
+		switch r {
+		case '+':
+			min = 1
+		case '?':
+			max = 1
+		case '{':
+			if min, max, err = p.bounds(); err != nil {
+				return nil, err
+			}
+		}
+		n = &repeat{id: p.newID(), expr: n, min: min, max: max}
+	}
+	return n, nil
+}
+
+//bounds parses the content of a {m}, {m,} or {m,n} quantifier after the opening brace.
+func (p *parser) bounds() (int, int, error) {
+	start := p.pos - 1
+	var content []rune
+	for ; p.pos < len(p.input) && p.input[p.pos] != '}'; p.pos++ {
+		if !unicode.IsSpace(p.input[p.pos]) {
+			content = append(content, p.input[p.pos])
+		}
+	}
+	if p.pos == len(p.input) {
+		return 0, 0, fmt.Errorf("missing } of quantifier at offset %d", start)
+	}
+	p.pos++
+	parts := strings.Split(string(content), ",")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("malformed quantifier at offset %d", start)
+	}
+	min, err := parseBound(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed quantifier at offset %d: %v", start, err)
+	}
+	max := min
+	if len(parts) == 2 {
+		max = -1
+		if parts[1] != "" {
+			if max, err = parseBound(parts[1]); err != nil {
+				return 0, 0, fmt.Errorf("malformed quantifier at offset %d: %v", start, err)
+			}
+			if max < min {
+				return 0, 0, fmt.Errorf("malformed quantifier at offset %d: %d > %d", start, min,
+					max)
+			}
+		}
+	}
+	return min, max, nil
+}
+
+//parseBound returns the value of a quantifier bound.
+func parseBound(s string) (int, error) {
+	if s == "" {
+		return 0, fmt.Errorf("bound is missing")
+	}
+	value := 0
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("bound %q is not a number", s)
+		}
+		value = 10*value + int(r-'0')
+		if value > maxRepeat {
+			return 0, fmt.Errorf("bound %s is larger than %d", s, maxRepeat)
+		}
+	}
+	return value, nil
+}
+
+func (p *parser) atom() (node, error) {
+	r, _ := p.peek()
+	start := p.pos
+	p.pos++
+	switch r {
+	case '(':
+		if r, ok := p.peek(); ok && r == ')' {
+			p.pos++
+			return &empty{id: p.newID()}, nil
+		}
+		n, err := p.union()
+		if err != nil {
+			return nil, err
+		}
+		if r, ok := p.peek(); !ok || r != ')' {
+			return nil, fmt.Errorf("missing ) of group at offset %d", start)
+		}
+		p.pos++
+		return n, nil
+	case '[':
+		return p.bracket()
+	case '.':
+		return &charSet{id: p.newID(), match: func(rune) bool { return true }}, nil
+	case '^':
+		return &anchor{id: p.newID()}, nil
+	case '$':
+		return &anchor{id: p.newID(), atEnd: true}, nil
+	case '\\':
+		if p.pos == len(p.input) {
+			return nil, fmt.Errorf("missing character after \\ at offset %d", start)
+		}
+		p.pos++
+		return literal(p.newID(), p.input[p.pos-1]), nil
+	case ']', '*', '+', '?', '{', '}', '!':
+		return nil, fmt.Errorf("unexpected %q at offset %d", r, start)
+	}
+	return literal(p.newID(), r), nil
+}
+
+func literal(id int, r rune) *charSet {
+	return &charSet{id: id, match: func(c rune) bool { return c == r }}
+}
+
+//bracket parses a bracket expression after the opening bracket.
+func (p *parser) bracket() (node, error) {
+	start := p.pos - 1
+	n := &charSet{id: p.newID()}
+	if p.pos < len(p.input) && p.input[p.pos] == '^' {
+		n.negated = true
+		p.pos++
+	}
+	var matchers []func(rune) bool
+	for first := true; ; first = false {
+		if p.pos == len(p.input) {
+			return nil, fmt.Errorf("missing ] of bracket expression at offset %d", start)
+		}
+		r := p.input[p.pos]
+		if r == ']' && !first {
+			p.pos++
+			break
+		}
+		if r == '[' && p.pos+1 < len(p.input) && p.input[p.pos+1] == ':' {
+			end := strings.Index(string(p.input[p.pos+2:]), ":]")
+			if end < 0 {
+				return nil, fmt.Errorf("missing :] of character class at offset %d", p.pos)
+			}
+			name := string(p.input[p.pos+2:])[:end]
+			class, ok := characterClass(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown character class %q at offset %d", name, p.pos)
+			}
+			matchers = append(matchers, class)
+			p.pos += len([]rune(name)) + 4
+			continue
+		}
+		lo, err := p.bracketChar()
+		if err != nil {
+			return nil, err
+		}
+		hi := lo
+		if p.pos+1 < len(p.input) && p.input[p.pos] == '-' && p.input[p.pos+1] != ']' {
+			p.pos++
+			if hi, err = p.bracketChar(); err != nil {
+				return nil, err
+			}
+			if hi < lo {
+				return nil, fmt.Errorf("invalid range %c-%c at offset %d", lo, hi, p.pos)
+			}
+		}
+		matchers = append(matchers, func(c rune) bool { return lo <= c && c <= hi })
+	}
+	n.match = func(c rune) bool {
+		for _, m := range matchers {
+			if m(c) {
+				return true
+			}
+		}
+		return false
+	}
+	return n, nil
+}
+
+//bracketChar returns the next, possibly escaped, character of a bracket expression.
+func (p *parser) bracketChar() (rune, error) {
+	if p.input[p.pos] == '\\' {
+		p.pos++
+		if p.pos == len(p.input) {
+			return 0, fmt.Errorf("missing character after \\ at offset %d", p.pos-1)
+		}
+	}
+	p.pos++
+	return p.input[p.pos-1], nil
+}
+
+//posixClasses contains the POSIX character classes. They only contain ASCII characters.
+var posixClasses = map[string]func(r rune) bool{
+	"alnum": func(r rune) bool { return isASCIILetter(r) || isASCIIDigit(r) },
+	"alpha": isASCIILetter,
+	"blank": func(r rune) bool { return r == ' ' || r == '\t' },
+	"cntrl": func(r rune) bool { return r < 0x20 || r == 0x7f },
+	"digit": isASCIIDigit,
+	"graph": func(r rune) bool { return r > 0x20 && r < 0x7f },
+	"lower": func(r rune) bool { return r >= 'a' && r <= 'z' },
+	"print": func(r rune) bool { return r >= 0x20 && r < 0x7f },
+	"punct": func(r rune) bool {
+		return r > 0x20 && r < 0x7f && !isASCIILetter(r) && !isASCIIDigit(r)
+	},
+	"space": func(r rune) bool { return strings.ContainsRune(" \t\n\v\f\r", r) },
+	"upper": func(r rune) bool { return r >= 'A' && r <= 'Z' },
+	"xdigit": func(r rune) bool {
+		return isASCIIDigit(r) || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F'
+	},
+}
+
+func isASCIILetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+//characterClass returns a function deciding whether a character is in the POSIX character class,
+//Unicode general category or Unicode script called name.
+func characterClass(name string) (func(r rune) bool, bool) {
+	if class, ok := posixClasses[name]; ok {
+		return class, true
+	}
+	if table, ok := unicode.Categories[name]; ok {
+		return func(r rune) bool { return unicode.Is(table, r) }, true
+	}
+	if table, ok := unicode.Scripts[name]; ok {
+		return func(r rune) bool { return unicode.Is(table, r) }, true
+	}
+	return nil, false
+}
@@ -0,0 +1,93 @@
+package nameset
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+)
+
+func TestMatch(t *testing.T) {
+	var tests = []struct {
+		expr    string
+		match   []string
+		noMatch []string
+	}{
+		{"www", []string{"www"}, []string{"ww", "wwww", "", "WWW"}},
+		{"[a-z0-9-]+", []string{"a", "www", "a-1"}, []string{"", "A", "a_b", "a.b"}},
+		{"[^_]*", []string{"", "www", "a.b"}, []string{"_ftp"}},
+		{"[[:Ll:][:Nd:]]{1,3}", []string{"a", "ä1", "zzz"}, []string{"", "aaaa", "A", "-"}},
+		{"[[:Greek:]]+", []string{"αβγ"}, []string{"abc", "αb"}},
+		{"[[:alpha:]]+", []string{"abcXYZ"}, []string{"ä", "a1"}},
+		{"[[:L:]]*", []string{"äÖ", ""}, []string{"a1"}},
+		{".{0,63}", []string{"", strings.Repeat("a", 63)}, []string{strings.Repeat("a", 64)}},
+		{"a{2,}", []string{"aa", "aaaaa"}, []string{"", "a"}},
+		{"a{2}b?", []string{"aa", "aab"}, []string{"a", "aaa", "aabb"}},
+		{"a|bc", []string{"a", "bc"}, []string{"abc", "b"}},
+		{"(ab)+", []string{"ab", "abab"}, []string{"", "aba"}},
+		{"[a-z]+ & !www", []string{"w", "ww", "wwww", "mail"}, []string{"www", "WWW"}},
+		{"!(xn--.*)", []string{"", "xn-", "axn--"}, []string{"xn--", "xn--abc"}},
+		{"[a-z-]{1,63} & ![-].* & !.*[-]", []string{"a", "a-b"}, []string{"-a", "a-", "-"}},
+		{"[[:Ll:][:Nd:]-]{1,63} & ![-].* & !xn--.*", []string{"www", "zürich-1", "x-n"},
+			[]string{"", "-www", "xn--zrich-kva", "WWW", strings.Repeat("a", 64)}},
+		{"a|b&c", []string{"a"}, []string{"b", "c"}},
+		{"!a b", []string{"bb", "b", "a"}, []string{"ab"}},
+		{"a(!b)", []string{"a", "abb"}, []string{"ab"}},
+		{"a\\.b | \\!", []string{"a.b", "!"}, []string{"axb", "a\\.b"}},
+		{"^a$", []string{"a"}, []string{"", "aa"}},
+		{"a^", []string{}, []string{"a", ""}},
+		{"()|a", []string{"", "a"}, []string{"aa"}},
+		{"[]a]+", []string{"]", "a]"}, []string{"b"}},
+		{"[a\\]]", []string{"]", "a"}, []string{"\\"}},
+		{"(a*)*b", []string{"b", "aab"}, []string{strings.Repeat("a", 100)}},
+	}
+	for i, test := range tests {
+		m, err := Compile(test.expr)
+		if err != nil {
+			t.Fatalf("%d: Was not able to compile %s: %v", i, test.expr, err)
+		}
+		for _, name := range test.match {
+			if !m.Match(name) {
+				t.Errorf("%d: %s does not match %q", i, test.expr, name)
+			}
+		}
+		for _, name := range test.noMatch {
+			if m.Match(name) {
+				t.Errorf("%d: %s matches %q", i, test.expr, name)
+			}
+		}
+	}
+}
+
+func TestCompileMalformed(t *testing.T) {
+	for i, expr := range []string{"", " ", "(a", "a)", "[a", "[]", "[b-a]", "[[:foo:]]", "[[:Ll]",
+		"*", "a**b|", "a{", "a{x}", "a{3,2}", "a{256}", "a{1,2,3}", "!", "a&", "|a", "a&!", "a\\", "a!b",
+		"{1}", "}"} {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("%d: Malformed expression was accepted: %q", i, expr)
+		}
+	}
+}
+
+func TestFromObjects(t *testing.T) {
+	m, err := FromObjects([]object.Object{
+		object.Object{Type: object.OTNameset, Value: object.NamesetExpr("[a-z]+")},
+		object.Object{Type: object.OTIP4Addr, Value: "192.0.2.1"},
+		object.Object{Type: object.OTNameset, Value: object.NamesetExpr(".{1,3}")},
+	})
+	if err != nil {
+		t.Fatalf("Was not able to compile nameset objects: %v", err)
+	}
+	for name, want := range map[string]bool{"www": true, "mail": false, "w1": false, "": false} {
+		if m.Match(name) != want {
+			t.Errorf("Wrong match result for %q. expected=%t actual=%t", name, want, !want)
+		}
+	}
+	if m, err := FromObjects([]object.Object{}); m != nil || err != nil {
+		t.Errorf("Expected no matcher and no error. actual=%v,%v", m, err)
+	}
+	_, err = FromObjects([]object.Object{{Type: object.OTNameset, Value: object.NamesetExpr("(")}})
+	if err == nil {
+		t.Error("Malformed nameset object was accepted")
+	}
+}
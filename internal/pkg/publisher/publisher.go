@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
 	"sort"
@@ -12,8 +13,11 @@ import (
 	log "github.com/inconshreveable/log15"
 	"github.com/netsec-ethz/rains/internal/pkg/connection"
 	"github.com/netsec-ethz/rains/internal/pkg/datastructures/bitarray"
+	"github.com/netsec-ethz/rains/internal/pkg/keyManager"
 	"github.com/netsec-ethz/rains/internal/pkg/keys"
 	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/nameset"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
 	"github.com/netsec-ethz/rains/internal/pkg/section"
 	"github.com/netsec-ethz/rains/internal/pkg/siglib"
 	"github.com/netsec-ethz/rains/internal/pkg/signature"
@@ -108,6 +112,13 @@ func (r *Rainspub) Publish() error {
 		}
 		log.Info("Writing updated zonefile to disk completed successfully")
 	}
+	if r.Config.MasterFilePath != "" {
+		masterFile := encoder.EncodeMasterFile([]section.WithSigForward{zone})
+		if err := ioutil.WriteFile(r.Config.MasterFilePath, []byte(masterFile), 0600); err != nil {
+			return err
+		}
+		log.Info("Writing DNS master file to disk completed successfully")
+	}
 	r.publishZone(output)
 	return nil
 }
@@ -376,26 +387,48 @@ func addSignatureMetaData(zone *section.Zone, shards []*section.Shard, pshards [
 //isConsistent performs the checks specified in config
 func isConsistent(zone *section.Zone, shards []*section.Shard, pshards []*section.Pshard,
 	config ConsistencyConfig) bool {
-	if !doConsistencyCheck(zone, config) {
+	var names *nameset.Matcher
+	if config.DoConsistencyCheck || config.CheckNameset {
+		var err error
+		if names, err = zoneNameset(zone); err != nil {
+			log.Error("zone's nameset is malformed", "error", err)
+			return false
+		}
+	}
+	if !doConsistencyCheck(zone, names, config) {
 		return false
 	}
 	for _, shard := range shards {
-		if !doConsistencyCheck(shard, config) {
+		if !doConsistencyCheck(shard, names, config) {
 			return false
 		}
 	}
 	for _, pshard := range pshards {
-		if !doConsistencyCheck(pshard, config) {
+		if !doConsistencyCheck(pshard, names, config) {
 			return false
 		}
 	}
 	return true
 }
 
-//doConsistencyCheck returns true if section is consistent
-func doConsistencyCheck(section section.WithSigForward, config ConsistencyConfig) bool {
+//zoneNameset returns a matcher for the nameset expressions contained in the zone's assertions with
+//subject name @. It returns nil if the zone does not publish a nameset.
+func zoneNameset(zone *section.Zone) (*nameset.Matcher, error) {
+	var objs []object.Object
+	for _, a := range zone.Content {
+		if a.SubjectName == "@" {
+			objs = append(objs, a.Content...)
+		}
+	}
+	return nameset.FromObjects(objs)
+}
+
+//doConsistencyCheck returns true if section is consistent. The subject names of the section's
+//assertions are checked against names if it is not nil.
+func doConsistencyCheck(section section.WithSigForward, names *nameset.Matcher,
+	config ConsistencyConfig) bool {
 	if config.DoConsistencyCheck {
-		if !siglib.ValidSectionAndSignature(section) {
+		if !siglib.ValidSectionAndSignature(section) || !siglib.CheckNameset(section, names) {
 			log.Error("zone content is not consistent")
 			return false
 		}
@@ -415,13 +448,19 @@ func doConsistencyCheck(section section.WithSigForward, config ConsistencyConfig
 				return false
 			}
 		}
+		if config.CheckNameset {
+			if !siglib.CheckNameset(section, names) {
+				log.Error("zone content is not consistent")
+				return false
+			}
+		}
 	}
 	return true
 }
 
 func signZoneContent(zone *section.Zone, shards []*section.Shard, pshards []*section.Pshard,
 	keyPath string) error {
-	keys, err := LoadPrivateKeys(keyPath)
+	keys, err := keyManager.LoadPrivateKeys(keyPath)
 	if err != nil {
 		return fmt.Errorf("Was not able to load private keys: %v", err)
 	}
@@ -453,6 +492,12 @@ func (r *Rainspub) publishZone(zoneContent []section.Section) {
 			Content:      zoneContent,
 			Capabilities: []message.Capability{message.NoCapability},
 		}
+		if r.Config.InfraKeyPath != "" {
+			if err := r.signMessage(&msg); err != nil {
+				log.Error("Was not able to sign message", "error", err)
+				return
+			}
+		}
 		unsuccessfulServers := r.publishSections(msg)
 		if unsuccessfulServers != nil {
 			log.Warn("Was not able to connect and successfully publish to all authoritative servers", "unsuccessfulServers", unsuccessfulServers)
@@ -462,6 +507,15 @@ func (r *Rainspub) publishZone(zoneContent []section.Section) {
 	}
 }
 
+//signMessage signs msg with the infrastructure keys stored at r.Config.InfraKeyPath.
+func (r *Rainspub) signMessage(msg *message.Message) error {
+	ks, err := keyManager.LoadPrivateKeys(r.Config.InfraKeyPath)
+	if err != nil {
+		return err
+	}
+	return siglib.SignMessage(msg, ks, time.Minute)
+}
+
 //publishSections establishes connections to all authoritative servers according to the r.Config. It
 //then sends sections to all of them. It returns the connection information of those servers it was
 //not able to push sections, otherwise nil is returned.
@@ -15,6 +15,7 @@ type Config struct {
 	AuthServers     []connection.Info
 	SrcAddr         connection.Info // Only used for SCION addresses.
 	PrivateKeyPath  string
+	InfraKeyPath    string //empty if messages are not signed
 	ShardingConf    ShardingConfig
 	PShardingConf   PShardingConfig
 	MetaDataConf    MetaDataConfig
@@ -22,6 +23,7 @@ type Config struct {
 	DoSigning       bool
 	MaxZoneSize     int
 	OutputPath      string
+	MasterFilePath  string //empty if the zone is not exported as a DNS master file
 	DoPublish       bool
 }
 
@@ -69,6 +71,7 @@ type ConsistencyConfig struct {
 	SortZone           bool
 	SigNotExpired      bool
 	CheckStringFields  bool
+	CheckNameset       bool
 }
 
 //DefaultConfig return the default configuration for the zone publisher.
@@ -77,6 +80,7 @@ func DefaultConfig() Config {
 		ZonefilePath:   "data/zonefiles/zf.txt",
 		AuthServers:    []connection.Info{},
 		PrivateKeyPath: "data/keys/key_sec.pem",
+		InfraKeyPath:   "",
 		ShardingConf: ShardingConfig{
 			DoSharding:            true,
 			KeepShards:            false,
@@ -110,10 +114,12 @@ func DefaultConfig() Config {
 			SortZone:           false,
 			SigNotExpired:      false,
 			CheckStringFields:  false,
+			CheckNameset:       false,
 		},
-		DoSigning:   true,
-		MaxZoneSize: 60000,
-		OutputPath:  "",
-		DoPublish:   true,
+		DoSigning:      true,
+		MaxZoneSize:    60000,
+		OutputPath:     "",
+		MasterFilePath: "",
+		DoPublish:      true,
 	}
 }
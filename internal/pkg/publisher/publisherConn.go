@@ -173,10 +173,17 @@ func waitForResponse(conn net.Conn, token token.Token, serverError chan<- bool)
 //be closed.
 func handleResponse(conn net.Conn, n *section.Notification) bool {
 	switch n.Type {
-	case section.NTHeartbeat, section.NTNoAssertionsExist, section.NTNoAssertionAvail:
+	case section.NTHeartbeat, section.NTStaleAnswer, section.NTNoAssertionsExist,
+		section.NTNoAssertionAvail:
 	//nop
 	case section.NTCapHashNotKnown:
-	//TODO CFE send back the whole capability list in an empty message
+		msg := message.Message{
+			Token:        token.New(),
+			Capabilities: []message.Capability{message.NoCapability},
+		}
+		if err := cbor.NewWriter(conn).Marshal(&msg); err != nil {
+			log.Warn("Was not able to send capability list", "error", err)
+		}
 	case section.NTBadMessage:
 		log.Error("Sent msg was malformed", "data", n.Data)
 	case section.NTRcvInconsistentMsg:
@@ -185,6 +192,8 @@ func handleResponse(conn net.Conn, n *section.Notification) bool {
 		log.Error("Sent msg was too large", "data", n.Data)
 		//What should we do in this case. apparently it is not possible to send a zone because
 		//it is too large. send shards instead?
+	case section.NTRateLimited:
+		log.Warn("Throttled by server", "data", n.Data)
 	case section.NTUnspecServerErr:
 		log.Error("Unspecified error of other server", "data", n.Data)
 		//TODO CFE resend?
@@ -0,0 +1,142 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	cbor "github.com/britram/borat"
+
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+)
+
+//Address contains information about a reverse lookup query
+type Address struct {
+	SubjectAddr address.Prefix
+	Context     string
+	Types       []object.Type
+	Expiration  int64 //unix seconds
+	Options     []Option
+	KeyPhase    int
+	CurrentTime int64
+}
+
+// UnmarshalMap unpacks a CBOR marshaled map to this struct.
+func (q *Address) UnmarshalMap(m map[int]interface{}) error {
+	if addr, ok := m[5].([]interface{}); ok {
+		if err := q.SubjectAddr.UnmarshalArray(addr); err != nil {
+			return err
+		}
+	} else {
+		return errors.New("cbor address query map does not contain a subject address")
+	}
+	if n, ok := m[6].(string); ok {
+		q.Context = n
+	} else {
+		return errors.New("cbor address query map does not contain a context name")
+	}
+	q.Types = make([]object.Type, 0)
+	if types, ok := m[10].([]interface{}); ok {
+		for _, qt := range types {
+			t, ok := qt.(int)
+			if !ok {
+				return errors.New("cbor address query encoding of a type array's element should be an int")
+			}
+			q.Types = append(q.Types, object.Type(t))
+		}
+	} else {
+		return errors.New("cbor address query map does not contain a types array")
+	}
+	if exp, ok := m[12].(int); ok {
+		q.Expiration = int64(exp)
+	} else {
+		return errors.New("cbor address query map does not contain an expiration")
+	}
+	q.Options = make([]Option, 0)
+	if opts, ok := m[13].([]interface{}); ok {
+		for _, opt := range opts {
+			o, ok := opt.(int)
+			if !ok {
+				return errors.New("cbor address query encoding of a option array's element should be an int")
+			}
+			q.Options = append(q.Options, Option(o))
+		}
+	} else {
+		return errors.New("cbor address query map does not contain a query options array")
+	}
+	if ct, ok := m[14].(int); ok {
+		q.CurrentTime = int64(ct)
+	} else {
+		return errors.New("cbor address query map does not contain the current time")
+	}
+	var ok bool
+	q.KeyPhase, ok = m[17].(int)
+	if !ok {
+		return errors.New("cbor address query encoding of the key phase should be an int")
+	}
+	return nil
+}
+
+// MarshalCBOR implements the CBORMarshaler interface.
+func (q *Address) MarshalCBOR(w *cbor.CBORWriter) error {
+	m := make(map[int]interface{})
+	m[5] = q.SubjectAddr
+	m[6] = q.Context
+	qtypes := make([]int, len(q.Types))
+	for i, qtype := range q.Types {
+		qtypes[i] = int(qtype)
+	}
+	m[10] = qtypes
+	m[12] = q.Expiration
+	qopts := make([]int, len(q.Options))
+	for i, qopt := range q.Options {
+		qopts[i] = int(qopt)
+	}
+	m[13] = qopts
+	m[14] = q.CurrentTime
+	m[17] = q.KeyPhase
+	return w.WriteIntMap(m)
+}
+
+//GetContext returns q's context
+func (q *Address) GetContext() string {
+	return q.Context
+}
+
+//GetExpiration returns q's expiration
+func (q *Address) GetExpiration() int64 {
+	return q.Expiration
+}
+
+//ContainsOption returns true if the query contains the given query option.
+func (q *Address) ContainsOption(option Option) bool {
+	return containsOption(option, q.Options)
+}
+
+//Sort sorts the content of the query lexicographically.
+func (q *Address) Sort() {
+	sort.Slice(q.Options, func(i, j int) bool { return q.Options[i] < q.Options[j] })
+}
+
+//CompareTo compares two address queries and returns 0 if they are equal, 1 if q is greater than
+//query and -1 if q is smaller than query
+func (q *Address) CompareTo(query *Address) int {
+	if c := q.SubjectAddr.CompareTo(query.SubjectAddr); c != 0 {
+		return c
+	}
+	//All remaining fields are compared in the same way as the ones of a name query.
+	return (&Name{Context: q.Context, Types: q.Types, Expiration: q.Expiration,
+		Options: q.Options, KeyPhase: q.KeyPhase, CurrentTime: q.CurrentTime}).CompareTo(
+		&Name{Context: query.Context, Types: query.Types, Expiration: query.Expiration,
+			Options: query.Options, KeyPhase: query.KeyPhase, CurrentTime: query.CurrentTime})
+}
+
+//String implements Stringer interface
+func (q *Address) String() string {
+	if q == nil {
+		return "AddressQuery:nil"
+	}
+	return fmt.Sprintf("AddressQuery:[CTX=%s SA=%s TYPE=%v EXP=%d OPT=%v CT=%d KP=%d]",
+		q.Context, q.SubjectAddr, q.Types, q.Expiration, q.Options, q.CurrentTime, q.KeyPhase)
+}
@@ -0,0 +1,59 @@
+package rains2dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+//Exchange is a minimal stub DNS client. It sends req to the DNS server at addr over network, which
+//is either "udp" or "tcp", and returns the server's response.
+func Exchange(network, addr string, req *Msg, timeout time.Duration) (*Msg, error) {
+	query, err := req.Pack()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	var data []byte
+	switch network {
+	case "udp":
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		data = make([]byte, maxEDNSUDPLen)
+		n, err := conn.Read(data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[:n]
+	case "tcp":
+		if _, err := conn.Write(append(appendUint16(nil, uint16(len(query))), query...)); err != nil {
+			return nil, err
+		}
+		length := make([]byte, 2)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return nil, err
+		}
+		data = make([]byte, binary.BigEndian.Uint16(length))
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unsupported network " + network)
+	}
+	resp := new(Msg)
+	if err := resp.Unpack(data); err != nil {
+		return nil, err
+	}
+	if resp.ID != req.ID || !resp.Response {
+		return nil, errors.New("response does not match the query")
+	}
+	return resp, nil
+}
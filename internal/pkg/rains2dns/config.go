@@ -0,0 +1,73 @@
+package rains2dns
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/connection"
+	"github.com/netsec-ethz/rains/internal/pkg/libresolve"
+)
+
+//Config lists configurations for the DNS gateway, see rains2dns flag description for detail.
+type Config struct {
+	Listeners             []ListenerConfig
+	Forwarders            []connection.Info //if empty, lookups are performed recursively
+	RootNameServers       []connection.Info
+	RootZonePublicKeyPath string
+	MaxConnections        int
+	MaxConcurrentQueries  int //over UDP
+	MaxRecursiveCount     int
+	MaxTTL                time.Duration //in seconds
+	//SelectionPolicy determines in which order forwarders or root name servers are contacted.
+	SelectionPolicy libresolve.SelectionPolicy
+	Backoff         time.Duration //in seconds
+	MaxBackoff      time.Duration //in seconds
+}
+
+//ListenerConfig specifies an address on which DNS queries are accepted over UDP and TCP and the
+//RAINS context in which they are looked up.
+type ListenerConfig struct {
+	Address string
+	Context string
+}
+
+//DefaultConfig returns the default configuration of the DNS gateway.
+func DefaultConfig() Config {
+	return Config{
+		Listeners:             []ListenerConfig{{Address: "127.0.0.1:5353", Context: "."}},
+		Forwarders:            []connection.Info{},
+		RootNameServers:       []connection.Info{},
+		RootZonePublicKeyPath: "data/keys/rootDelegationAssertion.gob",
+		MaxConnections:        10,
+		MaxConcurrentQueries:  100,
+		MaxRecursiveCount:     50,
+		MaxTTL:                time.Hour,
+		SelectionPolicy:       libresolve.Failover,
+		Backoff:               time.Second,
+		MaxBackoff:            5 * time.Minute,
+	}
+}
+
+//LoadConfig loads and returns the gateway configuration stored at configPath.
+func LoadConfig(configPath string) (Config, error) {
+	//Durations are specified in seconds in the config file.
+	config := DefaultConfig()
+	config.MaxTTL /= time.Second
+	config.Backoff /= time.Second
+	config.MaxBackoff /= time.Second
+	file, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		log.Error("Could not open config file...", "path", configPath, "error", err)
+		return Config{}, err
+	}
+	if err = json.Unmarshal(file, &config); err != nil {
+		log.Error("Could not unmarshal json format of config", "error", err)
+		return Config{}, err
+	}
+	config.MaxTTL *= time.Second
+	config.Backoff *= time.Second
+	config.MaxBackoff *= time.Second
+	return config, nil
+}
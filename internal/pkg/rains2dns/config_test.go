@@ -0,0 +1,43 @@
+package rains2dns
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/libresolve"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rains2dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gateway.conf")
+	data := []byte(`{"SelectionPolicy": "LowestRTT", "Backoff": 2, "MaxTTL": 60}`)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("was not able to load config: %v", err)
+	}
+	if config.SelectionPolicy != libresolve.LowestRTT || config.Backoff != 2*time.Second ||
+		config.MaxTTL != time.Minute {
+		t.Errorf("configured values were not loaded. actual=%+v", config)
+	}
+	//Values missing in the config file keep their defaults.
+	if config.MaxBackoff != DefaultConfig().MaxBackoff ||
+		config.MaxRecursiveCount != DefaultConfig().MaxRecursiveCount {
+		t.Errorf("default values were not kept. actual=%+v", config)
+	}
+	if err := ioutil.WriteFile(path, []byte(`{"SelectionPolicy": "Fastest"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("unknown selection policy was accepted")
+	}
+}
@@ -0,0 +1,259 @@
+package rains2dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//Type is the type of a DNS resource record or question.
+type Type uint16
+
+//DNS types which the gateway can answer.
+const (
+	TypeA     Type = 1
+	TypeNS    Type = 2
+	TypeCNAME Type = 5
+	TypeSOA   Type = 6
+	TypeAAAA  Type = 28
+	TypeSRV   Type = 33
+	TypeOPT   Type = 41
+	TypeTLSA  Type = 52
+	TypeANY   Type = 255
+)
+
+//ClassINET is the only DNS class served by the gateway.
+const ClassINET = 1
+
+//DNS response codes.
+const (
+	RcodeSuccess        = 0
+	RcodeFormatError    = 1
+	RcodeServerFailure  = 2
+	RcodeNameError      = 3
+	RcodeNotImplemented = 4
+	RcodeRefused        = 5
+)
+
+const (
+	//headerLen is the length of the fixed DNS header in bytes.
+	headerLen = 12
+	//maxUDPLen is the maximum length of a DNS message over UDP if the client does not announce a
+	//larger size with an OPT record.
+	maxUDPLen = 512
+	//maxNameLen is the maximum length of an encoded domain name.
+	maxNameLen = 255
+	//maxPointers is the maximum number of compression pointers followed while decoding a name.
+	maxPointers = 16
+)
+
+var errShortMsg = errors.New("dns message is too short")
+
+//Header contains the fields of a DNS message header except the section counts.
+type Header struct {
+	ID                 uint16
+	Response           bool
+	Opcode             uint8
+	Authoritative      bool
+	Truncated          bool
+	RecursionDesired   bool
+	RecursionAvailable bool
+	Rcode              uint8
+}
+
+//Question is an entry of the question section of a DNS message.
+type Question struct {
+	Name  string
+	Type  Type
+	Class uint16
+}
+
+//Resource is a DNS resource record. Data contains the record's wire encoded RDATA.
+type Resource struct {
+	Name  string
+	Type  Type
+	Class uint16
+	TTL   uint32
+	Data  []byte
+}
+
+//Msg is a DNS message. Only the subset of the wire format needed by the gateway and its clients is
+//supported. Names are encoded without compression.
+type Msg struct {
+	Header
+	Questions   []Question
+	Answers     []Resource
+	Authorities []Resource
+	Additionals []Resource
+}
+
+//Pack returns the wire encoding of m.
+func (m *Msg) Pack() ([]byte, error) {
+	b := make([]byte, headerLen, maxUDPLen)
+	binary.BigEndian.PutUint16(b[0:], m.ID)
+	flags := uint16(m.Opcode&0xf)<<11 | uint16(m.Rcode&0xf)
+	if m.Response {
+		flags |= 1 << 15
+	}
+	if m.Authoritative {
+		flags |= 1 << 10
+	}
+	if m.Truncated {
+		flags |= 1 << 9
+	}
+	if m.RecursionDesired {
+		flags |= 1 << 8
+	}
+	if m.RecursionAvailable {
+		flags |= 1 << 7
+	}
+	binary.BigEndian.PutUint16(b[2:], flags)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Answers)))
+	binary.BigEndian.PutUint16(b[8:], uint16(len(m.Authorities)))
+	binary.BigEndian.PutUint16(b[10:], uint16(len(m.Additionals)))
+	var err error
+	for _, q := range m.Questions {
+		if b, err = appendName(b, q.Name); err != nil {
+			return nil, err
+		}
+		b = appendUint16(appendUint16(b, uint16(q.Type)), q.Class)
+	}
+	for _, rrs := range [][]Resource{m.Answers, m.Authorities, m.Additionals} {
+		for _, rr := range rrs {
+			if b, err = appendName(b, rr.Name); err != nil {
+				return nil, err
+			}
+			if len(rr.Data) > 0xffff {
+				return nil, fmt.Errorf("rdata of %s is too long", rr.Name)
+			}
+			b = appendUint16(appendUint16(b, uint16(rr.Type)), rr.Class)
+			b = append(b, byte(rr.TTL>>24), byte(rr.TTL>>16), byte(rr.TTL>>8), byte(rr.TTL))
+			b = append(appendUint16(b, uint16(len(rr.Data))), rr.Data...)
+		}
+	}
+	return b, nil
+}
+
+//Unpack decodes the wire encoded DNS message b into m.
+func (m *Msg) Unpack(b []byte) error {
+	if len(b) < headerLen {
+		return errShortMsg
+	}
+	*m = Msg{}
+	m.ID = binary.BigEndian.Uint16(b[0:])
+	flags := binary.BigEndian.Uint16(b[2:])
+	m.Response = flags&(1<<15) != 0
+	m.Opcode = uint8(flags>>11) & 0xf
+	m.Authoritative = flags&(1<<10) != 0
+	m.Truncated = flags&(1<<9) != 0
+	m.RecursionDesired = flags&(1<<8) != 0
+	m.RecursionAvailable = flags&(1<<7) != 0
+	m.Rcode = uint8(flags & 0xf)
+	off := headerLen
+	var err error
+	for i := binary.BigEndian.Uint16(b[4:]); i > 0; i-- {
+		var q Question
+		if q.Name, off, err = readName(b, off); err != nil {
+			return err
+		}
+		if off+4 > len(b) {
+			return errShortMsg
+		}
+		q.Type = Type(binary.BigEndian.Uint16(b[off:]))
+		q.Class = binary.BigEndian.Uint16(b[off+2:])
+		off += 4
+		m.Questions = append(m.Questions, q)
+	}
+	for i, rrs := range []*[]Resource{&m.Answers, &m.Authorities, &m.Additionals} {
+		for n := binary.BigEndian.Uint16(b[6+2*i:]); n > 0; n-- {
+			var rr Resource
+			if rr.Name, off, err = readName(b, off); err != nil {
+				return err
+			}
+			if off+10 > len(b) {
+				return errShortMsg
+			}
+			rr.Type = Type(binary.BigEndian.Uint16(b[off:]))
+			rr.Class = binary.BigEndian.Uint16(b[off+2:])
+			rr.TTL = binary.BigEndian.Uint32(b[off+4:])
+			length := int(binary.BigEndian.Uint16(b[off+8:]))
+			off += 10
+			if off+length > len(b) {
+				return errShortMsg
+			}
+			rr.Data = append([]byte{}, b[off:off+length]...)
+			off += length
+			*rrs = append(*rrs, rr)
+		}
+	}
+	return nil
+}
+
+//appendName appends the uncompressed wire encoding of the fully qualified domain name name to b.
+func appendName(b []byte, name string) ([]byte, error) {
+	start := len(b)
+	name = strings.TrimSuffix(name, ".")
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) == 0 || len(label) > 63 {
+				return nil, fmt.Errorf("invalid label in domain name %s", name)
+			}
+			b = append(append(b, byte(len(label))), label...)
+		}
+	}
+	b = append(b, 0)
+	if len(b)-start > maxNameLen {
+		return nil, fmt.Errorf("domain name %s is too long", name)
+	}
+	return b, nil
+}
+
+//readName decodes the possibly compressed domain name starting at off in b. It returns the fully
+//qualified name and the offset of the first byte after the name.
+func readName(b []byte, off int) (string, int, error) {
+	var labels []string
+	end, pointers, length := -1, 0, 0
+	for {
+		if off >= len(b) {
+			return "", 0, errShortMsg
+		}
+		c := int(b[off])
+		switch c & 0xc0 {
+		case 0x00:
+			if c == 0 {
+				if end < 0 {
+					end = off + 1
+				}
+				return strings.Join(labels, ".") + ".", end, nil
+			}
+			if off+1+c > len(b) {
+				return "", 0, errShortMsg
+			}
+			length += c + 1
+			if length > maxNameLen {
+				return "", 0, errors.New("domain name is too long")
+			}
+			labels = append(labels, string(b[off+1:off+1+c]))
+			off += 1 + c
+		case 0xc0:
+			if off+2 > len(b) {
+				return "", 0, errShortMsg
+			}
+			if pointers++; pointers > maxPointers {
+				return "", 0, errors.New("too many compression pointers")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3fff)
+		default:
+			return "", 0, errors.New("unsupported label type")
+		}
+	}
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
@@ -0,0 +1,342 @@
+// Package rains2dns implements a gateway which answers DNS queries with information looked up in
+// RAINS.
+package rains2dns
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/query"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+const (
+	//maxAliasChain is the maximum number of name objects followed while answering a query.
+	maxAliasChain = 8
+	//queryValidity is the amount of time after which a query sent to RAINS expires.
+	queryValidity = 10 * time.Second
+	//tcpIdleTimeout is the amount of time after which an idle TCP connection is closed.
+	tcpIdleTimeout = 10 * time.Second
+	//maxEDNSUDPLen is the maximum length of a response sent over UDP to a client announcing a
+	//larger buffer in an OPT record.
+	maxEDNSUDPLen = 4096
+	//maxTCPLen is the maximum length of a DNS message over TCP.
+	maxTCPLen = 0xffff
+)
+
+//Resolver looks up a RAINS query and returns the answer. It is implemented by libresolve.Resolver.
+type Resolver interface {
+	ClientLookup(query *query.Name) (*message.Message, error)
+}
+
+//Gateway accepts DNS queries over UDP and TCP, looks them up in RAINS and translates the answers to
+//DNS records.
+type Gateway struct {
+	config       Config
+	resolver     Resolver
+	udpConns     []*net.UDPConn
+	tcpListeners []net.Listener
+	//udpWorkers limits the number of go routines answering queries received over UDP.
+	udpWorkers chan struct{}
+	shutdown   chan bool
+	wg         sync.WaitGroup
+}
+
+//New returns a gateway which looks up queries with resolver. At least one query received over UDP
+//is answered at a time.
+func New(config Config, resolver Resolver) *Gateway {
+	workers := config.MaxConcurrentQueries
+	if workers < 1 {
+		workers = 1
+	}
+	return &Gateway{
+		config:     config,
+		resolver:   resolver,
+		udpWorkers: make(chan struct{}, workers),
+		shutdown:   make(chan bool),
+	}
+}
+
+//Start opens a UDP socket and a TCP listener on the same port for each configured listener and
+//serves DNS queries on them in separate go routines until Shutdown is called.
+func (g *Gateway) Start() error {
+	for _, l := range g.config.Listeners {
+		context := l.Context
+		if context == "" {
+			context = "."
+		}
+		addr, err := net.ResolveUDPAddr("udp", l.Address)
+		if err != nil {
+			g.Shutdown()
+			return err
+		}
+		conn, err := net.ListenUDP("udp", addr)
+		if err != nil {
+			g.Shutdown()
+			return err
+		}
+		g.udpConns = append(g.udpConns, conn)
+		listener, err := net.Listen("tcp", conn.LocalAddr().String())
+		if err != nil {
+			g.Shutdown()
+			return err
+		}
+		g.tcpListeners = append(g.tcpListeners, listener)
+		g.wg.Add(2)
+		go g.serveUDP(conn, context)
+		go g.serveTCP(listener, context)
+		log.Info("Started DNS listener", "addr", conn.LocalAddr(), "context", context)
+	}
+	return nil
+}
+
+//Addrs returns the addresses on which the gateway listens in the order of the configured
+//listeners. The TCP listeners use the same ports.
+func (g *Gateway) Addrs() []net.Addr {
+	var addrs []net.Addr
+	for _, conn := range g.udpConns {
+		addrs = append(addrs, conn.LocalAddr())
+	}
+	return addrs
+}
+
+//Shutdown closes all listeners and waits until they have stopped. Queries in progress are still
+//answered over TCP connections which remain open until they are idle.
+func (g *Gateway) Shutdown() {
+	select {
+	case <-g.shutdown:
+		return
+	default:
+		close(g.shutdown)
+	}
+	for _, conn := range g.udpConns {
+		conn.Close()
+	}
+	for _, listener := range g.tcpListeners {
+		listener.Close()
+	}
+	g.wg.Wait()
+}
+
+//serveUDP answers each query received on conn in a new go routine. When MaxConcurrentQueries
+//queries are in progress, no further query is read until one of them has been answered.
+func (g *Gateway) serveUDP(conn *net.UDPConn, context string) {
+	defer g.wg.Done()
+	for {
+		buf := make([]byte, maxEDNSUDPLen)
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-g.shutdown:
+				return
+			default:
+			}
+			log.Warn("Failed to read DNS query", "error", err)
+			continue
+		}
+		g.udpWorkers <- struct{}{}
+		go func() {
+			defer func() { <-g.udpWorkers }()
+			if resp := g.handle(buf[:n], context, true); resp != nil {
+				if _, err := conn.WriteToUDP(resp, addr); err != nil {
+					log.Warn("Failed to send DNS response", "client", addr, "error", err)
+				}
+			}
+		}()
+	}
+}
+
+//serveTCP accepts connections on listener and answers the queries received over them.
+func (g *Gateway) serveTCP(listener net.Listener, context string) {
+	defer g.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-g.shutdown:
+				return
+			default:
+			}
+			log.Warn("Failed to accept DNS connection", "error", err)
+			continue
+		}
+		go g.handleTCP(conn, context)
+	}
+}
+
+//handleTCP answers the length prefixed queries received on conn until conn is closed or idle.
+func (g *Gateway) handleTCP(conn net.Conn, context string) {
+	defer conn.Close()
+	for {
+		conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+		length := make([]byte, 2)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return
+		}
+		data := make([]byte, binary.BigEndian.Uint16(length))
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return
+		}
+		resp := g.handle(data, context, false)
+		if resp == nil {
+			return
+		}
+		if _, err := conn.Write(append(appendUint16(nil, uint16(len(resp))), resp...)); err != nil {
+			return
+		}
+	}
+}
+
+//handle returns the encoded response to the encoded query data or nil if data cannot be answered.
+//A response exceeding the maximum length of the transport is truncated. Responses are dropped and
+//malformed queries are only answered over TCP such that two gateways, or a gateway and a spoofed
+//sender, cannot be made to answer each other's datagrams forever.
+func (g *Gateway) handle(data []byte, context string, udp bool) []byte {
+	var req Msg
+	if err := req.Unpack(data); err != nil {
+		log.Debug("Received malformed DNS query", "error", err)
+		if udp || len(data) < headerLen || data[2]&0x80 != 0 {
+			return nil
+		}
+		resp := &Msg{Header: Header{ID: binary.BigEndian.Uint16(data), Response: true,
+			Rcode: RcodeFormatError}}
+		encoding, _ := resp.Pack()
+		return encoding
+	}
+	if req.Response {
+		log.Debug("Dropped DNS response received as query", "id", req.ID)
+		return nil
+	}
+	resp := g.answer(&req, context)
+	limit := maxTCPLen
+	if udp {
+		limit = maxUDPLen
+	}
+	for _, rr := range req.Additionals {
+		if rr.Type != TypeOPT {
+			continue
+		}
+		resp.Additionals = []Resource{{Name: ".", Type: TypeOPT, Class: maxEDNSUDPLen}}
+		if udp && int(rr.Class) > limit {
+			limit = int(rr.Class)
+			if limit > maxEDNSUDPLen {
+				limit = maxEDNSUDPLen
+			}
+		}
+	}
+	encoding, err := resp.Pack()
+	if err != nil {
+		log.Warn("Failed to encode DNS response", "error", err)
+		resp = &Msg{Header: resp.Header, Questions: resp.Questions, Additionals: resp.Additionals}
+		resp.Rcode = RcodeServerFailure
+		encoding, err = resp.Pack()
+	}
+	if err == nil && len(encoding) > limit {
+		resp.Answers, resp.Authorities, resp.Truncated = nil, nil, true
+		encoding, err = resp.Pack()
+	}
+	if err != nil {
+		return nil
+	}
+	return encoding
+}
+
+//answer looks up the question of req in context and returns the response. Name objects are followed
+//and added as CNAME records.
+func (g *Gateway) answer(req *Msg, context string) *Msg {
+	resp := &Msg{Header: Header{ID: req.ID, Response: true, Opcode: req.Opcode,
+		RecursionDesired: req.RecursionDesired, RecursionAvailable: true}, Questions: req.Questions}
+	if req.Opcode != 0 {
+		resp.Rcode = RcodeNotImplemented
+		return resp
+	}
+	if len(req.Questions) != 1 {
+		resp.Rcode = RcodeFormatError
+		return resp
+	}
+	q := req.Questions[0]
+	types, ok := rainsTypes[q.Type]
+	if !ok || q.Class != ClassINET {
+		resp.Rcode = RcodeNotImplemented
+		return resp
+	}
+	lookupTypes := types
+	followAliases := q.Type != TypeCNAME && q.Type != TypeANY
+	if followAliases {
+		lookupTypes = append([]object.Type{object.OTName}, types...)
+	}
+	name := strings.ToLower(q.Name)
+	for i := 0; i <= maxAliasChain; i++ {
+		msg, err := g.lookup(name, context, lookupTypes)
+		if err != nil {
+			log.Warn("RAINS lookup failed", "name", name, "context", context, "error", err)
+			resp.Rcode = RcodeServerFailure
+			return resp
+		}
+		assertions := assertionsFor(msg, name, context)
+		var records []Resource
+		var alias *Resource
+		target := ""
+		for _, a := range assertions {
+			ttl := ttl(a.sigs, g.config.MaxTTL)
+			records = append(records, resources(a.assertion, name, q.Type, ttl)...)
+			if targets := aliases(a.assertion, types); followAliases && alias == nil &&
+				len(targets) > 0 {
+				if data, err := appendName(nil, targets[0]); err == nil {
+					alias = &Resource{Name: name, Type: TypeCNAME, Class: ClassINET, TTL: ttl,
+						Data: data}
+					target = targets[0]
+				}
+			}
+		}
+		if len(records) > 0 {
+			resp.Answers = append(resp.Answers, records...)
+			return resp
+		}
+		if alias != nil {
+			resp.Answers = append(resp.Answers, *alias)
+			name = strings.ToLower(target)
+			continue
+		}
+		if d, ok := findDenial(msg, name, context); ok {
+			if !d.nameExists && len(assertions) == 0 {
+				resp.Rcode = RcodeNameError
+			}
+			resp.Authorities = []Resource{soa(d.zone, ttl(d.sigs, g.config.MaxTTL))}
+			return resp
+		}
+		if len(assertions) > 0 {
+			return resp
+		}
+		for _, sec := range msg.Content {
+			if n, ok := sec.(*section.Notification); ok && n.Type == section.NTNoAssertionsExist {
+				resp.Rcode = RcodeNameError
+				return resp
+			}
+		}
+		resp.Rcode = RcodeServerFailure
+		return resp
+	}
+	log.Warn("Too many aliases", "question", q.Name, "context", context)
+	resp.Rcode = RcodeServerFailure
+	return resp
+}
+
+//lookup sends a query for name and types in context to RAINS and returns the answer.
+func (g *Gateway) lookup(name, context string, types []object.Type) (*message.Message, error) {
+	now := time.Now()
+	return g.resolver.ClientLookup(&query.Name{
+		Context:     context,
+		Name:        name,
+		Types:       types,
+		Expiration:  now.Add(queryValidity).Unix(),
+		CurrentTime: now.Unix(),
+	})
+}
@@ -0,0 +1,279 @@
+package rains2dns
+
+import (
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/query"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/signature"
+)
+
+//stubResolver answers a query with the sections stored under its context and name.
+type stubResolver map[string][]section.Section
+
+func (r stubResolver) ClientLookup(q *query.Name) (*message.Message, error) {
+	return &message.Message{Content: r[q.Context+" "+q.Name]}, nil
+}
+
+func newStubResolver() stubResolver {
+	sig := signature.Sig{ValidSince: time.Now().Unix(), ValidUntil: time.Now().Add(2 * time.Hour).Unix()}
+	www := &section.Assertion{SubjectName: "www", SubjectZone: "example.", Context: ".",
+		Signatures: []signature.Sig{sig},
+		Content: []object.Object{
+			{Type: object.OTIP4Addr, Value: "192.0.2.1"},
+			{Type: object.OTIP6Addr, Value: "2001:db8::1"},
+		}}
+	alias := &section.Assertion{SubjectName: "alias", SubjectZone: "example.", Context: ".",
+		Content: []object.Object{{Type: object.OTName,
+			Value: object.Name{Name: "www.example.", Types: []object.Type{object.OTIP4Addr}}}}}
+	srv := &section.Assertion{SubjectName: "_rains._udp", SubjectZone: "example.", Context: ".",
+		Content: []object.Object{{Type: object.OTServiceInfo,
+			Value: object.ServiceInfo{Name: "www.example.", Port: 55553, Priority: 1}}}}
+	zone := &section.Assertion{SubjectName: "@", SubjectZone: "example.", Context: ".",
+		Content: []object.Object{{Type: object.OTRedirection, Value: "ns.example."}}}
+	tlsa := &section.Assertion{SubjectName: "_443._tcp.www", SubjectZone: "example.", Context: ".",
+		Content: []object.Object{
+			{Type: object.OTCertInfo, Value: object.Certificate{Type: object.PTTLS,
+				Usage: object.CUEndEntity, HashAlgo: algorithmTypes.Sha256, Data: []byte{1, 2, 3}}},
+			{Type: object.OTCertInfo, Value: object.Certificate{Type: object.PTTLS,
+				Usage: object.CUEndEntity, HashAlgo: algorithmTypes.Shake256, Data: []byte{4}}},
+		}}
+	shard := &section.Shard{SubjectZone: "example.", Context: ".", RangeFrom: "m", RangeTo: "n",
+		Signatures: []signature.Sig{sig}}
+	return stubResolver{
+		". www.example.":           {www},
+		". alias.example.":         {alias},
+		". _rains._udp.example.":   {srv},
+		". example.":               {zone},
+		". _443._tcp.www.example.": {tlsa},
+		". missing.example.":       {shard},
+		"other www.example.":       {&section.Notification{Type: section.NTNoAssertionsExist}},
+	}
+}
+
+func rr(name string, t Type, ttl uint32, data []byte) Resource {
+	return Resource{Name: name, Type: t, Class: ClassINET, TTL: ttl, Data: data}
+}
+
+func encodedName(name string) []byte {
+	b, _ := appendName(nil, name)
+	return b
+}
+
+func TestGateway(t *testing.T) {
+	config := DefaultConfig()
+	config.Listeners = []ListenerConfig{
+		{Address: "127.0.0.1:0", Context: "."},
+		{Address: "127.0.0.1:0", Context: "other"},
+	}
+	gateway := New(config, newStubResolver())
+	if err := gateway.Start(); err != nil {
+		t.Fatalf("was not able to start gateway: %v", err)
+	}
+	defer gateway.Shutdown()
+	addrs := gateway.Addrs()
+	srvData := append([]byte{0, 1, 0, 0, 0xd9, 0x01}, encodedName("www.example.")...)
+	var tests = []struct {
+		listener int
+		name     string
+		qType    Type
+		rcode    uint8
+		answers  []Resource
+	}{
+		{0, "www.example.", TypeA, RcodeSuccess,
+			[]Resource{rr("www.example.", TypeA, 3600, net.ParseIP("192.0.2.1").To4())}},
+		{0, "WWW.example.", TypeAAAA, RcodeSuccess,
+			[]Resource{rr("www.example.", TypeAAAA, 3600, net.ParseIP("2001:db8::1"))}},
+		{0, "alias.example.", TypeA, RcodeSuccess, []Resource{
+			rr("alias.example.", TypeCNAME, 0, encodedName("www.example.")),
+			rr("www.example.", TypeA, 3600, net.ParseIP("192.0.2.1").To4())}},
+		{0, "alias.example.", TypeAAAA, RcodeSuccess, nil},
+		{0, "_rains._udp.example.", TypeSRV, RcodeSuccess,
+			[]Resource{rr("_rains._udp.example.", TypeSRV, 0, srvData)}},
+		{0, "example.", TypeNS, RcodeSuccess,
+			[]Resource{rr("example.", TypeNS, 0, encodedName("ns.example."))}},
+		{0, "_443._tcp.www.example.", TypeTLSA, RcodeSuccess,
+			[]Resource{rr("_443._tcp.www.example.", TypeTLSA, 0, []byte{3, 0, 1, 1, 2, 3})}},
+		{0, "missing.example.", TypeA, RcodeNameError, nil},
+		{0, "www.example.", Type(15), RcodeNotImplemented, nil},
+		{1, "www.example.", TypeA, RcodeNameError, nil},
+	}
+	for i, test := range tests {
+		for _, network := range []string{"udp", "tcp"} {
+			req := &Msg{Header: Header{ID: uint16(i), RecursionDesired: true},
+				Questions: []Question{{Name: test.name, Type: test.qType, Class: ClassINET}}}
+			resp, err := Exchange(network, addrs[test.listener].String(), req, time.Second)
+			if err != nil {
+				t.Fatalf("%d %s: exchange failed: %v", i, network, err)
+			}
+			if resp.Rcode != test.rcode {
+				t.Errorf("%d %s: wrong rcode. expected=%d actual=%d", i, network, test.rcode,
+					resp.Rcode)
+			}
+			if !reflect.DeepEqual(resp.Answers, test.answers) {
+				t.Errorf("%d %s: wrong answers.\nexpected=%v\nactual=  %v", i, network,
+					test.answers, resp.Answers)
+			}
+		}
+	}
+}
+
+func TestNegativeAnswerHasSOA(t *testing.T) {
+	gateway := New(DefaultConfig(), newStubResolver())
+	req := &Msg{Questions: []Question{{Name: "missing.example.", Type: TypeA, Class: ClassINET}}}
+	resp := gateway.answer(req, ".")
+	if len(resp.Authorities) != 1 || resp.Authorities[0].Type != TypeSOA ||
+		resp.Authorities[0].Name != "example." || resp.Authorities[0].TTL != 3600 {
+		t.Errorf("expected SOA of example. with TTL 3600, got %v", resp.Authorities)
+	}
+}
+
+func TestTruncation(t *testing.T) {
+	resolver := stubResolver{}
+	a := &section.Assertion{SubjectName: "big", SubjectZone: "example.", Context: "."}
+	for i := 0; i < 100; i++ {
+		a.Content = append(a.Content, object.Object{Type: object.OTIP4Addr,
+			Value: net.IPv4(10, 0, 0, byte(i)).String()})
+	}
+	resolver[". big.example."] = []section.Section{a}
+	gateway := New(DefaultConfig(), resolver)
+	req := &Msg{Questions: []Question{{Name: "big.example.", Type: TypeA, Class: ClassINET}}}
+	query, _ := req.Pack()
+	var resp Msg
+	if err := resp.Unpack(gateway.handle(query, ".", true)); err != nil {
+		t.Fatalf("was not able to decode response: %v", err)
+	}
+	if !resp.Truncated || len(resp.Answers) != 0 {
+		t.Errorf("expected empty truncated response over UDP, got %d answers", len(resp.Answers))
+	}
+	if err := resp.Unpack(gateway.handle(query, ".", false)); err != nil {
+		t.Fatalf("was not able to decode response: %v", err)
+	}
+	if resp.Truncated || len(resp.Answers) != 100 {
+		t.Errorf("expected 100 answers over TCP, got %d", len(resp.Answers))
+	}
+}
+
+func TestUnanswerableMessages(t *testing.T) {
+	gateway := New(DefaultConfig(), newStubResolver())
+	response, _ := (&Msg{Header: Header{ID: 1, Response: true}, Questions: []Question{
+		{Name: "www.example.", Type: TypeA, Class: ClassINET}}}).Pack()
+	malformed := []byte{0, 2, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 3, 'w'}
+	malformedResponse := append([]byte{}, malformed...)
+	malformedResponse[2] |= 0x80
+	var tests = []struct {
+		name     string
+		data     []byte
+		udp      bool
+		answered bool
+	}{
+		{"response over udp", response, true, false},
+		{"response over tcp", response, false, false},
+		{"malformed query over udp", malformed, true, false},
+		{"malformed query over tcp", malformed, false, true},
+		{"malformed response over tcp", malformedResponse, false, false},
+		{"short message over tcp", malformed[:4], false, false},
+	}
+	for _, test := range tests {
+		resp := gateway.handle(test.data, ".", test.udp)
+		if !test.answered {
+			if resp != nil {
+				t.Errorf("%s: message was answered", test.name)
+			}
+			continue
+		}
+		var msg Msg
+		if err := msg.Unpack(resp); err != nil || msg.Rcode != RcodeFormatError || msg.ID != 2 {
+			t.Errorf("%s: expected format error, got %v err=%v", test.name, msg, err)
+		}
+	}
+}
+
+//slowResolver answers all queries with resolver after a delay and records the maximal number of
+//concurrent lookups.
+type slowResolver struct {
+	resolver Resolver
+	mux      sync.Mutex
+	current  int
+	max      int
+}
+
+func (r *slowResolver) ClientLookup(q *query.Name) (*message.Message, error) {
+	r.mux.Lock()
+	r.current++
+	if r.current > r.max {
+		r.max = r.current
+	}
+	r.mux.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	r.mux.Lock()
+	r.current--
+	r.mux.Unlock()
+	return r.resolver.ClientLookup(q)
+}
+
+func TestMaxConcurrentQueries(t *testing.T) {
+	config := DefaultConfig()
+	config.Listeners = []ListenerConfig{{Address: "127.0.0.1:0", Context: "."}}
+	config.MaxConcurrentQueries = 2
+	resolver := &slowResolver{resolver: newStubResolver()}
+	gateway := New(config, resolver)
+	if err := gateway.Start(); err != nil {
+		t.Fatalf("was not able to start gateway: %v", err)
+	}
+	defer gateway.Shutdown()
+	conn, err := net.Dial("udp", gateway.Addrs()[0].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	nofQueries := 6
+	for i := 0; i < nofQueries; i++ {
+		req := &Msg{Header: Header{ID: uint16(i)},
+			Questions: []Question{{Name: "www.example.", Type: TypeA, Class: ClassINET}}}
+		query, _ := req.Pack()
+		if _, err := conn.Write(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, maxEDNSUDPLen)
+	for i := 0; i < nofQueries; i++ {
+		if _, err := conn.Read(buf); err != nil {
+			t.Fatalf("only %d of %d queries were answered: %v", i, nofQueries, err)
+		}
+	}
+	resolver.mux.Lock()
+	defer resolver.mux.Unlock()
+	if resolver.max > config.MaxConcurrentQueries {
+		t.Errorf("too many concurrent lookups. expected<=%d actual=%d",
+			config.MaxConcurrentQueries, resolver.max)
+	}
+}
+
+func TestUnpackCompressedName(t *testing.T) {
+	//Header, question www.example. A IN and an answer whose name points to the question.
+	data := []byte{0, 1, 0x81, 0x80, 0, 1, 0, 1, 0, 0, 0, 0,
+		3, 'w', 'w', 'w', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0, 0, 1, 0, 1,
+		0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 192, 0, 2, 1}
+	var m Msg
+	if err := m.Unpack(data); err != nil {
+		t.Fatalf("was not able to decode message: %v", err)
+	}
+	expected := []Resource{rr("www.example.", TypeA, 60, []byte{192, 0, 2, 1})}
+	if !reflect.DeepEqual(m.Answers, expected) {
+		t.Errorf("wrong answers. expected=%v actual=%v", expected, m.Answers)
+	}
+	//A pointer loop must not be followed forever.
+	data[30] = 29
+	if err := m.Unpack(data); err == nil {
+		t.Error("expected an error for a compression pointer loop")
+	}
+}
@@ -0,0 +1,247 @@
+package rains2dns
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/signature"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+)
+
+//rainsTypes maps a DNS question type to the RAINS object types answering it.
+var rainsTypes = map[Type][]object.Type{
+	TypeA:     {object.OTIP4Addr},
+	TypeAAAA:  {object.OTIP6Addr},
+	TypeCNAME: {object.OTName},
+	TypeSRV:   {object.OTServiceInfo},
+	TypeNS:    {object.OTRedirection},
+	TypeTLSA:  {object.OTCertInfo},
+	TypeANY: {object.OTIP4Addr, object.OTIP6Addr, object.OTName, object.OTServiceInfo,
+		object.OTRedirection, object.OTCertInfo},
+}
+
+//dnsTypes maps a RAINS object type to the DNS type it is translated to.
+var dnsTypes = map[object.Type]Type{
+	object.OTIP4Addr:     TypeA,
+	object.OTIP6Addr:     TypeAAAA,
+	object.OTName:        TypeCNAME,
+	object.OTServiceInfo: TypeSRV,
+	object.OTRedirection: TypeNS,
+	object.OTCertInfo:    TypeTLSA,
+}
+
+//tlsaMatchingTypes maps a RAINS hash algorithm to the matching type of a TLSA record. Certificates
+//hashed with other algorithms cannot be expressed in DNS.
+var tlsaMatchingTypes = map[algorithmTypes.Hash]byte{
+	algorithmTypes.NoHashAlgo: 0,
+	algorithmTypes.Sha256:     1,
+	algorithmTypes.Sha512:     2,
+}
+
+//validAssertion is an assertion together with the signatures which authenticate it. These are the
+//signatures of the assertion itself or of the shard or zone containing it.
+type validAssertion struct {
+	assertion *section.Assertion
+	sigs      []signature.Sig
+}
+
+//denial is a shard or zone proving that no assertion exists for a name outside of it.
+type denial struct {
+	zone string
+	sigs []signature.Sig
+	//nameExists is true if the shard or zone contains an assertion for the name.
+	nameExists bool
+}
+
+//assertionsFor returns all assertions in msg about name in context, including those contained in
+//shards and zones.
+func assertionsFor(msg *message.Message, name, context string) []validAssertion {
+	var result []validAssertion
+	for _, sec := range msg.Content {
+		switch sec := sec.(type) {
+		case *section.Assertion:
+			if sec.Context == context && strings.EqualFold(sec.FQDN(), name) {
+				result = append(result, validAssertion{assertion: sec, sigs: sec.AllSigs()})
+			}
+		case *section.Shard:
+			if sec.Context == context {
+				result = append(result, containedAssertions(sec.Content, sec.SubjectZone, name,
+					sec.AllSigs())...)
+			}
+		case *section.Zone:
+			if sec.Context == context {
+				result = append(result, containedAssertions(sec.Content, sec.SubjectZone, name,
+					sec.AllSigs())...)
+			}
+		}
+	}
+	return result
+}
+
+//containedAssertions returns the assertions about name in content of a shard or zone of zone.
+func containedAssertions(content []*section.Assertion, zone, name string,
+	sigs []signature.Sig) []validAssertion {
+	var result []validAssertion
+	for _, a := range content {
+		if strings.EqualFold(fqdn(a.SubjectName, zone), name) {
+			result = append(result, validAssertion{assertion: a, sigs: sigs})
+		}
+	}
+	return result
+}
+
+//findDenial returns a shard or zone in msg which covers name in context and true. It returns false
+//if msg does not contain such a section.
+func findDenial(msg *message.Message, name, context string) (denial, bool) {
+	for _, sec := range msg.Content {
+		switch sec := sec.(type) {
+		case *section.Shard:
+			subjectName, ok := relativeName(name, sec.SubjectZone)
+			if sec.Context == context && ok && sec.InRange(subjectName) {
+				return denial{zone: sec.SubjectZone, sigs: sec.AllSigs(),
+					nameExists: len(containedAssertions(sec.Content, sec.SubjectZone, name, nil)) > 0}, true
+			}
+		case *section.Zone:
+			if _, ok := relativeName(name, sec.SubjectZone); sec.Context == context && ok {
+				return denial{zone: sec.SubjectZone, sigs: sec.AllSigs(),
+					nameExists: len(containedAssertions(sec.Content, sec.SubjectZone, name, nil)) > 0}, true
+			}
+		}
+	}
+	return denial{}, false
+}
+
+//resources returns the DNS records of type t translated from the objects of a. Objects which cannot
+//be translated are skipped.
+func resources(a *section.Assertion, name string, t Type, ttl uint32) []Resource {
+	var result []Resource
+	for _, o := range a.Content {
+		dnsType, ok := dnsTypes[o.Type]
+		if !ok || (t != TypeANY && t != dnsType) {
+			continue
+		}
+		if data, ok := rdata(o); ok {
+			result = append(result, Resource{Name: name, Type: dnsType, Class: ClassINET, TTL: ttl,
+				Data: data})
+		}
+	}
+	return result
+}
+
+//rdata returns the wire encoded RDATA of the DNS record corresponding to o and true. It returns false
+//if o cannot be expressed in DNS.
+func rdata(o object.Object) ([]byte, bool) {
+	var data []byte
+	var err error
+	switch v := o.Value.(type) {
+	case string:
+		switch o.Type {
+		case object.OTIP4Addr:
+			data = net.ParseIP(v).To4()
+		case object.OTIP6Addr:
+			if ip := net.ParseIP(v); ip != nil && ip.To4() == nil {
+				data = ip.To16()
+			}
+		case object.OTRedirection:
+			data, err = appendName(nil, v)
+		}
+	case object.Name:
+		data, err = appendName(nil, v.Name)
+	case object.ServiceInfo:
+		//RAINS has no weights, all targets of the same priority are equally likely.
+		data = appendUint16(appendUint16(appendUint16(nil, uint16(v.Priority)), 0), v.Port)
+		data, err = appendName(data, v.Name)
+	case object.Certificate:
+		matching, ok := tlsaMatchingTypes[v.HashAlgo]
+		if !ok || (v.Type != object.PTUnspecified && v.Type != object.PTTLS) {
+			return nil, false
+		}
+		//The selector is 0 as RAINS certificates contain or hash the full certificate.
+		data = append([]byte{byte(v.Usage), 0, matching}, v.Data...)
+	}
+	return data, err == nil && len(data) > 0
+}
+
+//aliases returns the names to which a's name objects point for any of the given RAINS types.
+func aliases(a *section.Assertion, types []object.Type) []string {
+	var result []string
+	for _, o := range a.Content {
+		n, ok := o.Value.(object.Name)
+		if !ok || o.Type != object.OTName {
+			continue
+		}
+		for _, t := range n.Types {
+			if containsType(types, t) {
+				result = append(result, n.Name)
+				break
+			}
+		}
+	}
+	return result
+}
+
+//soa returns a start of authority record for zone which lets DNS resolvers cache a negative answer
+//for ttl seconds. RAINS has no such record, thus all fields except the name and the minimum TTL are
+//synthesized.
+func soa(zone string, ttl uint32) Resource {
+	data, _ := appendName(nil, zone)
+	rname := "hostmaster." + zone
+	if zone == "." {
+		rname = "hostmaster."
+	}
+	data, _ = appendName(data, rname)
+	for _, v := range []uint32{uint32(time.Now().Unix()), 3600, 600, 86400, ttl} {
+		data = append(data, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(data[len(data)-4:], v)
+	}
+	return Resource{Name: zone, Type: TypeSOA, Class: ClassINET, TTL: ttl, Data: data}
+}
+
+//ttl returns the number of seconds until the overlap of sigs expires, at most maxTTL.
+func ttl(sigs []signature.Sig, maxTTL time.Duration) uint32 {
+	_, until := util.GetOverlapValidityForSignatures(sigs)
+	remaining := time.Until(time.Unix(until, 0))
+	if remaining <= 0 {
+		return 0
+	}
+	if remaining > maxTTL {
+		remaining = maxTTL
+	}
+	return uint32(remaining / time.Second)
+}
+
+//fqdn returns the fully qualified domain name of subjectName in zone.
+func fqdn(subjectName, zone string) string {
+	a := section.Assertion{SubjectName: subjectName, SubjectZone: zone}
+	return a.FQDN()
+}
+
+//relativeName returns the subject name of the fully qualified domain name name in zone and true. It
+//returns false if name is not in zone.
+func relativeName(name, zone string) (string, bool) {
+	name, zone = strings.ToLower(name), strings.ToLower(zone)
+	switch {
+	case name == zone:
+		return "@", true
+	case zone == ".":
+		return strings.TrimSuffix(name, "."), name != "."
+	case strings.HasSuffix(name, "."+zone):
+		return strings.TrimSuffix(name, "."+zone), true
+	}
+	return "", false
+}
+
+func containsType(types []object.Type, t object.Type) bool {
+	for _, typ := range types {
+		if typ == t {
+			return true
+		}
+	}
+	return false
+}
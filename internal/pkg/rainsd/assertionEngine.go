@@ -2,12 +2,16 @@ package rainsd
 
 import (
 	"fmt"
+	"time"
 
 	log "github.com/inconshreveable/log15"
 	"github.com/netsec-ethz/rains/internal/pkg/cache"
 	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/nameset"
 	"github.com/netsec-ethz/rains/internal/pkg/object"
 	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/siglib"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
 	"github.com/netsec-ethz/rains/internal/pkg/util"
 )
 
@@ -17,48 +21,145 @@ import (
 //rains signature on the message
 func (s *Server) assert(ss util.SectionWithSigSender) {
 	log.Debug("Adding section to cache", "section", ss)
-	if sectionsAreInconsistent(ss.Sections, s.caches.AssertionsCache, s.caches.NegAssertionCache) {
-		log.Warn("section is inconsistent with cached elements.", "sections", ss.Sections)
+	if s.config.EnforceNameset {
+		sections := s.dropNamesetViolations(ss.Sections)
+		if len(sections) < len(ss.Sections) {
+			sendNotificationMsg(ss.Token, ss.Sender, section.NTRcvInconsistentMsg,
+				"subject name is not in the zone's nameset", s)
+		}
+		if len(sections) == 0 {
+			return
+		}
+		ss.Sections = sections
+	}
+	authorities, _ := s.zones()
+	conflicts, superseded := inconsistentCachedSections(ss.Sections, authorities,
+		s.caches.AssertionsCache, s.caches.NegAssertionCache)
+	if len(conflicts) > 0 {
+		log.Warn("section is inconsistent with cached elements.", "sections", ss.Sections,
+			"conflicts", conflicts, "policy", s.config.InconsistencyPolicy)
+		handleInconsistency(conflicts, s.config.InconsistencyPolicy, s.caches.AssertionsCache,
+			s.caches.NegAssertionCache)
 		sendNotificationMsg(ss.Token, ss.Sender, section.NTRcvInconsistentMsg, "", s)
 		return
 	}
-	addSectionsToCache(ss.Sections, s.config.Authorities, s.caches.AssertionsCache,
-		s.caches.NegAssertionCache, s.caches.ZoneKeyCache)
-	pendingKeysCallback(ss, s.caches.PendingKeys, s.queues.Normal)
+	if len(superseded) > 0 {
+		log.Info("Replacing cached sections superseded by received sections", "sections",
+			ss.Sections, "superseded", superseded)
+		handleInconsistency(superseded, EvictConflicting, s.caches.AssertionsCache,
+			s.caches.NegAssertionCache)
+	}
+	addSectionsToCache(ss.Sections, s.servedZones(), s.config.StaleGracePeriod,
+		s.caches.AssertionsCache, s.caches.NegAssertionCache, s.caches.AddressCache,
+		s.caches.ZoneKeyCache)
+	//The sections are on disk before they are answered or acknowledged.
+	syncStores(s.caches)
+	pendingKeysCallback(ss, s)
 	pendingQueriesCallback(ss, s)
+	s.infraKeyCallback(ss)
 	log.Info(fmt.Sprintf("Finished handling %T", ss.Sections), "section", ss.Sections)
 }
 
-//sectionsAreInconsistent returns true if at least one section is not consistent with cached element
-//which are valid at the same time.
-func sectionsAreInconsistent(sec []section.WithSigForward, assertionsCache cache.Assertion,
-	negAssertionCache cache.NegativeAssertion) bool {
-	return false
+//dropNamesetViolations returns all sections whose subject names and whose contained assertions'
+//subject names are in the nameset of their zone. A zone's nameset consists of the nameset objects
+//of the zone's assertions with subject name @ in sections. Only if sections do not contain any, the
+//nameset is taken from the cached assertions which have not yet expired, such that a newly
+//published nameset replaces the old one. A zone without a nameset allows all names.
+func (s *Server) dropNamesetViolations(sections []section.WithSigForward) []section.WithSigForward {
+	published := make(map[ZoneContext][]object.Object)
+	for _, sec := range sections {
+		zone := ZoneContext{Zone: sec.GetSubjectZone(), Context: sec.GetContext()}
+		for _, a := range containedAssertions(sec) {
+			if a.SubjectName == "@" {
+				published[zone] = append(published[zone], a.Content...)
+			}
+		}
+	}
+	var result []section.WithSigForward
+	for _, sec := range sections {
+		zone := ZoneContext{Zone: sec.GetSubjectZone(), Context: sec.GetContext()}
+		objs := published[zone]
+		if len(objs) == 0 {
+			objs = s.cachedNameset(zone)
+		}
+		names, err := nameset.FromObjects(objs)
+		if err != nil {
+			log.Warn("Zone's nameset is malformed and not enforced", "zone", zone, "error", err)
+		} else if !siglib.CheckNameset(sec, names) {
+			log.Warn("Dropped section violating the zone's nameset", "section", sec)
+			continue
+		}
+		result = append(result, sec)
+	}
+	return result
 }
 
-//addSectionToCache adds sec to the cache if it comlies with the server's caching policy
+//cachedNameset returns the objects of the cached assertions with subject name @ of zone which
+//have not yet expired.
+func (s *Server) cachedNameset(zone ZoneContext) []object.Object {
+	//Assertions with subject name @ are cached under the name @ in their zone.
+	name := "@." + zone.Zone
+	if zone.Zone == "." {
+		name = "@."
+	}
+	var objs []object.Object
+	cached, _ := s.caches.AssertionsCache.Get(name, zone.Context, object.OTNameset, true)
+	for _, a := range cached {
+		if servable, _ := s.isServable(a, false); servable && a.SubjectName == "@" &&
+			a.SubjectZone == zone.Zone {
+			objs = append(objs, a.Content...)
+		}
+	}
+	return objs
+}
+
+//containedAssertions returns sec if it is an assertion or the assertions contained in sec if it
+//is a shard or a zone.
+func containedAssertions(sec section.WithSigForward) []*section.Assertion {
+	switch sec := sec.(type) {
+	case *section.Assertion:
+		return []*section.Assertion{sec}
+	case *section.Shard:
+		return sec.Content
+	case *section.Zone:
+		return sec.Content
+	}
+	return nil
+}
+
+//addSectionToCache adds sec to the cache if it comlies with the server's caching policy. Cached
+//sections are kept gracePeriod past their expiration such that they can be served to queries
+//accepting expired assertions.
 func addSectionsToCache(sections []section.WithSigForward, authorities []ZoneContext,
-	assertionsCache cache.Assertion, negAssertionCache cache.NegativeAssertion,
+	gracePeriod time.Duration, assertionsCache cache.Assertion,
+	negAssertionCache cache.NegativeAssertion, addressCache cache.Address,
 	zoneKeyCache cache.ZonePublicKey) {
 	for _, sec := range sections {
 		isAuth := isAuthoritative(sec, authorities)
 		switch sec := sec.(type) {
 		case *section.Assertion:
 			if shouldAssertionBeCached(sec) {
-				addAssertionToCache(sec, isAuth, assertionsCache, zoneKeyCache)
+				addAssertionToCache(sec, isAuth, gracePeriod, assertionsCache, zoneKeyCache)
 			}
 		case *section.Shard:
 			if shouldShardBeCached(sec) {
-				addShardToCache(sec, isAuth, assertionsCache, negAssertionCache, zoneKeyCache)
+				addShardToCache(sec, isAuth, gracePeriod, assertionsCache, negAssertionCache,
+					zoneKeyCache)
 			}
 		case *section.Pshard:
 			if shouldPshardBeCached(sec) {
-				addPshardToCache(sec, isAuth, assertionsCache, negAssertionCache, zoneKeyCache)
+				addPshardToCache(sec, isAuth, gracePeriod, assertionsCache, negAssertionCache,
+					zoneKeyCache)
 			}
 		case *section.Zone:
 			if shouldZoneBeCached(sec) {
-				addZoneToCache(sec, isAuth, assertionsCache, negAssertionCache, zoneKeyCache)
+				addZoneToCache(sec, isAuth, gracePeriod, assertionsCache, negAssertionCache,
+					zoneKeyCache)
 			}
+		case *section.AddressAssertion:
+			addAddressAssertionToCache(sec, isAuth, gracePeriod, addressCache, zoneKeyCache)
+		case *section.AddressZone:
+			addAddressZoneToCache(sec, isAuth, gracePeriod, addressCache, zoneKeyCache)
 		default:
 			log.Error("Not supported message section with sig. This case must be prevented beforehand")
 		}
@@ -90,9 +191,9 @@ func shouldZoneBeCached(zone *section.Zone) bool {
 
 //addAssertionToCache adds a to the assertion cache and to the public key cache in case a holds a
 //public key.
-func addAssertionToCache(a *section.Assertion, isAuthoritative bool, assertionsCache cache.Assertion,
-	zoneKeyCache cache.ZonePublicKey) {
-	assertionsCache.Add(a, a.ValidUntil(), isAuthoritative)
+func addAssertionToCache(a *section.Assertion, isAuthoritative bool, gracePeriod time.Duration,
+	assertionsCache cache.Assertion, zoneKeyCache cache.ZonePublicKey) {
+	assertionsCache.Add(a, cacheExpiration(a, gracePeriod), isAuthoritative)
 	log.Info("Added assertion to cache", "assertion", *a)
 	for _, obj := range a.Content {
 		if obj.Type == object.OTDelegation {
@@ -110,56 +211,137 @@ func addAssertionToCache(a *section.Assertion, isAuthoritative bool, assertionsC
 
 //addShardToCache adds shard to the negAssertion cache and all contained assertions to the
 //assertionsCache.
-func addShardToCache(shard *section.Shard, isAuthoritative bool, assertionsCache cache.Assertion,
-	negAssertionCache cache.NegativeAssertion, zoneKeyCache cache.ZonePublicKey) {
+func addShardToCache(shard *section.Shard, isAuthoritative bool, gracePeriod time.Duration,
+	assertionsCache cache.Assertion, negAssertionCache cache.NegativeAssertion,
+	zoneKeyCache cache.ZonePublicKey) {
 	for _, assertion := range shard.Content {
 		if shouldAssertionBeCached(assertion) {
 			a := assertion.Copy(shard.Context, shard.SubjectZone)
-			addAssertionToCache(a, isAuthoritative, assertionsCache, zoneKeyCache)
+			addAssertionToCache(a, isAuthoritative, gracePeriod, assertionsCache, zoneKeyCache)
 		}
 	}
-	negAssertionCache.AddShard(shard, shard.ValidUntil(), isAuthoritative)
+	negAssertionCache.AddShard(shard, cacheExpiration(shard, gracePeriod), isAuthoritative)
 	log.Debug("Added shard to cache", "shard", *shard)
 }
 
 //addPshardToCache adds pshard to the negAssertion cache
-func addPshardToCache(pshard *section.Pshard, isAuthoritative bool, assertionsCache cache.Assertion,
-	negAssertionCache cache.NegativeAssertion, zoneKeyCache cache.ZonePublicKey) {
-	negAssertionCache.AddPshard(pshard, pshard.ValidUntil(), isAuthoritative)
+func addPshardToCache(pshard *section.Pshard, isAuthoritative bool, gracePeriod time.Duration,
+	assertionsCache cache.Assertion, negAssertionCache cache.NegativeAssertion,
+	zoneKeyCache cache.ZonePublicKey) {
+	negAssertionCache.AddPshard(pshard, cacheExpiration(pshard, gracePeriod), isAuthoritative)
 	log.Debug("Added pshard to cache", "pshard", *pshard)
 }
 
 //addZoneToCache adds zone and all contained shards to the negAssertion cache and all contained
 //assertions to the assertionCache.
-func addZoneToCache(zone *section.Zone, isAuthoritative bool, assertionsCache cache.Assertion,
-	negAssertionCache cache.NegativeAssertion, zoneKeyCache cache.ZonePublicKey) {
+func addZoneToCache(zone *section.Zone, isAuthoritative bool, gracePeriod time.Duration,
+	assertionsCache cache.Assertion, negAssertionCache cache.NegativeAssertion,
+	zoneKeyCache cache.ZonePublicKey) {
 	for _, assertion := range zone.Content {
 		if shouldAssertionBeCached(assertion) {
 			a := assertion.Copy(zone.Context, zone.SubjectZone)
-			addAssertionToCache(a, isAuthoritative, assertionsCache, zoneKeyCache)
+			addAssertionToCache(a, isAuthoritative, gracePeriod, assertionsCache, zoneKeyCache)
 		}
 	}
-	negAssertionCache.AddZone(zone, zone.ValidUntil(), isAuthoritative)
+	negAssertionCache.AddZone(zone, cacheExpiration(zone, gracePeriod), isAuthoritative)
 	log.Debug("Added zone to cache", "zone", *zone)
 }
 
-func pendingKeysCallback(mss util.SectionWithSigSender, pendingKeys cache.PendingKey,
-	normalChannel chan util.MsgSectionSender) {
-	if ss, ok := pendingKeys.GetAndRemove(mss.Token); ok {
-		normalChannel <- ss
+//addAddressAssertionToCache adds a to the address cache. Public keys delegated in a are added to the
+//public key cache under a's prefix.
+func addAddressAssertionToCache(a *section.AddressAssertion, isAuthoritative bool,
+	gracePeriod time.Duration, addressCache cache.Address, zoneKeyCache cache.ZonePublicKey) {
+	addressCache.AddAssertion(a, cacheExpiration(a, gracePeriod), isAuthoritative)
+	log.Info("Added address assertion to cache", "addressAssertion", *a)
+	//The public key cache stores keys together with an assertion. The delegation is represented
+	//as an assertion about the zone named after a's prefix.
+	delegation := &section.Assertion{
+		Signatures:  a.Signatures,
+		SubjectName: "@",
+		SubjectZone: a.SubjectAddr.String(),
+		Context:     a.Context,
+	}
+	for _, obj := range a.Content {
+		if obj.Type == object.OTDelegation {
+			delegation.Content = append(delegation.Content, obj)
+		}
+	}
+	if len(delegation.Content) > 0 {
+		delegation.SetValidSince(a.ValidSince())
+		delegation.SetValidUntil(a.ValidUntil())
+		addZoneKeysToCache(delegation, isAuthoritative, zoneKeyCache)
+	}
+}
+
+//addAddressZoneToCache adds zone and all contained address assertions to the address cache.
+func addAddressZoneToCache(zone *section.AddressZone, isAuthoritative bool,
+	gracePeriod time.Duration, addressCache cache.Address, zoneKeyCache cache.ZonePublicKey) {
+	for _, a := range zone.Content {
+		if len(a.Signatures) > 0 {
+			addAddressAssertionToCache(a.Copy(zone.Context), isAuthoritative, gracePeriod,
+				addressCache, zoneKeyCache)
+		}
 	}
+	addressCache.AddZone(zone, cacheExpiration(zone, gracePeriod), isAuthoritative)
+	log.Debug("Added address zone to cache", "addressZone", *zone)
 }
 
-func pendingQueriesCallback(mss util.SectionWithSigSender, s *Server) {
-	msss := s.caches.PendingQueries.GetAndRemove(mss.Token)
-	if len(msss) == 0 {
-		return
+//cacheExpiration returns the time until which sec is kept in a cache. It is gracePeriod after sec
+//expires.
+func cacheExpiration(sec section.WithSig, gracePeriod time.Duration) int64 {
+	return sec.ValidUntil() + int64(gracePeriod/time.Second)
+}
+
+func pendingKeysCallback(mss util.SectionWithSigSender, s *Server) {
+	if ss, ok := s.caches.PendingKeys.GetAndRemove(mss.Token); ok {
+		s.enqueue(s.queues.Normal, ss)
 	}
+}
+
+//pendingQueriesCallback sends mss' sections to all queriers waiting for an answer with mss' token.
+//If an answer deadline is configured, the sections are first gathered together with all other
+//answers arriving under the same token until no answer has arrived for the duration of the
+//deadline or the query expires.
+func pendingQueriesCallback(mss util.SectionWithSigSender, s *Server) {
 	answer := []section.Section{}
 	for _, sec := range mss.Sections {
 		answer = append(answer, sec)
 	}
+	if s.config.AnswerCoalescingDeadline == 0 {
+		for _, ss := range s.caches.PendingQueries.GetAndRemove(mss.Token) {
+			sendSections(answer, ss.Token, ss.Sender, s)
+		}
+		return
+	}
+	deadline, isFirst := s.caches.PendingQueries.AddAnswer(mss.Token, answer,
+		time.Now().Add(s.config.AnswerCoalescingDeadline))
+	if !deadline.IsZero() && isFirst {
+		time.AfterFunc(time.Until(deadline), func() { s.sendCoalescedAnswer(mss.Token) })
+	}
+}
+
+//sendCoalescedAnswer sends the answer gathered for token t to all waiting queriers once the
+//entry's deadline has passed. If the deadline has been reset in the mean time, it tries again when
+//the new deadline is reached.
+func (s *Server) sendCoalescedAnswer(t token.Token) {
+	msss, answer, deadline := s.caches.PendingQueries.GetAndRemoveAnswer(t)
+	if len(msss) == 0 {
+		if !deadline.IsZero() {
+			time.AfterFunc(time.Until(deadline), func() { s.sendCoalescedAnswer(t) })
+		}
+		return
+	}
+	log.Debug("Sending coalesced answer", "token", t, "answer", answer)
 	for _, ss := range msss {
 		sendSections(answer, ss.Token, ss.Sender, s)
 	}
 }
+
+//reapPendingQueries removes all expired entries from the pending query cache and sends the answers
+//gathered for them to their queriers.
+func (s *Server) reapPendingQueries() {
+	for _, ss := range s.caches.PendingQueries.RemoveExpiredValues() {
+		log.Debug("Sending coalesced answer of expired query", "token", ss.Token)
+		sendSections(ss.Sections, ss.Token, ss.Sender, s)
+	}
+}
@@ -0,0 +1,58 @@
+package rainsd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+)
+
+func TestAnswerCoalescing(t *testing.T) {
+	s, client := newDatagramServer(t, 0)
+	defer s.udpConn.Close()
+	defer client.Close()
+	s.caches.PendingQueries = cache.NewPendingQuery(10)
+	querier := client.LocalAddr().(*net.UDPAddr)
+	a1 := ipAssertion("www", "ethz.ch.", "192.0.2.1")
+	a2 := ipAssertion("www", "ethz.ch.", "192.0.2.2")
+	var tests = []struct {
+		name       string
+		deadline   time.Duration
+		expiration time.Time
+		reap       bool
+	}{
+		{"answers arriving before the deadline", 100 * time.Millisecond,
+			time.Now().Add(time.Minute), false},
+		{"deadline capped at the query's expiration", time.Hour, time.Now(), false},
+		{"reaped entry", time.Hour, time.Now().Add(-time.Minute), true},
+	}
+	for _, test := range tests {
+		s.config.AnswerCoalescingDeadline = test.deadline
+		q := nameQueryMsg("www.ethz.ch.")
+		forwarded := token.New()
+		s.caches.PendingQueries.Add(util.MsgSectionSender{Sender: querier, Sections: q.Content,
+			Token: q.Token}, forwarded, test.expiration.Unix())
+		if test.reap {
+			//Add the answers without starting a timer such that only the reaper sends them.
+			s.caches.PendingQueries.AddAnswer(forwarded, []section.Section{a1, a2},
+				time.Now().Add(test.deadline))
+			s.reapPendingQueries()
+		} else {
+			for _, a := range []*section.Assertion{a1, a2} {
+				pendingQueriesCallback(util.SectionWithSigSender{Token: forwarded,
+					Sections: []section.WithSigForward{a}}, s)
+			}
+		}
+		answer := readDatagram(t, client)
+		if answer.Token != q.Token || len(answer.Content) != 2 {
+			t.Errorf("%s: answers were not sent together. actual=%v", test.name, answer)
+		}
+		if s.caches.PendingQueries.Len() != 0 {
+			t.Errorf("%s: answered query is still pending", test.name)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package rainsd
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/scionproto/scion/go/lib/snet"
+)
+
+//blacklist contains the zones and peers from which this server does not accept any traffic. It can
+//be modified at runtime and keeps track of how much traffic has been dropped because of it.
+type blacklist struct {
+	zones map[string]bool
+	peers map[string]bool //peerKey -> true
+	//mux protects zones and peers from simultaneous access.
+	mux sync.RWMutex
+
+	zoneDrops uint64 //number of dropped sections from blacklisted zones
+	peerDrops uint64 //number of dropped messages and connections from blacklisted peers
+}
+
+//newBlacklist returns a blacklist containing zones and peers. An error is returned if a peer
+//address cannot be parsed.
+func newBlacklist(zones, peers []string) (*blacklist, error) {
+	b := &blacklist{zones: make(map[string]bool), peers: make(map[string]bool)}
+	for _, zone := range zones {
+		b.AddZone(zone)
+	}
+	for _, peer := range peers {
+		if err := b.AddPeer(peer); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+//AddZone adds zone to the blacklist.
+func (b *blacklist) AddZone(zone string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.zones[zone] = true
+	log.Info("Added zone to blacklist", "zone", zone)
+}
+
+//RemoveZone removes zone from the blacklist.
+func (b *blacklist) RemoveZone(zone string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	delete(b.zones, zone)
+	log.Info("Removed zone from blacklist", "zone", zone)
+}
+
+//AddPeer adds the peer with address addr to the blacklist. addr is either an IP address (with an
+//optional port) or a SCION address of the form ISD-AS,[IP].
+func (b *blacklist) AddPeer(addr string) error {
+	key, err := parsePeer(addr)
+	if err != nil {
+		return err
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.peers[key] = true
+	log.Info("Added peer to blacklist", "peer", key)
+	return nil
+}
+
+//RemovePeer removes the peer with address addr from the blacklist.
+func (b *blacklist) RemovePeer(addr string) error {
+	key, err := parsePeer(addr)
+	if err != nil {
+		return err
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	delete(b.peers, key)
+	log.Info("Removed peer from blacklist", "peer", key)
+	return nil
+}
+
+//IsZoneBlacklisted returns true if zone is blacklisted. Each positive answer is counted and logged
+//as the caller is expected to drop the corresponding section.
+func (b *blacklist) IsZoneBlacklisted(zone string) bool {
+	b.mux.RLock()
+	blacklisted := b.zones[zone]
+	b.mux.RUnlock()
+	if blacklisted {
+		drops := atomic.AddUint64(&b.zoneDrops, 1)
+		log.Info("Dropped section of blacklisted zone", "zone", zone, "zoneDrops", drops)
+	}
+	return blacklisted
+}
+
+//IsPeerBlacklisted returns true if addr is blacklisted. Each positive answer is counted and logged
+//as the caller is expected to drop the corresponding traffic.
+func (b *blacklist) IsPeerBlacklisted(addr net.Addr) bool {
+	b.mux.RLock()
+	blacklisted := b.peers[peerKey(addr)]
+	b.mux.RUnlock()
+	if blacklisted {
+		drops := atomic.AddUint64(&b.peerDrops, 1)
+		log.Info("Dropped traffic from blacklisted peer", "peer", addr, "peerDrops", drops)
+	}
+	return blacklisted
+}
+
+//String returns the blacklisted zones and peers together with the drop counters.
+func (b *blacklist) String() string {
+	b.mux.RLock()
+	zones := make([]string, 0, len(b.zones))
+	for zone := range b.zones {
+		zones = append(zones, zone)
+	}
+	peers := make([]string, 0, len(b.peers))
+	for peer := range b.peers {
+		peers = append(peers, peer)
+	}
+	b.mux.RUnlock()
+	sort.Strings(zones)
+	sort.Strings(peers)
+	return fmt.Sprintf("zones=[%s] peers=[%s] zoneDrops=%d peerDrops=%d", strings.Join(zones, " "),
+		strings.Join(peers, " "), atomic.LoadUint64(&b.zoneDrops), atomic.LoadUint64(&b.peerDrops))
+}
+
+//peerKey returns the host part of addr which identifies a peer independent of its port.
+func peerKey(addr net.Addr) string {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP.String()
+	case *net.UDPAddr:
+		return addr.IP.String()
+	case *snet.Addr:
+		if addr.Host == nil {
+			return addr.IA.String()
+		}
+		return fmt.Sprintf("%s,[%v]", addr.IA, addr.Host.L3)
+	default:
+		return addr.String()
+	}
+}
+
+//parsePeer returns the peerKey of the address encoded in addr.
+func parsePeer(addr string) (string, error) {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip.String(), nil
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			return ip.String(), nil
+		}
+	}
+	if scionAddr, err := snet.AddrFromString(addr); err == nil {
+		return peerKey(scionAddr), nil
+	}
+	return "", fmt.Errorf("%s is neither an IP nor a SCION address", addr)
+}
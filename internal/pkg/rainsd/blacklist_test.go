@@ -0,0 +1,91 @@
+package rainsd
+
+import (
+	"net"
+	"testing"
+
+	"github.com/scionproto/scion/go/lib/snet"
+)
+
+func TestParsePeer(t *testing.T) {
+	var tests = []struct {
+		addr  string
+		key   string
+		valid bool
+	}{
+		{"192.0.2.1", "192.0.2.1", true},
+		{"192.0.2.1:5022", "192.0.2.1", true},
+		{"2001:db8::1", "2001:db8::1", true},
+		{"[2001:db8::1]:5022", "2001:db8::1", true},
+		{"1-ff00:0:110,[192.0.2.1]", "1-ff00:0:110,[192.0.2.1]", true},
+		{"1-ff00:0:110,[192.0.2.1]:5022", "1-ff00:0:110,[192.0.2.1]", true},
+		{"ethz.ch.", "", false},
+		{"192.0.2.256", "", false},
+		{"", "", false},
+	}
+	for _, test := range tests {
+		key, err := parsePeer(test.addr)
+		if (err == nil) != test.valid || key != test.key {
+			t.Errorf("%s: wrong peer key. expected=%s actual=%s error=%v", test.addr, test.key,
+				key, err)
+		}
+	}
+}
+
+func TestBlacklist(t *testing.T) {
+	if _, err := newBlacklist(nil, []string{"not an address"}); err == nil {
+		t.Error("blacklist with malformed peer was created")
+	}
+	b, err := newBlacklist([]string{"evil.ch."}, []string{"192.0.2.1", "1-ff00:0:110,[192.0.2.2]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scionAddr, err := snet.AddrFromString("1-ff00:0:110,[192.0.2.2]:5022")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherAS, err := snet.AddrFromString("1-ff00:0:111,[192.0.2.2]:5022")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		addr        net.Addr
+		blacklisted bool
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5022}, true},
+		{&net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 53}, true},
+		{&net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 5022}, false},
+		{scionAddr, true},
+		{otherAS, false},
+	}
+	for _, test := range tests {
+		if b.IsPeerBlacklisted(test.addr) != test.blacklisted {
+			t.Errorf("%v: wrong blacklist match. expected=%t", test.addr, test.blacklisted)
+		}
+	}
+	if !b.IsZoneBlacklisted("evil.ch.") || b.IsZoneBlacklisted("ethz.ch.") ||
+		b.IsZoneBlacklisted("sub.evil.ch.") {
+		t.Error("wrong zone blacklist match")
+	}
+	b.AddZone("ethz.ch.")
+	b.RemoveZone("evil.ch.")
+	if b.IsZoneBlacklisted("evil.ch.") || !b.IsZoneBlacklisted("ethz.ch.") {
+		t.Error("zone blacklist was not modified")
+	}
+	if err := b.AddPeer("192.0.2.2:1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.RemovePeer("192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if b.IsPeerBlacklisted(tests[0].addr) || !b.IsPeerBlacklisted(tests[2].addr) {
+		t.Error("peer blacklist was not modified")
+	}
+	if err := b.RemovePeer("not an address"); err == nil {
+		t.Error("malformed peer was removed")
+	}
+	expected := "zones=[ethz.ch.] peers=[1-ff00:0:110,[192.0.2.2] 192.0.2.2] zoneDrops=2 peerDrops=4"
+	if b.String() != expected {
+		t.Errorf("wrong blacklist string. expected=%s actual=%s", expected, b.String())
+	}
+}
@@ -1,9 +1,21 @@
 package rainsd
 
 import (
+	"io"
+	"os"
+	"path"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+
 	"github.com/netsec-ethz/rains/internal/pkg/cache"
 )
 
+const (
+	assertionStoreFileName    = "assertions.log"
+	negAssertionStoreFileName = "negAssertions.log"
+)
+
 type Caches struct {
 	//connCache stores connections of this server. It is not guaranteed that a returned connection is still active.
 	ConnCache cache.Connection
@@ -29,9 +41,23 @@ type Caches struct {
 	//for a shard the range is given as declared in the section.
 	//An entry is marked as extrenal if it might be evicted by a LRU caching strategy.
 	NegAssertionCache cache.NegativeAssertion
+
+	//addressCache contains address assertions and address zones which are looked up by the longest
+	//prefix containing a queried address.
+	AddressCache cache.Address
+
+	//stores contains the caches which are backed by files and must be closed on shutdown.
+	stores []store
+}
+
+//store is a cache which is backed by a file.
+type store interface {
+	io.Closer
+	//Sync commits all changes since the last sync to disk.
+	Sync() error
 }
 
-func initCaches(config Config) *Caches {
+func initCaches(config Config) (*Caches, error) {
 	caches := new(Caches)
 	caches.ConnCache = cache.NewConnection(config.MaxConnections)
 	caches.Capabilities = cache.NewCapability(config.CapabilitiesCacheSize)
@@ -39,15 +65,75 @@ func initCaches(config Config) *Caches {
 		config.MaxPublicKeysPerZone)
 	caches.PendingKeys = cache.NewPendingKey(config.PendingKeyCacheSize)
 	caches.PendingQueries = cache.NewPendingQuery(config.PendingQueryCacheSize)
-	caches.AssertionsCache = cache.NewAssertion(config.AssertionCacheSize)
-	caches.NegAssertionCache = cache.NewNegAssertion(config.NegativeAssertionCacheSize)
-	return caches
+	caches.AddressCache = cache.NewAddress(config.AddressCacheSize)
+	if config.AssertionStorePath == "" {
+		caches.AssertionsCache = cache.NewAssertion(config.AssertionCacheSize)
+		caches.NegAssertionCache = cache.NewNegAssertion(config.NegativeAssertionCacheSize)
+		return caches, nil
+	}
+	if err := os.MkdirAll(config.AssertionStorePath, os.ModePerm); err != nil {
+		return nil, err
+	}
+	assertions, err := cache.NewPersistentAssertion(path.Join(config.AssertionStorePath,
+		assertionStoreFileName), config.AssertionCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	negAssertions, err := cache.NewPersistentNegAssertion(path.Join(config.AssertionStorePath,
+		negAssertionStoreFileName), config.NegativeAssertionCacheSize)
+	if err != nil {
+		assertions.Close()
+		return nil, err
+	}
+	caches.AssertionsCache, caches.NegAssertionCache = assertions, negAssertions
+	caches.stores = []store{assertions, negAssertions}
+	return caches, nil
 }
 
-func initReapers(config Config, caches *Caches, stop chan bool) {
-	go repeatFuncCaller(caches.ZoneKeyCache.RemoveExpiredKeys, config.ReapZoneKeyCacheInterval, stop)
-	go repeatFuncCaller(caches.PendingKeys.RemoveExpiredValues, config.ReapPendingKeyCacheInterval, stop)
-	go repeatFuncCaller(caches.AssertionsCache.RemoveExpiredValues, config.ReapAssertionCacheInterval, stop)
-	go repeatFuncCaller(caches.NegAssertionCache.RemoveExpiredValues, config.ReapNegAssertionCacheInterval, stop)
-	go repeatFuncCaller(caches.PendingQueries.RemoveExpiredValues, config.ReapPendingQCacheInterval, stop)
+//syncStores commits the changes of all caches which are backed by files to disk.
+func syncStores(caches *Caches) {
+	for _, store := range caches.stores {
+		if err := store.Sync(); err != nil {
+			log.Error("Was not able to sync persistent cache", "error", err)
+		}
+	}
+}
+
+//closeStores closes all caches which are backed by files.
+func closeStores(caches *Caches) {
+	for _, store := range caches.stores {
+		if err := store.Close(); err != nil {
+			log.Error("Was not able to close persistent cache", "error", err)
+		}
+	}
+}
+
+func initReapers(s *Server) {
+	caches := s.caches
+	go repeatFuncCaller(caches.ZoneKeyCache.RemoveExpiredKeys,
+		func() time.Duration { return s.Config().ReapZoneKeyCacheInterval }, s.shutdown)
+	go repeatFuncCaller(caches.PendingKeys.RemoveExpiredValues,
+		func() time.Duration { return s.Config().ReapPendingKeyCacheInterval }, s.shutdown)
+	go repeatFuncCaller(caches.AssertionsCache.RemoveExpiredValues,
+		func() time.Duration { return s.Config().ReapAssertionCacheInterval }, s.shutdown)
+	go repeatFuncCaller(caches.NegAssertionCache.RemoveExpiredValues,
+		func() time.Duration { return s.Config().ReapNegAssertionCacheInterval }, s.shutdown)
+	go repeatFuncCaller(caches.AddressCache.RemoveExpiredValues,
+		func() time.Duration { return s.Config().ReapAssertionCacheInterval }, s.shutdown)
+	go repeatFuncCaller(s.reapPendingQueries,
+		func() time.Duration { return s.Config().ReapPendingQCacheInterval }, s.shutdown)
+}
+
+//resizeCaches sets the maximum size of all caches to the values in config. Cached entries are kept
+//as long as they fit into the resized caches.
+func resizeCaches(caches *Caches, config Config) {
+	caches.ConnCache.Resize(config.MaxConnections)
+	caches.Capabilities.Resize(config.CapabilitiesCacheSize)
+	caches.ZoneKeyCache.Resize(config.ZoneKeyCacheSize, config.ZoneKeyCacheWarnSize,
+		config.MaxPublicKeysPerZone)
+	caches.PendingKeys.Resize(config.PendingKeyCacheSize)
+	caches.PendingQueries.Resize(config.PendingQueryCacheSize)
+	caches.AssertionsCache.Resize(config.AssertionCacheSize)
+	caches.NegAssertionCache.Resize(config.NegativeAssertionCacheSize)
+	caches.AddressCache.Resize(config.AddressCacheSize)
 }
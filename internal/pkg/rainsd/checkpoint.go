@@ -0,0 +1,379 @@
+package rainsd
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/cbor"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/siglib"
+	"github.com/netsec-ethz/rains/internal/pkg/zonefile"
+)
+
+//CheckPointEncoding determines the format in which the content of the caches is written to the
+//check point files.
+type CheckPointEncoding int
+
+//go:generate stringer -type=CheckPointEncoding
+//go:generate jsonenums -type=CheckPointEncoding
+const (
+	//GobCheckPoint encodes the cached sections with gob.
+	GobCheckPoint CheckPointEncoding = iota
+	//CBORCheckPoint encodes the cached sections as the content of a CBOR encoded RAINS message.
+	CBORCheckPoint
+	//ZonefileCheckPoint encodes the cached sections in the zone file format.
+	ZonefileCheckPoint
+)
+
+//extension returns the file extension of check point files in encoding e.
+func (e CheckPointEncoding) extension() string {
+	switch e {
+	case CBORCheckPoint:
+		return ".cbor"
+	case ZonefileCheckPoint:
+		return ".txt"
+	default:
+		return ".gob"
+	}
+}
+
+const (
+	aCheckPointFileName = "assertionCheckPoint"
+	nCheckPointFileName = "negAssertionCheckPoint"
+	zCheckPointFileName = "zoneKeyCheckPoint"
+)
+
+type checkPointValue struct {
+	Sections   []section.Section
+	ValidSince []int64
+	ValidUntil []int64
+}
+
+func initStoreCachesContent(s *Server) {
+	config, caches := s.Config(), s.caches
+	if err := os.MkdirAll(config.CheckPointPath, os.ModePerm); err != nil {
+		log.Error("Was not able to create folders", "error", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	go repeatFuncCaller(func() {
+		s.checkpoint(checkPointFile(config, aCheckPointFileName), config,
+			caches.AssertionsCache.Checkpoint)
+	}, func() time.Duration { return s.Config().AssertionCheckPointInterval }, s.shutdown)
+	go repeatFuncCaller(func() {
+		s.checkpoint(checkPointFile(config, nCheckPointFileName), config,
+			caches.NegAssertionCache.Checkpoint)
+	}, func() time.Duration { return s.Config().NegAssertionCheckPointInterval }, s.shutdown)
+	go repeatFuncCaller(func() {
+		s.checkpoint(checkPointFile(config, zCheckPointFileName), config,
+			zoneKeyCheckpoint(caches.ZoneKeyCache))
+	}, func() time.Duration { return s.Config().ZoneKeyCheckPointInterval }, s.shutdown)
+}
+
+//checkpointCaches writes the content of the assertion, negative assertion and zone key caches to
+//their check point files in the configured check point path.
+func (s *Server) checkpointCaches() {
+	config, caches := s.Config(), s.caches
+	s.checkpoint(checkPointFile(config, aCheckPointFileName), config,
+		caches.AssertionsCache.Checkpoint)
+	s.checkpoint(checkPointFile(config, nCheckPointFileName), config,
+		caches.NegAssertionCache.Checkpoint)
+	s.checkpoint(checkPointFile(config, zCheckPointFileName), config,
+		zoneKeyCheckpoint(caches.ZoneKeyCache))
+}
+
+//zoneKeyCheckpoint returns a function returning the delegation assertions of zoneKeyCache.
+//Delegations of address prefixes are omitted as their signatures are over an address assertion
+//such that they cannot be verified when the check point is loaded.
+func zoneKeyCheckpoint(zoneKeyCache cache.ZonePublicKey) func() []section.Section {
+	return func() []section.Section {
+		sections := []section.Section{}
+		for _, s := range zoneKeyCache.Checkpoint() {
+			if a, ok := s.(*section.Assertion); ok {
+				if _, err := address.Parse(a.SubjectZone); err == nil {
+					continue
+				}
+			}
+			sections = append(sections, s)
+		}
+		return sections
+	}
+}
+
+//checkPointFile returns the path of the current check point file with base name name.
+func checkPointFile(config Config, name string) string {
+	return path.Join(config.CheckPointPath, name+config.CheckPointEncoding.extension())
+}
+
+//checkPointGeneration returns the path of the check point file which is generation generations
+//older than the current check point file at path.
+func checkPointGeneration(path string, generation int) string {
+	if generation == 0 {
+		return path
+	}
+	return fmt.Sprintf("%s.%d", path, generation)
+}
+
+//checkpoint writes values to the check point file at path. Writes are serialized such that a
+//periodic and a final check point do not rotate the same generations concurrently.
+func (s *Server) checkpoint(path string, config Config, values func() []section.Section) {
+	s.checkpointMutex.Lock()
+	defer s.checkpointMutex.Unlock()
+	if err := writeCheckpoint(path, config.CheckPointEncoding, config.CheckPointGenerations,
+		values()); err != nil {
+		log.Error("Was not able to checkpoint cache", "path", path, "error", err)
+	}
+}
+
+//writeCheckpoint encodes sections into a temporary file next to path which atomically replaces the
+//current check point file once it is completely written to disk. Before that, the previous check
+//points are shifted by one generation and at most generations of them are kept. The directory is
+//synced afterwards such that the renames survive a crash.
+func writeCheckpoint(path string, encoding CheckPointEncoding, generations int,
+	sections []section.Section) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if err := encodeCheckpoint(tmp, encoding, sections); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	for i := generations; i > 0; i-- {
+		err := os.Rename(checkPointGeneration(path, i-1), checkPointGeneration(path, i))
+		if err != nil && !os.IsNotExist(err) {
+			log.Warn("Was not able to rotate check point", "path", path, "generation", i, "error", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+//syncDir commits the entries of the directory at path to disk.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+//encodeCheckpoint writes sections to w in encoding.
+func encodeCheckpoint(w io.Writer, encoding CheckPointEncoding, sections []section.Section) error {
+	switch encoding {
+	case GobCheckPoint:
+		value := checkPointValue{Sections: sections}
+		for _, s := range value.Sections {
+			value.ValidSince = append(value.ValidSince, s.(section.WithSigForward).ValidSince())
+			value.ValidUntil = append(value.ValidUntil, s.(section.WithSigForward).ValidUntil())
+		}
+		return gob.NewEncoder(w).Encode(value)
+	case CBORCheckPoint:
+		return cbor.NewWriter(w).Marshal(&message.Message{Content: sections})
+	case ZonefileCheckPoint:
+		_, err := io.WriteString(w, zonefile.IO{}.Encode(sections))
+		return err
+	default:
+		return fmt.Errorf("unsupported check point encoding: %v", encoding)
+	}
+}
+
+//decodeCheckpoint returns the sections encoded in data.
+func decodeCheckpoint(data []byte, encoding CheckPointEncoding) ([]section.WithSigForward, error) {
+	sections := []section.WithSigForward{}
+	switch encoding {
+	case GobCheckPoint:
+		value := checkPointValue{}
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+			return nil, err
+		}
+		for _, s := range value.Sections {
+			sec, ok := s.(section.WithSigForward)
+			if !ok {
+				return nil, fmt.Errorf("unexpected section type in check point: %T", s)
+			}
+			sections = append(sections, sec)
+		}
+	case CBORCheckPoint:
+		msg := message.Message{}
+		if err := cbor.NewReader(bytes.NewReader(data)).Unmarshal(&msg); err != nil {
+			return nil, err
+		}
+		for _, s := range msg.Content {
+			sec, ok := s.(section.WithSigForward)
+			if !ok {
+				return nil, fmt.Errorf("unexpected section type in check point: %T", s)
+			}
+			sections = append(sections, sec)
+		}
+	case ZonefileCheckPoint:
+		if len(bytes.TrimSpace(data)) == 0 {
+			return sections, nil
+		}
+		return zonefile.IO{}.Decode(data)
+	default:
+		return nil, fmt.Errorf("unsupported check point encoding: %v", encoding)
+	}
+	return sections, nil
+}
+
+//readCheckpoint returns the sections of the most recent generation of the check point file at
+//path which can be read and decoded.
+func readCheckpoint(path string, encoding CheckPointEncoding, generations int) (
+	[]section.WithSigForward, error) {
+	err := errors.New("no check point generation found")
+	for i := 0; i <= generations; i++ {
+		var data []byte
+		if data, err = ioutil.ReadFile(checkPointGeneration(path, i)); err != nil {
+			continue
+		}
+		var sections []section.WithSigForward
+		if sections, err = decodeCheckpoint(data, encoding); err != nil {
+			log.Warn("Was not able to decode check point", "path", checkPointGeneration(path, i),
+				"error", err)
+			continue
+		}
+		if i > 0 {
+			log.Warn("Loaded older check point generation", "path", checkPointGeneration(path, i))
+		}
+		return sections, nil
+	}
+	return nil, err
+}
+
+//loadCheckpoint returns the sections of the most recent readable generation of the check point
+//file with base name name. If there is no check point in the configured encoding, e.g. because the
+//encoding was changed, the gob encoded check point written by earlier servers is loaded instead. It
+//is migrated to the configured encoding with the next check point.
+func loadCheckpoint(config Config, name string) ([]section.WithSigForward, error) {
+	file := checkPointFile(config, name)
+	sections, err := readCheckpoint(file, config.CheckPointEncoding, config.CheckPointGenerations)
+	if _, statErr := os.Stat(file); config.CheckPointEncoding == GobCheckPoint ||
+		!os.IsNotExist(statErr) {
+		return sections, err
+	}
+	gobFile := path.Join(config.CheckPointPath, name+GobCheckPoint.extension())
+	if _, statErr := os.Stat(gobFile); statErr != nil {
+		return sections, err
+	}
+	log.Info("Loading gob encoded check point", "path", gobFile, "encoding",
+		config.CheckPointEncoding)
+	return readCheckpoint(gobFile, GobCheckPoint, config.CheckPointGenerations)
+}
+
+//verifyCheckpointSections returns all sections whose signatures are valid according to the keys in
+//zoneKeyCache and which have not yet expired. The validity of each returned section is recomputed
+//from its signatures instead of taken from the check point. The second return value is the
+//sections for which not all public keys are cached.
+func verifyCheckpointSections(sections []section.WithSigForward, zoneKeyCache cache.ZonePublicKey,
+	config Config) (verified, missing []section.WithSigForward) {
+	now := time.Now().Unix()
+	for _, sec := range sections {
+		if len(sec.AllSigs()) == 0 {
+			log.Warn("Dropped unsigned section from check point", "section", sec)
+			continue
+		}
+		publicKeys := make(map[keys.PublicKeyID][]keys.PublicKey)
+		missingKeys := make(map[missingKeyMetaData]bool)
+		publicKeysPresent(sec, zoneKeyCache, publicKeys, missingKeys)
+		if len(missingKeys) > 0 {
+			missing = append(missing, sec)
+			continue
+		}
+		sec.SetValidSince(0)
+		sec.SetValidUntil(0)
+		if !siglib.CheckSectionSignatures(sec, publicKeys, config.MaxCacheValidity) {
+			log.Warn("Dropped section with invalid signature from check point", "section", sec)
+			continue
+		}
+		if sec.ValidUntil() < now {
+			log.Info("Dropped expired section from check point", "section", sec)
+			continue
+		}
+		verified = append(verified, sec)
+	}
+	return
+}
+
+func loadCaches(config Config, caches *Caches, authorities []ZoneContext) {
+
+	//load zone key check point. Delegations are verified top down starting with the root zone
+	//public key until no further delegation can be verified.
+	sections, err := loadCheckpoint(config, zCheckPointFileName)
+	if err != nil {
+		log.Warn("Was not able to load zone key check point from file", "error", err)
+	}
+	for len(sections) > 0 {
+		verified, missing := verifyCheckpointSections(sections, caches.ZoneKeyCache, config)
+		for _, s := range verified {
+			if s, ok := s.(*section.Assertion); ok {
+				addZoneKeysToCache(s, isAuthoritative(s, authorities), caches.ZoneKeyCache)
+			} else {
+				log.Warn("Invalid type for zone key cache", "type", fmt.Sprintf("%T", s))
+			}
+		}
+		if len(verified) == 0 {
+			log.Warn("Dropped zone key check point entries without cached public key",
+				"count", len(missing))
+			break
+		}
+		sections = missing
+	}
+
+	//load assertion and negAssertion check points
+	for _, name := range []string{aCheckPointFileName, nCheckPointFileName} {
+		sections, err := loadCheckpoint(config, name)
+		if err != nil {
+			log.Warn("Was not able to load check point from file", "name", name, "error", err)
+			continue
+		}
+		verified, missing := verifyCheckpointSections(sections, caches.ZoneKeyCache, config)
+		if len(missing) > 0 {
+			log.Warn("Dropped check point entries without cached public key", "name", name,
+				"count", len(missing))
+		}
+		addSectionsToCache(verified, authorities, config.StaleGracePeriod, caches.AssertionsCache,
+			caches.NegAssertionCache, caches.AddressCache, caches.ZoneKeyCache)
+	}
+}
+
+//addZoneKeysToCache adds all public keys delegated in a to the zone key cache.
+func addZoneKeysToCache(a *section.Assertion, isAuthoritative bool,
+	zoneKeyCache cache.ZonePublicKey) {
+	for _, o := range a.Content {
+		if o.Type == object.OTDelegation {
+			publicKey, _ := o.Value.(keys.PublicKey)
+			publicKey.ValidSince = a.ValidSince()
+			publicKey.ValidUntil = a.ValidUntil()
+			if ok := zoneKeyCache.Add(a, publicKey, isAuthoritative); !ok {
+				log.Warn("number of entries in the zoneKeyCache reached a critical amount")
+			}
+		}
+	}
+}
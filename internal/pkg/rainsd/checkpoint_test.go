@@ -0,0 +1,186 @@
+package rainsd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/siglib"
+	"github.com/netsec-ethz/rains/internal/pkg/signature"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+	"golang.org/x/crypto/ed25519"
+)
+
+func checkPointSections() []section.Section {
+	sig := signature.Sig{
+		PublicKeyID: keys.PublicKeyID{Algorithm: algorithmTypes.Ed25519, KeySpace: keys.RainsKeySpace},
+		ValidSince:  time.Now().Unix(),
+		ValidUntil:  time.Now().Add(time.Hour).Unix(),
+		Data:        []byte("signature"),
+	}
+	a := ipAssertion("www", "ethz.ch.", "192.0.2.1")
+	a.AddSig(sig)
+	s := shard("a", "z", contentAssertion(ipAssertion("b", "", "192.0.2.2")))
+	s.AddSig(sig)
+	return []section.Section{a, s}
+}
+
+func TestCheckpointEncodings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, encoding := range []CheckPointEncoding{GobCheckPoint, CBORCheckPoint,
+		ZonefileCheckPoint} {
+		path := filepath.Join(dir, aCheckPointFileName+encoding.extension())
+		sections := checkPointSections()
+		if err := writeCheckpoint(path, encoding, 2, sections); err != nil {
+			t.Fatalf("%s: was not able to write check point: %v", encoding, err)
+		}
+		decoded, err := readCheckpoint(path, encoding, 2)
+		if err != nil {
+			t.Fatalf("%s: was not able to read check point: %v", encoding, err)
+		}
+		if len(decoded) != len(sections) {
+			t.Fatalf("%s: wrong number of sections. expected=%d actual=%d", encoding,
+				len(sections), len(decoded))
+		}
+		for i, s := range sections {
+			if !reflect.DeepEqual(decoded[i], s) {
+				t.Errorf("%s: decoded section differs. expected=%v actual=%v", encoding, s,
+					decoded[i])
+			}
+		}
+	}
+}
+
+func TestCheckpointGenerations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, aCheckPointFileName+GobCheckPoint.extension())
+	sections := checkPointSections()
+	for i := range sections {
+		if err := writeCheckpoint(path, GobCheckPoint, 1, sections[i:i+1]); err != nil {
+			t.Fatalf("was not able to write check point %d: %v", i, err)
+		}
+	}
+	if _, err := os.Stat(checkPointGeneration(path, 2)); !os.IsNotExist(err) {
+		t.Error("more generations than configured are kept")
+	}
+	if err := ioutil.WriteFile(path, []byte("corrupted"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := readCheckpoint(path, GobCheckPoint, 1)
+	if err != nil {
+		t.Fatalf("older generation was not loaded: %v", err)
+	}
+	if len(decoded) != 1 || !reflect.DeepEqual(decoded[0], sections[0]) {
+		t.Errorf("wrong generation loaded. expected=%v actual=%v", sections[:1], decoded)
+	}
+	if err := ioutil.WriteFile(checkPointGeneration(path, 1), []byte("corrupted"),
+		0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readCheckpoint(path, GobCheckPoint, 1); err == nil {
+		t.Error("no error returned although all generations are corrupted")
+	}
+}
+
+//signedCheckPointSections returns the public key of ethz.ch. and assertions signed with the
+//corresponding private key. Only the first assertion is valid. The second one was modified after
+//it was signed and the signature of the third one has expired.
+func signedCheckPointSections(t *testing.T) (keys.PublicKey, []section.Section) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := keys.PublicKeyID{Algorithm: algorithmTypes.Ed25519, KeySpace: keys.RainsKeySpace}
+	key := keys.PublicKey{
+		PublicKeyID: id,
+		ValidSince:  time.Now().Add(-3 * time.Hour).Unix(),
+		ValidUntil:  time.Now().Add(time.Hour).Unix(),
+		Key:         pubKey,
+	}
+	sections := []section.Section{}
+	for _, sig := range []struct {
+		ip         string
+		validUntil time.Time
+	}{
+		{"192.0.2.1", time.Now().Add(time.Hour)},
+		{"192.0.2.2", time.Now().Add(time.Hour)},
+		{"192.0.2.3", time.Now().Add(-time.Hour)},
+	} {
+		a := ipAssertion("www", "ethz.ch.", sig.ip)
+		a.AddSig(signature.Sig{
+			PublicKeyID: id,
+			ValidSince:  time.Now().Add(-2 * time.Hour).Unix(),
+			ValidUntil:  sig.validUntil.Unix(),
+		})
+		ks := map[keys.PublicKeyID]interface{}{id: privKey}
+		if err := siglib.SignSectionUnsafe(a, ks); err != nil {
+			t.Fatal(err)
+		}
+		sections = append(sections, a)
+	}
+	sections[1].(*section.Assertion).Content[0].Value = "192.0.2.10"
+	return key, sections
+}
+
+func TestLoadCaches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	key, sections := signedCheckPointSections(t)
+	delegation := &section.Assertion{SubjectName: "ethz", SubjectZone: "ch.", Context: ".",
+		Content: []object.Object{{Type: object.OTDelegation, Value: key}}}
+	var tests = []struct {
+		name     string
+		written  CheckPointEncoding
+		encoding CheckPointEncoding
+	}{
+		{"configured encoding", CBORCheckPoint, CBORCheckPoint},
+		{"gob check point of an earlier run", GobCheckPoint, ZonefileCheckPoint},
+	}
+	for _, test := range tests {
+		config := Config{
+			CheckPointPath:     filepath.Join(dir, test.encoding.String()),
+			CheckPointEncoding: test.encoding,
+			MaxCacheValidity:   util.MaxCacheValidity{AssertionValidity: time.Hour},
+		}
+		if err := os.Mkdir(config.CheckPointPath, 0700); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(config.CheckPointPath, aCheckPointFileName+test.written.extension())
+		if err := writeCheckpoint(path, test.written, 0, sections); err != nil {
+			t.Fatal(err)
+		}
+		caches := &Caches{
+			ZoneKeyCache:      cache.NewZoneKey(10, 10, 10),
+			AssertionsCache:   cache.NewAssertion(10),
+			NegAssertionCache: cache.NewNegAssertion(10),
+			AddressCache:      cache.NewAddress(10),
+		}
+		caches.ZoneKeyCache.Add(delegation, key, false)
+		loadCaches(config, caches, nil)
+		//The modified and the expired assertion are dropped.
+		cached, ok := caches.AssertionsCache.Get("www.ethz.ch.", ".", object.OTIP4Addr, false)
+		if !ok || len(cached) != 1 || cached[0].Hash() != sections[0].(*section.Assertion).Hash() {
+			t.Errorf("%s: wrong assertions loaded. expected=%v actual=%v", test.name,
+				sections[:1], cached)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package rainsd
+
+import (
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+//InconsistencyPolicy determines how a server treats cached sections which contradict a newly
+//received section that does not supersede them. Such a received section is never cached. A section
+//supersedes the cached sections it contradicts if it is newer than all of them or if it belongs to
+//a zone the server is authoritative for. In this case the contradicting sections are always
+//removed and the received section is cached, such that a re-published zone is accepted right away.
+type InconsistencyPolicy int
+
+//go:generate stringer -type=InconsistencyPolicy
+//go:generate jsonenums -type=InconsistencyPolicy
+const (
+	//KeepCached leaves the cache unchanged.
+	KeepCached InconsistencyPolicy = iota
+	//EvictConflicting removes all cached sections contradicting the received section such that
+	//the received section is accepted when it is received the next time.
+	EvictConflicting
+	//EvictZone removes all cached sections of the zone the contradiction occurred in.
+	EvictZone
+)
+
+//rangeInterval is the interval covered by a shard or pshard where the open range markers '<' and
+//'>' are replaced by the empty string such that it can be used with section.Intersect.
+type rangeInterval struct {
+	begin string
+	end   string
+}
+
+//Begin returns the beginning of the range
+func (r rangeInterval) Begin() string {
+	return r.begin
+}
+
+//End returns the end of the range
+func (r rangeInterval) End() string {
+	return r.end
+}
+
+//toRangeInterval returns the interval covered by sec.
+func toRangeInterval(sec section.Interval) rangeInterval {
+	r := rangeInterval{begin: sec.Begin(), end: sec.End()}
+	if r.begin == "<" {
+		r.begin = ""
+	}
+	if r.end == ">" {
+		r.end = ""
+	}
+	return r
+}
+
+//inRange returns true if name is within the range covered by sec.
+func inRange(sec section.Interval, name string) bool {
+	r := toRangeInterval(sec)
+	return (r.begin == "" || r.begin < name) && (r.end == "" || name < r.end)
+}
+
+//inconsistentCachedSections returns all cached sections which are valid at the same time as one
+//of sections and contradict it. The cached sections contradicting a section which supersedes them
+//are returned as superseded, all others as conflicts.
+func inconsistentCachedSections(sections []section.WithSigForward, authorities []ZoneContext,
+	assertionsCache cache.Assertion, negAssertionCache cache.NegativeAssertion) (
+	conflicts, superseded []section.WithSigForward) {
+	for _, sec := range sections {
+		cached := conflictingCachedSections(sec, assertionsCache, negAssertionCache)
+		if supersedes(sec, cached, authorities) {
+			superseded = append(superseded, cached...)
+		} else {
+			conflicts = append(conflicts, cached...)
+		}
+	}
+	return conflicts, superseded
+}
+
+//supersedes returns true if sec belongs to one of authorities or if it became valid later than all
+//cached sections.
+func supersedes(sec section.WithSigForward, cached []section.WithSigForward,
+	authorities []ZoneContext) bool {
+	if isAuthoritative(sec, authorities) {
+		return true
+	}
+	for _, c := range cached {
+		if c.ValidSince() >= sec.ValidSince() {
+			return false
+		}
+	}
+	return true
+}
+
+//conflictingCachedSections returns all cached sections of sec's zone and context which are valid
+//at the same time as sec and contradict it.
+func conflictingCachedSections(sec section.WithSigForward, assertionsCache cache.Assertion,
+	negAssertionCache cache.NegativeAssertion) []section.WithSigForward {
+	switch sec.(type) {
+	case *section.AddressAssertion, *section.AddressZone:
+		//Address sections are not cached together with names.
+		return nil
+	}
+	var conflicts []section.WithSigForward
+	negSections, _ := negAssertionCache.Get(sec.GetSubjectZone(), sec.GetContext(), section.TotalInterval{})
+	if a, ok := sec.(*section.Assertion); ok {
+		for _, s := range negSections {
+			if validityOverlaps(a, s) && !isAssertionConsistent(a, s) {
+				conflicts = append(conflicts, s)
+			}
+		}
+		return conflicts
+	}
+	assertions, _ := assertionsCache.GetInRange(sec.GetSubjectZone(), sec.GetContext(), toRangeInterval(sec))
+	for _, a := range assertions {
+		if validityOverlaps(a, sec) && !isAssertionConsistent(a, sec) {
+			conflicts = append(conflicts, a)
+		}
+	}
+	for _, s := range negSections {
+		if validityOverlaps(s, sec) && !areNegSectionsConsistent(s, sec) {
+			conflicts = append(conflicts, s)
+		}
+	}
+	return conflicts
+}
+
+//validityOverlaps returns true if there is a point in time where s1 and s2 are both valid.
+func validityOverlaps(s1, s2 section.WithSigForward) bool {
+	return s1.ValidSince() < s2.ValidUntil() && s2.ValidSince() < s1.ValidUntil()
+}
+
+//isAssertionConsistent returns true if a's subject name is not in s' range or if s does not
+//contradict a. A shard or zone contradicts a if it does not contain all of a's objects. A pshard
+//contradicts a if at least one of a's object types is not in its bloom filter.
+func isAssertionConsistent(a *section.Assertion, s section.WithSigForward) bool {
+	switch s := s.(type) {
+	case *section.Shard:
+		return !inRange(s, a.SubjectName) || containsObjects(s.Content, a)
+	case *section.Zone:
+		return containsObjects(s.Content, a)
+	case *section.Pshard:
+		if !inRange(s, a.SubjectName) {
+			return true
+		}
+		for _, o := range a.Content {
+			if ok, err := s.BloomFilter.Contains(a.SubjectName, s.SubjectZone, s.Context, o.Type); err == nil && !ok {
+				return false
+			}
+		}
+		return true
+	default:
+		log.Warn("Not supported section type for a consistency check", "type", s)
+		return true
+	}
+}
+
+//containsObjects returns true if all objects of a are present in assertions of content having the
+//same subject name as a.
+func containsObjects(content []*section.Assertion, a *section.Assertion) bool {
+	for _, o := range a.Content {
+		found := false
+		for _, c := range content {
+			if c.SubjectName != a.SubjectName {
+				continue
+			}
+			for _, obj := range c.Content {
+				if obj.CompareTo(o) == 0 {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+//areNegSectionsConsistent returns true if all assertions of s1 are consistent with s2 and vice
+//versa. Two pshards are always consistent with each other.
+func areNegSectionsConsistent(s1, s2 section.WithSigForward) bool {
+	for _, a := range sectionContent(s1) {
+		if !isAssertionConsistent(a, s2) {
+			return false
+		}
+	}
+	for _, a := range sectionContent(s2) {
+		if !isAssertionConsistent(a, s1) {
+			return false
+		}
+	}
+	return true
+}
+
+//sectionContent returns the assertions contained in a shard or zone and nil otherwise.
+func sectionContent(s section.WithSigForward) []*section.Assertion {
+	switch s := s.(type) {
+	case *section.Shard:
+		return s.Content
+	case *section.Zone:
+		return s.Content
+	}
+	return nil
+}
+
+//handleInconsistency removes cached sections according to policy after conflicts have been found
+//to contradict a received section.
+func handleInconsistency(conflicts []section.WithSigForward, policy InconsistencyPolicy,
+	assertionsCache cache.Assertion, negAssertionCache cache.NegativeAssertion) {
+	switch policy {
+	case KeepCached:
+	case EvictConflicting:
+		for _, c := range conflicts {
+			if a, ok := c.(*section.Assertion); ok {
+				assertionsCache.Remove(a)
+			} else {
+				negAssertionCache.Remove(c)
+			}
+		}
+		log.Info("Removed conflicting sections from cache", "sections", conflicts)
+	case EvictZone:
+		zones := make(map[string]bool)
+		for _, c := range conflicts {
+			zones[c.GetSubjectZone()] = true
+		}
+		for zone := range zones {
+			assertionsCache.RemoveZone(zone)
+			negAssertionCache.RemoveZone(zone)
+			log.Info("Removed all sections of zone from cache", "zone", zone)
+		}
+	default:
+		log.Warn("Unknown inconsistency policy", "policy", policy)
+	}
+}
@@ -0,0 +1,250 @@
+package rainsd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/datastructures/bitarray"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+func ipAssertion(name, zone, ip string) *section.Assertion {
+	return &section.Assertion{
+		SubjectName: name,
+		SubjectZone: zone,
+		Context:     ".",
+		Content:     []object.Object{{Type: object.OTIP4Addr, Value: ip}},
+	}
+}
+
+//contentAssertion returns a copy of a without subject zone and context as it is contained in a
+//shard or zone.
+func contentAssertion(a *section.Assertion) *section.Assertion {
+	return &section.Assertion{SubjectName: a.SubjectName, Content: a.Content}
+}
+
+func pshard(from, to string, content ...*section.Assertion) *section.Pshard {
+	p := &section.Pshard{
+		SubjectZone: "ethz.ch.",
+		Context:     ".",
+		RangeFrom:   from,
+		RangeTo:     to,
+		BloomFilter: section.BloomFilter{
+			Algorithm: section.BloomKM12,
+			Hash:      algorithmTypes.Shake256,
+			Filter:    make(bitarray.BitArray, 16),
+		},
+	}
+	for _, a := range content {
+		for _, o := range a.Content {
+			p.BloomFilter.Add(a.SubjectName, p.SubjectZone, p.Context, o.Type)
+		}
+	}
+	return p
+}
+
+func shard(from, to string, content ...*section.Assertion) *section.Shard {
+	return &section.Shard{SubjectZone: "ethz.ch.", Context: ".", RangeFrom: from, RangeTo: to,
+		Content: content}
+}
+
+func TestIsAssertionConsistent(t *testing.T) {
+	a := ipAssertion("b", "ethz.ch.", "192.0.2.1")
+	other := ipAssertion("b", "ethz.ch.", "192.0.2.2")
+	var tests = []struct {
+		name  string
+		sec   section.WithSigForward
+		valid bool
+	}{
+		{"shard containing assertion", shard("<", ">", contentAssertion(a)), true},
+		{"shard missing object", shard("<", ">", contentAssertion(other)), false},
+		{"shard missing assertion", shard("<", ">"), false},
+		{"shard with open begin", shard("<", "c"), false},
+		{"shard with open end", shard("a", ">"), false},
+		{"name before shard range", shard("c", ">"), true},
+		{"name after shard range", shard("<", "a"), true},
+		{"name on shard boundary", shard("b", "c"), true},
+		{"zone containing assertion", &section.Zone{SubjectZone: "ethz.ch.", Context: ".",
+			Content: []*section.Assertion{contentAssertion(other), contentAssertion(a)}}, true},
+		{"zone missing assertion", &section.Zone{SubjectZone: "ethz.ch.", Context: ".",
+			Content: []*section.Assertion{contentAssertion(other)}}, false},
+		{"pshard containing assertion", pshard("<", ">", a), true},
+		{"pshard missing assertion", pshard("<", ">"), false},
+		{"pshard with open begin", pshard("<", "c"), false},
+		{"pshard with open end", pshard("a", ">"), false},
+		{"name before pshard range", pshard("c", ">"), true},
+		{"name after pshard range", pshard("<", "a"), true},
+		{"unsupported section", other, true},
+	}
+	for _, test := range tests {
+		if isAssertionConsistent(a, test.sec) != test.valid {
+			t.Errorf("%s: wrong consistency. expected=%t", test.name, test.valid)
+		}
+	}
+}
+
+func TestAreNegSectionsConsistent(t *testing.T) {
+	a := ipAssertion("b", "ethz.ch.", "192.0.2.1")
+	other := ipAssertion("b", "ethz.ch.", "192.0.2.2")
+	var tests = []struct {
+		name  string
+		s1    section.WithSigForward
+		s2    section.WithSigForward
+		valid bool
+	}{
+		{"equal shards", shard("a", "c", contentAssertion(a)),
+			shard("<", ">", contentAssertion(a)), true},
+		{"disjoint shards", shard("a", "c", contentAssertion(a)), shard("c", ">"), true},
+		{"shard missing assertion", shard("a", "c", contentAssertion(a)), shard("<", "d"), false},
+		{"shards with different objects", shard("a", "c", contentAssertion(a)),
+			shard("a", "c", contentAssertion(other)), false},
+		{"zone missing assertion of shard", shard("a", "c", contentAssertion(a)),
+			&section.Zone{SubjectZone: "ethz.ch.", Context: "."}, false},
+		{"pshard missing assertion of zone", &section.Zone{SubjectZone: "ethz.ch.",
+			Context: ".", Content: []*section.Assertion{contentAssertion(a)}}, pshard("a", ">"), false},
+		{"pshard containing assertion of shard", shard("a", "c", contentAssertion(a)),
+			pshard("<", ">", a), true},
+		{"pshards", pshard("<", ">"), pshard("a", "c", a), true},
+	}
+	for _, test := range tests {
+		if areNegSectionsConsistent(test.s1, test.s2) != test.valid {
+			t.Errorf("%s: wrong consistency. expected=%t", test.name, test.valid)
+		}
+		if areNegSectionsConsistent(test.s2, test.s1) != test.valid {
+			t.Errorf("%s: consistency is not symmetric. expected=%t", test.name, test.valid)
+		}
+	}
+}
+
+func TestInconsistentCachedSections(t *testing.T) {
+	now := time.Now()
+	cachedShard := shard("a", "c", contentAssertion(ipAssertion("b", "ethz.ch.", "192.0.2.1")))
+	cachedShard.SetValidSince(now.Add(-time.Hour).Unix())
+	cachedShard.SetValidUntil(now.Add(time.Hour).Unix())
+	assertions := cache.NewAssertion(10)
+	negAssertions := cache.NewNegAssertion(10)
+	negAssertions.AddShard(cachedShard, cachedShard.ValidUntil(), false)
+	var tests = []struct {
+		name        string
+		validSince  time.Time
+		authorities []ZoneContext
+		superseded  bool
+	}{
+		{"older section", now.Add(-2 * time.Hour), nil, false},
+		{"section of same age", now.Add(-time.Hour), nil, false},
+		{"newer section", now, nil, true},
+		{"older section of authoritative zone", now.Add(-2 * time.Hour),
+			[]ZoneContext{{Zone: "ethz.ch.", Context: "."}}, true},
+		{"older section of other authoritative zone", now.Add(-2 * time.Hour),
+			[]ZoneContext{{Zone: "example.com.", Context: "."}}, false},
+	}
+	for _, test := range tests {
+		a := ipAssertion("b", "ethz.ch.", "192.0.2.2")
+		a.SetValidSince(test.validSince.Unix())
+		a.SetValidUntil(now.Add(2 * time.Hour).Unix())
+		conflicts, superseded := inconsistentCachedSections([]section.WithSigForward{a},
+			test.authorities, assertions, negAssertions)
+		want := []section.WithSigForward{cachedShard}
+		if test.superseded && (len(conflicts) != 0 || !reflect.DeepEqual(superseded, want)) ||
+			!test.superseded && (len(superseded) != 0 || !reflect.DeepEqual(conflicts, want)) {
+			t.Errorf("%s: wrong classification. expected superseded=%t actual conflicts=%v "+
+				"superseded=%v", test.name, test.superseded, conflicts, superseded)
+		}
+	}
+}
+
+func TestHandleInconsistency(t *testing.T) {
+	conflicting := ipAssertion("b", "ethz.ch.", "192.0.2.1")
+	sameZone := ipAssertion("c", "ethz.ch.", "192.0.2.2")
+	otherZone := ipAssertion("b", "example.com.", "192.0.2.3")
+	conflictingShard := shard("a", "c")
+	otherShard := shard("<", ">")
+	otherShard.SubjectZone = "example.com."
+	var tests = []struct {
+		policy        InconsistencyPolicy
+		assertions    []*section.Assertion
+		negAssertions []section.WithSigForward
+	}{
+		{KeepCached, []*section.Assertion{conflicting, sameZone, otherZone},
+			[]section.WithSigForward{conflictingShard, otherShard}},
+		{EvictConflicting, []*section.Assertion{sameZone, otherZone},
+			[]section.WithSigForward{otherShard}},
+		{EvictZone, []*section.Assertion{otherZone}, []section.WithSigForward{otherShard}},
+	}
+	for _, test := range tests {
+		assertions := cache.NewAssertion(100)
+		negAssertions := cache.NewNegAssertion(100)
+		exp := time.Now().Add(time.Hour).Unix()
+		for _, a := range []*section.Assertion{conflicting, sameZone, otherZone} {
+			assertions.Add(a, exp, false)
+		}
+		negAssertions.AddShard(conflictingShard, exp, false)
+		negAssertions.AddShard(otherShard, exp, false)
+		handleInconsistency([]section.WithSigForward{conflicting, conflictingShard}, test.policy,
+			assertions, negAssertions)
+		if assertions.Len() != len(test.assertions) {
+			t.Errorf("%s: wrong number of cached assertions. expected=%d actual=%d", test.policy,
+				len(test.assertions), assertions.Len())
+		}
+		for _, a := range test.assertions {
+			if _, ok := assertions.Get(a.FQDN(), ".", object.OTIP4Addr, true); !ok {
+				t.Errorf("%s: assertion %s was removed", test.policy, a.FQDN())
+			}
+		}
+		if negAssertions.Len() != len(test.negAssertions) {
+			t.Errorf("%s: wrong number of cached shards. expected=%d actual=%d", test.policy,
+				len(test.negAssertions), negAssertions.Len())
+		}
+		for _, s := range test.negAssertions {
+			if _, ok := negAssertions.Get(s.GetSubjectZone(), ".", section.TotalInterval{}); !ok {
+				t.Errorf("%s: shard of %s was removed", test.policy, s.GetSubjectZone())
+			}
+		}
+	}
+}
+
+func TestDropNamesetViolations(t *testing.T) {
+	nameset := func(expr string, validUntil time.Time) *section.Assertion {
+		a := &section.Assertion{SubjectName: "@", SubjectZone: "ethz.ch.", Context: ".",
+			Content: []object.Object{{Type: object.OTNameset, Value: object.NamesetExpr(expr)}}}
+		a.SetValidUntil(validUntil.Unix())
+		return a
+	}
+	now := time.Now()
+	lower := ipAssertion("www", "ethz.ch.", "192.0.2.1")
+	upper := ipAssertion("WWW", "ethz.ch.", "192.0.2.1")
+	var tests = []struct {
+		name     string
+		cached   *section.Assertion
+		sections []section.WithSigForward
+		expected []section.WithSigForward
+	}{
+		{"no nameset", nil, []section.WithSigForward{lower, upper},
+			[]section.WithSigForward{lower, upper}},
+		{"cached nameset", nameset("[a-z]+", now.Add(time.Hour)),
+			[]section.WithSigForward{lower, upper}, []section.WithSigForward{lower}},
+		{"published nameset replaces cached one", nameset("[a-z]+", now.Add(time.Hour)),
+			[]section.WithSigForward{nameset("[A-Z]+", now.Add(time.Hour)), upper},
+			[]section.WithSigForward{nameset("[A-Z]+", now.Add(time.Hour)), upper}},
+		{"expired cached nameset", nameset("[a-z]+", now.Add(-time.Minute)),
+			[]section.WithSigForward{lower, upper}, []section.WithSigForward{lower, upper}},
+	}
+	for _, test := range tests {
+		s := &Server{
+			config: Config{StaleGracePeriod: time.Hour},
+			caches: &Caches{AssertionsCache: cache.NewAssertion(10)},
+		}
+		if test.cached != nil {
+			s.caches.AssertionsCache.Add(test.cached,
+				cacheExpiration(test.cached, s.config.StaleGracePeriod), false)
+		}
+		if result := s.dropNamesetViolations(test.sections); !reflect.DeepEqual(result,
+			test.expected) {
+			t.Errorf("%s: wrong sections. expected=%v actual=%v", test.name, test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package rainsd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+)
+
+//listenControl opens the server's control socket and accepts connections on it in a new go
+//routine. Over it, an external service can change the server's state at runtime. The socket is only
+//accessible by the user running the server. Each line received is executed as a command and
+//answered with a single line starting with OK or ERROR.
+func (s *Server) listenControl() {
+	path := s.config.ControlSocket
+	if path == "" {
+		return
+	}
+	//Remove a stale socket file of a previous run, but never any other file.
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			log.Error("Control socket path exists and is not a socket", "path", path)
+			return
+		}
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		log.Error("Was not able to listen on control socket", "path", path, "error", err)
+		return
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		log.Error("Was not able to restrict access to control socket", "path", path, "error", err)
+		listener.Close()
+		return
+	}
+	s.listenerMutex.Lock()
+	s.controlListener = listener
+	s.listenerMutex.Unlock()
+	log.Info("Started control socket listener", "path", path)
+	go s.serveControl(listener)
+}
+
+//serveControl handles all connections accepted by listener until it is closed.
+func (s *Server) serveControl(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Info("Control socket listener stopped", "reason", err)
+			return
+		}
+		go s.handleControlConnection(conn)
+	}
+}
+
+//handleControlConnection executes all commands received on conn.
+func (s *Server) handleControlConnection(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		response := s.executeControlCommand(strings.Fields(scanner.Text()))
+		if _, err := fmt.Fprintln(conn, response); err != nil {
+			log.Warn("Was not able to write control response", "error", err)
+			return
+		}
+	}
+}
+
+//executeControlCommand executes the command cmd and returns the response to be sent back.
+//Supported commands are:
+//blacklist
+//blacklist (add|remove) zone <zone>
+//blacklist (add|remove) peer <address>
+//connections
+//reload
+func (s *Server) executeControlCommand(cmd []string) string {
+	if len(cmd) == 0 {
+		return "ERROR: empty command"
+	}
+	log.Info("Received control command", "command", strings.Join(cmd, " "))
+	switch cmd[0] {
+	case "blacklist":
+		return blacklistCommand(s.blacklist, cmd[1:])
+	case "connections":
+		return connectionsCommand(s)
+	case "reload":
+		return reloadCommand(s)
+	default:
+		return fmt.Sprintf("ERROR: unknown command %s", cmd[0])
+	}
+}
+
+//connectionsCommand returns the remote address, the time when a message was last received and the
+//number of missed heartbeats of all cached connections, sorted by address.
+func connectionsCommand(s *Server) string {
+	infos := s.caches.ConnCache.Info()
+	entries := make([]string, len(infos))
+	for i, info := range infos {
+		entries[i] = fmt.Sprintf("%s,lastSeen=%s,missed=%d", info.RemoteAddr,
+			info.LastSeen.Format(time.RFC3339), info.MissedHeartbeats)
+	}
+	sort.Strings(entries)
+	return strings.TrimSpace("OK " + strings.Join(entries, " "))
+}
+
+//reloadCommand reloads the server's configuration and reports the changed settings which require a
+//restart.
+func reloadCommand(s *Server) string {
+	restart, err := s.ReloadConfig()
+	if err != nil {
+		return fmt.Sprintf("ERROR: %v", err)
+	}
+	if len(restart) > 0 {
+		return "OK restart required for: " + strings.Join(restart, " ")
+	}
+	return "OK"
+}
+
+//blacklistCommand executes a blacklist command with arguments args on b.
+func blacklistCommand(b *blacklist, args []string) string {
+	if len(args) == 0 {
+		return "OK " + b.String()
+	}
+	if len(args) != 3 || (args[0] != "add" && args[0] != "remove") {
+		return "ERROR: usage: blacklist [(add|remove) (zone|peer) <value>]"
+	}
+	var err error
+	switch args[1] {
+	case "zone":
+		if args[0] == "add" {
+			b.AddZone(args[2])
+		} else {
+			b.RemoveZone(args[2])
+		}
+	case "peer":
+		if args[0] == "add" {
+			err = b.AddPeer(args[2])
+		} else {
+			err = b.RemovePeer(args[2])
+		}
+	default:
+		return fmt.Sprintf("ERROR: unknown blacklist type %s", args[1])
+	}
+	if err != nil {
+		return fmt.Sprintf("ERROR: %v", err)
+	}
+	return "OK"
+}
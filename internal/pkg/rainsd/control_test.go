@@ -0,0 +1,98 @@
+package rainsd
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteControlCommand(t *testing.T) {
+	b, err := newBlacklist(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{blacklist: b}
+	var tests = []struct {
+		cmd      string
+		response string
+	}{
+		{"", "ERROR: empty command"},
+		{"unknown", "ERROR: unknown command unknown"},
+		{"blacklist", "OK zones=[] peers=[] zoneDrops=0 peerDrops=0"},
+		{"blacklist add zone evil.ch.", "OK"},
+		{"blacklist add peer 192.0.2.1:5022", "OK"},
+		{"blacklist add peer 1-ff00:0:110,[192.0.2.2]", "OK"},
+		{"blacklist", "OK zones=[evil.ch.] peers=[1-ff00:0:110,[192.0.2.2] 192.0.2.1] " +
+			"zoneDrops=0 peerDrops=0"},
+		{"blacklist remove zone evil.ch.", "OK"},
+		{"blacklist remove peer 192.0.2.1", "OK"},
+		{"blacklist", "OK zones=[] peers=[1-ff00:0:110,[192.0.2.2]] zoneDrops=0 peerDrops=0"},
+		{"blacklist add peer ethz.ch.", "ERROR: ethz.ch. is neither an IP nor a SCION address"},
+		{"blacklist add address 192.0.2.1", "ERROR: unknown blacklist type address"},
+		{"blacklist add zone", "ERROR: usage: blacklist [(add|remove) (zone|peer) <value>]"},
+		{"blacklist drop zone evil.ch.",
+			"ERROR: usage: blacklist [(add|remove) (zone|peer) <value>]"},
+	}
+	for _, test := range tests {
+		if response := s.executeControlCommand(strings.Fields(test.cmd)); response != test.response {
+			t.Errorf("%q: wrong response. expected=%s actual=%s", test.cmd, test.response, response)
+		}
+	}
+}
+
+func TestListenControl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b, err := newBlacklist(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{blacklist: b, config: Config{ControlSocket: file}}
+	s.listenControl()
+	if s.controlListener != nil {
+		s.controlListener.Close()
+		t.Error("control socket replaced a file which is not a socket")
+	}
+	if data, err := ioutil.ReadFile(file); err != nil || string(data) != "data" {
+		t.Errorf("file was modified. data=%s error=%v", data, err)
+	}
+
+	//A stale socket of a previous run is replaced.
+	s.config.ControlSocket = filepath.Join(dir, "rainsd.sock")
+	for i := 0; i < 2; i++ {
+		s.listenControl()
+		if s.controlListener == nil {
+			t.Fatalf("%d: control socket was not opened", i)
+		}
+		info, err := os.Stat(s.config.ControlSocket)
+		if err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("%d: wrong permissions of control socket. info=%v error=%v", i, info, err)
+		}
+		conn, err := net.Dial("unix", s.config.ControlSocket)
+		if err != nil {
+			t.Fatalf("%d: was not able to connect to control socket: %v", i, err)
+		}
+		conn.SetDeadline(time.Now().Add(time.Second))
+		conn.Write([]byte("blacklist\n"))
+		response, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil || !strings.HasPrefix(response, "OK") {
+			t.Errorf("%d: wrong response. response=%q error=%v", i, response, err)
+		}
+		conn.Close()
+		//Closing a unix listener removes its socket file. Simulate a crashed server instead.
+		s.controlListener.(*net.UnixListener).SetUnlinkOnClose(false)
+		s.controlListener.Close()
+	}
+}
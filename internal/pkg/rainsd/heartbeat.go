@@ -0,0 +1,57 @@
+package rainsd
+
+import (
+	"bytes"
+	"sync/atomic"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/cbor"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+)
+
+//initHeartbeats starts a go routine which sends heartbeats on idle connections if a heartbeat
+//interval is configured.
+func initHeartbeats(s *Server) {
+	if s.config.HeartbeatInterval <= 0 {
+		return
+	}
+	go repeatFuncCaller(s.sendHeartbeats,
+		func() time.Duration { return s.Config().HeartbeatInterval }, s.shutdown)
+}
+
+//sendHeartbeats sends a NTHeartbeat notification over each cached connection on which nothing has
+//been received during the heartbeat interval. Connections on which MaxMissedHeartbeats have been
+//sent without receiving anything are closed and removed from the connection cache.
+func (s *Server) sendHeartbeats() {
+	config := s.Config()
+	probe, evicted := s.caches.ConnCache.Probe(config.HeartbeatInterval, config.MaxMissedHeartbeats)
+	for _, conn := range evicted {
+		log.Info("Closed connection after missed heartbeats", "conn", conn.RemoteAddr(),
+			"missed", config.MaxMissedHeartbeats)
+	}
+	atomic.AddUint64(&s.metrics.heartbeatEvictions, uint64(len(evicted)))
+	for _, conn := range probe {
+		//The notification carries the token of its own message which marks it as a request.
+		tok := token.New()
+		msg := message.Message{
+			Token:        tok,
+			Capabilities: []message.Capability{message.Capability(s.capabilityHash)},
+			Content:      []section.Section{&section.Notification{Type: section.NTHeartbeat, Token: tok}},
+		}
+		s.signMessage(&msg)
+		encoding := new(bytes.Buffer)
+		if err := cbor.NewWriter(encoding).Marshal(&msg); err != nil {
+			log.Warn("Was not able to encode heartbeat", "error", err)
+			continue
+		}
+		if _, err := conn.Write(encoding.Bytes()); err != nil {
+			log.Info("Was not able to send heartbeat", "conn", conn.RemoteAddr(), "error", err)
+			s.caches.ConnCache.CloseAndRemoveConnection(conn)
+			continue
+		}
+		s.metrics.notificationSent(section.NTHeartbeat)
+	}
+}
@@ -0,0 +1,64 @@
+package rainsd
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/cbor"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+func TestSendHeartbeats(t *testing.T) {
+	s := &Server{
+		config:  Config{MaxMissedHeartbeats: 2},
+		caches:  &Caches{ConnCache: cache.NewConnection(10)},
+		metrics: newMetrics(),
+	}
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	s.caches.ConnCache.AddConnection(conn)
+	received := make(chan message.Message, 10)
+	go func() {
+		defer close(received)
+		reader := cbor.NewReader(peer)
+		for {
+			var msg message.Message
+			if err := reader.Unmarshal(&msg); err != nil {
+				return
+			}
+			received <- msg
+		}
+	}()
+	//A heartbeat is sent in each interval until MaxMissedHeartbeats have remained unanswered.
+	for i := 1; i <= s.config.MaxMissedHeartbeats; i++ {
+		s.sendHeartbeats()
+		select {
+		case msg := <-received:
+			if n, ok := msg.Content[0].(*section.Notification); len(msg.Content) != 1 || !ok ||
+				n.Type != section.NTHeartbeat || n.Token != msg.Token {
+				t.Errorf("%d: wrong heartbeat. actual=%v", i, msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%d: no heartbeat received", i)
+		}
+	}
+	s.sendHeartbeats()
+	if s.caches.ConnCache.Len() != 0 {
+		t.Error("connection which missed all heartbeats was not evicted")
+	}
+	if evictions := atomic.LoadUint64(&s.metrics.heartbeatEvictions); evictions != 1 {
+		t.Errorf("wrong number of evictions. expected=1 actual=%d", evictions)
+	}
+	select {
+	case msg, ok := <-received:
+		if ok {
+			t.Errorf("heartbeat sent on evicted connection: %v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Error("evicted connection was not closed")
+	}
+}
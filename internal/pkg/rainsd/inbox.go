@@ -3,10 +3,10 @@ package rainsd
 import (
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	log "github.com/inconshreveable/log15"
-	"github.com/netsec-ethz/rains/internal/pkg/cache"
 	"github.com/netsec-ethz/rains/internal/pkg/message"
 	"github.com/netsec-ethz/rains/internal/pkg/query"
 	"github.com/netsec-ethz/rains/internal/pkg/section"
@@ -31,88 +31,106 @@ type InputQueues struct {
 
 //deliver pushes all incoming messages to the prio or normal channel.
 //A message is added to the priority channel if it is the response to a non-expired delegation query
-func deliver(msg *message.Message, sender net.Addr, prioChannel chan util.MsgSectionSender,
-	normalChannel chan util.MsgSectionSender, notificationChannel chan util.MsgSectionSender,
-	pendingKeys cache.PendingKey) {
+//Messages from blacklisted peers, messages exceeding the sender's rate limit, and messages which are
+//not authentic are dropped. Messages waiting for the infrastructure key of their sender are
+//dispatched once the key has arrived.
+func (s *Server) deliver(msg *message.Message, sender net.Addr) {
+	if s.blacklist.IsPeerBlacklisted(sender) {
+		return
+	}
+	if s.isThrottled(msg, sender) {
+		return
+	}
+	if !s.isMessageAuthentic(msg, sender) {
+		return
+	}
+	s.dispatch(msg, sender)
+}
 
-	//TODO Check message signatures here once they are implemented
+//dispatch pushes the sections of msg to the queue on which they are processed. Sections of
+//blacklisted zones are dropped. During a graceful shutdown queries are answered with
+//NTUnspecServerErr.
+func (s *Server) dispatch(msg *message.Message, sender net.Addr) {
 
-	processCapability(msg.Capabilities, sender, msg.Token)
+	s.processCapability(msg.Capabilities, sender, msg.Token)
 
 	//handle notification separately. Assertions and Queries are processed together respectively.
 	queries := []section.Section{}
 	sections := []section.Section{}
 	for _, m := range msg.Content {
 		switch m := m.(type) {
-		case *section.Assertion, *section.Shard, *section.Pshard, *section.Zone:
-			if !isZoneBlacklisted(m.(section.WithSig).GetSubjectZone()) {
+		case *section.Assertion, *section.Shard, *section.Pshard, *section.Zone,
+			*section.AddressAssertion, *section.AddressZone:
+			if !s.blacklist.IsZoneBlacklisted(m.(section.WithSig).GetSubjectZone()) {
 				sections = append(sections, m)
 			}
-		case *query.Name:
+		case *query.Name, *query.Address:
 			log.Debug(fmt.Sprintf("add %T to normal queue", m))
 			queries = append(queries, m)
 		case *section.Notification:
 			log.Debug("Add notification to notification queue", "token", msg.Token)
-			notificationChannel <- util.MsgSectionSender{
+			s.enqueue(s.queues.Notify, util.MsgSectionSender{
 				Sender:   sender,
 				Sections: []section.Section{m},
 				Token:    msg.Token,
-			}
+			})
 		default:
 			log.Warn(fmt.Sprintf("unsupported message section type %T", m))
 			return
 		}
 	}
-	if len(queries) > 0 {
-		normalChannel <- util.MsgSectionSender{Sender: sender, Sections: queries, Token: msg.Token}
+	if len(queries) > 0 && s.isDraining() {
+		log.Debug("Reject queries during graceful shutdown", "token", msg.Token)
+		sendNotificationMsg(msg.Token, sender, section.NTUnspecServerErr, "", s)
+	} else if len(queries) > 0 {
+		s.pushNormal(util.MsgSectionSender{Sender: sender, Sections: queries, Token: msg.Token})
 	}
 	if len(sections) > 0 {
 		mss := util.MsgSectionSender{Sender: sender, Sections: sections, Token: msg.Token}
-		if pendingKeys.ContainsToken(msg.Token) {
+		if s.caches.PendingKeys.ContainsToken(msg.Token) ||
+			s.pendingInfraKeys.containsToken(msg.Token) {
 			log.Debug("add section with signature to priority queue", "token", msg.Token)
-			prioChannel <- mss
+			s.enqueue(s.queues.Prio, mss)
 		} else {
 			log.Debug("add section with signature to normal queue", "token", msg.Token)
-			normalChannel <- mss
+			s.pushNormal(mss)
 		}
 	}
 }
 
 //processCapability processes capabilities and sends a notification back to the sender if the hash
-//is not understood.
-func processCapability(caps []message.Capability, sender net.Addr, token token.Token) {
-	log.Debug("Processing Capabilities not yet supported")
-	/*log.Debug("Process capabilities", "capabilities", caps)
-	if len(caps) > 0 {
-		isHash := !strings.HasPrefix(string(caps[0]), "urn:")
-		if isHash {
-			if caps, ok := capabilities.Get([]byte(caps[0])); ok {
-				addCapabilityAndRespond(sender, caps)
-			} else { //capability hash not understood
-				sendNotificationMsg(token, sender, section.NTCapHashNotKnown, capabilityHash)
-			}
+//is not understood. A known capability list is stored with the sender's entry in the connection
+//cache.
+func (s *Server) processCapability(caps []message.Capability, sender net.Addr, token token.Token) {
+	if len(caps) == 0 {
+		return
+	}
+	log.Debug("Process capabilities", "capabilities", caps)
+	if capabilityIsHash(string(caps[0])) {
+		if list, ok := s.caches.Capabilities.Get([]byte(caps[0])); ok {
+			s.caches.ConnCache.AddCapabilityList(sender, list)
 		} else {
-			addCapabilityAndRespond(sender, caps)
+			log.Debug("Capability hash not known", "hash", caps[0], "sender", sender)
+			sendNotificationMsg(token, sender, section.NTCapHashNotKnown, s.capabilityList, s)
 		}
-	}*/
-}
-
-//addCapabilityAndRespond adds caps to the connection cache entry of sender and sends its own
-//capabilities back if it has not already received capability information on this connection.
-func addCapabilityAndRespond(sender net.Addr, caps []message.Capability) {
-	/*if !connCache.AddCapabilityList(sender, caps) {
-		sendCapability(sender, []message.Capability{message.Capability(capabilityHash)})
-	}*/
+	} else {
+		s.caches.Capabilities.Add(caps)
+		s.caches.ConnCache.AddCapabilityList(sender, caps)
+	}
 }
 
-//isZoneBlacklisted returns true if zone is blacklisted
-func isZoneBlacklisted(zone string) bool {
-	return false
+//addCapabilityAndRespond adds caps to the capability cache and to the connection cache entry of
+//sender. It then sends this server's full capability list back to sender.
+func (s *Server) addCapabilityAndRespond(sender net.Addr, caps []message.Capability) {
+	s.caches.Capabilities.Add(caps)
+	s.caches.ConnCache.AddCapabilityList(sender, caps)
+	sendCapability(sender, transportCapabilities(s.config), s)
 }
 
 //workBoth works on the prioChannel and on the normalChannel. A worker only fetches a message from
-//the normalChannel if the prioChannel is empty. the channel normalWorkers enforces a maximum number
-//of go routines working on the prioChannel and normalChannel.
+//the normalChannel if the prioChannel is empty. If fair queuing is enabled, the normal messages are
+//taken from the fair queue instead. the channel normalWorkers enforces a maximum number of go
+//routines working on the prioChannel and normalChannel.
 func (s *Server) workBoth() {
 	for {
 		select {
@@ -134,6 +152,10 @@ func (s *Server) workBoth() {
 		default:
 			//do nothing
 		}
+		if msg, ok := s.fairQueue.pop(); ok {
+			go normalWorkerHandler(s, msg)
+			continue
+		}
 		select {
 		case msg := <-s.queues.Normal:
 			go normalWorkerHandler(s, msg)
@@ -144,10 +166,17 @@ func (s *Server) workBoth() {
 	}
 }
 
+//enqueue adds mss to queue. mss is counted as in flight until a worker has handled it.
+func (s *Server) enqueue(queue chan util.MsgSectionSender, mss util.MsgSectionSender) {
+	atomic.AddInt64(&s.inFlight, 1)
+	queue <- mss
+}
+
 //normalWorkerHandler handles sections on the normalChannel
 func normalWorkerHandler(s *Server, msg util.MsgSectionSender) {
 	if msg.Sections != nil {
 		s.verify(msg)
+		atomic.AddInt64(&s.inFlight, -1)
 	}
 	<-s.queues.NormalW
 }
@@ -155,11 +184,11 @@ func normalWorkerHandler(s *Server, msg util.MsgSectionSender) {
 //workPrio works on the prioChannel. It waits on the prioChannel and creates a new go routine which handles the section.
 //the channel prioWorkers enforces a maximum number of go routines working on the prioChannel.
 //The prio channel is necessary to avoid a blocking of the server. e.g. in the following unrealistic scenario
-//1) normal queue fills up with non delegation queries which all are missing a public key
-//2) The non-delegation queries get processed by the normalWorkers and added to the pendingSignature cache
-//3) For each non-delegation query that gets taken off the queue a new non-delegation query or expired
-//   delegation query wins against all waiting valid delegation-queries.
-//4) Then although the server is working all the time, no section is added to the caches.
+//  1. normal queue fills up with non delegation queries which all are missing a public key
+//  2. The non-delegation queries get processed by the normalWorkers and added to the pendingSignature cache
+//  3. For each non-delegation query that gets taken off the queue a new non-delegation query or expired
+//     delegation query wins against all waiting valid delegation-queries.
+//  4. Then although the server is working all the time, no section is added to the caches.
 func (s *Server) workPrio() {
 	for {
 		select {
@@ -182,9 +211,12 @@ func (s *Server) workPrio() {
 func prioWorkerHandler(s *Server, msg util.MsgSectionSender, prioWorker bool) {
 	if msg.Sections != nil {
 		s.verify(msg)
+		atomic.AddInt64(&s.inFlight, -1)
 	}
 	if prioWorker {
 		<-s.queues.PrioW
+	} else {
+		<-s.queues.NormalW
 	}
 }
 
@@ -213,6 +245,7 @@ func (s *Server) workNotification() {
 func handleNotification(s *Server, msg util.MsgSectionSender) {
 	if msg.Sections != nil {
 		s.notify(msg)
+		atomic.AddInt64(&s.inFlight, -1)
 	}
-	<-s.queues.NormalW
+	<-s.queues.NotifyW
 }
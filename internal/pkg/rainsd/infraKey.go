@@ -0,0 +1,222 @@
+package rainsd
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/query"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/siglib"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+)
+
+const (
+	//messageSigValidity is the duration for which signatures on outgoing messages are valid.
+	messageSigValidity = time.Minute
+	//maxPendingInfraKeyMsgs is the maximum number of messages held back per infrastructure key
+	//lookup.
+	maxPendingInfraKeyMsgs = 100
+)
+
+//signMessage signs msg with the server's infrastructure keys. msg is not signed if no
+//infrastructure keys are configured.
+func (s *Server) signMessage(msg *message.Message) {
+	if len(s.infraPrivateKeys) == 0 {
+		return
+	}
+	if err := siglib.SignMessage(msg, s.infraPrivateKeys, messageSigValidity); err != nil {
+		log.Warn("Was not able to sign message", "token", msg.Token, "error", err)
+	}
+}
+
+//isMessageAuthentic returns true if msg should be processed. Signatures can only be verified on
+//messages from peers with a configured infrastructure key name. A message from such a peer is
+//rejected if its signatures are invalid or, if the server is configured to do so, if it is
+//unsigned. A signed message is held back while the peer's infrastructure key is looked up. Answers
+//to such a lookup are accepted as their sections are verified like all other sections. Messages
+//from all other senders are accepted.
+func (s *Server) isMessageAuthentic(msg *message.Message, sender net.Addr) bool {
+	name, ok := s.infraKeyNames[peerKey(sender)]
+	if !ok || s.pendingInfraKeys.containsToken(msg.Token) {
+		return true
+	}
+	if len(msg.Signatures) == 0 {
+		if s.config.RejectUnsignedMessages {
+			log.Warn("Dropped unsigned message from peer", "sender", sender, "token", msg.Token)
+			return false
+		}
+		return true
+	}
+	pkeys := s.infraKeys(name)
+	if len(pkeys) == 0 {
+		s.awaitInfraKey(name, msg, sender)
+		return false
+	}
+	return isSignedBy(msg, sender, name, pkeys)
+}
+
+//isSignedBy returns true if the signatures on msg are valid under the infrastructure keys pkeys
+//of name.
+func isSignedBy(msg *message.Message, sender net.Addr, name string,
+	pkeys map[keys.PublicKeyID][]keys.PublicKey) bool {
+	if !siglib.CheckMessageSignatures(msg, pkeys) {
+		log.Warn("Dropped message with invalid signature", "sender", sender, "infraKeyName", name,
+			"token", msg.Token)
+		return false
+	}
+	return true
+}
+
+//infraKeys returns the infrastructure keys of name which are in the assertion cache. Only verified
+//assertions are cached.
+func (s *Server) infraKeys(name string) map[keys.PublicKeyID][]keys.PublicKey {
+	assertions, _ := s.caches.AssertionsCache.Get(name, ".", object.OTInfraKey, true)
+	pkeys := make(map[keys.PublicKeyID][]keys.PublicKey)
+	for _, a := range assertions {
+		if a.ValidUntil() < time.Now().Unix() {
+			continue
+		}
+		for _, o := range a.Content {
+			if pkey, ok := o.Value.(keys.PublicKey); ok && o.Type == object.OTInfraKey {
+				pkey.ValidSince = a.ValidSince()
+				pkey.ValidUntil = a.ValidUntil()
+				pkeys[pkey.PublicKeyID] = append(pkeys[pkey.PublicKeyID], pkey)
+			}
+		}
+	}
+	return pkeys
+}
+
+//awaitInfraKey holds back msg until the infrastructure key of name is cached. If no lookup of name
+//is outstanding, a query for it is sent to the recursive resolver or, if there is none, to sender.
+//The answer is verified and cached as any other section before the held back messages are
+//processed. They are dropped if no answer arrives within the query validity.
+func (s *Server) awaitInfraKey(name string, msg *message.Message, sender net.Addr) {
+	t, isFirst := s.pendingInfraKeys.add(name, msg, sender)
+	if !isFirst {
+		return
+	}
+	q := message.Message{Token: t, Content: []section.Section{&query.Name{
+		Name:        name,
+		Context:     ".",
+		Types:       []object.Type{object.OTInfraKey},
+		Expiration:  time.Now().Add(s.config.QueryValidity).Unix(),
+		CurrentTime: time.Now().Unix(),
+	}}}
+	log.Info("Look up infrastructure key", "name", name, "token", t)
+	if s.resolver != nil {
+		s.sendToRecursiveResolver(q)
+	} else {
+		go s.sendTo(q, sender, 0, 0)
+	}
+	time.AfterFunc(s.config.QueryValidity, func() {
+		if _, msgs, ok := s.pendingInfraKeys.getAndRemove(t); ok {
+			log.Warn("Dropped messages as infrastructure key lookup remained unanswered",
+				"name", name, "#messages", len(msgs))
+		}
+	})
+}
+
+//infraKeyCallback processes the messages held back for the infrastructure key queried with the
+//token of ss as soon as the key is cached. Messages with invalid signatures are dropped.
+func (s *Server) infraKeyCallback(ss util.SectionWithSigSender) {
+	name, ok := s.pendingInfraKeys.name(ss.Token)
+	if !ok {
+		return
+	}
+	pkeys := s.infraKeys(name)
+	if len(pkeys) == 0 {
+		return //the key might still arrive in another answer with the same token
+	}
+	_, msgs, ok := s.pendingInfraKeys.getAndRemove(ss.Token)
+	if !ok {
+		return
+	}
+	for _, m := range msgs {
+		if isSignedBy(m.msg, m.sender, name, pkeys) {
+			s.dispatch(m.msg, m.sender)
+		}
+	}
+}
+
+//pendingInfraKeyMsg is a message waiting for the infrastructure key of its sender.
+type pendingInfraKeyMsg struct {
+	msg    *message.Message
+	sender net.Addr
+}
+
+//pendingInfraKeys holds back messages of peers whose infrastructure key is being looked up.
+type pendingInfraKeys struct {
+	//tokens maps the token of an outstanding infrastructure key query to the queried name.
+	tokens map[token.Token]string
+	//msgs maps an infrastructure key name to the messages waiting for it.
+	msgs map[string][]pendingInfraKeyMsg
+	//maxMsgs is the maximum number of messages waiting for the same key.
+	maxMsgs int
+	mutex   sync.Mutex
+}
+
+func newPendingInfraKeys(maxMsgs int) *pendingInfraKeys {
+	return &pendingInfraKeys{
+		tokens:  make(map[token.Token]string),
+		msgs:    make(map[string][]pendingInfraKeyMsg),
+		maxMsgs: maxMsgs,
+	}
+}
+
+//add adds msg to the messages waiting for the infrastructure key of name. It returns a new token
+//and true if there is no outstanding lookup for name yet. msg is dropped if maxMsgs messages are
+//already waiting for the key.
+func (p *pendingInfraKeys) add(name string, msg *message.Message, sender net.Addr) (token.Token,
+	bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.msgs[name]) >= p.maxMsgs {
+		log.Warn("Dropped message as too many messages wait for infrastructure key", "name", name,
+			"sender", sender, "token", msg.Token)
+		return token.Token{}, false
+	}
+	_, pending := p.msgs[name]
+	p.msgs[name] = append(p.msgs[name], pendingInfraKeyMsg{msg: msg, sender: sender})
+	if pending {
+		return token.Token{}, false
+	}
+	t := token.New()
+	p.tokens[t] = name
+	return t, true
+}
+
+//containsToken returns true if t is the token of an outstanding infrastructure key query.
+func (p *pendingInfraKeys) containsToken(t token.Token) bool {
+	_, ok := p.name(t)
+	return ok
+}
+
+//name returns the name queried with token t. It returns false if t is not outstanding.
+func (p *pendingInfraKeys) name(t token.Token) (string, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	name, ok := p.tokens[t]
+	return name, ok
+}
+
+//getAndRemove returns the name queried with token t and the messages waiting for it and removes
+//them. It returns false if t is not outstanding.
+func (p *pendingInfraKeys) getAndRemove(t token.Token) (string, []pendingInfraKeyMsg, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	name, ok := p.tokens[t]
+	if !ok {
+		return "", nil, false
+	}
+	msgs := p.msgs[name]
+	delete(p.tokens, t)
+	delete(p.msgs, name)
+	return name, msgs, true
+}
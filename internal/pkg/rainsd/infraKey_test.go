@@ -0,0 +1,49 @@
+package rainsd
+
+import (
+	"net"
+	"testing"
+
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+)
+
+func TestPendingInfraKeys(t *testing.T) {
+	p := newPendingInfraKeys(2)
+	sender := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5022}
+	msgs := []*message.Message{
+		{Token: token.New()},
+		{Token: token.New()},
+		{Token: token.New()},
+	}
+	tok, isFirst := p.add("peer.example.", msgs[0], sender)
+	if !isFirst || !p.containsToken(tok) {
+		t.Fatal("first message did not start a lookup")
+	}
+	if _, isFirst := p.add("peer.example.", msgs[1], sender); isFirst {
+		t.Error("second message started another lookup")
+	}
+	if _, isFirst := p.add("peer.example.", msgs[2], sender); isFirst {
+		t.Error("message exceeding the limit started a lookup")
+	}
+	if p.containsToken(msgs[0].Token) {
+		t.Error("token of a held back message is pending")
+	}
+	if name, ok := p.name(tok); !ok || name != "peer.example." {
+		t.Errorf("wrong name for token. expected=peer.example. actual=%s", name)
+	}
+	name, held, ok := p.getAndRemove(tok)
+	if !ok || name != "peer.example." || len(held) != 2 || held[0].msg != msgs[0] ||
+		held[1].msg != msgs[1] {
+		t.Errorf("wrong messages returned. name=%s held=%v", name, held)
+	}
+	if p.containsToken(tok) {
+		t.Error("token is still pending after removal")
+	}
+	if _, _, ok := p.getAndRemove(tok); ok {
+		t.Error("removed token returned messages")
+	}
+	if tok2, isFirst := p.add("peer.example.", msgs[2], sender); !isFirst || tok2 == tok {
+		t.Error("message after removal did not start a new lookup")
+	}
+}
@@ -0,0 +1,120 @@
+package rainsd
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/cbor"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+)
+
+//rejectTooLarge sends a NTMsgTooLarge notification to sender. Its data contains this server's
+//maximum message size such that sender can split its messages accordingly. The rejected message is
+//not decoded, thus the notification carries the zero token.
+func (s *Server) rejectTooLarge(sender net.Addr) {
+	log.Warn("Rejected message exceeding the maximum message size", "sender", sender,
+		"maxMessageSize", s.config.MaxMessageSize)
+	sendNotificationMsg(token.Token{}, sender, section.NTMsgTooLarge,
+		strconv.Itoa(s.config.MaxMessageSize), s)
+}
+
+const (
+	//minPeerMessageLimit is the smallest maximum message size of a peer this server respects. A
+	//smaller announced limit is raised to it such that a peer cannot make this server split its
+	//messages into a large number of tiny messages.
+	minPeerMessageLimit = 1024
+	//maxPeerMessageLimits is the maximum number of peers whose announced message size is stored.
+	//The least recently used limit is removed when it is exceeded.
+	maxPeerMessageLimits = 10000
+	//peerMessageLimitValidity is the duration after which an announced message size is forgotten.
+	peerMessageLimitValidity = time.Hour
+	//messageSigOverhead is the number of bytes reserved per infrastructure key for the signature
+	//added to a message after it has been split.
+	messageSigOverhead = 128
+)
+
+//peerMessageLimit is the maximum message size announced by a peer.
+type peerMessageLimit struct {
+	limit      int
+	expiration time.Time
+}
+
+//setPeerMessageLimit stores the maximum message size announced by peer in a NTMsgTooLarge
+//notification for peerMessageLimitValidity. The limit is only stored if the notification was
+//received on a connection as the sender of a datagram can be spoofed. As the peer closes the
+//connection after rejecting a message, the limit is stored per peer and not per connection.
+func (s *Server) setPeerMessageLimit(peer net.Addr, data string) {
+	limit, err := strconv.Atoi(data)
+	if err != nil || limit <= 0 {
+		return
+	}
+	if isDatagramAddr(peer) {
+		log.Debug("Ignored maximum message size announced in a datagram", "peer", peer)
+		return
+	}
+	if limit < minPeerMessageLimit {
+		log.Warn("Peer announced a too small maximum message size", "peer", peer, "limit", limit,
+			"minimum", minPeerMessageLimit)
+		limit = minPeerMessageLimit
+	}
+	key := peerKey(peer)
+	s.peerMessageLimits.Remove(key)
+	s.peerMessageLimits.GetOrAdd(key, peerMessageLimit{limit: limit,
+		expiration: time.Now().Add(peerMessageLimitValidity)}, false)
+	if s.peerMessageLimits.Len() > maxPeerMessageLimits {
+		key, _ := s.peerMessageLimits.GetLeastRecentlyUsed()
+		s.peerMessageLimits.Remove(key)
+	}
+}
+
+//messageLimit returns the maximum size of a message sent to receiver or 0 if it is unlimited. It is
+//the non expired limit announced by receiver or otherwise this server's own limit.
+func (s *Server) messageLimit(receiver net.Addr) int {
+	key := peerKey(receiver)
+	if v, ok := s.peerMessageLimits.Get(key); ok {
+		if l := v.(peerMessageLimit); time.Now().Before(l.expiration) {
+			return l.limit
+		}
+		s.peerMessageLimits.Remove(key)
+	}
+	return s.config.MaxMessageSize
+}
+
+//splitMessage returns msg's content split into two messages with the same token and true if the
+//encoding of msg exceeds the message size limit of receiver. The returned messages are not signed.
+//Room for the signatures added afterwards is reserved in the limit. It returns false if msg does
+//not have to be split or if it cannot be split because it contains a single section. Messages sent
+//over SCION or UDP are never split as a client only reads a single datagram per query. Instead,
+//sendDatagram replaces a message not fitting into a datagram by a NTMsgTooLarge notification.
+func (s *Server) splitMessage(msg message.Message, receiver net.Addr) ([]message.Message, bool) {
+	if isDatagramAddr(receiver) {
+		return nil, false
+	}
+	limit := s.messageLimit(receiver)
+	if limit <= 0 {
+		return nil, false
+	}
+	limit -= len(s.infraPrivateKeys) * messageSigOverhead
+	msg.Signatures = nil
+	encoding := new(bytes.Buffer)
+	if err := cbor.NewWriter(encoding).Marshal(&msg); err != nil || encoding.Len() <= limit {
+		return nil, false
+	}
+	if len(msg.Content) < 2 {
+		log.Warn("Section exceeds the maximum message size of receiver", "receiver", receiver,
+			"size", encoding.Len(), "limit", limit)
+		return nil, false
+	}
+	log.Debug("Split message exceeding the maximum message size of receiver", "receiver", receiver,
+		"size", encoding.Len(), "limit", limit, "sections", len(msg.Content))
+	half := len(msg.Content) / 2
+	return []message.Message{
+		{Token: msg.Token, Capabilities: msg.Capabilities, Content: msg.Content[:half]},
+		{Token: msg.Token, Capabilities: msg.Capabilities, Content: msg.Content[half:]},
+	}, true
+}
@@ -0,0 +1,104 @@
+package rainsd
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/cbor"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/lruCache"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+)
+
+func TestPeerMessageLimit(t *testing.T) {
+	s := &Server{config: Config{MaxMessageSize: 4000}, peerMessageLimits: lruCache.New()}
+	peer := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5022}
+	other := &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 5022}
+	var tests = []struct {
+		name  string
+		peer  net.Addr
+		data  string
+		limit int
+	}{
+		{"announced limit", peer, "2000", 2000},
+		{"announced limit of other peer", other, "3000", 3000},
+		{"limit below minimum", peer, "10", minPeerMessageLimit},
+		{"malformed limit", peer, "abc", minPeerMessageLimit},
+		{"negative limit", peer, "-5", minPeerMessageLimit},
+		{"other connection of peer", &net.TCPAddr{IP: peer.IP, Port: 40000}, "2000", 2000},
+		{"datagram peer", &net.UDPAddr{IP: net.ParseIP("192.0.2.3"), Port: 5022}, "2000", 4000},
+	}
+	for _, test := range tests {
+		s.setPeerMessageLimit(test.peer, test.data)
+		if limit := s.messageLimit(test.peer); limit != test.limit {
+			t.Errorf("%s: wrong limit. expected=%d actual=%d", test.name, test.limit, limit)
+		}
+	}
+	if limit := s.messageLimit(other); limit != 3000 {
+		t.Errorf("limit of other peer was changed. expected=3000 actual=%d", limit)
+	}
+	s.peerMessageLimits.Remove(peerKey(other))
+	s.peerMessageLimits.GetOrAdd(peerKey(other), peerMessageLimit{limit: 3000,
+		expiration: time.Now().Add(-time.Second)}, false)
+	if limit := s.messageLimit(other); limit != 4000 || s.peerMessageLimits.Len() != 1 {
+		t.Errorf("expired limit was not removed. limit=%d", limit)
+	}
+	for i := 0; i <= maxPeerMessageLimits; i++ {
+		s.setPeerMessageLimit(&net.TCPAddr{IP: net.IPv4(10, byte(i>>16), byte(i>>8), byte(i))},
+			"2000")
+	}
+	if s.peerMessageLimits.Len() != maxPeerMessageLimits {
+		t.Errorf("wrong number of stored limits. expected=%d actual=%d", maxPeerMessageLimits,
+			s.peerMessageLimits.Len())
+	}
+	if limit := s.messageLimit(peer); limit != 4000 {
+		t.Errorf("least recently used limit was not removed. actual=%d", limit)
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	s := &Server{config: Config{MaxMessageSize: 1000}, peerMessageLimits: lruCache.New()}
+	receiver := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5022}
+	var content []section.Section
+	for i := 0; i < 40; i++ {
+		content = append(content, ipAssertion("host"+strconv.Itoa(i), "ethz.ch.", "192.0.2.1"))
+	}
+	msg := message.Message{Token: token.New(), Content: content}
+	parts, ok := s.splitMessage(msg, receiver)
+	if !ok || len(parts) != 2 {
+		t.Fatalf("message exceeding the limit was not split. parts=%d", len(parts))
+	}
+	for i, part := range parts {
+		if part.Token != msg.Token || len(part.Content) != len(content)/2 ||
+			len(part.Signatures) != 0 {
+			t.Errorf("%d: wrong part. token=%v sections=%d signatures=%d", i, part.Token,
+				len(part.Content), len(part.Signatures))
+		}
+	}
+	if _, ok := s.splitMessage(message.Message{Token: msg.Token, Content: content[:1]},
+		receiver); ok {
+		t.Error("message with a single section was split")
+	}
+	if _, ok := s.splitMessage(msg, &net.UDPAddr{IP: receiver.IP, Port: receiver.Port}); ok {
+		t.Error("message sent over UDP was split")
+	}
+	//Room for the signature of a message is reserved.
+	encoding := new(bytes.Buffer)
+	small := message.Message{Token: msg.Token, Content: content[:2]}
+	if err := cbor.NewWriter(encoding).Marshal(&small); err != nil {
+		t.Fatal(err)
+	}
+	s.config.MaxMessageSize = encoding.Len() + messageSigOverhead/2
+	if _, ok := s.splitMessage(small, receiver); ok {
+		t.Error("message fitting into the limit was split")
+	}
+	s.infraPrivateKeys = map[keys.PublicKeyID]interface{}{keys.PublicKeyID{}: nil}
+	if _, ok := s.splitMessage(small, receiver); !ok {
+		t.Error("no room was reserved for the signature")
+	}
+}
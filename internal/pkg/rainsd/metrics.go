@@ -0,0 +1,353 @@
+package rainsd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/connection"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/signature"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+)
+
+const metricsPath = "/metrics"
+
+//metrics collects operational counters of a server. They are exported over http in the Prometheus
+//text format.
+type metrics struct {
+	assertionCache    cacheStats
+	negAssertionCache cacheStats
+	addressCache      cacheStats
+	zoneKeyCache      cacheStats
+	pendingKeyCache   cacheStats
+	pendingQueryCache cacheStats
+
+	sigChecks   uint64
+	sigFailures uint64
+
+	throttledQueries    uint64
+	throttledSections   uint64
+	droppedQueued       uint64
+	suppressedResponses uint64
+
+	heartbeatEvictions uint64
+
+	//connections contains the number of open connections per connection oriented transport.
+	connections map[connection.Type]*int64
+	//listeners contains the number of open listening sockets per transport.
+	listeners map[connection.Type]*int64
+
+	notifMux      sync.Mutex
+	notifSent     map[section.NotificationType]uint64
+	notifReceived map[section.NotificationType]uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		connections: map[connection.Type]*int64{connection.TCP: new(int64)},
+		listeners: map[connection.Type]*int64{
+			connection.TCP:   new(int64),
+			connection.SCION: new(int64),
+			connection.UDP:   new(int64),
+		},
+		notifSent:     make(map[section.NotificationType]uint64),
+		notifReceived: make(map[section.NotificationType]uint64),
+	}
+}
+
+//cacheStats counts the lookups on a cache.
+type cacheStats struct {
+	hits   uint64
+	misses uint64
+}
+
+//record increases the hit counter if hit is true and otherwise the miss counter.
+func (c *cacheStats) record(hit bool) {
+	if hit {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+}
+
+//signatureChecked counts a verification of a section's signatures.
+func (m *metrics) signatureChecked(valid bool) {
+	atomic.AddUint64(&m.sigChecks, 1)
+	if !valid {
+		atomic.AddUint64(&m.sigFailures, 1)
+	}
+}
+
+//connectionOpened increases the number of open connections of transport t by one.
+func (m *metrics) connectionOpened(t connection.Type) {
+	if c, ok := m.connections[t]; ok {
+		atomic.AddInt64(c, 1)
+	}
+}
+
+//connectionClosed decreases the number of open connections of transport t by one.
+func (m *metrics) connectionClosed(t connection.Type) {
+	if c, ok := m.connections[t]; ok {
+		atomic.AddInt64(c, -1)
+	}
+}
+
+//listenerOpened increases the number of open listening sockets of transport t by one.
+func (m *metrics) listenerOpened(t connection.Type) {
+	if c, ok := m.listeners[t]; ok {
+		atomic.AddInt64(c, 1)
+	}
+}
+
+//listenerClosed decreases the number of open listening sockets of transport t by one.
+func (m *metrics) listenerClosed(t connection.Type) {
+	if c, ok := m.listeners[t]; ok {
+		atomic.AddInt64(c, -1)
+	}
+}
+
+//notificationSent counts a sent notification of type t.
+func (m *metrics) notificationSent(t section.NotificationType) {
+	m.notifMux.Lock()
+	defer m.notifMux.Unlock()
+	m.notifSent[t]++
+}
+
+//notificationReceived counts a received notification of type t.
+func (m *metrics) notificationReceived(t section.NotificationType) {
+	m.notifMux.Lock()
+	defer m.notifMux.Unlock()
+	m.notifReceived[t]++
+}
+
+//listenMetrics starts serving the server's metrics on s.config.MetricsAddr in a new go routine.
+//Nothing is served if no address is configured.
+func (s *Server) listenMetrics() {
+	if s.config.MetricsAddr == "" {
+		return
+	}
+	listener, err := net.Listen("tcp", s.config.MetricsAddr)
+	if err != nil {
+		log.Error("Was not able to listen on metrics address", "addr", s.config.MetricsAddr,
+			"error", err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(metricsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		s.writeMetrics(w)
+	})
+	s.metricsServer = &http.Server{Handler: mux}
+	log.Info("Serving metrics", "addr", listener.Addr(), "path", metricsPath)
+	go func(srv *http.Server) {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Warn("Metrics server stopped", "error", err)
+		}
+	}(s.metricsServer)
+}
+
+//writeMetrics writes the current values of all metrics to w in the Prometheus text format.
+func (s *Server) writeMetrics(w io.Writer) {
+	m := s.metrics
+
+	writeHeader(w, "rainsd_queue_length", "gauge", "Number of messages waiting in an input queue.")
+	fmt.Fprintf(w, "rainsd_queue_length{queue=\"prio\"} %d\n", len(s.queues.Prio))
+	fmt.Fprintf(w, "rainsd_queue_length{queue=\"normal\"} %d\n",
+		len(s.queues.Normal)+s.fairQueue.Len())
+	fmt.Fprintf(w, "rainsd_queue_length{queue=\"notification\"} %d\n", len(s.queues.Notify))
+	writeHeader(w, "rainsd_queue_capacity", "gauge", "Maximum number of messages in an input queue.")
+	fmt.Fprintf(w, "rainsd_queue_capacity{queue=\"prio\"} %d\n", cap(s.queues.Prio))
+	fmt.Fprintf(w, "rainsd_queue_capacity{queue=\"normal\"} %d\n", cap(s.queues.Normal))
+	fmt.Fprintf(w, "rainsd_queue_capacity{queue=\"notification\"} %d\n", cap(s.queues.Notify))
+	writeHeader(w, "rainsd_workers_busy", "gauge", "Number of workers processing an input queue.")
+	fmt.Fprintf(w, "rainsd_workers_busy{queue=\"prio\"} %d\n", len(s.queues.PrioW))
+	fmt.Fprintf(w, "rainsd_workers_busy{queue=\"normal\"} %d\n", len(s.queues.NormalW))
+	fmt.Fprintf(w, "rainsd_workers_busy{queue=\"notification\"} %d\n", len(s.queues.NotifyW))
+	writeHeader(w, "rainsd_workers_max", "gauge", "Maximum number of workers of an input queue.")
+	fmt.Fprintf(w, "rainsd_workers_max{queue=\"prio\"} %d\n", cap(s.queues.PrioW))
+	fmt.Fprintf(w, "rainsd_workers_max{queue=\"normal\"} %d\n", cap(s.queues.NormalW))
+	fmt.Fprintf(w, "rainsd_workers_max{queue=\"notification\"} %d\n", cap(s.queues.NotifyW))
+
+	caches := []struct {
+		name  string
+		size  int
+		stats *cacheStats
+	}{
+		{"assertion", s.caches.AssertionsCache.Len(), &m.assertionCache},
+		{"negAssertion", s.caches.NegAssertionCache.Len(), &m.negAssertionCache},
+		{"address", s.caches.AddressCache.Len(), &m.addressCache},
+		{"zoneKey", s.caches.ZoneKeyCache.Len(), &m.zoneKeyCache},
+		{"pendingKey", s.caches.PendingKeys.Len(), &m.pendingKeyCache},
+		{"pendingQuery", s.caches.PendingQueries.Len(), &m.pendingQueryCache},
+	}
+	writeHeader(w, "rainsd_cache_size", "gauge", "Number of elements in a cache.")
+	for _, c := range caches {
+		fmt.Fprintf(w, "rainsd_cache_size{cache=%q} %d\n", c.name, c.size)
+	}
+	writeHeader(w, "rainsd_cache_hits_total", "counter", "Number of successful cache lookups.")
+	for _, c := range caches {
+		fmt.Fprintf(w, "rainsd_cache_hits_total{cache=%q} %d\n", c.name,
+			atomic.LoadUint64(&c.stats.hits))
+	}
+	writeHeader(w, "rainsd_cache_misses_total", "counter", "Number of unsuccessful cache lookups.")
+	for _, c := range caches {
+		fmt.Fprintf(w, "rainsd_cache_misses_total{cache=%q} %d\n", c.name,
+			atomic.LoadUint64(&c.stats.misses))
+	}
+
+	writeHeader(w, "rainsd_signature_verifications_total", "counter",
+		"Number of sections whose signatures have been verified.")
+	fmt.Fprintf(w, "rainsd_signature_verifications_total %d\n", atomic.LoadUint64(&m.sigChecks))
+	writeHeader(w, "rainsd_signature_verification_failures_total", "counter",
+		"Number of sections whose signatures could not be verified.")
+	fmt.Fprintf(w, "rainsd_signature_verification_failures_total %d\n",
+		atomic.LoadUint64(&m.sigFailures))
+
+	writeHeader(w, "rainsd_rate_limited_total", "counter",
+		"Number of messages dropped because a rate limit was exceeded.")
+	fmt.Fprintf(w, "rainsd_rate_limited_total{reason=\"query\"} %d\n",
+		atomic.LoadUint64(&m.throttledQueries))
+	fmt.Fprintf(w, "rainsd_rate_limited_total{reason=\"assertion\"} %d\n",
+		atomic.LoadUint64(&m.throttledSections))
+	fmt.Fprintf(w, "rainsd_rate_limited_total{reason=\"queue\"} %d\n",
+		atomic.LoadUint64(&m.droppedQueued))
+	fmt.Fprintf(w, "rainsd_rate_limited_total{reason=\"response\"} %d\n",
+		atomic.LoadUint64(&m.suppressedResponses))
+
+	m.notifMux.Lock()
+	writeHeader(w, "rainsd_notifications_sent_total", "counter", "Number of sent notifications.")
+	writeNotificationCounts(w, "rainsd_notifications_sent_total", m.notifSent)
+	writeHeader(w, "rainsd_notifications_received_total", "counter",
+		"Number of received notifications.")
+	writeNotificationCounts(w, "rainsd_notifications_received_total", m.notifReceived)
+	m.notifMux.Unlock()
+
+	writeHeader(w, "rainsd_heartbeat_evictions_total", "counter",
+		"Number of connections closed because they missed too many heartbeats.")
+	fmt.Fprintf(w, "rainsd_heartbeat_evictions_total %d\n", atomic.LoadUint64(&m.heartbeatEvictions))
+	writeHeader(w, "rainsd_connections", "gauge", "Number of open connections per transport.")
+	fmt.Fprintf(w, "rainsd_connections{transport=%q} %d\n", connection.TCP,
+		atomic.LoadInt64(m.connections[connection.TCP]))
+	writeHeader(w, "rainsd_listeners", "gauge", "Number of open listening sockets per transport.")
+	for _, t := range []connection.Type{connection.TCP, connection.SCION, connection.UDP} {
+		fmt.Fprintf(w, "rainsd_listeners{transport=%q} %d\n", t, atomic.LoadInt64(m.listeners[t]))
+	}
+}
+
+//writeHeader writes the help and type lines of metric name to w.
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+//writeNotificationCounts writes counts sorted by notification type to w.
+func writeNotificationCounts(w io.Writer, name string, counts map[section.NotificationType]uint64) {
+	types := []section.NotificationType{}
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	for _, t := range types {
+		fmt.Fprintf(w, "%s{type=%q} %d\n", name, t, counts[t])
+	}
+}
+
+//instrumentCaches replaces the assertion, negative assertion, address, zone key and pending caches
+//of caches with wrappers counting the hits and misses of their lookups in m.
+func instrumentCaches(caches *Caches, m *metrics) {
+	caches.AssertionsCache = meteredAssertionCache{caches.AssertionsCache, &m.assertionCache}
+	caches.NegAssertionCache = meteredNegAssertionCache{caches.NegAssertionCache,
+		&m.negAssertionCache}
+	caches.AddressCache = meteredAddressCache{caches.AddressCache, &m.addressCache}
+	caches.ZoneKeyCache = meteredZoneKeyCache{caches.ZoneKeyCache, &m.zoneKeyCache}
+	caches.PendingKeys = meteredPendingKeyCache{caches.PendingKeys, &m.pendingKeyCache}
+	caches.PendingQueries = meteredPendingQueryCache{caches.PendingQueries, &m.pendingQueryCache}
+}
+
+type meteredAssertionCache struct {
+	cache.Assertion
+	stats *cacheStats
+}
+
+func (c meteredAssertionCache) Get(fqdn, context string, objType object.Type, strict bool) (
+	[]*section.Assertion, bool) {
+	a, ok := c.Assertion.Get(fqdn, context, objType, strict)
+	c.stats.record(ok)
+	return a, ok
+}
+
+type meteredNegAssertionCache struct {
+	cache.NegativeAssertion
+	stats *cacheStats
+}
+
+func (c meteredNegAssertionCache) Get(subjectZone, context string, interval section.Interval) (
+	[]section.WithSigForward, bool) {
+	s, ok := c.NegativeAssertion.Get(subjectZone, context, interval)
+	c.stats.record(ok)
+	return s, ok
+}
+
+type meteredAddressCache struct {
+	cache.Address
+	stats *cacheStats
+}
+
+func (c meteredAddressCache) Get(subjectAddr address.Prefix, context string,
+	types []object.Type) ([]section.WithSig, bool) {
+	s, ok := c.Address.Get(subjectAddr, context, types)
+	c.stats.record(ok)
+	return s, ok
+}
+
+type meteredZoneKeyCache struct {
+	cache.ZonePublicKey
+	stats *cacheStats
+}
+
+func (c meteredZoneKeyCache) Get(zone, context string, sigMetaData signature.MetaData) (
+	keys.PublicKey, *section.Assertion, bool) {
+	k, a, ok := c.ZonePublicKey.Get(zone, context, sigMetaData)
+	c.stats.record(ok)
+	return k, a, ok
+}
+
+type meteredPendingKeyCache struct {
+	cache.PendingKey
+	stats *cacheStats
+}
+
+func (c meteredPendingKeyCache) GetAndRemove(t token.Token) (util.MsgSectionSender, bool) {
+	ss, ok := c.PendingKey.GetAndRemove(t)
+	c.stats.record(ok)
+	return ss, ok
+}
+
+type meteredPendingQueryCache struct {
+	cache.PendingQuery
+	stats *cacheStats
+}
+
+func (c meteredPendingQueryCache) GetAndRemove(t token.Token) []util.MsgSectionSender {
+	ss := c.PendingQuery.GetAndRemove(t)
+	c.stats.record(len(ss) > 0)
+	return ss
+}
+
+func (c meteredPendingQueryCache) AddAnswer(t token.Token, sections []section.Section,
+	deadline time.Time) (time.Time, bool) {
+	deadline, isFirst := c.PendingQuery.AddAnswer(t, sections, deadline)
+	c.stats.record(!deadline.IsZero())
+	return deadline, isFirst
+}
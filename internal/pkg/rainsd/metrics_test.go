@@ -0,0 +1,192 @@
+package rainsd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/connection"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/signature"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+	"golang.org/x/crypto/ed25519"
+)
+
+//meteredCaches returns caches whose lookups are counted in m.
+func meteredCaches(m *metrics) *Caches {
+	caches := &Caches{
+		ZoneKeyCache:      cache.NewZoneKey(10, 10, 10),
+		PendingKeys:       cache.NewPendingKey(10),
+		PendingQueries:    cache.NewPendingQuery(10),
+		AssertionsCache:   cache.NewAssertion(10),
+		NegAssertionCache: cache.NewNegAssertion(10),
+		AddressCache:      cache.NewAddress(10),
+	}
+	instrumentCaches(caches, m)
+	return caches
+}
+
+func TestMeteredCaches(t *testing.T) {
+	m := newMetrics()
+	caches := meteredCaches(m)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	a := ipAssertion("www", "ethz.ch.", "192.0.2.1")
+	caches.AssertionsCache.Get("www.ethz.ch.", ".", object.OTIP4Addr, false)
+	caches.AssertionsCache.Add(a, exp, false)
+	caches.AssertionsCache.Get("www.ethz.ch.", ".", object.OTIP4Addr, false)
+
+	interval := section.StringInterval{Name: "b"}
+	caches.NegAssertionCache.Get("ethz.ch.", ".", interval)
+	caches.NegAssertionCache.AddShard(shard("a", "c"), exp, false)
+	caches.NegAssertionCache.Get("ethz.ch.", ".", interval)
+
+	prefix, _ := address.Parse("192.0.2.1")
+	types := []object.Type{object.OTName}
+	caches.AddressCache.Get(prefix, ".", types)
+	caches.AddressCache.AddAssertion(&section.AddressAssertion{SubjectAddr: prefix, Context: ".",
+		Content: []object.Object{{Type: object.OTName, Value: object.Name{Name: "www.ethz.ch.",
+			Types: []object.Type{object.OTIP4Addr}}}}}, exp, false)
+	caches.AddressCache.Get(prefix, ".", types)
+
+	key := keys.PublicKey{
+		PublicKeyID: keys.PublicKeyID{Algorithm: algorithmTypes.Ed25519},
+		ValidSince:  time.Now().Add(-time.Hour).Unix(),
+		ValidUntil:  exp,
+		Key:         ed25519.PublicKey([]byte("TestKey")),
+	}
+	sigMetaData := signature.MetaData{
+		PublicKeyID: key.PublicKeyID,
+		ValidSince:  time.Now().Unix(),
+		ValidUntil:  time.Now().Add(time.Minute).Unix(),
+	}
+	delegation := &section.Assertion{SubjectName: "ch", SubjectZone: ".", Context: ".",
+		Content: []object.Object{{Type: object.OTDelegation, Value: key}}}
+	caches.ZoneKeyCache.Get("ch.", ".", sigMetaData)
+	caches.ZoneKeyCache.Add(delegation, key, false)
+	caches.ZoneKeyCache.Get("ch.", ".", sigMetaData)
+
+	tok := token.New()
+	caches.PendingKeys.GetAndRemove(tok)
+	caches.PendingKeys.Add(util.MsgSectionSender{Token: tok}, tok, exp)
+	caches.PendingKeys.GetAndRemove(tok)
+
+	caches.PendingQueries.AddAnswer(tok, nil, time.Now())
+	caches.PendingQueries.GetAndRemove(tok)
+	caches.PendingQueries.Add(util.MsgSectionSender{Token: tok}, tok, exp)
+	caches.PendingQueries.AddAnswer(tok, nil, time.Now())
+	caches.PendingQueries.GetAndRemove(tok)
+
+	var tests = []struct {
+		name   string
+		stats  cacheStats
+		hits   uint64
+		misses uint64
+	}{
+		{"assertion", m.assertionCache, 1, 1},
+		{"negAssertion", m.negAssertionCache, 1, 1},
+		{"address", m.addressCache, 1, 1},
+		{"zoneKey", m.zoneKeyCache, 1, 1},
+		{"pendingKey", m.pendingKeyCache, 1, 1},
+		{"pendingQuery", m.pendingQueryCache, 2, 2},
+	}
+	for _, test := range tests {
+		if test.stats.hits != test.hits || test.stats.misses != test.misses {
+			t.Errorf("%s: wrong lookup counts. expected=%d/%d actual=%d/%d", test.name, test.hits,
+				test.misses, test.stats.hits, test.stats.misses)
+		}
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	m := newMetrics()
+	s := &Server{
+		metrics: m,
+		caches:  meteredCaches(m),
+		queues: InputQueues{
+			Prio:    make(chan util.MsgSectionSender, 10),
+			Normal:  make(chan util.MsgSectionSender, 20),
+			Notify:  make(chan util.MsgSectionSender, 30),
+			PrioW:   make(chan struct{}, 1),
+			NormalW: make(chan struct{}, 2),
+			NotifyW: make(chan struct{}, 3),
+		},
+	}
+	s.queues.Normal <- util.MsgSectionSender{}
+	s.queues.NormalW <- struct{}{}
+	s.caches.AssertionsCache.Add(ipAssertion("www", "ethz.ch.", "192.0.2.1"),
+		time.Now().Add(time.Hour).Unix(), false)
+	s.caches.AssertionsCache.Get("www.ethz.ch.", ".", object.OTIP4Addr, false)
+	m.signatureChecked(true)
+	m.signatureChecked(false)
+	m.notificationSent(section.NTHeartbeat)
+	m.notificationSent(section.NTHeartbeat)
+	m.notificationReceived(section.NTMsgTooLarge)
+	m.connectionOpened(connection.TCP)
+	m.connectionOpened(connection.TCP)
+	m.connectionClosed(connection.TCP)
+	m.connectionOpened(connection.UDP)
+	m.listenerOpened(connection.TCP)
+	m.listenerOpened(connection.UDP)
+
+	out := new(bytes.Buffer)
+	s.writeMetrics(out)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	var tests = []string{
+		`rainsd_queue_length{queue="normal"} 1`,
+		`rainsd_queue_capacity{queue="notification"} 30`,
+		`rainsd_workers_busy{queue="normal"} 1`,
+		`rainsd_workers_max{queue="prio"} 1`,
+		`rainsd_cache_size{cache="assertion"} 1`,
+		`rainsd_cache_hits_total{cache="assertion"} 1`,
+		`rainsd_cache_misses_total{cache="assertion"} 0`,
+		`rainsd_signature_verifications_total 2`,
+		`rainsd_signature_verification_failures_total 1`,
+		`rainsd_rate_limited_total{reason="query"} 0`,
+		`rainsd_notifications_sent_total{type="` + section.NTHeartbeat.String() + `"} 2`,
+		`rainsd_notifications_received_total{type="` + section.NTMsgTooLarge.String() + `"} 1`,
+		`rainsd_heartbeat_evictions_total 0`,
+		`rainsd_connections{transport="` + connection.TCP.String() + `"} 1`,
+		`rainsd_listeners{transport="` + connection.TCP.String() + `"} 1`,
+		`rainsd_listeners{transport="` + connection.SCION.String() + `"} 0`,
+		`rainsd_listeners{transport="` + connection.UDP.String() + `"} 1`,
+	}
+	for _, want := range tests {
+		found := false
+		for _, line := range lines {
+			found = found || line == want
+		}
+		if !found {
+			t.Errorf("metric is missing: %s", want)
+		}
+	}
+	//Every sample is preceded by the help and type lines of its metric and no transport other than
+	//TCP is reported as having connections.
+	declared := make(map[string]bool)
+	for i, line := range lines {
+		if strings.HasPrefix(line, "# TYPE ") {
+			if !strings.HasPrefix(lines[i-1], "# HELP "+strings.Fields(line)[2]+" ") {
+				t.Errorf("help line missing before: %s", line)
+			}
+			declared[strings.Fields(line)[2]] = true
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := strings.FieldsFunc(line, func(r rune) bool { return r == '{' || r == ' ' })[0]
+		if !declared[name] {
+			t.Errorf("type of metric %s is not declared", name)
+		}
+		if name == "rainsd_connections" && !strings.Contains(line, connection.TCP.String()) {
+			t.Errorf("connectionless transport reported as connection: %s", line)
+		}
+	}
+}
@@ -15,30 +15,30 @@ import (
 func (s *Server) notify(msgSender util.MsgSectionSender) {
 	notifLog := log.New("notificationMsgSection", msgSender.Sections[0])
 	sec := msgSender.Sections[0].(*section.Notification)
+	s.metrics.notificationReceived(sec.Type)
 	switch sec.Type {
 	case section.NTHeartbeat:
+		//A heartbeat request carries the token of its own message. The response carries this token
+		//in a new message such that it is not answered again.
+		if sec.Token == msgSender.Token {
+			sendNotificationMsg(sec.Token, msgSender.Sender, section.NTHeartbeat, "", s)
+		}
+	case section.NTStaleAnswer:
+		notifLog.Info("Received answer contains expired sections")
 	case section.NTCapHashNotKnown:
+		//The sender did not understand our capability hash. Data contains the sender's capability
+		//list, its hash, or nothing.
 		if len(sec.Data) == 0 {
-			caps, _ := s.caches.ConnCache.GetCapabilityList(s.config.ServerAddress.Addr)
-			sendCapability(msgSender.Sender, caps, s)
-		} else {
-			if capabilityIsHash(sec.Data) {
-				if caps, ok := s.caches.Capabilities.Get([]byte(sec.Data)); ok {
-					s.caches.ConnCache.AddCapabilityList(msgSender.Sender, caps)
-					ownCaps, _ := s.caches.ConnCache.GetCapabilityList(s.config.ServerAddress.Addr)
-					sendCapability(msgSender.Sender, ownCaps, s)
-				} else {
-					sendNotificationMsg(msgSender.Token, msgSender.Sender, section.NTCapHashNotKnown, "", s)
-				}
+			sendCapability(msgSender.Sender, transportCapabilities(s.config), s)
+		} else if capabilityIsHash(sec.Data) {
+			if caps, ok := s.caches.Capabilities.Get([]byte(sec.Data)); ok {
+				s.addCapabilityAndRespond(msgSender.Sender, caps)
 			} else {
-				cList := []message.Capability{}
-				for _, c := range strings.Split(sec.Data, " ") {
-					cList = append(cList, message.Capability(c))
-				}
-				s.caches.ConnCache.AddCapabilityList(msgSender.Sender, cList)
-				ownCaps, _ := s.caches.ConnCache.GetCapabilityList(s.config.ServerAddress.Addr)
-				sendCapability(msgSender.Sender, ownCaps, s)
+				sendNotificationMsg(msgSender.Token, msgSender.Sender, section.NTCapHashNotKnown,
+					s.capabilityList, s)
 			}
+		} else {
+			s.addCapabilityAndRespond(msgSender.Sender, parseCapabilities(sec.Data))
 		}
 	case section.NTBadMessage:
 		notifLog.Error("Sent msg was malformed")
@@ -47,8 +47,13 @@ func (s *Server) notify(msgSender util.MsgSectionSender) {
 		notifLog.Error("Sent msg was inconsistent")
 		dropPendingSectionsAndQueries(msgSender.Token, sec, true, s)
 	case section.NTMsgTooLarge:
-		notifLog.Error("Sent msg was too large")
-		//TODO CFE resend message in smaller chunks
+		//Data contains the maximum message size of the sender. Subsequent messages to it are split
+		//accordingly.
+		notifLog.Error("Sent msg was too large", "limit", sec.Data)
+		s.setPeerMessageLimit(msgSender.Sender, sec.Data)
+	case section.NTRateLimited:
+		notifLog.Warn("Throttled by other server")
+		dropPendingSectionsAndQueries(msgSender.Token, sec, false, s)
 	case section.NTNoAssertionsExist:
 		notifLog.Info("Bad request, only clients receive this notification type")
 		sendNotificationMsg(msgSender.Token, msgSender.Sender, section.NTBadMessage, "", s)
@@ -72,6 +77,15 @@ func capabilityIsHash(capabilities string) bool {
 	return !strings.HasPrefix(capabilities, "urn:")
 }
 
+//parseCapabilities returns the capabilities contained in the space separated list capabilities.
+func parseCapabilities(capabilities string) []message.Capability {
+	caps := []message.Capability{}
+	for _, c := range strings.Fields(capabilities) {
+		caps = append(caps, message.Capability(c))
+	}
+	return caps
+}
+
 //dropPendingSectionsAndQueries removes all entries from the pending caches matching token and
 //forwards the received notification or unspecServerErr depending on serverError flag
 func dropPendingSectionsAndQueries(token token.Token, notification *section.Notification,
@@ -1,12 +1,16 @@
 package rainsd
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"math"
 	"net"
+	"sort"
 	"strings"
 	"time"
 
+	cbor "github.com/britram/borat"
 	log "github.com/inconshreveable/log15"
 	"github.com/netsec-ethz/rains/internal/pkg/cache"
 	"github.com/netsec-ethz/rains/internal/pkg/libresolve"
@@ -25,20 +29,68 @@ const (
 //processQuery processes msgSender containing a query section
 func (s *Server) processQuery(msgSender util.MsgSectionSender) {
 	queries := []*query.Name{}
+	nameQueries := []section.Section{}
+	addrQueries := []*query.Address{}
 	for _, sec := range msgSender.Sections {
-		if q, ok := sec.(*query.Name); ok {
+		switch q := sec.(type) {
+		case *query.Name:
 			queries = append(queries, q)
-		} else {
+			nameQueries = append(nameQueries, q)
+		case *query.Address:
+			addrQueries = append(addrQueries, q)
+		default:
 			log.Error("Not supported query message section. This case must be prevented beforehand")
 			return
 		}
 	}
-	if len(s.config.Authorities) == 0 {
+	if len(addrQueries) != 0 {
+		answerAddressQueries(addrQueries, msgSender, s)
+	}
+	if len(queries) == 0 {
+		return
+	}
+	msgSender.Sections = nameQueries
+	authorities, intermediaryFor := s.zones()
+	switch {
+	case len(intermediaryFor) != 0:
+		//intermediary
+		answerQueriesIntermediary(queries, msgSender, s)
+	case len(authorities) != 0:
+		//naming server
+		answerQueriesAuthoritative(queries, authorities, msgSender.Sender,
+			msgSender.Token, s)
+	default:
 		//caching resolver
 		answerQueriesCachingResolver(msgSender, s)
-	} else {
-		//naming server
-		answerQueriesAuthoritative(queries, msgSender.Sender, msgSender.Token, s)
+	}
+}
+
+//answerQueriesIntermediary is how an intermediary answers queries. Queries about zones it serves
+//are answered authoritatively on behalf of the zones' authorities. Delegation queries about other
+//zones are forwarded to the recursive resolver and all other queries are dropped.
+func answerQueriesIntermediary(qs []*query.Name, ss util.MsgSectionSender, s *Server) {
+	log.Info("Start processing query as intermediary", "queries", qs)
+	zones := s.servedZones()
+	served := []*query.Name{}
+	delegations := []section.Section{}
+	for _, q := range qs {
+		if isServedName(q, zones) {
+			served = append(served, q)
+		} else if containsType(q.Types, object.OTDelegation) {
+			delegations = append(delegations, q)
+		} else {
+			log.Info("Query is not about a name this intermediary serves", "query", q,
+				"zones", zones)
+			sendNotificationMsg(ss.Token, ss.Sender, section.NTNoAssertionAvail,
+				fmt.Sprintf("%s is not served by this intermediary", q.Name), s)
+		}
+	}
+	if len(served) != 0 {
+		answerQueriesAuthoritative(served, zones, ss.Sender, ss.Token, s)
+	}
+	if len(delegations) != 0 {
+		answerQueriesCachingResolver(util.MsgSectionSender{Sender: ss.Sender, Token: ss.Token,
+			Sections: delegations}, s)
 	}
 }
 
@@ -47,16 +99,38 @@ func answerQueriesCachingResolver(ss util.MsgSectionSender, s *Server) {
 	log.Info("Start processing query as cr", "queries", ss.Sections)
 	queries := []*query.Name{}
 	sections := []section.Section{}
+	containsExpired := false
 	for _, q := range ss.Sections {
 		q := q.(*query.Name)
-		if secs := cacheLookup(q, ss.Sender, ss.Token, s); secs != nil {
+		if secs, expired := cacheLookup(q, ss.Sender, ss.Token, s); secs != nil {
 			sections = append(sections, secs...)
+			if expired {
+				containsExpired = true
+				s.refreshExpired(q)
+			}
 		} else {
 			queries = append(queries, q)
 		}
 	}
+	if containsExpired {
+		sections = append(sections, expiredAnswerNotification(ss.Token))
+	}
+	//Queries which must be answered from the cache are never forwarded.
+	forward := []*query.Name{}
+	for _, q := range queries {
+		if q.ContainsOption(query.QOCachedAnswersOnly) {
+			log.Info("No cached answer for query restricted to cached answers", "query", q)
+			sendNotificationMsg(ss.Token, ss.Sender, section.NTNoAssertionAvail,
+				fmt.Sprintf("no cached answer for %s", q.Name), s)
+		} else {
+			forward = append(forward, q)
+		}
+	}
+	queries = forward
 	if len(queries) == 0 {
-		sendSections(sections, ss.Token, ss.Sender, s)
+		if len(sections) > 0 {
+			sendSections(sections, ss.Token, ss.Sender, s)
+		}
 		return
 	}
 
@@ -85,83 +159,174 @@ func answerQueriesCachingResolver(ss util.MsgSectionSender, s *Server) {
 	}
 }
 
-//answerQueryAuthoritative is how an authoritative server answers queries
-func answerQueriesAuthoritative(qs []*query.Name, sender net.Addr, token token.Token, s *Server) {
-	log.Info("Start processing query as authority", "queries", qs)
+//answerAddressQueries answers address queries from the address cache. A caching resolver forwards
+//queries without a cached answer to the recursive resolver. Otherwise the querier is notified that
+//no assertion is available.
+func answerAddressQueries(qs []*query.Address, ss util.MsgSectionSender, s *Server) {
+	log.Info("Start processing address queries", "queries", qs)
+	authorities, intermediaryFor := s.zones()
+	isCachingResolver := len(authorities) == 0 && len(intermediaryFor) == 0
+	sections := []section.Section{}
+	forward := []section.Section{}
+	containsExpired := false
+	validUntil := time.Now().Add(s.config.QueryValidity).Unix() //Upper bound for forwarded query expiration time
 	for _, q := range qs {
-		for i, auth := range s.config.Authorities {
-			// check this server is authoritative for the name and the query has the right context
-			if strings.HasSuffix(q.Name, auth.Zone) && q.Name != auth.Zone && q.Context == auth.Context {
-				break
-			}
-			if i == len(s.config.Authorities)-1 {
-				log.Info("Query is not about a name this zone has authority over", "name", q.Name,
-					"authorities", s.config.Authorities)
-				return
+		expiredOk := q.ContainsOption(query.QOExpiredAssertionsOk)
+		cached, _ := s.caches.AddressCache.Get(q.SubjectAddr, q.Context, q.Types)
+		answered := false
+		for _, sec := range cached {
+			if servable, expired := s.isServable(sec, expiredOk); servable {
+				sections = append(sections, sec)
+				answered = true
+				containsExpired = containsExpired || expired
 			}
 		}
+		if answered {
+			continue
+		}
+		if !isCachingResolver || q.ContainsOption(query.QOCachedAnswersOnly) {
+			log.Info("No cached answer for address query", "query", q)
+			sendNotificationMsg(ss.Token, ss.Sender, section.NTNoAssertionAvail,
+				fmt.Sprintf("no cached answer for %s", q.SubjectAddr), s)
+			continue
+		}
+		if !s.resolver.SupportsAddressLookup() {
+			log.Info("Recursive resolver does not look up address queries", "query", q)
+			sendNotificationMsg(ss.Token, ss.Sender, section.NTServerNotCapable,
+				fmt.Sprintf("no reverse lookup for %s", q.SubjectAddr), s)
+			continue
+		}
+		if q.Expiration < validUntil {
+			validUntil = q.Expiration
+		}
+		forward = append(forward, q)
+	}
+	if containsExpired {
+		sections = append(sections, expiredAnswerNotification(ss.Token))
+	}
+	if len(sections) > 0 {
+		sendSections(sections, ss.Token, ss.Sender, s)
+	}
+	if len(forward) == 0 {
+		return
+	}
+	tok := ss.Token
+	if !qs[0].ContainsOption(query.QOTokenTracing) {
+		tok = token.New()
+	}
+	pending := util.MsgSectionSender{Sender: ss.Sender, Token: ss.Token, Sections: forward}
+	if isNew := s.caches.PendingQueries.Add(pending, tok, validUntil); isNew {
+		for _, q := range forward {
+			q.(*query.Address).Expiration = validUntil
+		}
+		log.Info("Forwarding address queries to recursive resolver", "queries", forward)
+		s.sendToRecursiveResolver(message.Message{Token: tok, Content: forward})
+	} else {
+		log.Info("Address query has already been sent to recursive resolver", "queries", forward)
+	}
+}
+
+//answerQueryAuthoritative is how an authoritative server answers queries about names in zones
+func answerQueriesAuthoritative(qs []*query.Name, zones []ZoneContext, sender net.Addr,
+	token token.Token, s *Server) {
+	log.Info("Start processing query as authority", "queries", qs)
+	for _, q := range qs {
+		if !isServedName(q, zones) {
+			log.Info("Query is not about a name this zone has authority over", "name", q.Name,
+				"authorities", zones)
+			return
+		}
 	}
 
 	queries := []*query.Name{}
 	sections := []section.Section{}
+	containsExpired := false
 	for _, q := range qs {
-		if secs := cacheLookup(q, sender, token, s); secs != nil {
+		if secs, expired := cacheLookup(q, sender, token, s); secs != nil {
 			sections = append(sections, secs...)
+			containsExpired = containsExpired || expired
 		} else {
 			queries = append(queries, q)
 		}
 	}
-
 	if len(queries) != 0 {
 		//glueRecordNames assumes that the names of delegates do not contain a dot '.'.
-		names := glueRecordNames(queries, s.config.Authorities)
-		for name := range names {
-			glueRecords, err := s.glueRecordLookup(name.Zone, name.Context, s.caches.AssertionsCache)
+		names := glueRecordNames(queries, zones)
+		for name, expiredOk := range names {
+			glueRecords, expired, err := s.glueRecordLookup(name.Zone, name.Context, expiredOk,
+				s.caches.AssertionsCache)
 			if err != nil {
 				log.Warn("Was not able to find all glue records.", "name", name, "error", err.Error())
 				return
 			}
 			sections = append(sections, glueRecords...)
+			containsExpired = containsExpired || expired
 		}
 	}
+	if containsExpired {
+		sections = append(sections, expiredAnswerNotification(token))
+	}
 	sendSections(sections, token, sender, s)
 	log.Info("Finished handling query by sending records from cache", "queries", qs,
 		"sections", sections)
 }
 
-//cacheLookup answers q with a cached entry if there is one. True is returned in case of a cache hit
-func cacheLookup(q *query.Name, sender net.Addr, token token.Token, s *Server) []section.Section {
-	assertions := assertionCacheLookup(q, s)
+//isServedName returns true if q's name is in one of zones and q has the zone's context.
+func isServedName(q *query.Name, zones []ZoneContext) bool {
+	for _, zone := range zones {
+		if strings.HasSuffix(q.Name, zone.Zone) && q.Name != zone.Zone && q.Context == zone.Context {
+			return true
+		}
+	}
+	return false
+}
+
+//containsType returns true if t is one of types.
+func containsType(types []object.Type, t object.Type) bool {
+	for _, typ := range types {
+		if typ == t {
+			return true
+		}
+	}
+	return false
+}
+
+//cacheLookup answers q with a cached entry if there is one. The returned bool is true if the answer
+//contains expired sections, which is only the case if q accepts expired assertions.
+func cacheLookup(q *query.Name, sender net.Addr, token token.Token, s *Server) (
+	[]section.Section, bool) {
+	assertions, expired := assertionCacheLookup(q, s)
 	if len(assertions) > 0 {
-		return assertions
+		return assertions, expired
 	}
 
 	log.Debug("No direct entry found in assertion cache.", "name", q.Name,
 		"context", q.Context, "type", q.Types)
 	//negative answer lookup (note that it can occur a positive answer if assertion removed from cache)
-	sections := negativeCacheLookup(q, sender, token, s)
+	sections, expired := negativeCacheLookup(q, sender, token, s)
 	if len(sections) > 0 {
-		return sections
+		return sections, expired
 	}
-	return nil
+	return nil, false
 }
 
-func assertionCacheLookup(q *query.Name, s *Server) (assertions []section.Section) {
+func assertionCacheLookup(q *query.Name, s *Server) (assertions []section.Section, expired bool) {
 	assertionSet := make(map[string]bool)
 	asKey := func(a *section.Assertion) string {
 		return fmt.Sprintf("%s_%s_%s", a.SubjectName, a.SubjectZone, a.Context)
 	}
-
+	expiredOk := q.ContainsOption(query.QOExpiredAssertionsOk)
 	for _, t := range q.Types {
 		if asserts, ok := s.caches.AssertionsCache.Get(q.Name, q.Context, t, true); ok {
 			for _, a := range asserts {
 				if _, ok := assertionSet[asKey(a)]; ok {
 					continue
 				}
-				if a.ValidUntil() > time.Now().Unix() {
-					log.Debug(fmt.Sprintf("appending valid assertion: %v", a))
+				if servable, isExpired := s.isServable(a, expiredOk); servable {
+					log.Debug(fmt.Sprintf("appending assertion: %v", a), "expired", isExpired)
 					assertions = append(assertions, a)
 					assertionSet[asKey(a)] = true
+					expired = expired || isExpired
 				}
 			}
 		}
@@ -169,33 +334,169 @@ func assertionCacheLookup(q *query.Name, s *Server) (assertions []section.Sectio
 	return
 }
 
-func negativeCacheLookup(q *query.Name, sender net.Addr, token token.Token, s *Server) []section.Section {
+func negativeCacheLookup(q *query.Name, sender net.Addr, token token.Token, s *Server) (
+	[]section.Section, bool) {
 	subject, zone, err := toSubjectZone(q.Name)
 	if err != nil {
 		sendNotificationMsg(token, sender, section.NTRcvInconsistentMsg,
 			"query name must end with root zone dot '.'", s)
 		log.Warn("failed to concert query name to subject and zone", "error", err)
+		return nil, false
+	}
+	cached, _ := s.caches.NegAssertionCache.Get(zone, q.Context, section.StringInterval{Name: subject})
+	expiredOk := q.ContainsOption(query.QOExpiredAssertionsOk)
+	minimize := s.config.MinLastHopAnswerSize || q.ContainsOption(query.QOMinLastHopAnswerSize)
+	answer := []section.WithSigForward{}
+	for _, sec := range cached {
+		if servable, _ := s.isServable(sec, expiredOk); servable {
+			answer = append(answer, sec)
+		}
+	}
+	expired := false
+	sections := []section.Section{}
+	for _, sec := range filterAnswer(q, subject, answer, minimize) {
+		_, isExpired := s.isServable(sec, expiredOk)
+		expired = expired || isExpired
+		sections = append(sections, sec)
+	}
+	return sections, expired
+}
+
+//isServable returns true if sec can be used to answer a query. This is the case if sec has not yet
+//expired or if expiredOk is set and sec has expired less than the stale grace period ago. The
+//second return value is true if sec has expired.
+func (s *Server) isServable(sec section.WithSig, expiredOk bool) (bool, bool) {
+	now := time.Now().Unix()
+	if sec.ValidUntil() > now {
+		return true, false
+	}
+	return expiredOk && cacheExpiration(sec, s.config.StaleGracePeriod) > now, true
+}
+
+//expiredAnswerNotification returns a notification marking the answer to the query with token tok
+//as containing expired sections.
+func expiredAnswerNotification(tok token.Token) *section.Notification {
+	return &section.Notification{
+		Token: tok,
+		Type:  section.NTStaleAnswer,
+		Data:  "answer contains expired sections",
+	}
+}
+
+//refreshExpired sends q without the expired assertions ok option to the recursive resolver such
+//that the expired sections in the cache get replaced. The answer is only added to the cache.
+func (s *Server) refreshExpired(q *query.Name) {
+	options := []query.Option{}
+	for _, opt := range q.Options {
+		if opt != query.QOExpiredAssertionsOk {
+			options = append(options, opt)
+		}
+	}
+	refresh := &query.Name{
+		Context:    q.Context,
+		Name:       q.Name,
+		Types:      q.Types,
+		Expiration: time.Now().Add(s.config.QueryValidity).Unix(),
+		Options:    options,
+		KeyPhase:   q.KeyPhase,
+	}
+	log.Info("Refreshing expired cached answer", "query", refresh)
+	s.sendToRecursiveResolver(message.Message{Token: token.New(),
+		Content: []section.Section{refresh}})
+}
+
+//filterAnswer returns the sections which are sent as an answer to q where subject is q's subject
+//name and sections are the cached shards, pshards and zones whose range contains subject. All
+//sections are returned if minimize is false. Otherwise, the smallest section is returned where
+//shards and zones containing an assertion answering q are preferred over sections proving that no
+//such assertion exists. Among the latter pshards are preferred over shards and shards over zones.
+func filterAnswer(q *query.Name, subject string, sections []section.WithSigForward,
+	minimize bool) []section.WithSigForward {
+	if !minimize || len(sections) == 0 {
+		return sections
+	}
+	positive := []section.WithSigForward{}
+	negative := []section.WithSigForward{}
+	for _, sec := range sections {
+		if containsAnswer(sec, subject, q.Types) {
+			positive = append(positive, sec)
+		} else if provesNonexistence(sec, q) {
+			negative = append(negative, sec)
+		}
+	}
+	candidates := positive
+	if len(candidates) == 0 {
+		candidates = negative
+	}
+	if len(candidates) == 0 {
 		return nil
 	}
-	answer, _ := s.caches.NegAssertionCache.Get(zone, q.Context, section.StringInterval{Name: subject})
-	return filterAnswer(answer)
+	sizes := make(map[section.WithSigForward]int)
+	for _, sec := range candidates {
+		sizes[sec] = encodedSize(sec)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		ri, rj := answerRank(candidates[i]), answerRank(candidates[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return sizes[candidates[i]] < sizes[candidates[j]]
+	})
+	return candidates[:1]
 }
 
-func filterAnswer(sections []section.WithSigForward) (answer []section.Section) {
-	//TODO CFE For each type check if one of the zone or shards contain the queried
-	//assertion. If there is at least one assertion answer with it. If no assertion is
-	//contained in a zone or shard for any of the queried connection, answer with the shortest
-	//element. shortest according to what? size in bytes? how to efficiently determine that.
-	//e.g. using gob encoding. alternatively we could also count the number of contained
-	//elements.
-	for _, s := range sections {
-		answer = append(answer, s)
+//containsAnswer returns true if sec is a shard or zone containing an assertion about subject with
+//an object of one of the given types.
+func containsAnswer(sec section.WithSigForward, subject string, types []object.Type) bool {
+	for _, a := range sectionContent(sec) {
+		if a.SubjectName != subject {
+			continue
+		}
+		for _, o := range a.Content {
+			if containsType(types, o.Type) {
+				return true
+			}
+		}
 	}
-	return
+	return false
+}
+
+//provesNonexistence returns true if sec shows that there is no assertion answering q. A shard or
+//zone is a proof as it contains all assertions in its range while a pshard is only a proof if its
+//bloom filter does not contain any of the queried types.
+func provesNonexistence(sec section.WithSigForward, q *query.Name) bool {
+	if pshard, ok := sec.(*section.Pshard); ok {
+		isNonexistent, err := pshard.IsNonexistent(q)
+		return err == nil && isNonexistent
+	}
+	return true
+}
+
+//answerRank returns the preference of sec's type as an answer. Lower is better.
+func answerRank(sec section.WithSigForward) int {
+	switch sec.(type) {
+	case *section.Pshard:
+		return 0
+	case *section.Shard:
+		return 1
+	default:
+		return 2
+	}
+}
+
+//encodedSize returns the number of bytes of sec's cbor encoding.
+func encodedSize(sec section.WithSigForward) int {
+	encoding := new(bytes.Buffer)
+	if err := sec.MarshalCBOR(cbor.NewCBORWriter(encoding)); err != nil {
+		log.Warn("Was not able to encode section", "section", sec, "error", err)
+		return math.MaxInt32
+	}
+	return encoding.Len()
 }
 
 //glueRecordNames returns the unique names for which glue records should be looked up based on qs.
-//It assumes that the names of all delegates do not contain a dot '.'.
+//A name maps to true if all queries for it accept expired assertions. It assumes that the names of
+//all delegates do not contain a dot '.'.
 func glueRecordNames(qs []*query.Name, zoneAuths []ZoneContext) map[ZoneContext]bool {
 	result := make(map[ZoneContext]bool)
 	for _, q := range qs {
@@ -208,101 +509,118 @@ func glueRecordNames(qs []*query.Name, zoneAuths []ZoneContext) map[ZoneContext]
 				} else { //root zone
 					name = names[len(names)-1] + auth.Zone
 				}
-				result[ZoneContext{name, q.Context}] = true
+				expiredOk, ok := result[ZoneContext{name, q.Context}]
+				result[ZoneContext{name, q.Context}] = (expiredOk || !ok) &&
+					q.ContainsOption(query.QOExpiredAssertionsOk)
 			}
 		}
 	}
 	return result
 }
 
-func (s *Server) glueRecordLookup(name, context string, cache cache.Assertion) ([]section.Section, error) {
+//glueRecordLookup returns the servable delegation assertions of name and the redirect and address
+//assertions through which the delegate can be reached. Expired assertions are only returned if
+//expiredOk is set, in which case the returned bool is true if one of the assertions has expired.
+func (s *Server) glueRecordLookup(name, context string, expiredOk bool, cache cache.Assertion) (
+	[]section.Section, bool, error) {
 	var assertions []section.Section
-	asserts, ok := cache.Get(name, context, object.OTDelegation, true)
-	if !ok {
-		return nil, errors.New("no delegation assertion found")
+	asserts, expired := s.servableAssertions(name, context, object.OTDelegation, expiredOk, cache)
+	if len(asserts) == 0 {
+		return nil, false, errors.New("no delegation assertion found")
 	}
 	for _, a := range asserts {
 		assertions = append(assertions, a) //append delegations
 	}
 
 	//Follow redirect and get all assertions along the way
-	asserts, ok = cache.Get(name, context, object.OTRedirection, true) //returns cached redirect assertions in random order
-	if !ok {
-		return nil, errors.New("no redirect assertion found")
+	//returns cached redirect assertions in random order
+	asserts, redirExpired := s.servableAssertions(name, context, object.OTRedirection, expiredOk,
+		cache)
+	if len(asserts) == 0 {
+		return nil, false, errors.New("no redirect assertion found")
 	}
 	for _, a := range asserts {
 		for _, o := range a.Content {
 			if o.Type == object.OTRedirection {
-				if answers, err := s.handleRedirect(o.Value.(string), context, cache,
-					libresolve.AllowedRedirectTypes); err == nil {
+				if answers, addrExpired, err := s.handleRedirect(o.Value.(string), context,
+					expiredOk, cache, libresolve.AllowedRedirectTypes); err == nil {
 					assertions = append(assertions, a) //append redir
 					for _, answer := range answers {
 						assertions = append(assertions, answer) //append addr, and if necessary srv and/or names.
 					}
-					return assertions, nil
+					return assertions, expired || redirExpired || addrExpired, nil
 				}
 			}
 		}
 	}
-	return nil, errors.New("no redir ended in a host addr")
+	return nil, false, errors.New("no redir ended in a host addr")
 }
 
-func (s *Server) handleRedirect(name, context string, cache cache.Assertion, allowedTypes map[object.Type]bool) ([]*section.Assertion, error) {
-	if allowedTypes[object.OTIP6Addr] {
-		if asserts, ok := cache.Get(name, context, object.OTIP6Addr, true); ok {
-			return asserts, nil
+//servableAssertions returns the assertions of name, context and objType in cache which can be used
+//to answer a query. The returned bool is true if one of them has expired.
+func (s *Server) servableAssertions(name, context string, objType object.Type, expiredOk bool,
+	cache cache.Assertion) ([]*section.Assertion, bool) {
+	cached, _ := cache.Get(name, context, objType, true)
+	var asserts []*section.Assertion
+	expired := false
+	for _, a := range cached {
+		if servable, isExpired := s.isServable(a, expiredOk); servable {
+			asserts = append(asserts, a)
+			expired = expired || isExpired
 		}
 	}
-	if allowedTypes[object.OTIP4Addr] {
-		if asserts, ok := cache.Get(name, context, object.OTIP4Addr, true); ok {
-			return asserts, nil
-		}
-	}
-	if allowedTypes[object.OTScionAddr6] {
-		if asserts, ok := cache.Get(name, context, object.OTScionAddr6, true); ok {
-			return asserts, nil
+	return asserts, expired
+}
+
+//handleRedirect returns the servable assertions through which name can be reached, following
+//service info and name assertions. The returned bool is true if one of them has expired.
+func (s *Server) handleRedirect(name, context string, expiredOk bool, cache cache.Assertion,
+	allowedTypes map[object.Type]bool) ([]*section.Assertion, bool, error) {
+	for _, t := range []object.Type{object.OTIP6Addr, object.OTIP4Addr, object.OTScionAddr6,
+		object.OTScionAddr4} {
+		if !allowedTypes[t] {
+			continue
 		}
-	}
-	if allowedTypes[object.OTScionAddr4] {
-		if asserts, ok := cache.Get(name, context, object.OTScionAddr4, true); ok {
-			return asserts, nil
+		if asserts, expired := s.servableAssertions(name, context, t, expiredOk,
+			cache); len(asserts) > 0 {
+			return asserts, expired, nil
 		}
 	}
 	if allowedTypes[object.OTServiceInfo] && strings.HasPrefix(name, rainsSrvPrefix) {
-		if asserts, ok := cache.Get(name, context, object.OTServiceInfo, true); ok {
-			for _, srv := range asserts {
-				for _, srvObj := range srv.Content {
-					if srvObj.Type == object.OTServiceInfo {
-						srvVal := srvObj.Value.(object.ServiceInfo)
-						if as, err := s.handleRedirect(srvVal.Name, context, cache,
-							libresolve.AllowedAddrTypes); err == nil {
-							return append(as, srv), nil
-						}
+		asserts, _ := s.servableAssertions(name, context, object.OTServiceInfo, expiredOk, cache)
+		for _, srv := range asserts {
+			for _, srvObj := range srv.Content {
+				if srvObj.Type == object.OTServiceInfo {
+					srvVal := srvObj.Value.(object.ServiceInfo)
+					if as, addrExpired, err := s.handleRedirect(srvVal.Name, context, expiredOk,
+						cache, libresolve.AllowedAddrTypes); err == nil {
+						_, expired := s.isServable(srv, expiredOk)
+						return append(as, srv), expired || addrExpired, nil
 					}
 				}
 			}
 		}
 	}
 	if allowedTypes[object.OTName] {
-		if asserts, ok := cache.Get(name, context, object.OTName, true); ok {
-			for _, name := range asserts {
-				for _, nameObj := range name.Content {
-					if nameObj.Type == object.OTName {
-						nameVal := nameObj.Value.(object.Name)
-						allowTypes := make(map[object.Type]bool)
-						for _, t := range nameVal.Types {
-							allowTypes[t] = true
-						}
-						if as, err := s.handleRedirect(nameVal.Name, context, cache,
-							allowTypes); err == nil {
-							return append(as, name), nil
-						}
+		asserts, _ := s.servableAssertions(name, context, object.OTName, expiredOk, cache)
+		for _, name := range asserts {
+			for _, nameObj := range name.Content {
+				if nameObj.Type == object.OTName {
+					nameVal := nameObj.Value.(object.Name)
+					allowTypes := make(map[object.Type]bool)
+					for _, t := range nameVal.Types {
+						allowTypes[t] = true
+					}
+					if as, addrExpired, err := s.handleRedirect(nameVal.Name, context, expiredOk,
+						cache, allowTypes); err == nil {
+						_, expired := s.isServable(name, expiredOk)
+						return append(as, name), expired || addrExpired, nil
 					}
 				}
 			}
 		}
 	}
-	return nil, fmt.Errorf("redir name did not end in a host addr. redirName=%s", name)
+	return nil, false, fmt.Errorf("redir name did not end in a host addr. redirName=%s", name)
 }
 
 // toSubjectZone splits a name into a subject and zone.
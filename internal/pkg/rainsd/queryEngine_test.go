@@ -0,0 +1,249 @@
+package rainsd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/libresolve"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/query"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestStaleAnswers(t *testing.T) {
+	s := &Server{
+		config: Config{StaleGracePeriod: time.Hour},
+		caches: &Caches{AssertionsCache: cache.NewAssertion(10)},
+	}
+	now := time.Now()
+	var tests = []struct {
+		name       string
+		validUntil time.Time
+		expiredOk  bool
+		servable   bool
+		expired    bool
+	}{
+		{"valid", now.Add(time.Hour), false, true, false},
+		{"valid and expired ok", now.Add(time.Hour), true, true, false},
+		{"expired", now.Add(-time.Minute), false, false, true},
+		{"expired within grace period", now.Add(-time.Minute), true, true, true},
+		{"expired beyond grace period", now.Add(-2 * time.Hour), true, false, true},
+	}
+	for i, test := range tests {
+		a := ipAssertion(fmt.Sprintf("host%d", i), "ethz.ch.", "192.0.2.1")
+		a.SetValidUntil(test.validUntil.Unix())
+		s.caches.AssertionsCache.Add(a, cacheExpiration(a, s.config.StaleGracePeriod), false)
+		if servable, expired := s.isServable(a, test.expiredOk); servable != test.servable ||
+			expired != test.expired {
+			t.Errorf("%s: wrong servability. expected=(%t,%t) actual=(%t,%t)", test.name,
+				test.servable, test.expired, servable, expired)
+		}
+		q := &query.Name{Name: a.FQDN(), Context: ".", Types: []object.Type{object.OTIP4Addr}}
+		if test.expiredOk {
+			q.Options = []query.Option{query.QOExpiredAssertionsOk}
+		}
+		assertions, expired := assertionCacheLookup(q, s)
+		if test.servable && (len(assertions) != 1 || assertions[0].(*section.Assertion) != a ||
+			expired != test.expired) {
+			t.Errorf("%s: wrong answer. expected=%v expired=%t actual=%v expired=%t", test.name,
+				a, test.expired, assertions, expired)
+		}
+		if !test.servable && len(assertions) != 0 {
+			t.Errorf("%s: unservable assertion in answer %v", test.name, assertions)
+		}
+	}
+
+	//Glue records are filtered in the same way.
+	var glueTests = []struct {
+		name         string
+		expiredDeleg bool
+		expiredAddr  bool
+		expiredOk    bool
+		found        bool
+		expired      bool
+	}{
+		{"valid glue", false, false, false, true, false},
+		{"expired delegation", true, false, false, false, false},
+		{"expired address", false, true, false, false, false},
+		{"expired delegation and expired ok", true, false, true, true, true},
+		{"expired address and expired ok", false, true, true, true, true},
+	}
+	for _, test := range glueTests {
+		s.caches.AssertionsCache = cache.NewAssertion(10)
+		deleg := &section.Assertion{SubjectName: "sub", SubjectZone: "ethz.ch.", Context: ".",
+			Content: []object.Object{{Type: object.OTDelegation, Value: keys.PublicKey{
+				PublicKeyID: keys.PublicKeyID{Algorithm: algorithmTypes.Ed25519},
+				Key:         ed25519.PublicKey([]byte("TestKey")),
+			}}}}
+		redir := &section.Assertion{SubjectName: "sub", SubjectZone: "ethz.ch.", Context: ".",
+			Content: []object.Object{{Type: object.OTRedirection, Value: "ns.ethz.ch."}}}
+		addr := ipAssertion("ns", "ethz.ch.", "192.0.2.1")
+		for _, a := range []*section.Assertion{deleg, redir, addr} {
+			a.SetValidUntil(now.Add(time.Hour).Unix())
+		}
+		if test.expiredDeleg {
+			deleg.SetValidUntil(now.Add(-time.Minute).Unix())
+		}
+		if test.expiredAddr {
+			addr.SetValidUntil(now.Add(-time.Minute).Unix())
+		}
+		for _, a := range []*section.Assertion{deleg, redir, addr} {
+			s.caches.AssertionsCache.Add(a, cacheExpiration(a, s.config.StaleGracePeriod), false)
+		}
+		sections, expired, err := s.glueRecordLookup("sub.ethz.ch.", ".", test.expiredOk,
+			s.caches.AssertionsCache)
+		if (err == nil) != test.found || expired != test.expired {
+			t.Errorf("%s: wrong glue records. expected found=%t expired=%t actual=%v expired=%t "+
+				"error=%v", test.name, test.found, test.expired, sections, expired, err)
+		}
+		if test.found && len(sections) != 3 {
+			t.Errorf("%s: wrong number of glue records. expected=3 actual=%d", test.name,
+				len(sections))
+		}
+	}
+}
+
+func TestAnswerQueriesIntermediary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "intermediary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, client := newDatagramServer(t, 0)
+	defer s.udpConn.Close()
+	defer client.Close()
+	upstream, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	s.resolver, err = libresolve.New(nil, []net.Addr{upstream.LocalAddr()},
+		rootDelegation(t, dir, "root"), libresolve.Forward, s.udpConn.LocalAddr(), 10,
+		util.MaxCacheValidity{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.config.IntermediaryFor = []ZoneContext{{Zone: "ethz.ch.", Context: "."}}
+	s.config.QueryValidity = time.Minute
+	s.caches.AssertionsCache = cache.NewAssertion(10)
+	s.caches.NegAssertionCache = cache.NewNegAssertion(10)
+	s.caches.PendingQueries = cache.NewPendingQuery(10)
+	a := ipAssertion("www", "ethz.ch.", "192.0.2.1")
+	a.SetValidUntil(time.Now().Add(time.Hour).Unix())
+	s.caches.AssertionsCache.Add(a, a.ValidUntil(), false)
+
+	//Queries about a served zone are answered on behalf of its authority.
+	q := nameQueryMsg("www.ethz.ch.")
+	s.processQuery(util.MsgSectionSender{Sender: client.LocalAddr(), Sections: q.Content,
+		Token: q.Token})
+	answer := readDatagram(t, client)
+	if answered, ok := answer.Content[0].(*section.Assertion); answer.Token != q.Token ||
+		len(answer.Content) != 1 || !ok || answered.Hash() != a.Hash() {
+		t.Errorf("query about served zone was not answered. actual=%v", answer)
+	}
+
+	//Delegation queries about other zones are forwarded.
+	q = nameQueryMsg("example.com.")
+	q.Content[0].(*query.Name).Types = []object.Type{object.OTDelegation}
+	s.processQuery(util.MsgSectionSender{Sender: client.LocalAddr(), Sections: q.Content,
+		Token: q.Token})
+	forwarded := readDatagram(t, upstream)
+	if fq, ok := forwarded.Content[0].(*query.Name); len(forwarded.Content) != 1 || !ok ||
+		fq.Name != "example.com." {
+		t.Errorf("delegation query was not forwarded. actual=%v", forwarded)
+	}
+	if s.caches.PendingQueries.Len() != 1 {
+		t.Error("forwarded delegation query is not pending")
+	}
+
+	//All other queries are dropped and the querier is notified.
+	q = nameQueryMsg("www.example.com.")
+	s.processQuery(util.MsgSectionSender{Sender: client.LocalAddr(), Sections: q.Content,
+		Token: q.Token})
+	answer = readDatagram(t, client)
+	if n, ok := answer.Content[0].(*section.Notification); len(answer.Content) != 1 || !ok ||
+		n.Type != section.NTNoAssertionAvail || n.Token != q.Token {
+		t.Errorf("querier was not notified about unserved name. actual=%v", answer)
+	}
+}
+
+func TestAnswerAddressQueries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "addressQueries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, client := newDatagramServer(t, 0)
+	defer s.udpConn.Close()
+	defer client.Close()
+	//A recursive resolver does not look up address queries.
+	s.resolver, err = libresolve.New(nil, nil, rootDelegation(t, dir, "root"),
+		libresolve.Recursive, s.udpConn.LocalAddr(), 10, util.MaxCacheValidity{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.config.QueryValidity = time.Minute
+	s.caches.AddressCache = cache.NewAddress(10)
+	s.caches.PendingQueries = cache.NewPendingQuery(10)
+	cached, _ := address.Parse("192.0.2.1")
+	a := &section.AddressAssertion{SubjectAddr: cached, Context: ".",
+		Content: []object.Object{{Type: object.OTName, Value: object.Name{Name: "www.ethz.ch.",
+			Types: []object.Type{object.OTIP4Addr}}}}}
+	a.SetValidUntil(time.Now().Add(time.Hour).Unix())
+	s.caches.AddressCache.AddAssertion(a, a.ValidUntil(), false)
+	var tests = []struct {
+		name         string
+		addr         string
+		options      []query.Option
+		authorities  []ZoneContext
+		answered     bool
+		notification section.NotificationType
+	}{
+		{"cached answer", "192.0.2.1", nil, nil, true, 0},
+		{"resolver without reverse lookups", "192.0.2.2", nil, nil, false,
+			section.NTServerNotCapable},
+		{"cached answers only", "192.0.2.2", []query.Option{query.QOCachedAnswersOnly}, nil,
+			false, section.NTNoAssertionAvail},
+		{"authoritative server", "192.0.2.2", nil, []ZoneContext{{Zone: "ethz.ch.",
+			Context: "."}}, false, section.NTNoAssertionAvail},
+	}
+	for _, test := range tests {
+		s.config.Authorities = test.authorities
+		prefix, _ := address.Parse(test.addr)
+		q := &query.Address{SubjectAddr: prefix, Context: ".", Types: []object.Type{object.OTName},
+			Expiration: time.Now().Add(time.Minute).Unix(), Options: test.options}
+		tok := token.New()
+		answerAddressQueries([]*query.Address{q}, util.MsgSectionSender{Sender: client.LocalAddr(),
+			Sections: []section.Section{q}, Token: tok}, s)
+		answer := readDatagram(t, client)
+		if len(answer.Content) != 1 {
+			t.Errorf("%s: wrong answer. actual=%v", test.name, answer)
+			continue
+		}
+		if test.answered {
+			if aa, ok := answer.Content[0].(*section.AddressAssertion); answer.Token != tok ||
+				!ok || aa.Hash() != a.Hash() {
+				t.Errorf("%s: query was not answered from the cache. actual=%v", test.name,
+					answer)
+			}
+		} else if n, ok := answer.Content[0].(*section.Notification); !ok ||
+			n.Type != test.notification || n.Token != tok {
+			t.Errorf("%s: wrong notification. expected=%v actual=%v", test.name,
+				test.notification, answer)
+		}
+	}
+	if s.caches.PendingQueries.Len() != 0 {
+		t.Error("address query without an upstream is pending")
+	}
+}
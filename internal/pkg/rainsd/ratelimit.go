@@ -0,0 +1,409 @@
+package rainsd
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/query"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+	"github.com/scionproto/scion/go/lib/snet"
+)
+
+const (
+	//rateLimitNotifyInterval is the minimal time between two NTRateLimited notifications sent to
+	//the same client.
+	rateLimitNotifyInterval = time.Second
+	//rateLimitCleanupInterval is the time between two removals of idle token buckets.
+	rateLimitCleanupInterval = time.Minute
+	//responseRateIPv4PrefixLen and responseRateIPv6PrefixLen are the lengths of the prefixes whose
+	//hosts share a response rate limit.
+	responseRateIPv4PrefixLen = 24
+	responseRateIPv6PrefixLen = 56
+)
+
+//tokenBucket holds the state of a single client of a rateLimiter.
+type tokenBucket struct {
+	tokens       float64
+	last         time.Time
+	lastNotified time.Time
+	suppressed   int //number of consecutively rejected messages
+}
+
+/*
+ * rateLimiter is a token bucket rate limiter with one bucket per client. Each bucket is refilled
+ * with rate tokens per second up to burst tokens. Buckets of idle clients are removed periodically.
+ * All methods can be called on a nil rateLimiter in which case nothing is limited.
+ */
+type rateLimiter struct {
+	rate        float64
+	burst       float64
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+	//mux protects all fields from simultaneous access.
+	mux sync.Mutex
+}
+
+//newRateLimiter returns a rate limiter allowing each client rate messages per second and bursts of
+//up to burst messages. It returns nil if rate is not positive.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(burst)
+	if b < 1 {
+		b = 1
+	}
+	return &rateLimiter{
+		rate:        rate,
+		burst:       b,
+		buckets:     make(map[string]*tokenBucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+//bucket returns the refilled bucket of key. It must be called with l.mux locked.
+func (l *rateLimiter) bucket(key string, now time.Time) *tokenBucket {
+	if now.Sub(l.lastCleanup) > rateLimitCleanupInterval {
+		for k, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastCleanup = now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+		return b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	return b
+}
+
+//allow returns true and takes n tokens from the bucket of key if it contains enough of them. A
+//message costing more than the burst size requires a full bucket.
+func (l *rateLimiter) allow(key string, n int) bool {
+	if l == nil {
+		return true
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	b := l.bucket(key, time.Now())
+	cost := float64(n)
+	if cost > l.burst {
+		cost = l.burst
+	}
+	if b.tokens < cost {
+		b.suppressed++
+		return false
+	}
+	b.tokens -= cost
+	b.suppressed = 0
+	return true
+}
+
+//shouldNotify returns true if the client key has not been notified about being throttled during
+//the last rateLimitNotifyInterval.
+func (l *rateLimiter) shouldNotify(key string) bool {
+	if l == nil {
+		return false
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	now := time.Now()
+	b := l.bucket(key, now)
+	if now.Sub(b.lastNotified) < rateLimitNotifyInterval {
+		return false
+	}
+	b.lastNotified = now
+	return true
+}
+
+//slip returns true if the number of consecutively rejected messages of key is a multiple of
+//every. It always returns false if every is not positive.
+func (l *rateLimiter) slip(key string, every int) bool {
+	if l == nil || every <= 0 {
+		return false
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	b, ok := l.buckets[key]
+	return ok && b.suppressed > 0 && b.suppressed%every == 0
+}
+
+//responseRateKey returns the key of the response rate limit bucket of addr. Hosts in the same
+//network prefix share a bucket such that an attacker cannot circumvent the limit by spoofing
+//neighboring addresses.
+func responseRateKey(addr net.Addr) string {
+	var ip net.IP
+	prefix := ""
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		ip = addr.IP
+	case *net.UDPAddr:
+		ip = addr.IP
+	case *snet.Addr:
+		prefix = addr.IA.String() + ","
+		if addr.Host != nil && addr.Host.L3 != nil {
+			ip = addr.Host.L3.IP()
+		}
+	}
+	if ip == nil {
+		return peerKey(addr)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return prefix + ip4.Mask(net.CIDRMask(responseRateIPv4PrefixLen, 32)).String()
+	}
+	return prefix + ip.Mask(net.CIDRMask(responseRateIPv6PrefixLen, 128)).String()
+}
+
+//isThrottled returns true if msg exceeds the query or assertion rate limit of sender. Each query
+//costs one token, all sections of a message together cost one token. Messages answering a query
+//of this server are never throttled. A throttled sender is notified at most once per
+//rateLimitNotifyInterval.
+func (s *Server) isThrottled(msg *message.Message, sender net.Addr) bool {
+	if s.queryLimiter == nil && s.assertionLimiter == nil {
+		return false
+	}
+	if s.caches.PendingKeys.ContainsToken(msg.Token) ||
+		s.caches.PendingQueries.ContainsToken(msg.Token) {
+		return false
+	}
+	queries, sections := 0, 0
+	for _, sec := range msg.Content {
+		switch sec.(type) {
+		case *query.Name, *query.Address:
+			queries++
+		case *section.Assertion, *section.Shard, *section.Pshard, *section.Zone,
+			*section.AddressAssertion, *section.AddressZone:
+			sections = 1
+		}
+	}
+	key := peerKey(sender)
+	limiter := s.queryLimiter
+	allowed := true
+	if queries > 0 {
+		allowed = s.queryLimiter.allow(key, queries)
+		if !allowed {
+			atomic.AddUint64(&s.metrics.throttledQueries, 1)
+		}
+	}
+	if allowed && sections > 0 {
+		limiter = s.assertionLimiter
+		allowed = s.assertionLimiter.allow(key, sections)
+		if !allowed {
+			atomic.AddUint64(&s.metrics.throttledSections, 1)
+		}
+	}
+	if allowed {
+		return false
+	}
+	log.Debug("Throttled message", "sender", sender, "token", msg.Token)
+	if limiter.shouldNotify(key) {
+		sendNotificationMsg(msg.Token, sender, section.NTRateLimited, "", s)
+	}
+	return true
+}
+
+//limitResponse applies the response rate limit to msg sent to receiver. It returns false if msg
+//must be dropped. Every ResponseRateLimitSlip-th suppressed response is replaced by a
+//NTRateLimited notification such that legitimate clients learn why they do not get an answer.
+//Messages containing a query are not responses and therefore never limited.
+func (s *Server) limitResponse(msg *message.Message, receiver net.Addr) bool {
+	if s.responseLimiter == nil {
+		return true
+	}
+	for _, sec := range msg.Content {
+		switch sec.(type) {
+		case *query.Name, *query.Address:
+			return true
+		}
+	}
+	key := responseRateKey(receiver)
+	if s.responseLimiter.allow(key, 1) {
+		return true
+	}
+	atomic.AddUint64(&s.metrics.suppressedResponses, 1)
+	if !s.responseLimiter.slip(key, s.config.ResponseRateLimitSlip) {
+		log.Debug("Suppressed response", "receiver", receiver, "token", msg.Token)
+		return false
+	}
+	tok := msg.Token
+	if len(msg.Content) > 0 {
+		if n, ok := msg.Content[0].(*section.Notification); ok {
+			tok = n.Token
+		}
+	}
+	msg.Content = []section.Section{&section.Notification{Type: section.NTRateLimited, Token: tok}}
+	s.metrics.notificationSent(section.NTRateLimited)
+	return true
+}
+
+/*
+ * fairQueue buffers the messages of the normal input queue per sender and hands them out round
+ * robin such that a single sender cannot starve all others. Each sender can have at most
+ * maxPerSender queued messages. When all maxTotal slots are occupied, the oldest message of the
+ * sender with the most queued messages is dropped. All methods can be called on a nil fairQueue.
+ */
+type fairQueue struct {
+	maxPerSender int
+	maxTotal     int
+	queues       map[string][]util.MsgSectionSender
+	senders      []string //senders with a non empty queue in round robin order
+	len          int
+	lastNotified map[string]time.Time
+	//mux protects all fields from simultaneous access.
+	mux sync.Mutex
+}
+
+//newFairQueue returns a fair queue with the given limits. It returns nil if maxPerSender is not
+//positive.
+func newFairQueue(maxPerSender, maxTotal int) *fairQueue {
+	if maxPerSender <= 0 {
+		return nil
+	}
+	if maxTotal < 1 {
+		maxTotal = 1
+	}
+	return &fairQueue{
+		maxPerSender: maxPerSender,
+		maxTotal:     maxTotal,
+		queues:       make(map[string][]util.MsgSectionSender),
+		lastNotified: make(map[string]time.Time),
+	}
+}
+
+//push appends msg to the queue of sender key. It returns false if msg was dropped because key has
+//already maxPerSender messages queued. The second return value is true if another message was
+//dropped to make room for msg.
+func (q *fairQueue) push(key string, msg util.MsgSectionSender) (pushed, evicted bool) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if len(q.queues[key]) >= q.maxPerSender {
+		return false, false
+	}
+	if q.len >= q.maxTotal {
+		longest := key
+		for k, msgs := range q.queues {
+			if len(msgs) > len(q.queues[longest]) {
+				longest = k
+			}
+		}
+		if len(q.queues[longest]) == 0 {
+			return false, false
+		}
+		log.Debug("Dropped message of sender with most queued messages", "sender",
+			q.queues[longest][0].Sender, "token", q.queues[longest][0].Token)
+		q.remove(longest)
+		evicted = true
+	}
+	if len(q.queues[key]) == 0 {
+		q.senders = append(q.senders, key)
+	}
+	q.queues[key] = append(q.queues[key], msg)
+	q.len++
+	return true, evicted
+}
+
+//remove drops the oldest message of sender key. It must be called with q.mux locked.
+func (q *fairQueue) remove(key string) {
+	q.queues[key] = q.queues[key][1:]
+	q.len--
+	if len(q.queues[key]) == 0 {
+		delete(q.queues, key)
+		for i, k := range q.senders {
+			if k == key {
+				q.senders = append(q.senders[:i], q.senders[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+//pop returns the oldest message of the next sender in round robin order and true, or false if the
+//queue is empty.
+func (q *fairQueue) pop() (util.MsgSectionSender, bool) {
+	if q == nil {
+		return util.MsgSectionSender{}, false
+	}
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if len(q.senders) == 0 {
+		return util.MsgSectionSender{}, false
+	}
+	key := q.senders[0]
+	msg := q.queues[key][0]
+	q.remove(key)
+	if len(q.queues[key]) > 0 {
+		q.senders = append(q.senders[1:], key)
+	}
+	return msg, true
+}
+
+//shouldNotify returns true if sender key has not been notified about a dropped message during the
+//last rateLimitNotifyInterval.
+func (q *fairQueue) shouldNotify(key string) bool {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	now := time.Now()
+	if now.Sub(q.lastNotified[key]) < rateLimitNotifyInterval {
+		return false
+	}
+	if len(q.lastNotified) >= q.maxTotal {
+		for k, t := range q.lastNotified {
+			if now.Sub(t) >= rateLimitNotifyInterval {
+				delete(q.lastNotified, k)
+			}
+		}
+	}
+	q.lastNotified[key] = now
+	return true
+}
+
+//Len returns the number of queued messages.
+func (q *fairQueue) Len() int {
+	if q == nil {
+		return 0
+	}
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return q.len
+}
+
+//pushNormal adds mss to the normal input queue. If fair queuing is enabled and the sender already
+//has the maximum number of messages queued, mss is dropped and the sender is notified at most once
+//per rateLimitNotifyInterval.
+func (s *Server) pushNormal(mss util.MsgSectionSender) {
+	if s.fairQueue == nil {
+		s.enqueue(s.queues.Normal, mss)
+		return
+	}
+	key := peerKey(mss.Sender)
+	atomic.AddInt64(&s.inFlight, 1)
+	pushed, evicted := s.fairQueue.push(key, mss)
+	if evicted {
+		atomic.AddInt64(&s.inFlight, -1)
+	}
+	if !pushed {
+		atomic.AddInt64(&s.inFlight, -1)
+		atomic.AddUint64(&s.metrics.droppedQueued, 1)
+		log.Debug("Sender exceeded its share of the normal queue", "sender", mss.Sender,
+			"token", mss.Token)
+		if !s.fairQueue.shouldNotify(key) {
+			return
+		}
+		sendNotificationMsg(mss.Token, mss.Sender, section.NTRateLimited,
+			fmt.Sprintf("more than %d messages queued", s.fairQueue.maxPerSender), s)
+	}
+}
@@ -0,0 +1,141 @@
+package rainsd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+	"github.com/scionproto/scion/go/lib/snet"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if l := newRateLimiter(0, 5); l != nil || !l.allow("a", 10) || l.shouldNotify("a") {
+		t.Error("disabled rate limiter limits")
+	}
+	l := newRateLimiter(1, 2)
+	if !l.allow("a", 1) || !l.allow("a", 1) {
+		t.Error("messages within burst were rejected")
+	}
+	if l.allow("a", 1) {
+		t.Error("message exceeding burst was allowed")
+	}
+	if !l.allow("b", 1) {
+		t.Error("clients share a bucket")
+	}
+	l.buckets["a"].last = time.Now().Add(-time.Second)
+	if !l.allow("a", 1) || l.allow("a", 1) {
+		t.Error("bucket was not refilled with rate tokens per second")
+	}
+	l.buckets["a"].last = time.Now().Add(-time.Hour)
+	if !l.allow("a", 5) || l.allow("a", 1) {
+		t.Error("message costing more than burst does not require exactly a full bucket")
+	}
+	if !l.shouldNotify("a") || l.shouldNotify("a") {
+		t.Error("client was not notified exactly once per interval")
+	}
+	l.buckets["b"].last = time.Now().Add(-time.Hour)
+	l.lastCleanup = time.Now().Add(-2 * rateLimitCleanupInterval)
+	l.allow("a", 1)
+	if _, ok := l.buckets["b"]; ok {
+		t.Error("bucket of idle client was not removed")
+	}
+	if _, ok := l.buckets["a"]; !ok {
+		t.Error("bucket of active client was removed")
+	}
+}
+
+func TestRateLimiterSlip(t *testing.T) {
+	l := newRateLimiter(1, 1)
+	l.allow("a", 1)
+	if l.slip("a", 2) {
+		t.Error("slip without rejected messages")
+	}
+	for i, want := range []bool{false, true, false, true} {
+		l.allow("a", 1)
+		if l.slip("a", 2) != want {
+			t.Errorf("%d: wrong slip after rejection. expected=%t", i, want)
+		}
+	}
+	if l.slip("a", 0) || l.slip("unknown", 1) {
+		t.Error("slip although disabled or client is unknown")
+	}
+	l.buckets["a"].last = time.Now().Add(-time.Hour)
+	l.allow("a", 1)
+	if l.slip("a", 1) {
+		t.Error("rejection counter was not reset by an allowed message")
+	}
+}
+
+func TestResponseRateKey(t *testing.T) {
+	scionAddr, err := snet.AddrFromString("1-ff00:0:110,[192.0.2.17]:5022")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		addr net.Addr
+		key  string
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("192.0.2.17"), Port: 5022}, "192.0.2.0"},
+		{&net.UDPAddr{IP: net.ParseIP("192.0.2.255"), Port: 53}, "192.0.2.0"},
+		{&net.TCPAddr{IP: net.ParseIP("192.0.3.1")}, "192.0.3.0"},
+		{&net.UDPAddr{IP: net.ParseIP("2001:db8:1:2ff:3::1")}, "2001:db8:1:200::"},
+		{&net.TCPAddr{IP: net.ParseIP("2001:db8:1:300::1")}, "2001:db8:1:300::"},
+		{scionAddr, "1-ff00:0:110,192.0.2.0"},
+		{&net.UnixAddr{Name: "/tmp/rains", Net: "unix"}, "/tmp/rains"},
+	}
+	for _, test := range tests {
+		if key := responseRateKey(test.addr); key != test.key {
+			t.Errorf("%v: wrong key. expected=%s actual=%s", test.addr, test.key, key)
+		}
+	}
+}
+
+func TestFairQueue(t *testing.T) {
+	if q := newFairQueue(0, 10); q != nil || q.Len() != 0 {
+		t.Error("disabled fair queue is not empty")
+	}
+	msg := func(sender string) util.MsgSectionSender {
+		return util.MsgSectionSender{Sender: &net.TCPAddr{IP: net.ParseIP(sender)}, Token: token.New()}
+	}
+	q := newFairQueue(2, 4)
+	a1, a2, a3, b1, c1 := msg("192.0.2.1"), msg("192.0.2.1"), msg("192.0.2.1"), msg("192.0.2.2"),
+		msg("192.0.2.3")
+	for _, m := range []util.MsgSectionSender{a1, a2, b1} {
+		if pushed, evicted := q.push(peerKey(m.Sender), m); !pushed || evicted {
+			t.Errorf("message of %v was not pushed", m.Sender)
+		}
+	}
+	if pushed, _ := q.push(peerKey(a3.Sender), a3); pushed {
+		t.Error("sender exceeded its share of the queue")
+	}
+	if q.Len() != 3 {
+		t.Errorf("wrong queue length. expected=3 actual=%d", q.Len())
+	}
+	//round robin order
+	for i, want := range []util.MsgSectionSender{a1, b1, a2} {
+		if m, ok := q.pop(); !ok || m.Token != want.Token {
+			t.Errorf("%d: wrong message popped. expected=%v actual=%v", i, want, m)
+		}
+	}
+	if _, ok := q.pop(); ok || q.Len() != 0 {
+		t.Error("message popped from empty queue")
+	}
+	//eviction of the oldest message of the sender with the most queued messages
+	q = newFairQueue(3, 3)
+	for _, m := range []util.MsgSectionSender{a1, a2, b1} {
+		q.push(peerKey(m.Sender), m)
+	}
+	if pushed, evicted := q.push(peerKey(c1.Sender), c1); !pushed || !evicted {
+		t.Error("no message was evicted from the full queue")
+	}
+	for i, want := range []util.MsgSectionSender{a2, b1, c1} {
+		if m, ok := q.pop(); !ok || m.Token != want.Token {
+			t.Errorf("%d: wrong message popped after eviction. expected=%v actual=%v", i, want, m)
+		}
+	}
+	if !q.shouldNotify("a") || q.shouldNotify("a") || !q.shouldNotify("b") {
+		t.Error("sender was not notified exactly once per interval")
+	}
+}
@@ -0,0 +1,150 @@
+package rainsd
+
+import (
+	"crypto/tls"
+	"errors"
+	"reflect"
+	"sort"
+
+	log "github.com/inconshreveable/log15"
+)
+
+//liveConfigFields contains the names of all Config fields which Reload applies to a running server.
+//Changes to all other fields only take effect after a restart.
+var liveConfigFields = map[string]bool{
+	"RootZonePublicKeyPath":          true,
+	"AssertionCheckPointInterval":    true,
+	"NegAssertionCheckPointInterval": true,
+	"ZoneKeyCheckPointInterval":      true,
+	"LogLevel":                       true,
+	"MaxConnections":                 true,
+	"TLSCertificateFile":             true,
+	"TLSPrivateKeyFile":              true,
+	"CapabilitiesCacheSize":          true,
+	"ZoneKeyCacheSize":               true,
+	"ZoneKeyCacheWarnSize":           true,
+	"MaxPublicKeysPerZone":           true,
+	"PendingKeyCacheSize":            true,
+	"ReapZoneKeyCacheInterval":       true,
+	"ReapPendingKeyCacheInterval":    true,
+	"AssertionCacheSize":             true,
+	"NegativeAssertionCacheSize":     true,
+	"AddressCacheSize":               true,
+	"PendingQueryCacheSize":          true,
+	"Authorities":                    true,
+	"IntermediaryFor":                true,
+	"ReapAssertionCacheInterval":     true,
+	"ReapNegAssertionCacheInterval":  true,
+	"ReapPendingQCacheInterval":      true,
+}
+
+//SetConfigLoader sets the function which returns the configuration the server is set to when it is
+//reloaded.
+func (s *Server) SetConfigLoader(loader func() (Config, error)) {
+	s.configLoader = loader
+}
+
+//ReloadConfig obtains a new configuration from the server's config loader and applies it with
+//Reload.
+func (s *Server) ReloadConfig() ([]string, error) {
+	if s.configLoader == nil {
+		return nil, errors.New("no config loader is set")
+	}
+	config, err := s.configLoader()
+	if err != nil {
+		return nil, err
+	}
+	return s.Reload(config)
+}
+
+//Reload applies config to the running server. The TLS certificate and private key as well as the
+//root zone public key are reloaded from disk. The new root zone public key replaces the old one in
+//the zone key cache and in the delegations of the resolver. The log level, the served zones, the reap and check
+//point intervals and the cache sizes are changed in place without discarding cached entries. It
+//returns the names of all changed settings which only take effect after a restart. If the TLS
+//certificate or the log level is invalid, nothing is applied. If the root zone public key cannot be
+//loaded, all other settings have been applied.
+func (s *Server) Reload(config Config) ([]string, error) {
+	pool, cert, err := loadTLSCertificate(config.TLSCertificateFile, config.TLSPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if config.LogLevel != "" {
+		if _, err := log.LvlFromString(config.LogLevel); err != nil {
+			return nil, err
+		}
+	}
+	restart := restartRequired(s.Config(), config)
+	s.configMutex.Lock()
+	s.certPool, s.tlsCert = pool, cert
+	s.authority = make(map[ZoneContext]bool)
+	for _, auth := range config.Authorities {
+		s.authority[auth] = true
+	}
+	updated := reflect.ValueOf(config)
+	old := reflect.ValueOf(&s.config).Elem()
+	for name := range liveConfigFields {
+		old.FieldByName(name).Set(updated.FieldByName(name))
+	}
+	s.configMutex.Unlock()
+	if config.LogLevel != "" {
+		setLogLevel(config.LogLevel)
+	}
+	resizeCaches(s.caches, config)
+	if err := loadRootZonePublicKey(config.RootZonePublicKeyPath, s.caches.ZoneKeyCache,
+		config.MaxCacheValidity); err != nil {
+		return restart, err
+	}
+	if s.resolver != nil {
+		if err := s.resolver.LoadRootKey(config.RootZonePublicKeyPath); err != nil {
+			return restart, err
+		}
+	}
+	log.Info("Configuration reloaded", "restartRequired", restart)
+	return restart, nil
+}
+
+//restartRequired returns the sorted names of all fields which differ between old and updated and
+//cannot be applied to a running server. Empty and nil slices and maps are considered equal.
+func restartRequired(old, updated Config) []string {
+	names := []string{}
+	o, n := reflect.ValueOf(old), reflect.ValueOf(updated)
+	for i := 0; i < o.NumField(); i++ {
+		name := o.Type().Field(i).Name
+		if liveConfigFields[name] {
+			continue
+		}
+		if !configValueEqual(o.Field(i), n.Field(i)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+//configValueEqual returns true if a and b are deeply equal or both are empty slices or maps.
+func configValueEqual(a, b reflect.Value) bool {
+	if (a.Kind() == reflect.Slice || a.Kind() == reflect.Map) && a.Len() == 0 && b.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+//getCertificate returns the server's current TLS certificate.
+func (s *Server) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.configMutex.RLock()
+	defer s.configMutex.RUnlock()
+	cert := s.tlsCert
+	return &cert, nil
+}
+
+//setLogLevel sets the level of the root logger which writes to stdout. It returns an error if level
+//is not a valid log level.
+func setLogLevel(level string) error {
+	lvl, err := log.LvlFromString(level)
+	if err != nil {
+		return err
+	}
+	log.Root().SetHandler(log.LvlFilterHandler(lvl, log.CallerFileHandler(log.StdoutHandler)))
+	return nil
+}
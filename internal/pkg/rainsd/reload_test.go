@@ -0,0 +1,129 @@
+package rainsd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+)
+
+//tlsCertificate generates a self signed certificate and its private key in dir and returns the
+//paths to them.
+func tlsCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privKey, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	if err := ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE",
+		Bytes: cert}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY",
+		Bytes: privKey}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certPath, keyPath := tlsCertificate(t, dir)
+	config := Config{
+		RootZonePublicKeyPath: rootDelegation(t, dir, "root"),
+		TLSCertificateFile:    certPath,
+		TLSPrivateKeyFile:     keyPath,
+		MaxConnections:        10,
+		MaxMessageSize:        1000,
+		CapabilitiesCacheSize: 10,
+		ZoneKeyCacheSize:      10,
+		ZoneKeyCacheWarnSize:  10,
+		MaxPublicKeysPerZone:  10,
+		PendingKeyCacheSize:   10,
+		PendingQueryCacheSize: 10,
+		AssertionCacheSize:    10,
+		AddressCacheSize:      10,
+		MaxCacheValidity:      util.MaxCacheValidity{AssertionValidity: time.Hour},
+	}
+	authority := ZoneContext{Zone: "ethz.ch.", Context: "."}
+	var tests = []struct {
+		name    string
+		update  func(c *Config)
+		restart []string
+		err     bool
+	}{
+		{"live fields", func(c *Config) {
+			c.AssertionCacheSize = 20
+			c.Authorities = []ZoneContext{authority}
+		}, []string{}, false},
+		{"restart-only field", func(c *Config) { c.MaxMessageSize = 2000 },
+			[]string{"MaxMessageSize"}, false},
+		{"invalid TLS certificate", func(c *Config) {
+			c.AssertionCacheSize = 20
+			c.TLSCertificateFile = filepath.Join(dir, "missing.crt")
+		}, nil, true},
+		{"invalid log level", func(c *Config) {
+			c.AssertionCacheSize = 20
+			c.LogLevel = "verbose"
+		}, nil, true},
+	}
+	for _, test := range tests {
+		m := newMetrics()
+		s := &Server{config: config, caches: meteredCaches(m), authority: map[ZoneContext]bool{}}
+		s.caches.ConnCache = cache.NewConnection(10)
+		s.caches.Capabilities = cache.NewCapability(10)
+		updated := config
+		test.update(&updated)
+		restart, err := s.Reload(updated)
+		if (err != nil) != test.err {
+			t.Errorf("%s: unexpected error. expected=%v actual=%v", test.name, test.err, err)
+		}
+		if !reflect.DeepEqual(restart, test.restart) {
+			t.Errorf("%s: wrong restart fields. expected=%v actual=%v", test.name, test.restart,
+				restart)
+		}
+		//Restart-only fields and rejected configurations leave the running configuration untouched.
+		expected := config
+		if !test.err {
+			expected = updated
+			expected.MaxMessageSize = config.MaxMessageSize
+		}
+		if !reflect.DeepEqual(s.Config(), expected) {
+			t.Errorf("%s: wrong configuration. expected=%v actual=%v", test.name, expected,
+				s.Config())
+		}
+		if s.authority[authority] != (len(expected.Authorities) != 0) {
+			t.Errorf("%s: authorities were not applied. actual=%v", test.name, s.authority)
+		}
+	}
+}
@@ -4,19 +4,26 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	log "github.com/inconshreveable/log15"
 	"github.com/netsec-ethz/rains/internal/pkg/connection"
+	"github.com/netsec-ethz/rains/internal/pkg/keyManager"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
 	"github.com/netsec-ethz/rains/internal/pkg/libresolve"
+	"github.com/netsec-ethz/rains/internal/pkg/lruCache"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
 	"github.com/netsec-ethz/rains/internal/pkg/util"
 	"github.com/scionproto/scion/go/lib/snet"
 )
 
 const (
-	nofReapers       = 3
-	nofCheckPointers = 3
-	noListeners      = 1
-	shutdownChannels = nofReapers + nofCheckPointers + noListeners
+	//drainPollInterval is the time between two checks whether the server is drained during a
+	//graceful shutdown.
+	drainPollInterval = 10 * time.Millisecond
 )
 
 //Server represents a rainsd server instance.
@@ -25,6 +32,12 @@ type Server struct {
 	resolver *libresolve.Resolver
 	//config contains configurations of this server
 	config Config
+	//configMutex protects the fields of config which can be changed by Reload, authority, certPool
+	//and tlsCert from simultaneous access.
+	configMutex sync.RWMutex
+	//configLoader returns the configuration to which the server is set on a reload, or is nil if
+	//the server cannot be reloaded.
+	configLoader func() (Config, error)
 	//authority states the names over which this server has authority
 	authority map[ZoneContext]bool
 	//certPool stores received certificates
@@ -35,14 +48,59 @@ type Server struct {
 	capabilityHash string
 	//capabilityList contains the string representation of this server's capability list.
 	capabilityList string
-	//shutdown can be used to stop the go routines handling the input channels and closes them.
-	shutdown chan bool
+	//shutdown is closed to stop all go routines handling the input channels, the reapers, the
+	//check pointers and the heartbeats.
+	shutdown     chan bool
+	shutdownOnce sync.Once
 	//queues store the incoming sections and keeps track of how many go routines are working on it.
 	queues InputQueues
 	//caches contains all caches of this server
 	caches *Caches
 	//scionConn is the server UDP socket if we are in that mode, or nil otherwise.
 	scionConn snet.Conn
+	//udpConn is the server UDP socket if we are in plain UDP mode, or nil otherwise.
+	udpConn *net.UDPConn
+	//tcpListener accepts incoming TCP connections if we are in that mode, or nil otherwise.
+	tcpListener net.Listener
+	//draining is set to 1 when a graceful shutdown has started. It must be accessed atomically.
+	draining int32
+	//inFlight is the number of messages which are queued or handled by a worker. It must be
+	//accessed atomically.
+	inFlight int64
+	//infraPrivateKeys are used to sign outgoing messages. It is empty if messages are not signed.
+	infraPrivateKeys map[keys.PublicKeyID]interface{}
+	//infraKeyNames maps the peerKey of a peer to the name of its infrastructure key assertion.
+	infraKeyNames map[string]string
+	//pendingInfraKeys holds back signed messages from peers whose infrastructure key is being
+	//looked up.
+	pendingInfraKeys *pendingInfraKeys
+	//blacklist contains zones and peers from which no traffic is accepted
+	blacklist *blacklist
+	//queryLimiter and assertionLimiter limit the rate of queries and pushed sections per client.
+	//They are nil if the corresponding limit is disabled.
+	queryLimiter     *rateLimiter
+	assertionLimiter *rateLimiter
+	//responseLimiter limits the rate of responses sent over SCION per client network, or is nil if
+	//response rate limiting is disabled.
+	responseLimiter *rateLimiter
+	//peerMessageLimits maps the peerKey of a peer to the peerMessageLimit it announced.
+	peerMessageLimits *lruCache.Cache
+	//fairQueue buffers the messages of the normal queue per sender, or is nil if fair queuing is
+	//disabled.
+	fairQueue *fairQueue
+	//controlListener accepts connections on the control socket if one is configured, or nil
+	//otherwise.
+	controlListener net.Listener
+	//listenerMutex protects the listeners which are opened by the listening go routines and closed
+	//on shutdown.
+	listenerMutex sync.Mutex
+	//checkpointMutex serializes the writes of check point files.
+	checkpointMutex sync.Mutex
+	//metrics collects operational counters of this server
+	metrics *metrics
+	//metricsServer serves the metrics over http if a metrics address is configured, or nil
+	//otherwise.
+	metricsServer *http.Server
 }
 
 //New returns a pointer to a newly created rainsd server instance with the given config. The server
@@ -50,6 +108,11 @@ type Server struct {
 func New(config Config, id string) (server *Server, err error) {
 	log.Info("server New", "id", id)
 	server = &Server{config: config}
+	if config.LogLevel != "" {
+		if err = setLogLevel(config.LogLevel); err != nil {
+			return nil, err
+		}
+	}
 	server.authority = make(map[ZoneContext]bool)
 	for _, auth := range server.config.Authorities {
 		server.authority[auth] = true
@@ -58,9 +121,29 @@ func New(config Config, id string) (server *Server, err error) {
 		server.config.TLSPrivateKeyFile); err != nil {
 		return nil, err
 	}
-	server.capabilityHash, server.capabilityList = initOwnCapabilities(server.config.Capabilities)
+	server.capabilityHash, server.capabilityList = initOwnCapabilities(
+		transportCapabilities(server.config))
+	if server.config.InfraKeyPath != "" {
+		if server.infraPrivateKeys, err = keyManager.LoadPrivateKeys(server.config.InfraKeyPath); err != nil {
+			log.Warn("Failed to load infrastructure keys")
+			return nil, err
+		}
+	}
+	server.infraKeyNames = make(map[string]string)
+	for peer, name := range server.config.InfraKeyNames {
+		key, err := parsePeer(peer)
+		if err != nil {
+			return nil, err
+		}
+		server.infraKeyNames[key] = name
+	}
+	server.pendingInfraKeys = newPendingInfraKeys(maxPendingInfraKeyMsgs)
+	if server.blacklist, err = newBlacklist(server.config.ZoneBlacklist,
+		server.config.PeerBlacklist); err != nil {
+		return nil, err
+	}
 
-	server.shutdown = make(chan bool, shutdownChannels)
+	server.shutdown = make(chan bool)
 	server.queues = InputQueues{
 		Prio:    make(chan util.MsgSectionSender, server.config.PrioBufferSize),
 		Normal:  make(chan util.MsgSectionSender, server.config.NormalBufferSize),
@@ -69,11 +152,23 @@ func New(config Config, id string) (server *Server, err error) {
 		NormalW: make(chan struct{}, server.config.NormalWorkerCount),
 		NotifyW: make(chan struct{}, server.config.NotificationWorkerCount),
 	}
+	server.queryLimiter = newRateLimiter(server.config.QueryRateLimit, server.config.QueryRateBurst)
+	server.assertionLimiter = newRateLimiter(server.config.AssertionRateLimit,
+		server.config.AssertionRateBurst)
+	server.responseLimiter = newRateLimiter(server.config.ResponseRateLimit,
+		server.config.ResponseRateBurst)
+	server.peerMessageLimits = lruCache.New()
+	server.fairQueue = newFairQueue(server.config.MaxQueuedPerSender, server.config.NormalBufferSize)
 	log.Debug("Created server channels")
-	server.caches = initCaches(server.config)
+	server.metrics = newMetrics()
+	if server.caches, err = initCaches(server.config); err != nil {
+		return nil, err
+	}
+	instrumentCaches(server.caches, server.metrics)
 	if err = loadRootZonePublicKey(server.config.RootZonePublicKeyPath, server.caches.ZoneKeyCache,
 		server.config.MaxCacheValidity); err != nil {
 		log.Warn("Failed to load root zone public key")
+		closeStores(server.caches)
 		return nil, err
 	}
 
@@ -86,7 +181,10 @@ func (s *Server) Addr() net.Addr {
 	return s.config.ServerAddress.Addr
 }
 
+//Config returns the server's current configuration.
 func (s *Server) Config() Config {
+	s.configMutex.RLock()
+	defer s.configMutex.RUnlock()
 	return s.config
 }
 
@@ -97,33 +195,77 @@ func (s *Server) SetResolver(resolver *libresolve.Resolver) {
 
 //Start starts up the server and it begins to listen for incoming connections according to its
 //config.
-func (s *Server) Start(monitorResources bool, id string) error {
+func (s *Server) Start(id string) error {
 	go s.workPrio()
 	go s.workBoth()
 	go s.workNotification()
 	log.Debug("Goroutines working on input queue started")
-	initReapers(s.config, s.caches, s.shutdown)
+	initReapers(s)
+	initHeartbeats(s)
 	if s.config.PreLoadCaches {
-		loadCaches(s.config.CheckPointPath, s.caches, s.config.Authorities)
+		loadCaches(s.config, s.caches, s.servedZones())
 		log.Info("Caches loaded from checkpoint",
 			"assertions", s.caches.AssertionsCache.Len(),
 			"negAssertions", s.caches.NegAssertionCache.Len(),
 			"zoneKey", s.caches.ZoneKeyCache.Len())
 	}
-	initStoreCachesContent(s.config, s.caches, s.shutdown)
+	initStoreCachesContent(s)
 	log.Info("Reapers and Checkpointing started")
-	if monitorResources {
-		go measureSystemRessources()
-	}
+	s.listenMetrics()
+	s.listenControl()
 	s.listen(id)
 	return nil
 }
 
+//GracefulShutdown stops accepting new connections and queries and waits at most deadline for the
+//input queues and the pending queries to drain. The senders of queries which are still pending
+//afterwards are notified with NTUnspecServerErr. It then writes a final checkpoint of the caches
+//and shuts the server down.
+func (s *Server) GracefulShutdown(deadline time.Duration) {
+	log.Info("Start graceful shutdown", "deadline", deadline)
+	atomic.StoreInt32(&s.draining, 1)
+	s.listenerMutex.Lock()
+	if s.tcpListener != nil {
+		s.tcpListener.Close()
+	}
+	s.listenerMutex.Unlock()
+	end := time.Now().Add(deadline)
+	for !s.isDrained() && time.Now().Before(end) {
+		time.Sleep(drainPollInterval)
+	}
+	if !s.isDrained() {
+		log.Warn("Shutdown deadline passed before server was drained",
+			"prioQueue", len(s.queues.Prio), "normalQueue", len(s.queues.Normal)+s.fairQueue.Len(),
+			"notifyQueue", len(s.queues.Notify), "pendingQueries", s.caches.PendingQueries.Len())
+	}
+	for _, ss := range s.caches.PendingQueries.GetAndRemoveAll() {
+		sendNotificationMsg(ss.Token, ss.Sender, section.NTUnspecServerErr, "", s)
+	}
+	s.checkpointCaches()
+	log.Info("Final checkpoint written", "path", s.config.CheckPointPath)
+	s.Shutdown()
+}
+
+//isDraining returns true if a graceful shutdown has started.
+func (s *Server) isDraining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
+//isDrained returns true if no message is queued or being handled and there are no pending queries.
+func (s *Server) isDrained() bool {
+	return atomic.LoadInt64(&s.inFlight) == 0 && s.caches.PendingQueries.Len() == 0
+}
+
 //Shutdown closes the input channels and stops the function creating new go routines to handle the
 //input. Already running worker go routines will finish eventually.
 func (s *Server) Shutdown() {
-	for i := 0; i < shutdownChannels; i++ {
-		s.shutdown <- true
+	closed := false
+	s.shutdownOnce.Do(func() {
+		close(s.shutdown)
+		closed = true
+	})
+	if !closed {
+		return
 	}
 
 	// Unblock the switchboard listener to get the shutdown message delivered
@@ -134,13 +276,26 @@ func (s *Server) Shutdown() {
 		}
 	case connection.SCION:
 		s.scionConn.Close()
+	case connection.UDP:
+		if s.udpConn != nil {
+			s.udpConn.Close()
+		}
 	default:
 		log.Warn("Unsupported Network address type.")
 	}
 
+	s.listenerMutex.Lock()
+	if s.controlListener != nil {
+		s.controlListener.Close()
+	}
+	s.listenerMutex.Unlock()
+	if s.metricsServer != nil {
+		s.metricsServer.Close()
+	}
 	s.caches.ConnCache.CloseAndRemoveAllConnections()
 	s.queues.Normal <- util.MsgSectionSender{}
 	s.queues.Prio <- util.MsgSectionSender{}
 	s.queues.Notify <- util.MsgSectionSender{}
+	closeStores(s.caches)
 	log.Info("Server shut down")
 }
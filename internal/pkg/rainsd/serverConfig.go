@@ -17,15 +17,31 @@ type Config struct {
 	NegAssertionCheckPointInterval time.Duration //in seconds
 	ZoneKeyCheckPointInterval      time.Duration //in seconds
 	CheckPointPath                 string
+	CheckPointEncoding             CheckPointEncoding
+	CheckPointGenerations          int //number of previous check points kept
 	PreLoadCaches                  bool
+	ControlSocket                  string
+	MetricsAddr                    string        //empty if metrics are not served
+	ShutdownDeadline               time.Duration //in seconds
+	LogLevel                       string        //empty if the log handler is not changed
 
 	//switchboard
-	ServerAddress      connection.Info
-	MaxConnections     int
-	KeepAlivePeriod    time.Duration //in seconds
-	TCPTimeout         time.Duration //in seconds
-	TLSCertificateFile string
-	TLSPrivateKeyFile  string
+	ServerAddress          connection.Info
+	MaxConnections         int
+	MaxMessageSize         int           //in bytes, 0 if unlimited
+	KeepAlivePeriod        time.Duration //in seconds
+	TCPTimeout             time.Duration //in seconds
+	TLSCertificateFile     string
+	TLSPrivateKeyFile      string
+	PeerBlacklist          []string
+	InfraKeyPath           string            //empty if outgoing messages are not signed
+	InfraKeyNames          map[string]string //peer address -> name of its infrastructure key
+	RejectUnsignedMessages bool
+	HeartbeatInterval      time.Duration //in seconds, 0 if no heartbeats are sent
+	MaxMissedHeartbeats    int
+	ResponseRateLimit      float64 //responses/s per client network over SCION or UDP, 0 if unlimited
+	ResponseRateBurst      int
+	ResponseRateLimitSlip  int //every nth suppressed response is replaced by a notification, 0 never
 
 	// SCION specific settings
 	DispatcherSock string
@@ -40,6 +56,12 @@ type Config struct {
 	NotificationWorkerCount int
 	CapabilitiesCacheSize   int
 	Capabilities            []message.Capability
+	ZoneBlacklist           []string
+	QueryRateLimit          float64 //queries per second and client, 0 if unlimited
+	QueryRateBurst          int
+	AssertionRateLimit      float64 //section messages per second and client, 0 if unlimited
+	AssertionRateBurst      int
+	MaxQueuedPerSender      int //0 if the normal queue is not shared fairly among senders
 
 	//verify
 	ZoneKeyCacheSize            int
@@ -47,19 +69,29 @@ type Config struct {
 	MaxPublicKeysPerZone        int
 	PendingKeyCacheSize         int
 	DelegationQueryValidity     time.Duration //in seconds
+	DelegationQueryRetries      int
+	BackupServers               []connection.Info
 	ReapZoneKeyCacheInterval    time.Duration //in seconds
 	ReapPendingKeyCacheInterval time.Duration //in seconds
 
 	//engine
 	AssertionCacheSize            int
 	NegativeAssertionCacheSize    int
+	AddressCacheSize              int
+	AssertionStorePath            string //empty if assertions are only kept in memory
 	PendingQueryCacheSize         int
+	AnswerCoalescingDeadline      time.Duration //in seconds
 	QueryValidity                 time.Duration //in seconds
 	Authorities                   []ZoneContext
+	IntermediaryFor               []ZoneContext
 	MaxCacheValidity              util.MaxCacheValidity //in hours
 	ReapAssertionCacheInterval    time.Duration         //in seconds
 	ReapNegAssertionCacheInterval time.Duration         //in seconds
 	ReapPendingQCacheInterval     time.Duration         //in seconds
+	InconsistencyPolicy           InconsistencyPolicy
+	StaleGracePeriod              time.Duration //in seconds
+	MinLastHopAnswerSize          bool          //minimize negative answers of all queries
+	EnforceNameset                bool          //drop sections violating their zone's nameset
 }
 
 //DefaultConfig return the default configuration for the zone publisher.
@@ -71,18 +103,34 @@ func DefaultConfig() Config {
 		NegAssertionCheckPointInterval: time.Hour,
 		ZoneKeyCheckPointInterval:      30 * time.Minute,
 		CheckPointPath:                 "data/checkpoint/resolver/",
+		CheckPointEncoding:             GobCheckPoint,
+		CheckPointGenerations:          2,
 		PreLoadCaches:                  false,
+		ControlSocket:                  "",
+		MetricsAddr:                    "",
+		ShutdownDeadline:               10 * time.Second,
+		LogLevel:                       "info",
 
 		//switchboard
 		ServerAddress: connection.Info{
 			Type: connection.TCP,
 			Addr: serverAddr,
 		},
-		MaxConnections:     10000,
-		KeepAlivePeriod:    time.Minute,
-		TCPTimeout:         5 * time.Minute,
-		TLSCertificateFile: "data/cert/server.crt",
-		TLSPrivateKeyFile:  "data/cert/server.key",
+		MaxConnections:         10000,
+		MaxMessageSize:         0,
+		KeepAlivePeriod:        time.Minute,
+		TCPTimeout:             5 * time.Minute,
+		TLSCertificateFile:     "data/cert/server.crt",
+		TLSPrivateKeyFile:      "data/cert/server.key",
+		PeerBlacklist:          []string{},
+		InfraKeyPath:           "",
+		InfraKeyNames:          map[string]string{},
+		RejectUnsignedMessages: false,
+		HeartbeatInterval:      0,
+		MaxMissedHeartbeats:    3,
+		ResponseRateLimit:      0,
+		ResponseRateBurst:      20,
+		ResponseRateLimitSlip:  2,
 
 		// SCION specific settings
 		DispatcherSock: "/run/shm/dispatcher/default.sock",
@@ -97,6 +145,12 @@ func DefaultConfig() Config {
 		NotificationWorkerCount: 1,
 		CapabilitiesCacheSize:   10,
 		Capabilities:            []message.Capability{message.Capability("urn:x-rains:tlssrv")},
+		ZoneBlacklist:           []string{},
+		QueryRateLimit:          0,
+		QueryRateBurst:          50,
+		AssertionRateLimit:      0,
+		AssertionRateBurst:      20,
+		MaxQueuedPerSender:      0,
 
 		//verify
 		ZoneKeyCacheSize:            1000,
@@ -104,15 +158,21 @@ func DefaultConfig() Config {
 		MaxPublicKeysPerZone:        5,
 		PendingKeyCacheSize:         100,
 		DelegationQueryValidity:     time.Second,
+		DelegationQueryRetries:      1,
+		BackupServers:               []connection.Info{},
 		ReapZoneKeyCacheInterval:    15 * time.Minute,
 		ReapPendingKeyCacheInterval: 15 * time.Minute,
 
 		//engine
 		AssertionCacheSize:         10000,
 		NegativeAssertionCacheSize: 1000,
+		AddressCacheSize:           1000,
+		AssertionStorePath:         "",
 		PendingQueryCacheSize:      1000,
+		AnswerCoalescingDeadline:   0,
 		QueryValidity:              time.Second,
 		Authorities:                []ZoneContext{},
+		IntermediaryFor:            []ZoneContext{},
 		MaxCacheValidity: util.MaxCacheValidity{
 			AssertionValidity: 3 * time.Hour,
 			ShardValidity:     3 * time.Hour,
@@ -122,5 +182,9 @@ func DefaultConfig() Config {
 		ReapAssertionCacheInterval:    15 * time.Minute,
 		ReapNegAssertionCacheInterval: 15 * time.Minute,
 		ReapPendingQCacheInterval:     15 * time.Minute,
+		InconsistencyPolicy:           EvictConflicting,
+		StaleGracePeriod:              0,
+		MinLastHopAnswerSize:          false,
+		EnforceNameset:                false,
 	}
 }
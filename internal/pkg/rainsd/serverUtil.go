@@ -8,13 +8,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
-	"os"
-	"path"
+	"sort"
 	"strings"
 	"time"
 
 	log "github.com/inconshreveable/log15"
 	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/connection"
 	"github.com/netsec-ethz/rains/internal/pkg/keys"
 	"github.com/netsec-ethz/rains/internal/pkg/message"
 	"github.com/netsec-ethz/rains/internal/pkg/object"
@@ -24,16 +24,12 @@ import (
 	"github.com/netsec-ethz/rains/internal/pkg/util"
 )
 
-const (
-	aCheckPointFileName = "assertionCheckPoint.gob"
-	nCheckPointFileName = "negAssertionCheckPoint.gob"
-	zCheckPointFileName = "zoneKeyCheckPoint.gob"
-)
-
 type missingKeyMetaData struct {
 	Zone     string
 	Context  string
 	KeyPhase int
+	//IsAddress is true if Zone is an address prefix whose delegation is missing.
+	IsAddress bool
 }
 
 //ZoneContext stores a context and a zone
@@ -42,12 +38,6 @@ type ZoneContext struct {
 	Context string
 }
 
-type checkPointValue struct {
-	Sections   []section.Section
-	ValidSince []int64
-	ValidUntil []int64
-}
-
 //sendNotificationMsg sends a message containing freshly generated token and a notification section with
 //notificationType, token, and data to destination.
 func sendNotificationMsg(tok token.Token, destination net.Addr,
@@ -57,6 +47,7 @@ func sendNotificationMsg(tok token.Token, destination net.Addr,
 		Token: tok,
 		Data:  data,
 	}
+	s.metrics.notificationSent(notificationType)
 	sendSection(notification, token.Token{}, destination, s)
 }
 
@@ -82,6 +73,21 @@ func sendCapability(destination net.Addr, capabilities []message.Capability, s *
 	s.sendTo(msg, destination, 1, 1)
 }
 
+//peerCapable returns false if the capability list learned from peer does not contain c. A peer
+//whose capabilities are not known is assumed to be capable.
+func (s *Server) peerCapable(peer net.Addr, c message.Capability) bool {
+	caps, ok := s.caches.ConnCache.GetCapabilityList(peer)
+	if !ok || len(caps) == 0 {
+		return true
+	}
+	for _, capability := range caps {
+		if capability == c {
+			return true
+		}
+	}
+	return false
+}
+
 //LoadConfig loads server configuration
 func LoadConfig(configPath string) (Config, error) {
 	config := Config{}
@@ -97,12 +103,15 @@ func LoadConfig(configPath string) (Config, error) {
 	config.AssertionCheckPointInterval *= time.Second
 	config.NegAssertionCheckPointInterval *= time.Second
 	config.ZoneKeyCheckPointInterval *= time.Second
+	config.ShutdownDeadline *= time.Second
 	config.KeepAlivePeriod *= time.Second
+	config.HeartbeatInterval *= time.Second
 	config.TCPTimeout *= time.Second
 	config.DelegationQueryValidity *= time.Second
 	config.ReapZoneKeyCacheInterval *= time.Second
 	config.ReapPendingKeyCacheInterval *= time.Second
 	config.QueryValidity *= time.Second
+	config.AnswerCoalescingDeadline *= time.Second
 	config.MaxCacheValidity.PshardValidity *= time.Hour
 	config.MaxCacheValidity.AssertionValidity *= time.Hour
 	config.MaxCacheValidity.ShardValidity *= time.Hour
@@ -110,6 +119,7 @@ func LoadConfig(configPath string) (Config, error) {
 	config.ReapAssertionCacheInterval *= time.Second
 	config.ReapNegAssertionCacheInterval *= time.Second
 	config.ReapPendingQCacheInterval *= time.Second
+	config.StaleGracePeriod *= time.Second
 	return config, nil
 }
 
@@ -146,20 +156,34 @@ func loadTLSCertificate(certPath string, TLSPrivateKeyPath string) (*x509.CertPo
 }
 
 //initOwnCapabilities sorts capabilities in lexicographically increasing order.
-//It stores the hex encoded sha256 hash of the sorted capabilities to capabilityHash
-//and a string representation of the capability list to capabilityList
+//It returns the hex encoded sha256 hash of the sorted capabilities and a string representation of
+//the capability list.
 func initOwnCapabilities(capabilities []message.Capability) (string, string) {
-	//TODO CFE when we have CBOR use it to normalize&serialize the array before hashing it.
-	//Currently we use the hard coded version from the draft.
-	capabilityHash := "e5365a09be554ae55b855f15264dbc837b04f5831daeb321359e18cdabab5745"
 	cs := make([]string, len(capabilities))
 	for i, c := range capabilities {
 		cs[i] = string(c)
 	}
-	return capabilityHash, strings.Join(cs, " ")
+	sort.Strings(cs)
+	return message.CapabilityHash(capabilities), strings.Join(cs, " ")
+}
+
+//transportCapabilities returns the configured capabilities extended by the capability of the
+//transport on which the server listens if it is not yet part of them.
+func transportCapabilities(config Config) []message.Capability {
+	if config.ServerAddress.Type != connection.UDP {
+		return config.Capabilities
+	}
+	for _, c := range config.Capabilities {
+		if c == message.UDPSrv {
+			return config.Capabilities
+		}
+	}
+	return append(append([]message.Capability{}, config.Capabilities...), message.UDPSrv)
 }
 
-//loadRootZonePublicKey stores the root zone public key from disk into the zoneKeyCache.
+//loadRootZonePublicKey replaces the root zone public keys in the zoneKeyCache by the ones stored on
+//disk. The cached keys are only replaced if all keys on disk are valid such that a rotated root key
+//is no longer accepted.
 func loadRootZonePublicKey(keyPath string, zoneKeyCache cache.ZonePublicKey,
 	maxValidity util.MaxCacheValidity) error {
 	a := new(section.Assertion)
@@ -169,7 +193,7 @@ func loadRootZonePublicKey(keyPath string, zoneKeyCache cache.ZonePublicKey,
 		return err
 	}
 	log.Info("Content loaded from root zone public key", "a", a)
-	var keysAdded int
+	rootKeys := []keys.PublicKey{}
 	for _, c := range a.Content {
 		if c.Type == object.OTDelegation {
 			if publicKey, ok := c.Value.(keys.PublicKey); ok {
@@ -177,129 +201,46 @@ func loadRootZonePublicKey(keyPath string, zoneKeyCache cache.ZonePublicKey,
 				publicKey.ValidUntil = a.Signatures[0].ValidUntil
 				keyMap := make(map[keys.PublicKeyID][]keys.PublicKey)
 				keyMap[publicKey.PublicKeyID] = []keys.PublicKey{publicKey}
-				if siglib.CheckSectionSignatures(a, keyMap, maxValidity) {
-					if ok := zoneKeyCache.Add(a, publicKey, true); !ok {
-						return errors.New("Cache is smaller than the amount of root public keys")
-					}
-					log.Info("Added root public key to zone key cache.",
-						"context", a.Context,
-						"zone", a.SubjectZone,
-						"RootPublicKey", c.Value,
-					)
-					keysAdded++
-				} else {
+				if !siglib.CheckSectionSignatures(a, keyMap, maxValidity) {
 					return fmt.Errorf("Failed to validate signature for assertion: %v", a)
 				}
+				rootKeys = append(rootKeys, publicKey)
 			} else {
 				log.Warn(fmt.Sprintf("Was not able to cast to keys.PublicKey Got Type:%T", c.Value))
 			}
 		}
 	}
-	log.Info("Keys added to zoneKeyCache", "count", keysAdded)
-	return err
-}
-
-//measureSystemRessources measures current cpu usage
-func measureSystemRessources() {
-	//Not yet implemented
-}
-
-func initStoreCachesContent(config Config, caches *Caches, stop chan bool) {
-	if err := os.MkdirAll(config.CheckPointPath, os.ModePerm); err != nil {
-		log.Error("Was not able to create folders", "error", err)
-	}
-	time.Sleep(100 * time.Millisecond)
-	go repeatFuncCaller(func() {
-		checkpoint(path.Join(config.CheckPointPath, aCheckPointFileName),
-			caches.AssertionsCache.Checkpoint)
-	}, config.AssertionCheckPointInterval, stop)
-	go repeatFuncCaller(func() {
-		checkpoint(path.Join(config.CheckPointPath, nCheckPointFileName),
-			caches.NegAssertionCache.Checkpoint)
-	}, config.NegAssertionCheckPointInterval, stop)
-	go repeatFuncCaller(func() {
-		checkpoint(path.Join(config.CheckPointPath, zCheckPointFileName),
-			caches.ZoneKeyCache.Checkpoint)
-	}, config.ZoneKeyCheckPointInterval, stop)
-}
-
-func checkpoint(path string, values func() []section.Section) {
-	value := checkPointValue{Sections: values()}
-	for _, s := range value.Sections {
-		value.ValidSince = append(value.ValidSince, s.(section.WithSigForward).ValidSince())
-		value.ValidUntil = append(value.ValidUntil, s.(section.WithSigForward).ValidUntil())
+	if len(rootKeys) == 0 {
+		return fmt.Errorf("No root zone public key found in %s", keyPath)
 	}
-	if err := util.Save(path, value); err != nil {
-		log.Error("Was not able to checkpoint cache", "path", path, "error", err)
-	}
-}
-
-func loadCaches(cpPath string, caches *Caches, authorities []ZoneContext) {
-
-	//load assertion check point
-	sections, err := readMsgFromFile(path.Join(cpPath, aCheckPointFileName))
-	if err != nil {
-		log.Warn("Was not able to load assertion check point from file", "error", err)
-	}
-	for _, s := range sections {
-		if s, ok := s.(*section.Assertion); ok {
-			caches.AssertionsCache.Add(s, time.Now().Add(24*time.Hour).Unix(),
-				isAuthoritative(s, authorities))
-		} else {
-			log.Warn("Invalid type for assertion cache", "type", fmt.Sprintf("%T", s))
-		}
-	}
-
-	//load negAssertion check point
-	sections, err = readMsgFromFile(path.Join(cpPath, nCheckPointFileName))
-	if err != nil {
-		log.Warn("Was not able to load negAssertion check point from file", "error", err)
-	}
-	for _, s := range sections {
-		switch s := s.(type) {
-		case *section.Shard:
-			caches.NegAssertionCache.AddShard(s, time.Now().Add(24*time.Hour).Unix(),
-				isAuthoritative(s, authorities))
-		case *section.Pshard:
-			caches.NegAssertionCache.AddPshard(s, time.Now().Add(24*time.Hour).Unix(),
-				isAuthoritative(s, authorities))
-		case *section.Zone:
-			caches.NegAssertionCache.AddZone(s, time.Now().Add(24*time.Hour).Unix(),
-				isAuthoritative(s, authorities))
-		default:
-			log.Warn("Invalid type for negative Assertion cache", "type", fmt.Sprintf("%T", s))
+	zoneKeyCache.RemoveZone(a.FQDN(), a.Context)
+	for _, publicKey := range rootKeys {
+		if ok := zoneKeyCache.Add(a, publicKey, true); !ok {
+			return errors.New("Cache is smaller than the amount of root public keys")
 		}
+		log.Info("Added root public key to zone key cache.",
+			"context", a.Context,
+			"zone", a.SubjectZone,
+			"RootPublicKey", publicKey,
+		)
 	}
+	log.Info("Keys added to zoneKeyCache", "count", len(rootKeys))
+	return nil
+}
 
-	//load zone key check point
-	sections, err = readMsgFromFile(path.Join(cpPath, zCheckPointFileName))
-	if err != nil {
-		log.Warn("Was not able to load zone key check point from file", "error", err)
-	}
-	for _, s := range sections {
-		if s, ok := s.(*section.Assertion); ok {
-			for _, o := range s.Content {
-				if o.Type == object.OTDelegation {
-					caches.ZoneKeyCache.Add(s, o.Value.(keys.PublicKey),
-						isAuthoritative(s, authorities))
-				}
-			}
-		} else {
-			log.Warn("Invalid type for zone key cache", "type", fmt.Sprintf("%T", s))
-		}
-	}
+//servedZones returns the zones and contexts over which s has authority or for which it acts as an
+//intermediary.
+func (s *Server) servedZones() []ZoneContext {
+	authorities, intermediaryFor := s.zones()
+	return append(append([]ZoneContext{}, authorities...), intermediaryFor...)
 }
 
-func readMsgFromFile(path string) ([]section.Section, error) {
-	values := &checkPointValue{}
-	if err := util.Load(path, values); err != nil {
-		return nil, err
-	}
-	for i, s := range values.Sections {
-		s.(section.WithSigForward).SetValidSince(values.ValidSince[i])
-		s.(section.WithSigForward).SetValidUntil(values.ValidUntil[i])
-	}
-	return values.Sections, nil
+//zones returns the zones and contexts over which s has authority and those for which it acts as an
+//intermediary.
+func (s *Server) zones() ([]ZoneContext, []ZoneContext) {
+	s.configMutex.RLock()
+	defer s.configMutex.RUnlock()
+	return s.config.Authorities, s.config.IntermediaryFor
 }
 
 func isAuthoritative(s section.WithSigForward, authorities []ZoneContext) bool {
@@ -313,8 +254,9 @@ func isAuthoritative(s section.WithSigForward, authorities []ZoneContext) bool {
 	return isAuthoritative
 }
 
-//repeatFuncCaller executes function in intervals of waitTime
-func repeatFuncCaller(function func(), waitTime time.Duration, stop chan bool) {
+//repeatFuncCaller executes function in intervals of waitTime. waitTime is called after each
+//execution such that a changed interval takes effect without restarting the caller.
+func repeatFuncCaller(function func(), waitTime func() time.Duration, stop chan bool) {
 	for {
 		select {
 		case <-stop:
@@ -322,6 +264,6 @@ func repeatFuncCaller(function func(), waitTime time.Duration, stop chan bool) {
 		default:
 		}
 		function()
-		time.Sleep(waitTime)
+		time.Sleep(waitTime())
 	}
 }
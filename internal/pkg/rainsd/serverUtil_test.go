@@ -0,0 +1,78 @@
+package rainsd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/keyManager"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/signature"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+)
+
+//rootDelegation generates a root key pair in dir and returns the path to its self signed
+//delegation assertion.
+func rootDelegation(t *testing.T, dir, name string) string {
+	if err := keyManager.GenerateKey(dir, name, "root key", "ed25519", "pwd", 0); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name+".gob")
+	if err := keyManager.SelfSignedDelegation(filepath.Join(dir, name), path, "pwd", ".", ".",
+		time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRootZonePublicKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rootKey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	oldKey, newKey := rootDelegation(t, dir, "old"), rootDelegation(t, dir, "new")
+	corrupted := filepath.Join(dir, "corrupted.gob")
+	if err := ioutil.WriteFile(corrupted, []byte("corrupted"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	maxValidity := util.MaxCacheValidity{AssertionValidity: time.Hour}
+	zoneKeyCache := cache.NewZoneKey(10, 10, 10)
+	sigMetaData := signature.MetaData{
+		PublicKeyID: keys.PublicKeyID{Algorithm: algorithmTypes.Ed25519},
+		ValidSince:  time.Now().Unix(),
+		ValidUntil:  time.Now().Add(time.Minute).Unix(),
+	}
+	for _, test := range []struct {
+		path  string
+		valid bool
+		want  string
+	}{
+		{oldKey, true, oldKey},
+		{newKey, true, newKey},
+		{corrupted, false, newKey},
+	} {
+		if err := loadRootZonePublicKey(test.path, zoneKeyCache, maxValidity); (err == nil) !=
+			test.valid {
+			t.Errorf("%s: wrong error. expected valid=%t actual=%v", test.path, test.valid, err)
+		}
+		want := new(section.Assertion)
+		if err := util.Load(test.want, want); err != nil {
+			t.Fatal(err)
+		}
+		if zoneKeyCache.Len() != 1 {
+			t.Errorf("%s: old root key was not replaced. keys=%d", test.path, zoneKeyCache.Len())
+		}
+		pkey, _, ok := zoneKeyCache.Get(".", ".", sigMetaData)
+		if !ok || !reflect.DeepEqual(pkey.Key, want.Content[0].Value.(keys.PublicKey).Key) {
+			t.Errorf("%s: wrong root key cached. expected=%v actual=%v", test.path,
+				want.Content[0].Value, pkey)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package rainsd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/connection"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shutdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, client := newDatagramServer(t, 0)
+	defer client.Close()
+	s.config.ServerAddress = connection.Info{Type: connection.UDP, Addr: s.udpConn.LocalAddr()}
+	s.config.CheckPointPath = dir
+	s.config.CheckPointEncoding = GobCheckPoint
+	s.config.CheckPointGenerations = 1
+	s.caches.AssertionsCache = cache.NewAssertion(10)
+	s.caches.NegAssertionCache = cache.NewNegAssertion(10)
+	s.caches.ZoneKeyCache = cache.NewZoneKey(10, 10, 10)
+	s.caches.PendingQueries = cache.NewPendingQuery(10)
+	s.queues = InputQueues{
+		Prio:    make(chan util.MsgSectionSender, 1),
+		Normal:  make(chan util.MsgSectionSender, 1),
+		Notify:  make(chan util.MsgSectionSender, 10),
+		NotifyW: make(chan struct{}, 1),
+	}
+	s.shutdown = make(chan bool)
+	if s.tcpListener, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	go s.workNotification()
+	querier := client.LocalAddr()
+	a := checkPointSections()[0]
+	s.caches.AssertionsCache.Add(a.(*section.Assertion), time.Now().Add(time.Hour).Unix(), false)
+
+	//Pushed messages are handled before the server shuts down.
+	for i := 0; i < 10; i++ {
+		s.enqueue(s.queues.Notify, util.MsgSectionSender{Sender: querier, Token: token.New(),
+			Sections: []section.Section{&section.Notification{Type: section.NTStaleAnswer}}})
+	}
+	//A query which is not answered before the deadline is answered with an error.
+	q := nameQueryMsg("www.ethz.ch.")
+	s.caches.PendingQueries.Add(util.MsgSectionSender{Sender: querier, Sections: q.Content,
+		Token: q.Token}, token.New(), time.Now().Add(time.Minute).Unix())
+	s.GracefulShutdown(100 * time.Millisecond)
+
+	if inFlight := atomic.LoadInt64(&s.inFlight); inFlight != 0 {
+		t.Errorf("server was not drained. inFlight=%d", inFlight)
+	}
+	answer := readDatagram(t, client)
+	if n, ok := answer.Content[0].(*section.Notification); len(answer.Content) != 1 || !ok ||
+		n.Type != section.NTUnspecServerErr || n.Token != q.Token {
+		t.Errorf("pending querier was not notified. actual=%v", answer)
+	}
+	if s.caches.PendingQueries.Len() != 0 {
+		t.Error("pending queries were not removed")
+	}
+	sections, err := readCheckpoint(checkPointFile(s.config, aCheckPointFileName), GobCheckPoint, 1)
+	if err != nil || len(sections) != 1 || sections[0].(*section.Assertion).Hash() !=
+		a.(*section.Assertion).Hash() {
+		t.Errorf("final checkpoint was not written. sections=%v err=%v", sections, err)
+	}
+	if conn, err := s.tcpListener.Accept(); err == nil {
+		conn.Close()
+		t.Error("TCP listener was not closed")
+	}
+}
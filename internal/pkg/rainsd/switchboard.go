@@ -10,7 +10,9 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"strconv"
 	"time"
 
 	log "github.com/inconshreveable/log15"
@@ -19,36 +21,44 @@ import (
 	"github.com/netsec-ethz/rains/internal/pkg/connection"
 	"github.com/netsec-ethz/rains/internal/pkg/message"
 	"github.com/netsec-ethz/rains/internal/pkg/query"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
 	"github.com/scionproto/scion/go/lib/snet"
 )
 
 //sendTo sends message to the specified receiver.
 func (s *Server) sendTo(msg message.Message, receiver net.Addr, retries,
 	backoffMilliSeconds int) (err error) {
-	// In any case we add the capabilities of this server to the message.
-	msg.Capabilities = []message.Capability{message.Capability(s.capabilityHash)}
-	// SCION is a special case because it is operating on a connectionless protocol so we
-	// keep the server socket in the Server struct and use that to send.
-	if saddr, ok := receiver.(*snet.Addr); ok {
-		conn := s.scionConn
-		if conn == nil {
-			return errors.New("underlying scion connection was nil")
-		}
-		encoding := new(bytes.Buffer)
-		if err := cbor.NewWriter(encoding).Marshal(&msg); err != nil {
-			return fmt.Errorf("failed to marshal message to conn: %v", err)
-		}
-		if _, err := conn.WriteToSCION(encoding.Bytes(), saddr); err != nil {
-			log.Warn("Was not able to send encoded message")
-			return fmt.Errorf("unable to send encoded message: %v", err)
+	// Unless the message already carries a capability list, we add the hash of this server's
+	// capabilities to the message.
+	if len(msg.Capabilities) == 0 {
+		msg.Capabilities = []message.Capability{message.Capability(s.capabilityHash)}
+	}
+	receiver = s.streamReceiver(receiver)
+	if isDatagramAddr(receiver) && !s.limitResponse(&msg, receiver) {
+		return nil
+	}
+	if parts, ok := s.splitMessage(msg, receiver); ok {
+		for _, part := range parts {
+			if err := s.sendTo(part, receiver, retries, backoffMilliSeconds); err != nil {
+				return err
+			}
 		}
 		return nil
 	}
+	s.signMessage(&msg)
+	// SCION and UDP are special cases because they are operating on a connectionless protocol so
+	// we keep the server socket in the Server struct and use that to send.
+	if isDatagramAddr(receiver) {
+		return s.sendDatagram(msg, receiver)
+	}
 	conns, ok := s.caches.ConnCache.GetConnection(receiver)
 	if !ok {
 		switch receiver.(type) {
 		case *net.TCPAddr:
-			conn, err := createConnection(receiver, s.config.KeepAlivePeriod, s.certPool)
+			s.configMutex.RLock()
+			pool := s.certPool
+			s.configMutex.RUnlock()
+			conn, err := createConnection(receiver, s.config.KeepAlivePeriod, pool)
 			//add connection to cache
 			conns = append(conns, conn)
 			if err != nil {
@@ -62,8 +72,6 @@ func (s *Server) sendTo(msg message.Message, receiver net.Addr, retries,
 			} else {
 				log.Warn("Type assertion failed. Expected *net.TCPAddr", "addr", conn.RemoteAddr())
 			}
-			//add capabilities to message
-			msg.Capabilities = []message.Capability{message.Capability(s.capabilityHash)}
 			conns = []net.Conn{conn}
 		}
 	}
@@ -95,8 +103,11 @@ func (s *Server) sendTo(msg message.Message, receiver net.Addr, retries,
 
 func (s *Server) sendToRecursiveResolver(msg message.Message) {
 	for _, sec := range msg.Content {
-		if q, ok := sec.(*query.Name); ok {
+		switch q := sec.(type) {
+		case *query.Name:
 			go s.resolver.ServerLookup(q, s.Addr(), msg.Token)
+		case *query.Address:
+			go s.resolver.ServerAddressLookup(q, s.Addr(), msg.Token)
 		}
 	}
 }
@@ -127,7 +138,7 @@ func (s *Server) listen(id string) {
 	switch s.config.ServerAddress.Type {
 	case connection.TCP:
 		srvLogger.Info("Start TCP listener")
-		tlsConfig := &tls.Config{Certificates: []tls.Certificate{s.tlsCert}, InsecureSkipVerify: true}
+		tlsConfig := &tls.Config{GetCertificate: s.getCertificate, InsecureSkipVerify: true}
 		listener, err := tls.Listen(s.Addr().Network(),
 			s.config.ServerAddress.Addr.String(), tlsConfig)
 		if err != nil {
@@ -136,6 +147,11 @@ func (s *Server) listen(id string) {
 		}
 		defer listener.Close()
 		defer srvLogger.Info("TCP Shutdown listener")
+		s.metrics.listenerOpened(connection.TCP)
+		defer s.metrics.listenerClosed(connection.TCP)
+		s.listenerMutex.Lock()
+		s.tcpListener = listener
+		s.listenerMutex.Unlock()
 		for {
 			select {
 			case <-s.shutdown:
@@ -146,10 +162,15 @@ func (s *Server) listen(id string) {
 			}
 			conn, err := listener.Accept()
 			if err != nil {
+				if s.isDraining() {
+					srvLogger.Info("Stopped accepting connections for graceful shutdown")
+					return
+				}
 				srvLogger.Error("listener could not accept connection", "error", err)
 				continue
 			}
-			if isIPBlacklisted(conn.RemoteAddr()) {
+			if s.blacklist.IsPeerBlacklisted(conn.RemoteAddr()) {
+				conn.Close()
 				continue
 			}
 			s.caches.ConnCache.AddConnection(conn)
@@ -179,6 +200,8 @@ func (s *Server) listen(id string) {
 		}
 		defer listener.Close()
 		defer srvLogger.Info("SCION Shutdown listener", "id", id)
+		s.metrics.listenerOpened(connection.SCION)
+		defer s.metrics.listenerClosed(connection.SCION)
 		s.scionConn = listener
 		for {
 			select {
@@ -194,26 +217,147 @@ func (s *Server) listen(id string) {
 				log.Warn("Failed to ReadFromSCION", "err", err)
 				continue
 			}
-			data := buf[:n]
-			// Note: We cannot use handleConnection because UDP is connectionless and we have to
-			// manually stick the remote endpoint address in the handler.
-			var msg message.Message
-			if err := cbor.NewReader(bytes.NewReader(data)).Unmarshal(&msg); err != nil {
-				log.Warn("failed to unmarshal CBOR", "err", err)
+			s.handleDatagram(buf[:n], addr)
+		}
+	case connection.UDP:
+		addr, ok := s.config.ServerAddress.Addr.(*net.UDPAddr)
+		if !ok {
+			log.Warn(fmt.Sprintf("Type assertion failed. Expected *net.UDPAddr, got %T", addr))
+			return
+		}
+		listener, err := net.ListenUDP(addr.Network(), addr)
+		if err != nil {
+			srvLogger.Error("Listener error on startup", "error", err)
+			return
+		}
+		srvLogger.Info("Start UDP listener")
+		defer listener.Close()
+		defer srvLogger.Info("UDP Shutdown listener")
+		s.metrics.listenerOpened(connection.UDP)
+		defer s.metrics.listenerClosed(connection.UDP)
+		s.udpConn = listener
+		for {
+			select {
+			case <-s.shutdown:
+				// break out of the loop when receiving shutdown
+				srvLogger.Info("Received shutdown signal from UDP")
+				return
+			default:
+			}
+			buf := make([]byte, connection.MaxUDPPacketBytes)
+			n, addr, err := listener.ReadFromUDP(buf)
+			if err != nil {
+				if s.isDraining() {
+					srvLogger.Info("Stopped reading datagrams for graceful shutdown")
+					return
+				}
+				log.Warn("Failed to ReadFromUDP", "err", err)
 				continue
 			}
-			deliver(&msg, addr,
-				s.queues.Prio, s.queues.Normal, s.queues.Notify, s.caches.PendingKeys)
+			s.handleDatagram(buf[:n], addr)
 		}
 	default:
 		log.Warn("Unsupported Network address type.")
 	}
 }
 
+//handleDatagram decodes the message contained in data and passes it to the inbox along with sender.
+//Each datagram carries exactly one message.
+func (s *Server) handleDatagram(data []byte, sender net.Addr) {
+	if s.blacklist.IsPeerBlacklisted(sender) {
+		return
+	}
+	// Note: We cannot use handleConnection because UDP is connectionless and we have to
+	// manually stick the remote endpoint address in the handler.
+	if s.config.MaxMessageSize > 0 && len(data) > s.config.MaxMessageSize {
+		s.rejectTooLarge(sender)
+		return
+	}
+	var msg message.Message
+	if err := cbor.NewReader(bytes.NewReader(data)).Unmarshal(&msg); err != nil {
+		log.Warn("failed to unmarshal CBOR", "err", err)
+		return
+	}
+	s.deliver(&msg, sender)
+}
+
+//sendDatagram sends msg in a single datagram over the server's SCION or UDP socket. If msg does not
+//fit into a datagram, a NTMsgTooLarge notification is sent instead such that the receiver can
+//retry over a stream transport.
+func (s *Server) sendDatagram(msg message.Message, receiver net.Addr) error {
+	encoding := new(bytes.Buffer)
+	if err := cbor.NewWriter(encoding).Marshal(&msg); err != nil {
+		return fmt.Errorf("failed to marshal message to conn: %v", err)
+	}
+	if encoding.Len() > connection.MaxUDPPacketBytes {
+		log.Warn("Message does not fit into a datagram", "receiver", receiver,
+			"size", encoding.Len())
+		msg = message.Message{
+			Token:        msg.Token,
+			Capabilities: msg.Capabilities,
+			Content: []section.Section{&section.Notification{
+				Type:  section.NTMsgTooLarge,
+				Token: msg.Token,
+				Data:  strconv.Itoa(connection.MaxUDPPacketBytes),
+			}},
+		}
+		s.signMessage(&msg)
+		encoding.Reset()
+		if err := cbor.NewWriter(encoding).Marshal(&msg); err != nil {
+			return fmt.Errorf("failed to marshal message to conn: %v", err)
+		}
+	}
+	var err error
+	switch receiver := receiver.(type) {
+	case *snet.Addr:
+		if s.scionConn == nil {
+			return errors.New("underlying scion connection was nil")
+		}
+		_, err = s.scionConn.WriteToSCION(encoding.Bytes(), receiver)
+	case *net.UDPAddr:
+		if s.udpConn == nil {
+			return errors.New("underlying udp connection was nil")
+		}
+		_, err = s.udpConn.WriteToUDP(encoding.Bytes(), receiver)
+	}
+	if err != nil {
+		log.Warn("Was not able to send encoded message")
+		return fmt.Errorf("unable to send encoded message: %v", err)
+	}
+	return nil
+}
+
+//streamReceiver returns the TCP address of receiver if receiver is a UDP address of a peer which
+//announced over a TCP connection to the same address that it does not listen for UDP datagrams.
+//Otherwise receiver is returned.
+func (s *Server) streamReceiver(receiver net.Addr) net.Addr {
+	addr, ok := receiver.(*net.UDPAddr)
+	if !ok {
+		return receiver
+	}
+	tcpAddr := &net.TCPAddr{IP: addr.IP, Port: addr.Port, Zone: addr.Zone}
+	if s.peerCapable(tcpAddr, message.UDPSrv) {
+		return receiver
+	}
+	log.Debug("Peer does not listen for UDP datagrams, sending over TCP", "receiver", tcpAddr)
+	return tcpAddr
+}
+
+//isDatagramAddr returns true if messages to addr are sent over the server's connectionless socket.
+func isDatagramAddr(addr net.Addr) bool {
+	switch addr.(type) {
+	case *snet.Addr, *net.UDPAddr:
+		return true
+	}
+	return false
+}
+
 //handleConnection deframes all incoming messages on conn and passes them to the inbox along with the dstAddr
 func (s *Server) handleConnection(conn net.Conn, dstAddr net.Addr) {
 	log.Info("New connection", "serverAddr", s.Addr(), "conn", dstAddr)
-	reader := cbor.NewReader(conn)
+	s.metrics.connectionOpened(connection.TCP)
+	defer s.metrics.connectionClosed(connection.TCP)
+	reader := cbor.NewLimitedReader(conn, s.config.MaxMessageSize)
 	for {
 		var msg message.Message
 		select {
@@ -221,22 +365,19 @@ func (s *Server) handleConnection(conn net.Conn, dstAddr net.Addr) {
 			return
 		default:
 		}
-		//FIXME CFE how to check efficiently that message is not too large?
 		if err := reader.Unmarshal(&msg); err != nil {
-			if err.Error() == "failed to read tag: EOF" {
+			if err == io.EOF {
 				log.Info("Connection has been closed", "conn", dstAddr)
+			} else if err == cbor.ErrMessageTooLarge {
+				//The rest of the message cannot be skipped reliably, thus the connection is closed.
+				s.rejectTooLarge(conn.RemoteAddr())
 			} else {
 				log.Warn(fmt.Sprintf("failed to read from client: %v", err))
 			}
 			break
 		}
-		deliver(&msg, conn.RemoteAddr(),
-			s.queues.Prio, s.queues.Normal, s.queues.Notify, s.caches.PendingKeys)
+		s.caches.ConnCache.MarkSeen(conn)
+		s.deliver(&msg, conn.RemoteAddr())
 	}
 	s.caches.ConnCache.CloseAndRemoveConnection(conn)
 }
-
-//isIPBlacklisted returns true if addr is blacklisted
-func isIPBlacklisted(addr net.Addr) bool {
-	return false
-}
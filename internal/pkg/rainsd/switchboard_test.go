@@ -0,0 +1,230 @@
+package rainsd
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/cbor"
+	"github.com/netsec-ethz/rains/internal/pkg/connection"
+	"github.com/netsec-ethz/rains/internal/pkg/lruCache"
+	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/query"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+)
+
+//newDatagramServer returns a server sending over a plain UDP socket and a client socket.
+func newDatagramServer(t *testing.T, maxMessageSize int) (*Server, *net.UDPConn) {
+	srvConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newBlacklist(nil, []string{"192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{
+		config:    Config{MaxMessageSize: maxMessageSize},
+		caches:    &Caches{ConnCache: cache.NewConnection(10), Capabilities: cache.NewCapability(10)},
+		blacklist: b,
+		metrics:   newMetrics(),
+		queues:    InputQueues{Normal: make(chan util.MsgSectionSender, 10)},
+		udpConn:   srvConn,
+
+		peerMessageLimits: lruCache.New(),
+	}, client
+}
+
+//readDatagram returns the message contained in the next datagram received by conn.
+func readDatagram(t *testing.T, conn *net.UDPConn) message.Message {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, connection.MaxUDPPacketBytes)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("no datagram received: %v", err)
+	}
+	var msg message.Message
+	if err := cbor.NewReader(bytes.NewReader(buf[:n])).Unmarshal(&msg); err != nil {
+		t.Fatalf("was not able to decode datagram: %v", err)
+	}
+	return msg
+}
+
+func encode(t *testing.T, msg message.Message) []byte {
+	encoding := new(bytes.Buffer)
+	if err := cbor.NewWriter(encoding).Marshal(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return encoding.Bytes()
+}
+
+func nameQueryMsg(name string) message.Message {
+	return message.Message{Token: token.New(), Content: []section.Section{&query.Name{
+		Name:       name,
+		Context:    ".",
+		Expiration: time.Now().Add(time.Minute).Unix(),
+		Types:      []object.Type{object.OTIP4Addr},
+	}}}
+}
+
+func TestHandleDatagram(t *testing.T) {
+	s, client := newDatagramServer(t, 200)
+	defer s.udpConn.Close()
+	defer client.Close()
+	sender := client.LocalAddr().(*net.UDPAddr)
+	msg := nameQueryMsg("www.ethz.ch.")
+	s.handleDatagram(encode(t, msg), sender)
+	select {
+	case mss := <-s.queues.Normal:
+		if mss.Token != msg.Token || mss.Sender != sender || len(mss.Sections) != 1 {
+			t.Errorf("wrong message queued. expected=%v actual=%v", msg, mss)
+		}
+	default:
+		t.Error("query was not queued")
+	}
+	s.handleDatagram(encode(t, msg), &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 53})
+	s.handleDatagram([]byte("not cbor"), sender)
+	if len(s.queues.Normal) != 0 {
+		t.Error("message of blacklisted peer or malformed message was queued")
+	}
+	large := nameQueryMsg(string(bytes.Repeat([]byte("a"), 200)) + ".ethz.ch.")
+	s.handleDatagram(encode(t, large), sender)
+	if len(s.queues.Normal) != 0 {
+		t.Error("message exceeding the maximum message size was queued")
+	}
+	//The oversized message is not decoded, thus its token is unknown.
+	answer := readDatagram(t, client)
+	if n, ok := answer.Content[0].(*section.Notification); !ok || n.Type != section.NTMsgTooLarge ||
+		n.Token != (token.Token{}) || n.Data != "200" {
+		t.Errorf("too large message was not rejected. actual=%v", answer.Content)
+	}
+}
+
+func TestSendDatagram(t *testing.T) {
+	s, client := newDatagramServer(t, 0)
+	defer s.udpConn.Close()
+	defer client.Close()
+	receiver := client.LocalAddr().(*net.UDPAddr)
+	msg := nameQueryMsg("www.ethz.ch.")
+	if err := s.sendDatagram(msg, receiver); err != nil {
+		t.Fatalf("was not able to send datagram: %v", err)
+	}
+	if answer := readDatagram(t, client); answer.Token != msg.Token ||
+		len(answer.Content) != 1 || answer.Content[0].String() != msg.Content[0].String() {
+		t.Errorf("wrong message received. expected=%v actual=%v", msg, answer)
+	}
+	//A message which does not fit into a datagram is neither split nor sent.
+	s.config.MaxMessageSize = 1000
+	var content []section.Section
+	for i := 0; i < 200; i++ {
+		content = append(content, ipAssertion("host"+strconv.Itoa(i), "ethz.ch.", "192.0.2.1"))
+	}
+	large := message.Message{Token: token.New(), Content: content}
+	if err := s.sendTo(large, receiver, 0, 0); err != nil {
+		t.Fatalf("was not able to send datagram: %v", err)
+	}
+	answer := readDatagram(t, client)
+	if n, ok := answer.Content[0].(*section.Notification); len(answer.Content) != 1 || !ok ||
+		n.Type != section.NTMsgTooLarge || answer.Token != large.Token ||
+		n.Data != strconv.Itoa(connection.MaxUDPPacketBytes) {
+		t.Errorf("too large message was not replaced by a notification. actual=%v", answer.Content)
+	}
+	client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := client.Read(make([]byte, connection.MaxUDPPacketBytes)); err == nil {
+		t.Error("more than one datagram was sent")
+	}
+	s.udpConn = nil
+	if err := s.sendDatagram(msg, receiver); err == nil {
+		t.Error("no error returned although the server has no UDP socket")
+	}
+}
+
+func TestTransportCapabilities(t *testing.T) {
+	var tests = []struct {
+		t    connection.Type
+		caps []message.Capability
+		want []message.Capability
+	}{
+		{connection.TCP, []message.Capability{message.TLSOverTCP},
+			[]message.Capability{message.TLSOverTCP}},
+		{connection.UDP, []message.Capability{message.TLSOverTCP},
+			[]message.Capability{message.TLSOverTCP, message.UDPSrv}},
+		{connection.UDP, []message.Capability{message.UDPSrv}, []message.Capability{message.UDPSrv}},
+		{connection.UDP, nil, []message.Capability{message.UDPSrv}},
+	}
+	for _, test := range tests {
+		config := Config{ServerAddress: connection.Info{Type: test.t}, Capabilities: test.caps}
+		caps := transportCapabilities(config)
+		if !reflect.DeepEqual(caps, test.want) {
+			t.Errorf("%v %v: wrong capabilities. expected=%v actual=%v", test.t, test.caps,
+				test.want, caps)
+		}
+	}
+}
+
+func TestCapabilityTransport(t *testing.T) {
+	s, client := newDatagramServer(t, 0)
+	defer s.udpConn.Close()
+	defer client.Close()
+	//The peer listens for TCP connections on the same port as the client socket.
+	peerAddr := client.LocalAddr().(*net.UDPAddr)
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: peerAddr.IP, Port: peerAddr.Port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerConn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peerConn.Close()
+	s.caches.ConnCache.AddConnection(conn)
+	defer s.caches.ConnCache.CloseAndRemoveAllConnections()
+
+	var tests = []struct {
+		name string
+		caps []message.Capability
+		tcp  bool
+	}{
+		{"unknown capabilities", nil, false},
+		{"udp capable peer", []message.Capability{message.TLSOverTCP, message.UDPSrv}, false},
+		{"hash of udp capable peer", []message.Capability{message.Capability(
+			message.CapabilityHash([]message.Capability{message.TLSOverTCP, message.UDPSrv}))},
+			false},
+		{"tcp only peer", []message.Capability{message.TLSOverTCP}, true},
+		{"hash of tcp only peer", []message.Capability{message.Capability(
+			message.CapabilityHash([]message.Capability{message.TLSOverTCP}))}, true},
+	}
+	for _, test := range tests {
+		s.processCapability(test.caps, conn.RemoteAddr(), token.New())
+		msg := nameQueryMsg("www.ethz.ch.")
+		if err := s.sendTo(msg, peerAddr, 0, 0); err != nil {
+			t.Fatalf("%s: was not able to send message: %v", test.name, err)
+		}
+		var answer message.Message
+		if test.tcp {
+			peerConn.SetReadDeadline(time.Now().Add(time.Second))
+			err = cbor.NewReader(peerConn).Unmarshal(&answer)
+		} else {
+			answer = readDatagram(t, client)
+		}
+		if err != nil || answer.Token != msg.Token {
+			t.Errorf("%s: message not received over tcp=%t. error=%v", test.name, test.tcp, err)
+		}
+	}
+}
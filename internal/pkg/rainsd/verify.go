@@ -2,10 +2,12 @@ package rainsd
 
 import (
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
 	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/address"
 	"github.com/netsec-ethz/rains/internal/pkg/cache"
 	"github.com/netsec-ethz/rains/internal/pkg/keys"
 	"github.com/netsec-ethz/rains/internal/pkg/message"
@@ -29,27 +31,33 @@ func (s *Server) verify(msgSender util.MsgSectionSender) {
 	log.Info(fmt.Sprintf("Verify %T", msgSender.Sections), "server", s.Addr(), "util.MsgSectionSender", msgSender)
 	//msgSender.Sections contains either Queries or Assertions. It gets separated in the inbox.
 	switch msgSender.Sections[0].(type) {
-	case *section.Assertion, *section.Shard, *section.Pshard, *section.Zone:
+	case *section.Assertion, *section.Shard, *section.Pshard, *section.Zone,
+		*section.AddressAssertion, *section.AddressZone:
 		isAuthoritative := hasAuthority(msgSender, s)
-		if len(s.config.Authorities) != 0 {
-			//An authoritative server drops all messages containing sections over which it has no
-			//authority and are not a response to a query issued by this server
-			if !isAuthoritative && !s.caches.PendingKeys.ContainsToken(msgSender.Token) {
+		if len(s.servedZones()) != 0 {
+			//An authoritative server or intermediary drops all messages containing sections of
+			//zones it does not serve and which are not a response to a query issued by this server
+			if !isAuthoritative && !s.caches.PendingKeys.ContainsToken(msgSender.Token) &&
+				!s.caches.PendingQueries.ContainsToken(msgSender.Token) &&
+				!s.pendingInfraKeys.containsToken(msgSender.Token) {
 				log.Info("Drop message not part of authority", "msgSender", msgSender)
 				return
 			}
 		}
 		verifySections(msgSender, s, isAuthoritative)
-	case *query.Name:
+	case *query.Name, *query.Address:
 		verifyQueries(msgSender, s)
 	default:
 		log.Warn("Not supported Msg section to verify", "msgSection", msgSender)
 	}
 }
 
+//hasAuthority returns true if all sections of msgSender belong to a zone over which s has authority
+//or for which it acts as an intermediary.
 func hasAuthority(msgSender util.MsgSectionSender, s *Server) bool {
+	zones := s.servedZones()
 	for _, sec := range msgSender.Sections {
-		if !isAuthoritative(sec.(section.WithSigForward), s.config.Authorities) {
+		if !isAuthoritative(sec.(section.WithSigForward), zones) {
 			return false
 		}
 	}
@@ -98,7 +106,7 @@ func verifySections(ss util.MsgSectionSender, s *Server, isAuthoritative bool) {
 //verifyQueries forwards the received query to be processed if it is consistent and not expired.
 func verifyQueries(msgSender util.MsgSectionSender, s *Server) {
 	for i, q := range msgSender.Sections {
-		q := q.(*query.Name)
+		q := q.(section.Query)
 		if contextInvalid(q.GetContext()) {
 			sendNotificationMsg(msgSender.Token, msgSender.Sender, section.NTRcvInconsistentMsg,
 				"invalid context", s)
@@ -138,17 +146,53 @@ func publicKeysPresent(s section.WithSigForward, zoneKeyCache cache.ZonePublicKe
 	keysNeeded := make(map[signature.MetaData]bool)
 	s.NeededKeys(keysNeeded)
 	for sigData := range keysNeeded {
-		if key, _, ok := zoneKeyCache.Get(s.GetSubjectZone(), s.GetContext(), sigData); ok {
+		zone, isAddress := s.GetSubjectZone(), false
+		if prefix, start, ok := addressAuthorityStart(s); ok {
+			zone, isAddress = addressAuthority(prefix, start, s.GetContext(), sigData, zoneKeyCache)
+		}
+		if key, _, ok := zoneKeyCache.Get(zone, s.GetContext(), sigData); ok {
 			//returned public key is guaranteed to be valid
 			log.Debug("Corresponding Public key in cache.", "cacheKey=sigMetaData", sigData, "publicKey", key)
 			keys[sigData.PublicKeyID] = append(keys[sigData.PublicKeyID], key)
 		} else {
-			log.Debug("Public key not in zoneKeyCache", "zone", s.GetSubjectZone(),
+			log.Debug("Public key not in zoneKeyCache", "zone", zone,
 				"cacheKey=sigMetaData", sigData)
-			missingKeys[missingKeyMetaData{Zone: s.GetSubjectZone(), Context: s.GetContext(),
-				KeyPhase: sigData.KeyPhase}] = true
+			missingKeys[missingKeyMetaData{Zone: zone, Context: s.GetContext(),
+				KeyPhase: sigData.KeyPhase, IsAddress: isAddress}] = true
+		}
+	}
+}
+
+//addressAuthorityStart returns the subject address of s and the length of the longest prefix whose
+//authority can sign s. An address zone is signed by the authority over its own prefix and an
+//address assertion by the one over an enclosing prefix. It returns false if s is not an address
+//section.
+func addressAuthorityStart(s section.WithSigForward) (address.Prefix, int, bool) {
+	switch s := s.(type) {
+	case *section.AddressAssertion:
+		return s.SubjectAddr, s.SubjectAddr.Len() - 1, true
+	case *section.AddressZone:
+		return s.SubjectAddr, s.SubjectAddr.Len(), true
+	}
+	return address.Prefix{}, 0, false
+}
+
+//addressAuthority returns the longest prefix of prefix not longer than start for which a public key
+//matching sigData is cached. Authority over the prefixes of length zero is held by the root zone. If
+//no key is cached, the prefix of length start is returned together with true such that its
+//delegation is queried, or the root zone if start is negative.
+func addressAuthority(prefix address.Prefix, start int, context string, sigData signature.MetaData,
+	zoneKeyCache cache.ZonePublicKey) (string, bool) {
+	for n := start; n >= 0; n-- {
+		zone := prefix.Truncate(n).String()
+		if _, _, ok := zoneKeyCache.Get(zone, context, sigData); ok {
+			return zone, false
 		}
 	}
+	if _, _, ok := zoneKeyCache.Get(".", context, sigData); ok || start < 0 {
+		return ".", false
+	}
+	return prefix.Truncate(start).String(), true
 }
 
 //verifySignatures verifies all signatures of ss.Section and strips off expired signatures. It
@@ -159,7 +203,9 @@ func verifySignatures(ss util.MsgSectionSender, keys map[keys.PublicKeyID][]keys
 	for _, sec := range ss.Sections {
 		sec := sec.(section.WithSigForward)
 		sections = append(sections, sec)
-		if !siglib.CheckSectionSignatures(sec, keys, s.config.MaxCacheValidity) {
+		valid := siglib.CheckSectionSignatures(sec, keys, s.config.MaxCacheValidity)
+		s.metrics.signatureChecked(valid)
+		if !valid {
 			return nil, false
 		}
 	}
@@ -180,6 +226,21 @@ func handleMissingKeys(ss util.MsgSectionSender, missingKeys map[missingKeyMetaD
 	queries := []section.Section{}
 	for k := range missingKeys {
 		log.Info("MissingKeys", "key", k)
+		if k.IsAddress {
+			prefix, err := address.Parse(k.Zone)
+			if err != nil {
+				log.Error("Missing key of malformed address prefix", "prefix", k.Zone, "error", err)
+				continue
+			}
+			queries = append(queries, &query.Address{
+				SubjectAddr: prefix,
+				Context:     k.Context,
+				Expiration:  exp,
+				Types:       []object.Type{object.OTDelegation},
+				KeyPhase:    k.KeyPhase,
+			})
+			continue
+		}
 		queries = append(queries, &query.Name{
 			Name:       k.Zone,
 			Context:    k.Context,
@@ -188,13 +249,72 @@ func handleMissingKeys(ss util.MsgSectionSender, missingKeys map[missingKeyMetaD
 			KeyPhase:   k.KeyPhase,
 		})
 	}
+	s.sendDelegationQueries(queries, t, ss.Sender, isAuthoritative)
+	time.AfterFunc(time.Until(time.Unix(exp, 0)), func() {
+		s.retryDelegationQueries(queries, t, ss.Sender, isAuthoritative)
+	})
+}
+
+//sendDelegationQueries sends queries with token t to the recursive resolver if isAuthoritative is
+//true or otherwise to sender.
+func (s *Server) sendDelegationQueries(queries []section.Section, t token.Token, sender net.Addr,
+	isAuthoritative bool) {
 	msg := message.Message{Token: t, Content: queries}
 	if isAuthoritative {
 		log.Info("Send missing delegation keys to recursive resolver", "msg", msg)
 		s.sendToRecursiveResolver(msg)
 	} else {
-		s.sendTo(msg, ss.Sender, 0, 0)
+		s.sendTo(msg, sender, 0, 0)
+	}
+}
+
+//retryDelegationQueries is called when the delegation queries with token t have expired. If the
+//pending key cache still contains an entry for t, the queries are resent with a new token. They are
+//resent to the original destination until they have been retried DelegationQueryRetries times and
+//afterwards to each configured backup server once. The waiting sections are dropped when there is
+//no server left to query.
+func (s *Server) retryDelegationQueries(queries []section.Section, t token.Token,
+	sender net.Addr, isAuthoritative bool) {
+	newToken := token.New()
+	ss, retries, ok := s.caches.PendingKeys.Retry(t, newToken,
+		time.Now().Add(s.config.DelegationQueryValidity).Unix())
+	if !ok {
+		return //answer has arrived in the mean time or the entry has been removed
+	}
+	exp := getQueryValidity(ss.Sections[0].(section.WithSigForward).Sigs(keys.RainsKeySpace),
+		s.config.DelegationQueryValidity)
+	backup := retries - s.config.DelegationQueryRetries - 1
+	if backup >= len(s.config.BackupServers) || exp < time.Now().Unix() {
+		s.caches.PendingKeys.GetAndRemove(newToken)
+		log.Warn("Dropping sections as delegation queries remained unanswered", "retries",
+			retries-1, "sender", ss.Sender, "sections", ss.Sections)
+		return
+	}
+	resent := []section.Section{}
+	for _, q := range queries {
+		switch q := q.(type) {
+		case *query.Name:
+			q2 := *q
+			q2.Expiration = exp
+			resent = append(resent, &q2)
+		case *query.Address:
+			q2 := *q
+			q2.Expiration = exp
+			resent = append(resent, &q2)
+		}
+	}
+	if backup < 0 {
+		log.Info("Resending delegation queries", "retry", retries, "token", newToken)
+		s.sendDelegationQueries(resent, newToken, sender, isAuthoritative)
+	} else {
+		log.Info("Sending delegation queries to backup server", "retry", retries,
+			"backup", s.config.BackupServers[backup].Addr, "token", newToken)
+		s.sendTo(message.Message{Token: newToken, Content: resent},
+			s.config.BackupServers[backup].Addr, 0, 0)
 	}
+	time.AfterFunc(time.Until(time.Unix(exp, 0)), func() {
+		s.retryDelegationQueries(resent, newToken, sender, isAuthoritative)
+	})
 }
 
 //getQueryValidity returns the expiration value for a delegation query. It is either a configured
@@ -0,0 +1,125 @@
+package rainsd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
+	"github.com/netsec-ethz/rains/internal/pkg/cache"
+	"github.com/netsec-ethz/rains/internal/pkg/connection"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/query"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+	"github.com/netsec-ethz/rains/internal/pkg/signature"
+	"github.com/netsec-ethz/rains/internal/pkg/token"
+	"github.com/netsec-ethz/rains/internal/pkg/util"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestRetryDelegationQueries(t *testing.T) {
+	s, client := newDatagramServer(t, 0)
+	defer s.udpConn.Close()
+	defer client.Close()
+	backup, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	s.caches.PendingKeys = cache.NewPendingKey(10)
+	//Retries are triggered by hand before the timers started by retryDelegationQueries fire.
+	s.config.DelegationQueryValidity = time.Minute
+	s.config.DelegationQueryRetries = 1
+	a := ipAssertion("www", "ethz.ch.", "192.0.2.1")
+	a.AddSig(signature.Sig{
+		PublicKeyID: keys.PublicKeyID{Algorithm: algorithmTypes.Ed25519},
+		ValidSince:  time.Now().Unix(),
+		ValidUntil:  time.Now().Add(time.Hour).Unix(),
+	})
+	var tests = []struct {
+		name         string
+		backups      []connection.Info
+		destinations []*net.UDPConn
+	}{
+		{"backup server", []connection.Info{{Type: connection.UDP, Addr: backup.LocalAddr()}},
+			[]*net.UDPConn{client, backup}},
+		{"no backup server", nil, []*net.UDPConn{client}},
+	}
+	for _, test := range tests {
+		s.config.BackupServers = test.backups
+		tok := token.New()
+		queries := []section.Section{&query.Name{Name: "ethz.ch.", Context: ".",
+			Types: []object.Type{object.OTDelegation}}}
+		s.caches.PendingKeys.Add(util.MsgSectionSender{Sender: client.LocalAddr(),
+			Sections: []section.Section{a}, Token: token.New()}, tok, time.Now().Unix())
+		//The queries are resent to the sender DelegationQueryRetries times and then to each
+		//backup server once.
+		for i, dst := range test.destinations {
+			s.retryDelegationQueries(queries, tok, client.LocalAddr(), false)
+			msg := readDatagram(t, dst)
+			if q, ok := msg.Content[0].(*query.Name); msg.Token == tok || len(msg.Content) != 1 ||
+				!ok || q.Name != "ethz.ch." {
+				t.Errorf("%s: retry %d was not sent to the right server. actual=%v", test.name,
+					i+1, msg)
+			}
+			if !s.caches.PendingKeys.ContainsToken(msg.Token) {
+				t.Errorf("%s: pending entry was not moved to token of retry %d", test.name, i+1)
+			}
+			tok = msg.Token
+		}
+		//The waiting sections are dropped when there is no server left to query.
+		s.retryDelegationQueries(queries, tok, client.LocalAddr(), false)
+		if s.caches.PendingKeys.Len() != 0 {
+			t.Errorf("%s: waiting sections were not dropped", test.name)
+		}
+	}
+}
+
+func TestAddressAuthority(t *testing.T) {
+	prefix, err := address.Parse("192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := keys.PublicKey{
+		PublicKeyID: keys.PublicKeyID{Algorithm: algorithmTypes.Ed25519},
+		ValidSince:  time.Now().Add(-time.Hour).Unix(),
+		ValidUntil:  time.Now().Add(time.Hour).Unix(),
+		Key:         ed25519.PublicKey([]byte("TestKey")),
+	}
+	sigData := signature.MetaData{
+		PublicKeyID: key.PublicKeyID,
+		ValidSince:  time.Now().Unix(),
+		ValidUntil:  time.Now().Add(time.Minute).Unix(),
+	}
+	var tests = []struct {
+		name    string
+		cached  []string
+		start   int
+		zone    string
+		isQuery bool
+	}{
+		{"longest cached prefix", []string{".", "192.0.0.0/8", "192.0.2.0/24"}, 31,
+			"192.0.2.0/24", false},
+		{"shorter cached prefix", []string{"192.0.0.0/8"}, 31, "192.0.0.0/8", false},
+		{"prefix longer than start", []string{"192.0.2.0/31", "192.0.0.0/8"}, 24,
+			"192.0.0.0/8", false},
+		{"root zone", []string{"."}, 31, ".", false},
+		{"no key cached", nil, 31, "192.0.2.0/31", true},
+		{"no key cached and no prefix", nil, -1, ".", false},
+	}
+	for _, test := range tests {
+		zoneKeyCache := cache.NewZoneKey(10, 10, 10)
+		for _, zone := range test.cached {
+			delegation := &section.Assertion{SubjectName: "@", SubjectZone: zone, Context: ".",
+				Content: []object.Object{{Type: object.OTDelegation, Value: key}}}
+			zoneKeyCache.Add(delegation, key, false)
+		}
+		zone, isQuery := addressAuthority(prefix, test.start, ".", sigData, zoneKeyCache)
+		if zone != test.zone || isQuery != test.isQuery {
+			t.Errorf("%s: wrong authority. expected=(%s,%t) actual=(%s,%t)", test.name, test.zone,
+				test.isQuery, zone, isQuery)
+		}
+	}
+}
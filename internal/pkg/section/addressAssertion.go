@@ -0,0 +1,255 @@
+package section
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	cbor "github.com/britram/borat"
+	log "github.com/inconshreveable/log15"
+
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/signature"
+)
+
+//AddressAssertion contains information about an address prefix. It is used for reverse lookups.
+type AddressAssertion struct {
+	Signatures  []signature.Sig
+	SubjectAddr address.Prefix
+	Context     string
+	Content     []object.Object
+	validSince  int64 //unit: the number of seconds elapsed since January 1, 1970 UTC
+	validUntil  int64 //unit: the number of seconds elapsed since January 1, 1970 UTC
+	sign        bool  //set to true before signing and false afterwards
+}
+
+// UnmarshalMap provides functionality to unmarshal a map read in by CBOR.
+func (a *AddressAssertion) UnmarshalMap(m map[int]interface{}) error {
+	if sigs, ok := m[0].([]interface{}); ok {
+		a.Signatures = make([]signature.Sig, len(sigs))
+		for i, sig := range sigs {
+			sigVal, ok := sig.([]interface{})
+			if !ok {
+				return errors.New("cbor address assertion signatures entry is not an array")
+			}
+			if err := a.Signatures[i].UnmarshalArray(sigVal); err != nil {
+				return err
+			}
+		}
+	}
+	if addr, ok := m[5].([]interface{}); ok {
+		if err := a.SubjectAddr.UnmarshalArray(addr); err != nil {
+			return err
+		}
+	} else {
+		return errors.New("cbor address assertion map does not contain a subject address")
+	}
+	if ctx, ok := m[6].(string); ok {
+		a.Context = ctx
+	} //context is omitted in a contained address assertion
+
+	if objs, ok := m[7].([]interface{}); ok {
+		a.Content = make([]object.Object, len(objs))
+		for i, obj := range objs {
+			o, ok := obj.([]interface{})
+			if !ok {
+				return errors.New("cbor address assertion object is not an array")
+			}
+			if err := a.Content[i].UnmarshalArray(o); err != nil {
+				return err
+			}
+		}
+	} else {
+		return errors.New("cbor address assertion map does not contain an object array")
+	}
+	return nil
+}
+
+// MarshalCBOR implements the CBORMarshaler interface.
+func (a *AddressAssertion) MarshalCBOR(w *cbor.CBORWriter) error {
+	m := make(map[int]interface{})
+	if len(a.Signatures) > 0 && !a.sign {
+		m[0] = a.Signatures
+	}
+	m[5] = a.SubjectAddr
+	if a.Context != "" {
+		m[6] = a.Context
+	}
+	m[7] = a.Content
+	return w.WriteIntMap(m)
+}
+
+//AllSigs returns all address assertion's signatures
+func (a *AddressAssertion) AllSigs() []signature.Sig {
+	return a.Signatures
+}
+
+//Sigs returns a's signatures in keyspace
+func (a *AddressAssertion) Sigs(keySpace keys.KeySpaceID) []signature.Sig {
+	return filterSigs(a.Signatures, keySpace)
+}
+
+//AddSig adds the given signature
+func (a *AddressAssertion) AddSig(sig signature.Sig) {
+	a.Signatures = append(a.Signatures, sig)
+}
+
+//DeleteSig deletes ith signature
+func (a *AddressAssertion) DeleteSig(i int) {
+	a.Signatures = append(a.Signatures[:i], a.Signatures[i+1:]...)
+}
+
+//DeleteAllSigs deletes all signature
+func (a *AddressAssertion) DeleteAllSigs() {
+	a.Signatures = []signature.Sig{}
+}
+
+//GetContext returns the context of the address assertion
+func (a *AddressAssertion) GetContext() string {
+	return a.Context
+}
+
+//GetSubjectZone returns the subject address of the address assertion. The authority over an
+//address prefix is identified by the prefix itself.
+func (a *AddressAssertion) GetSubjectZone() string {
+	return a.SubjectAddr.String()
+}
+
+//Copy creates a copy of the address assertion with the given context
+func (a *AddressAssertion) Copy(context string) *AddressAssertion {
+	stub := &AddressAssertion{}
+	*stub = *a
+	stub.Context = context
+	return stub
+}
+
+//Begin returns the begining of the interval of this address assertion.
+func (a *AddressAssertion) Begin() string {
+	return a.SubjectAddr.String()
+}
+
+//End returns the end of the interval of this address assertion.
+func (a *AddressAssertion) End() string {
+	return a.SubjectAddr.String()
+}
+
+//UpdateValidity updates the validity of this address assertion if the validity period is extended.
+//It makes sure that the validity is never larger than maxValidity
+func (a *AddressAssertion) UpdateValidity(validSince, validUntil int64, maxValidity time.Duration) {
+	a.validSince, a.validUntil = UpdateValidity(validSince, validUntil, a.validSince, a.validUntil,
+		maxValidity)
+}
+
+//ValidSince returns the earliest validSince date of all contained signatures
+func (a *AddressAssertion) ValidSince() int64 {
+	return a.validSince
+}
+
+//ValidUntil returns the latest validUntil date of all contained signatures
+func (a *AddressAssertion) ValidUntil() int64 {
+	return a.validUntil
+}
+
+//SetValidSince sets the validSince time
+func (a *AddressAssertion) SetValidSince(validSince int64) {
+	a.validSince = validSince
+}
+
+//SetValidUntil sets the validUntil time
+func (a *AddressAssertion) SetValidUntil(validUntil int64) {
+	a.validUntil = validUntil
+}
+
+//Hash returns a string containing all information uniquely identifying an address assertion.
+func (a *AddressAssertion) Hash() string {
+	if a == nil {
+		return "AA_nil"
+	}
+	encoding := new(bytes.Buffer)
+	w := cbor.NewCBORWriter(encoding)
+	w.WriteArray([]interface{}{6, a})
+	return encoding.String()
+}
+
+//Sort sorts the content of the address assertion lexicographically.
+func (a *AddressAssertion) Sort() {
+	for _, o := range a.Content {
+		o.Sort()
+	}
+	sort.Slice(a.Content, func(i, j int) bool { return a.Content[i].CompareTo(a.Content[j]) < 0 })
+}
+
+//CompareTo compares two address assertions and returns 0 if they are equal, 1 if a is greater
+//than assertion and -1 if a is smaller than assertion
+func (a *AddressAssertion) CompareTo(assertion *AddressAssertion) int {
+	if c := a.SubjectAddr.CompareTo(assertion.SubjectAddr); c != 0 {
+		return c
+	} else if a.Context < assertion.Context {
+		return -1
+	} else if a.Context > assertion.Context {
+		return 1
+	} else if len(a.Content) < len(assertion.Content) {
+		return -1
+	} else if len(a.Content) > len(assertion.Content) {
+		return 1
+	}
+	for i, o := range a.Content {
+		if o.CompareTo(assertion.Content[i]) != 0 {
+			return o.CompareTo(assertion.Content[i])
+		}
+	}
+	return 0
+}
+
+//String implements Stringer interface
+func (a *AddressAssertion) String() string {
+	if a == nil {
+		return "AddressAssertion:nil"
+	}
+	return fmt.Sprintf("AddressAssertion:[SA=%s CTX=%s CONTENT=%v SIG=%v isSign=%t]",
+		a.SubjectAddr, a.Context, a.Content, a.Signatures, a.sign)
+}
+
+//IsConsistent returns true if a has a subject address and only contains objects which are allowed
+//in an address assertion.
+func (a *AddressAssertion) IsConsistent() bool {
+	if a.SubjectAddr.Net == nil {
+		log.Warn("Address assertion has no subject address", "addressAssertion", a)
+		return false
+	}
+	for _, o := range a.Content {
+		if !AllowedInAddressAssertion(o.Type) {
+			log.Warn("Object type is not allowed in an address assertion", "type", o.Type,
+				"addressAssertion", a)
+			return false
+		}
+	}
+	return true
+}
+
+//AllowedInAddressAssertion returns true if objects of type t can be contained in an address
+//assertion.
+func AllowedInAddressAssertion(t object.Type) bool {
+	switch t {
+	case object.OTName, object.OTRedirection, object.OTDelegation, object.OTRegistrar,
+		object.OTRegistrant:
+		return true
+	}
+	return false
+}
+
+//NeededKeys adds to keysNeeded key meta data which is necessary to verify all a's signatures.
+func (a *AddressAssertion) NeededKeys(keysNeeded map[signature.MetaData]bool) {
+	extractNeededKeys(a, keysNeeded)
+}
+
+func (a *AddressAssertion) AddSigInMarshaller() {
+	a.sign = false
+}
+func (a *AddressAssertion) DontAddSigInMarshaller() {
+	a.sign = true
+}
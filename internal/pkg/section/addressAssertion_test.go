@@ -0,0 +1,49 @@
+package section
+
+import (
+	"testing"
+
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+)
+
+func TestAddressAssertionIsConsistent(t *testing.T) {
+	subjectAddr, _ := address.Parse(ip4TestAddrCIDR24)
+	var tests = []struct {
+		input *AddressAssertion
+		want  bool
+	}{
+		{GetAddressAssertion(), true},
+		{new(AddressAssertion), false},
+		{&AddressAssertion{SubjectAddr: subjectAddr, Content: []object.Object{
+			object.Object{Type: object.OTIP4Addr, Value: ip4TestAddr}}}, false},
+	}
+	for i, test := range tests {
+		if test.input.IsConsistent() != test.want {
+			t.Errorf("%d: unexpected address assertion (in)consistency expected=%v actual=%v", i,
+				test.want, test.input.IsConsistent())
+		}
+	}
+}
+
+func TestAddressAssertionCompareTo(t *testing.T) {
+	addrs := []string{ip4TestAddrCIDR24, ip4TestAddrCIDR32, ip4TestAddr2CIDR, ip6TestAddrCIDR}
+	for i, a := range addrs {
+		for j, b := range addrs {
+			p, _ := address.Parse(a)
+			q, _ := address.Parse(b)
+			a1 := &AddressAssertion{SubjectAddr: p, Context: globalContext}
+			a2 := &AddressAssertion{SubjectAddr: q, Context: globalContext}
+			if (i < j && a1.CompareTo(a2) != -1) || (i == j && a1.CompareTo(a2) != 0) ||
+				(i > j && a1.CompareTo(a2) != 1) {
+				t.Errorf("Address assertions are not sorted correctly a1=%s a2=%s", a1, a2)
+			}
+		}
+	}
+	a1 := GetAddressAssertion()
+	a2 := GetAddressAssertion()
+	a2.Content = a2.Content[1:]
+	if a1.CompareTo(a2) != 1 || a2.CompareTo(a1) != -1 {
+		t.Error("Different content length are not sorted correctly")
+	}
+}
@@ -0,0 +1,267 @@
+package section
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	cbor "github.com/britram/borat"
+	log "github.com/inconshreveable/log15"
+
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+	"github.com/netsec-ethz/rains/internal/pkg/keys"
+	"github.com/netsec-ethz/rains/internal/pkg/signature"
+)
+
+//AddressZone contains all address assertions of an address prefix. Addresses of the prefix which
+//are not covered by a contained address assertion have no information.
+type AddressZone struct {
+	Signatures  []signature.Sig
+	SubjectAddr address.Prefix
+	Context     string
+	Content     []*AddressAssertion
+	validSince  int64 //unit: the number of seconds elapsed since January 1, 1970 UTC
+	validUntil  int64 //unit: the number of seconds elapsed since January 1, 1970 UTC
+	sign        bool  //set to true before signing and false afterwards
+}
+
+// UnmarshalMap decodes the output from the CBOR decoder into this struct.
+func (z *AddressZone) UnmarshalMap(m map[int]interface{}) error {
+	if sigs, ok := m[0].([]interface{}); ok {
+		z.Signatures = make([]signature.Sig, len(sigs))
+		for i, sig := range sigs {
+			sigVal, ok := sig.([]interface{})
+			if !ok {
+				return errors.New("cbor address zone signatures entry is not an array")
+			}
+			if err := z.Signatures[i].UnmarshalArray(sigVal); err != nil {
+				return err
+			}
+		}
+	} else {
+		return errors.New("cbor address zone map does not contain a signature")
+	}
+	if addr, ok := m[5].([]interface{}); ok {
+		if err := z.SubjectAddr.UnmarshalArray(addr); err != nil {
+			return err
+		}
+	} else {
+		return errors.New("cbor address zone map does not contain a subject address")
+	}
+	if ctx, ok := m[6].(string); ok {
+		z.Context = ctx
+	} else {
+		return errors.New("cbor address zone map does not contain a context")
+	}
+	if cont, ok := m[23].([]interface{}); ok {
+		z.Content = make([]*AddressAssertion, 0)
+		for _, obj := range cont {
+			as := &AddressAssertion{}
+			a, ok := obj.(map[int]interface{})
+			if !ok {
+				return errors.New("cbor address zone content entry is not a map")
+			}
+			if err := as.UnmarshalMap(a); err != nil {
+				return err
+			}
+			z.Content = append(z.Content, as)
+		}
+	} else {
+		return errors.New("cbor address zone map does not contain a content")
+	}
+	return nil
+}
+
+// MarshalCBOR implements the CBORMarshaler interface.
+func (z *AddressZone) MarshalCBOR(w *cbor.CBORWriter) error {
+	m := make(map[int]interface{})
+	m[23] = z.Content
+	if len(z.Signatures) > 0 && !z.sign {
+		m[0] = z.Signatures
+	}
+	m[5] = z.SubjectAddr
+	m[6] = z.Context
+	return w.WriteIntMap(m)
+}
+
+//AllSigs returns the address zone's signatures
+func (z *AddressZone) AllSigs() []signature.Sig {
+	return z.Signatures
+}
+
+//Sigs returns z's signatures in keyspace
+func (z *AddressZone) Sigs(keySpace keys.KeySpaceID) []signature.Sig {
+	return filterSigs(z.Signatures, keySpace)
+}
+
+//AddSig adds the given signature
+func (z *AddressZone) AddSig(sig signature.Sig) {
+	z.Signatures = append(z.Signatures, sig)
+}
+
+//DeleteSig deletes ith signature
+func (z *AddressZone) DeleteSig(i int) {
+	z.Signatures = append(z.Signatures[:i], z.Signatures[i+1:]...)
+}
+
+//DeleteAllSigs deletes all signature
+func (z *AddressZone) DeleteAllSigs() {
+	z.Signatures = []signature.Sig{}
+}
+
+//GetContext returns the context of the address zone
+func (z *AddressZone) GetContext() string {
+	return z.Context
+}
+
+//GetSubjectZone returns the subject address of the address zone. The authority over an address
+//prefix is identified by the prefix itself.
+func (z *AddressZone) GetSubjectZone() string {
+	return z.SubjectAddr.String()
+}
+
+//AddCtxToContent sets the context of all contained address assertions to the one of z.
+func (z *AddressZone) AddCtxToContent() {
+	for _, a := range z.Content {
+		a.Context = z.Context
+	}
+}
+
+//RemoveCtxFromContent removes the context of all contained address assertions.
+func (z *AddressZone) RemoveCtxFromContent() {
+	for _, a := range z.Content {
+		a.Context = ""
+	}
+}
+
+//Begin returns the begining of the interval of this address zone.
+func (z *AddressZone) Begin() string {
+	return ""
+}
+
+//End returns the end of the interval of this address zone.
+func (z *AddressZone) End() string {
+	return ""
+}
+
+//UpdateValidity updates the validity of this address zone if the validity period is extended.
+//It makes sure that the validity is never larger than maxValidity
+func (z *AddressZone) UpdateValidity(validSince, validUntil int64, maxValidity time.Duration) {
+	z.validSince, z.validUntil = UpdateValidity(validSince, validUntil, z.validSince, z.validUntil,
+		maxValidity)
+}
+
+//ValidSince returns the earliest validSince date of all contained signatures
+func (z *AddressZone) ValidSince() int64 {
+	return z.validSince
+}
+
+//ValidUntil returns the latest validUntil date of all contained signatures
+func (z *AddressZone) ValidUntil() int64 {
+	return z.validUntil
+}
+
+//SetValidSince sets the validSince time
+func (z *AddressZone) SetValidSince(validSince int64) {
+	z.validSince = validSince
+}
+
+//SetValidUntil sets the validUntil time
+func (z *AddressZone) SetValidUntil(validUntil int64) {
+	z.validUntil = validUntil
+}
+
+//Hash returns a string containing all information uniquely identifying an address zone.
+func (z *AddressZone) Hash() string {
+	if z == nil {
+		return "AZ_nil"
+	}
+	encoding := new(bytes.Buffer)
+	w := cbor.NewCBORWriter(encoding)
+	w.WriteArray([]interface{}{7, z})
+	return encoding.String()
+}
+
+//Sort sorts the content of the address zone lexicographically.
+func (z *AddressZone) Sort() {
+	for _, a := range z.Content {
+		a.Sort()
+	}
+	sort.Slice(z.Content, func(i, j int) bool {
+		return z.Content[i].CompareTo(z.Content[j]) < 0
+	})
+}
+
+//CompareTo compares two address zones and returns 0 if they are equal, 1 if z is greater than
+//zone and -1 if z is smaller than zone
+func (z *AddressZone) CompareTo(zone *AddressZone) int {
+	if c := z.SubjectAddr.CompareTo(zone.SubjectAddr); c != 0 {
+		return c
+	} else if z.Context < zone.Context {
+		return -1
+	} else if z.Context > zone.Context {
+		return 1
+	} else if len(z.Content) < len(zone.Content) {
+		return -1
+	} else if len(z.Content) > len(zone.Content) {
+		return 1
+	}
+	for i, a := range z.Content {
+		if a.CompareTo(zone.Content[i]) != 0 {
+			return a.CompareTo(zone.Content[i])
+		}
+	}
+	return 0
+}
+
+//String implements Stringer interface
+func (z *AddressZone) String() string {
+	if z == nil {
+		return "AddressZone:nil"
+	}
+	return fmt.Sprintf("AddressZone:[SA=%s CTX=%s CONTENT=%v SIG=%v]",
+		z.SubjectAddr, z.Context, z.Content, z.Signatures)
+}
+
+//IsConsistent returns true if all contained address assertions are consistent, have no context and
+//their subject address is within the address zone's prefix.
+func (z *AddressZone) IsConsistent() bool {
+	for _, a := range z.Content {
+		if a.Context != "" {
+			log.Warn("Contained address assertion has a context", "addressAssertion", a)
+			return false
+		}
+		if !z.SubjectAddr.Contains(a.SubjectAddr) {
+			log.Warn("Contained address assertion's subject address is outside the address zone",
+				"subjectAddr", a.SubjectAddr, "zone", z.SubjectAddr)
+			return false
+		}
+		if !a.IsConsistent() {
+			return false
+		}
+	}
+	return true
+}
+
+//NeededKeys adds to keysNeeded key meta data which is necessary to verify all z's signatures.
+func (z *AddressZone) NeededKeys(keysNeeded map[signature.MetaData]bool) {
+	extractNeededKeys(z, keysNeeded)
+	for _, a := range z.Content {
+		a.NeededKeys(keysNeeded)
+	}
+}
+
+func (z *AddressZone) AddSigInMarshaller() {
+	z.sign = false
+	for _, a := range z.Content {
+		a.AddSigInMarshaller()
+	}
+}
+func (z *AddressZone) DontAddSigInMarshaller() {
+	z.sign = true
+	for _, a := range z.Content {
+		a.DontAddSigInMarshaller()
+	}
+}
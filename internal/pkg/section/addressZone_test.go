@@ -0,0 +1,46 @@
+package section
+
+import (
+	"testing"
+
+	"github.com/netsec-ethz/rains/internal/pkg/address"
+)
+
+func TestAddressZoneIsConsistent(t *testing.T) {
+	outside, _ := address.Parse(ip4TestAddr2CIDR)
+	var tests = []struct {
+		input func(z *AddressZone)
+		want  bool
+	}{
+		{func(z *AddressZone) {}, true},
+		{func(z *AddressZone) { z.Content[0].Context = globalContext }, false},
+		{func(z *AddressZone) { z.Content[0].SubjectAddr = outside }, false},
+		{func(z *AddressZone) { z.Content[0].SubjectAddr = z.SubjectAddr }, true},
+		{func(z *AddressZone) { z.Content[0].Content = GetAssertion().Content }, false},
+	}
+	for i, test := range tests {
+		z := GetAddressZone()
+		test.input(z)
+		if z.IsConsistent() != test.want {
+			t.Errorf("%d: unexpected address zone (in)consistency expected=%v actual=%v", i,
+				test.want, z.IsConsistent())
+		}
+	}
+}
+
+func TestAddressZoneCompareTo(t *testing.T) {
+	z1 := GetAddressZone()
+	z2 := &AddressZone{SubjectAddr: z1.SubjectAddr, Context: z1.Context, Content: z1.Content}
+	if z1.CompareTo(z2) != 0 {
+		t.Error("Equal address zones are not equal")
+	}
+	z2.Content = nil
+	if z1.CompareTo(z2) != 1 || z2.CompareTo(z1) != -1 {
+		t.Error("Different content length are not sorted correctly")
+	}
+	z2.Content = z1.Content
+	z2.Context = "other"
+	if z1.CompareTo(z2) != -1 || z2.CompareTo(z1) != 1 {
+		t.Error("Different contexts are not sorted correctly")
+	}
+}
@@ -9,7 +9,8 @@ import (
 	"github.com/netsec-ethz/rains/internal/pkg/signature"
 )
 
-//Section can be either an Assertion, Shard, Zone, Query, Notification, AddressAssertion, AddressZone, AddressQuery section
+//Section can be either an Assertion, Shard, Pshard, Zone, AddressAssertion, AddressZone, query.Name,
+//query.Address or Notification section
 type Section interface {
 	Sort()
 	String() string
@@ -40,14 +41,14 @@ type WithSig interface {
 	DontAddSigInMarshaller()
 }
 
-//WithSigForward can be either an Assertion, Shard or Zone
+//WithSigForward can be either an Assertion, Shard, Pshard, Zone, AddressAssertion or AddressZone
 type WithSigForward interface {
 	WithSig
 	Interval
 }
 
 //Query is the interface for a query section. In the current implementation it can be
-//a query or an addressQuery
+//a query.Name or a query.Address
 type Query interface {
 	GetContext() string
 	GetExpiration() int64
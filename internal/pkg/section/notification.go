@@ -98,11 +98,13 @@ type NotificationType int
 //go:generate stringer -type=NotificationType
 const (
 	NTHeartbeat          NotificationType = 100
+	NTStaleAnswer        NotificationType = 110
 	NTCapHashNotKnown    NotificationType = 399
 	NTBadMessage         NotificationType = 400
 	NTRcvInconsistentMsg NotificationType = 403
 	NTNoAssertionsExist  NotificationType = 404
 	NTMsgTooLarge        NotificationType = 413
+	NTRateLimited        NotificationType = 429
 	NTUnspecServerErr    NotificationType = 500
 	NTServerNotCapable   NotificationType = 501
 	NTNoAssertionAvail   NotificationType = 504
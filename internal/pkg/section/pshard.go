@@ -230,10 +230,10 @@ func (s *Pshard) IsNonexistent(q *query.Name) (bool, error) {
 	if q.Context != s.Context {
 		return false, errors.New("query has different context")
 	}
-	if strings.HasSuffix(q.Name, s.SubjectZone) {
+	if !strings.HasSuffix(q.Name, s.SubjectZone) {
 		return false, errors.New("query has different suffix")
 	}
-	name := strings.TrimSuffix(q.Name, s.SubjectZone)
+	name := strings.TrimSuffix(strings.TrimSuffix(q.Name, s.SubjectZone), ".")
 	if !s.InRange(name) {
 		return false, errors.New("query is not in pshard's range")
 	}
@@ -4,6 +4,9 @@ import (
 	"math/rand"
 	"sort"
 	"testing"
+
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/query"
 )
 
 func TestPshardCopy(t *testing.T) {
@@ -81,6 +84,38 @@ func TestPshardInRange(t *testing.T) {
 	}
 }
 
+func TestPshardIsNonexistent(t *testing.T) {
+	pshard := &Pshard{
+		BloomFilter: GetBloomFilter(),
+		Context:     ".",
+		SubjectZone: "ethz.ch.",
+		RangeFrom:   "a",
+		RangeTo:     "x",
+	}
+	pshard.BloomFilter.Add("www", "ethz.ch.", ".", object.OTIP4Addr)
+	var tests = []struct {
+		input   *query.Name
+		want    bool
+		wantErr bool
+	}{
+		{&query.Name{Context: ".", Name: "www.ethz.ch.", Types: []object.Type{object.OTIP4Addr}}, false, false},
+		{&query.Name{Context: ".", Name: "www.ethz.ch.", Types: []object.Type{object.OTIP6Addr}}, true, false},
+		{&query.Name{Context: ".", Name: "www.ethz.ch.", Types: []object.Type{object.OTIP6Addr,
+			object.OTIP4Addr}}, false, false},
+		{&query.Name{Context: ".", Name: "mail.ethz.ch.", Types: []object.Type{object.OTIP4Addr}}, true, false},
+		{&query.Name{Context: "cx-", Name: "www.ethz.ch.", Types: []object.Type{object.OTIP4Addr}}, false, true},
+		{&query.Name{Context: ".", Name: "www.example.com.", Types: []object.Type{object.OTIP4Addr}}, false, true},
+		{&query.Name{Context: ".", Name: "zzz.ethz.ch.", Types: []object.Type{object.OTIP4Addr}}, false, true},
+	}
+	for i, test := range tests {
+		ok, err := pshard.IsNonexistent(test.input)
+		if ok != test.want || (err != nil) != test.wantErr {
+			t.Errorf("%d: unexpected result of IsNonexistent. expected=%t actual=%t error=%v", i,
+				test.want, ok, err)
+		}
+	}
+}
+
 func checkPshard(s1, s2 *Pshard, t *testing.T) {
 	if s1.Context != s2.Context {
 		t.Error("Pshard context mismatch")
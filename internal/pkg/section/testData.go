@@ -5,6 +5,7 @@ import (
 	"time"
 
 	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/address"
 	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
 	"github.com/netsec-ethz/rains/internal/pkg/datastructures/bitarray"
 	"github.com/netsec-ethz/rains/internal/pkg/keys"
@@ -72,6 +73,34 @@ func GetAssertion() *Assertion {
 	}
 }
 
+//GetAddressAssertion returns an address assertion containing all object types allowed in an
+//address assertion that is valid.
+func GetAddressAssertion() *AddressAssertion {
+	subjectAddr, _ := address.Parse(ip4TestAddrCIDR24)
+	nameObject := object.Object{Type: object.OTName, Value: object.Name{Name: testDomain,
+		Types: []object.Type{object.OTIP4Addr}}}
+	return &AddressAssertion{
+		Content:     append([]object.Object{nameObject}, AllAllowedNetworkObjects()...),
+		Context:     globalContext,
+		SubjectAddr: subjectAddr,
+	}
+}
+
+//GetAddressZone returns an address zone containing an address assertion of a host address that is
+//valid.
+func GetAddressZone() *AddressZone {
+	subjectAddr, _ := address.Parse(ip4TestAddrCIDR24)
+	hostAddr, _ := address.Parse(ip4TestAddrCIDR32)
+	a := GetAddressAssertion()
+	a.Context = ""
+	a.SubjectAddr = hostAddr
+	return &AddressZone{
+		Content:     []*AddressAssertion{a},
+		Context:     globalContext,
+		SubjectAddr: subjectAddr,
+	}
+}
+
 //Datastructure returns a datastructure object with valid content
 func GetBloomFilter() BloomFilter {
 	return BloomFilter{
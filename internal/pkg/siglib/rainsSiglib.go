@@ -14,6 +14,7 @@ import (
 
 	"github.com/netsec-ethz/rains/internal/pkg/keys"
 	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/nameset"
 	"github.com/netsec-ethz/rains/internal/pkg/object"
 	"github.com/netsec-ethz/rains/internal/pkg/query"
 	"github.com/netsec-ethz/rains/internal/pkg/section"
@@ -46,6 +47,14 @@ func CheckSectionSignatures(s section.WithSig, pkeys map[keys.PublicKeyID][]keys
 			}
 		}
 		s.RemoveCtxAndZoneFromContent()
+	case *section.AddressZone:
+		s.AddCtxToContent()
+		for _, a := range s.Content {
+			if len(a.Sigs(keys.RainsKeySpace)) > 0 && !checkSectionSignatures(a, pkeys, maxVal) {
+				return false
+			}
+		}
+		s.RemoveCtxFromContent()
 	}
 	s.AddSigInMarshaller()
 	return true
@@ -134,6 +143,16 @@ func SignSectionUnsafe(s section.WithSig, ks map[keys.PublicKeyID]interface{}) e
 			}
 		}
 		s.RemoveCtxAndZoneFromContent()
+	case *section.AddressZone:
+		s.AddCtxToContent()
+		for _, a := range s.Content {
+			if len(a.Sigs(keys.RainsKeySpace)) > 0 {
+				if err := signSectionUnsafe(a, ks); err != nil {
+					return err
+				}
+			}
+		}
+		s.RemoveCtxFromContent()
 	}
 	s.AddSigInMarshaller()
 	return nil
@@ -160,6 +179,75 @@ func signSectionUnsafe(s section.WithSig, ks map[keys.PublicKeyID]interface{}) e
 	return nil
 }
 
+//SignMessage signs msg with each private key in ks and replaces msg's signatures with the
+//resulting ones. The signatures are valid from now until now+validity. The whole message
+//including its capabilities and token is signed, so msg must not be modified afterwards.
+func SignMessage(msg *message.Message, ks map[keys.PublicKeyID]interface{},
+	validity time.Duration) error {
+	encoding, err := messageEncoding(msg)
+	if err != nil {
+		return err
+	}
+	msg.Signatures = nil
+	for keyID, key := range ks {
+		sig := signature.Sig{
+			PublicKeyID: keyID,
+			ValidSince:  time.Now().Unix(),
+			ValidUntil:  time.Now().Add(validity).Unix(),
+		}
+		if err := (&sig).SignData(key, encoding); err != nil {
+			return err
+		}
+		msg.Signatures = append(msg.Signatures, sig)
+	}
+	return nil
+}
+
+//CheckMessageSignatures verifies all signatures on msg but not the signatures of the contained
+//sections. Returns true if msg has at least one non expired signature and all non expired
+//signatures are valid under a time overlapping public key in pkeys.
+func CheckMessageSignatures(msg *message.Message, pkeys map[keys.PublicKeyID][]keys.PublicKey) bool {
+	if msg == nil || len(msg.Signatures) == 0 {
+		return false
+	}
+	encoding, err := messageEncoding(msg)
+	if err != nil {
+		log.Warn("Was not able to marshal message.", "error", err)
+		return false
+	}
+	valid := false
+	for _, sig := range msg.Signatures {
+		if sig.ValidUntil < time.Now().Unix() {
+			log.Info("message signature is expired", "signature", sig)
+			continue
+		}
+		key, ok := getPublicKey(pkeys[sig.PublicKeyID], sig.MetaData())
+		if !ok {
+			log.Warn("No time overlapping publicKey for message signature", "keys", pkeys,
+				"signature", sig)
+			return false
+		}
+		if !sig.VerifySignature(key.Key, encoding) {
+			log.Warn("Message signature does not match", "token", msg.Token, "signature", sig)
+			return false
+		}
+		valid = true
+	}
+	return valid
+}
+
+//messageEncoding returns the cbor encoding of msg without its signatures.
+func messageEncoding(msg *message.Message) ([]byte, error) {
+	sigs := msg.Signatures
+	msg.Signatures = nil
+	defer func() { msg.Signatures = sigs }()
+	encoding := new(bytes.Buffer)
+	if err := msg.MarshalCBOR(cbor.NewCBORWriter(encoding)); err != nil {
+		return nil, fmt.Errorf("Was not able to marshal message: %v", err)
+	}
+	return encoding.Bytes(), nil
+}
+
 //ValidSectionAndSignature returns true if the section is not nil, all the signatures ValidUntil are
 //in the future, the string fields do not contain  <whitespace>:<non whitespace>:<whitespace>, and
 //the section's content is sorted (by sorting it).
@@ -194,6 +282,31 @@ func CheckSignatureNotExpired(s section.WithSig) bool {
 	return true
 }
 
+//CheckNameset returns true if m is nil or if the subject names of s and of all assertions contained
+//in s are matched by m. The subject name @ referring to the zone itself is not checked.
+func CheckNameset(s section.WithSigForward, m *nameset.Matcher) bool {
+	if m == nil {
+		return true
+	}
+	var assertions []*section.Assertion
+	switch s := s.(type) {
+	case *section.Assertion:
+		assertions = []*section.Assertion{s}
+	case *section.Shard:
+		assertions = s.Content
+	case *section.Zone:
+		assertions = s.Content
+	}
+	for _, a := range assertions {
+		if a.SubjectName != "@" && !m.Match(a.SubjectName) {
+			log.Warn("Subject name is not in the zone's nameset", "subjectName", a.SubjectName,
+				"nameset", m)
+			return false
+		}
+	}
+	return true
+}
+
 //checkMessageStringFields returns true if the capabilities and all string fields in the contained
 //sections of the given message do not contain a zone file type marker, i.e. not a substring
 //matching regrex expression '\s:\S+:\s'
@@ -253,6 +366,20 @@ func CheckStringFields(s section.Section) bool {
 			}
 		}
 		return !(containsZoneFileType(s.Context) || containsZoneFileType(s.SubjectZone))
+	case *section.AddressAssertion:
+		if !checkObjectFields(s.Content) {
+			return false
+		}
+		return !containsZoneFileType(s.Context)
+	case *section.AddressZone:
+		for _, a := range s.Content {
+			if !CheckStringFields(a) {
+				return false
+			}
+		}
+		return !containsZoneFileType(s.Context)
+	case *query.Address:
+		return !containsZoneFileType(s.Context)
 	case *query.Name:
 		if containsZoneFileType(s.Context) {
 			return false
@@ -369,6 +496,10 @@ func updateSectionValidity(sec section.WithSig, pkeyValidSince, pkeyValidUntil,
 			maxValidity = maxVal.PshardValidity
 		case *section.Zone:
 			maxValidity = maxVal.ZoneValidity
+		case *section.AddressAssertion:
+			maxValidity = maxVal.AssertionValidity
+		case *section.AddressZone:
+			maxValidity = maxVal.ZoneValidity
 		default:
 			log.Warn("Not supported section", "type", fmt.Sprintf("%T", sec))
 			return
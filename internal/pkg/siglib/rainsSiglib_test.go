@@ -1,15 +1,18 @@
 package siglib
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
+	cbor "github.com/britram/borat"
 	log "github.com/inconshreveable/log15"
 	"golang.org/x/crypto/ed25519"
 
 	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
 	"github.com/netsec-ethz/rains/internal/pkg/keys"
 	"github.com/netsec-ethz/rains/internal/pkg/message"
+	"github.com/netsec-ethz/rains/internal/pkg/nameset"
 	"github.com/netsec-ethz/rains/internal/pkg/object"
 	"github.com/netsec-ethz/rains/internal/pkg/query"
 	"github.com/netsec-ethz/rains/internal/pkg/section"
@@ -46,6 +49,39 @@ func TestSignSectionUnsafe(t *testing.T) {
 	}
 }
 
+func TestSignAddressSections(t *testing.T) {
+	var tests = []struct {
+		sec section.WithSig
+	}{
+		{section.GetAddressAssertion()},
+		{section.GetAddressZone()},
+	}
+	for i, test := range tests {
+		genPublicKey, genPrivateKey, _ := ed25519.GenerateKey(nil)
+		sig := section.Signature()
+		test.sec.AddSig(sig)
+		test.sec.Sort()
+		ks := map[keys.PublicKeyID]interface{}{sig.PublicKeyID: genPrivateKey}
+		if err := SignSectionUnsafe(test.sec, ks); err != nil {
+			t.Fatalf("%d: Was not able to sign %T: %v", i, test.sec, err)
+		}
+		pubKey := keys.PublicKey{
+			PublicKeyID: sig.PublicKeyID,
+			ValidSince:  time.Now().Unix(),
+			ValidUntil:  time.Now().Add(time.Hour).Unix(),
+			Key:         genPublicKey,
+		}
+		ksPub := map[keys.PublicKeyID][]keys.PublicKey{sig.PublicKeyID: []keys.PublicKey{pubKey}}
+		maxVal := util.MaxCacheValidity{AssertionValidity: time.Hour, ZoneValidity: time.Hour}
+		if !CheckSectionSignatures(test.sec, ksPub, maxVal) {
+			t.Errorf("%d: Signature of %T is not valid", i, test.sec)
+		}
+		if test.sec.ValidUntil() == 0 {
+			t.Errorf("%d: Validity of %T was not updated", i, test.sec)
+		}
+	}
+}
+
 func TestSignErrors(t *testing.T) {
 	var tests = []struct {
 		section section.WithSig
@@ -95,6 +131,53 @@ func TestCheckSectionSignaturesErrors(t *testing.T) {
 	}
 }
 
+func TestSignMessage(t *testing.T) {
+	genPublicKey, genPrivateKey, _ := ed25519.GenerateKey(nil)
+	keyID := keys.PublicKeyID{Algorithm: algorithmTypes.Ed25519}
+	ks := map[keys.PublicKeyID]interface{}{keyID: genPrivateKey}
+	pubKey := keys.PublicKey{
+		PublicKeyID: keyID,
+		ValidSince:  time.Now().Unix(),
+		ValidUntil:  time.Now().Add(time.Hour).Unix(),
+		Key:         genPublicKey,
+	}
+	ksPub := map[keys.PublicKeyID][]keys.PublicKey{keyID: []keys.PublicKey{pubKey}}
+	msg := message.GetMessage()
+	if CheckMessageSignatures(&msg, ksPub) {
+		t.Error("Message without signatures must not be valid")
+	}
+	if err := SignMessage(&msg, ks, time.Minute); err != nil || len(msg.Signatures) != 1 {
+		t.Fatalf("Was not able to sign message: %v", err)
+	}
+	//Signature must still be valid after an encoding round trip
+	encoding := new(bytes.Buffer)
+	if err := msg.MarshalCBOR(cbor.NewCBORWriter(encoding)); err != nil {
+		t.Fatalf("Was not able to encode message: %v", err)
+	}
+	var decoded message.Message
+	if err := decoded.UnmarshalCBOR(cbor.NewCBORReader(encoding)); err != nil {
+		t.Fatalf("Was not able to decode message: %v", err)
+	}
+	if !CheckMessageSignatures(&decoded, ksPub) {
+		t.Error("Signature of decoded message is not valid")
+	}
+	//Modified message
+	decoded.Token[0]++
+	if CheckMessageSignatures(&decoded, ksPub) {
+		t.Error("Signature of modified message must not be valid")
+	}
+	//Wrong key
+	otherPublicKey, _, _ := ed25519.GenerateKey(nil)
+	pubKey.Key = otherPublicKey
+	if CheckMessageSignatures(&msg, map[keys.PublicKeyID][]keys.PublicKey{keyID: []keys.PublicKey{pubKey}}) {
+		t.Error("Signature must not be valid under a different key")
+	}
+	//Missing key
+	if CheckMessageSignatures(&msg, nil) {
+		t.Error("Signature must not be valid without a public key")
+	}
+}
+
 func TestCheckMessageStringFields(t *testing.T) {
 	log.Root().SetHandler(log.DiscardHandler())
 	msg := message.GetMessage()
@@ -144,6 +227,14 @@ func TestCheckStringFields(t *testing.T) {
 		{&query.Name{Context: ":ip:"}, false},
 		{&query.Name{Name: ":ip:"}, false},
 		{&section.Notification{Data: ":ip:"}, false},
+		{sections[6], true},
+		{sections[7], true},
+		{sections[8], true},
+		{&section.AddressAssertion{Context: ":ip:"}, false},
+		{&section.AddressAssertion{Content: []object.Object{object.Object{Type: object.OTRegistrant, Value: ":ip55:"}}}, false},
+		{&section.AddressZone{Context: ":ip:"}, false},
+		{&section.AddressZone{Content: []*section.AddressAssertion{&section.AddressAssertion{Context: ":ip:"}}}, false},
+		{&query.Address{Context: ":ip:"}, false},
 	}
 	for _, test := range tests {
 		val := CheckStringFields(test.input)
@@ -284,6 +375,33 @@ func TestCheckSignatureNotExpired(t *testing.T) {
 	}
 }
 
+func TestCheckNameset(t *testing.T) {
+	m := nameset.MustCompile("[a-z]+")
+	var tests = []struct {
+		s        section.WithSigForward
+		m        *nameset.Matcher
+		expected bool
+	}{
+		{&section.Assertion{SubjectName: "WWW"}, nil, true},
+		{&section.Assertion{SubjectName: "www"}, m, true},
+		{&section.Assertion{SubjectName: "@"}, m, true},
+		{&section.Assertion{SubjectName: "WWW"}, m, false},
+		{&section.Shard{Content: []*section.Assertion{{SubjectName: "a"}, {SubjectName: "b"}}}, m,
+			true},
+		{&section.Shard{Content: []*section.Assertion{{SubjectName: "a"}, {SubjectName: "_b"}}}, m,
+			false},
+		{&section.Zone{Content: []*section.Assertion{{SubjectName: "@"}, {SubjectName: "a"}}}, m,
+			true},
+		{&section.Zone{Content: []*section.Assertion{{SubjectName: "a1"}}}, m, false},
+		{&section.Pshard{}, m, true},
+	}
+	for i, test := range tests {
+		if ok := CheckNameset(test.s, test.m); ok != test.expected {
+			t.Errorf("%d: unexpected result. expected=%v actual=%v", i, test.expected, ok)
+		}
+	}
+}
+
 func TestUpdateSectionValidity(t *testing.T) {
 	now := time.Now().Unix()
 	var tests = []struct {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/address"
 	"github.com/netsec-ethz/rains/internal/pkg/cbor"
 	"github.com/netsec-ethz/rains/internal/pkg/connection"
 	"github.com/netsec-ethz/rains/internal/pkg/keys"
@@ -100,6 +101,20 @@ func NewQueryMessage(name, context string, expTime int64, objType []object.Type,
 	return message.Message{Token: token, Content: []section.Section{&query}}
 }
 
+//NewAddressQueryMessage creates a new message containing an address query body with values
+//obtained from the input parameter
+func NewAddressQueryMessage(subjectAddr address.Prefix, context string, expTime int64,
+	objType []object.Type, queryOptions []query.Option, token token.Token) message.Message {
+	query := query.Address{
+		Context:     context,
+		SubjectAddr: subjectAddr,
+		Expiration:  expTime,
+		Types:       objType,
+		Options:     queryOptions,
+	}
+	return message.Message{Token: token, Content: []section.Section{&query}}
+}
+
 //NewNotificationsMessage creates a new message containing notification bodies with values obtained from the input parameter
 func NewNotificationsMessage(tokens []token.Token, types []section.NotificationType, data []string) (message.Message, error) {
 	if len(tokens) != len(types) || len(types) != len(data) {
@@ -145,7 +160,8 @@ func SendQuery(msg message.Message, addr net.Addr, timeout time.Duration) (
 		if err := writer.Marshal(&msg); err != nil {
 			return message.Message{}, fmt.Errorf("failed to marshal message: %v", err)
 		}
-	case *snet.Addr:
+	case *snet.Addr, *net.UDPAddr:
+		//Connectionless transports carry exactly one message per datagram.
 		encoding := new(bytes.Buffer)
 		if err := cbor.NewWriter(encoding).Marshal(&msg); err != nil {
 			return message.Message{}, fmt.Errorf("failed to marshal message to conn: %v", err)
@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/netsec-ethz/rains/internal/pkg/address"
 	"github.com/netsec-ethz/rains/internal/pkg/message"
 	"github.com/netsec-ethz/rains/internal/pkg/signature"
 
@@ -84,6 +85,28 @@ func TestNewQueryMessage(t *testing.T) {
 	}
 }
 
+func TestNewAddressQueryMessage(t *testing.T) {
+	tok := token.New()
+	prefix, _ := address.Parse("192.0.2.1")
+	expected := message.Message{
+		Token: tok,
+		Content: []section.Section{
+			&query.Address{
+				SubjectAddr: prefix,
+				Context:     ".",
+				Expiration:  100,
+				Types:       []object.Type{object.OTName},
+				Options:     []query.Option{query.QOMinE2ELatency},
+			},
+		},
+	}
+	msg := NewAddressQueryMessage(prefix, ".", 100, []object.Type{object.OTName},
+		[]query.Option{query.QOMinE2ELatency}, tok)
+	if !reflect.DeepEqual(expected, msg) {
+		t.Errorf("Message containing address query do not match. expected=%v actual=%v", expected, msg)
+	}
+}
+
 func TestNewNotificationsMessage(t *testing.T) {
 	tokens := []token.Token{}
 	for i := 0; i < 10; i++ {
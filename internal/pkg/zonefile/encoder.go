@@ -22,6 +22,12 @@ func encodeQuery(q *query.Name) string {
 		q.Expiration, encodeQueryOptions(q.Options))
 }
 
+//encodeAddressQuery returns an encoding which resembles the zone file format
+func encodeAddressQuery(q *query.Address) string {
+	return fmt.Sprintf(":AQ: %s %s %s %d %s", q.Context, q.SubjectAddr, encodeObjectTypes(q.Types),
+		q.Expiration, encodeQueryOptions(q.Options))
+}
+
 //encodeNotification returns a notification in signable format (which resembles the zone file format)
 func encodeNotification(n *section.Notification) string {
 	return fmt.Sprintf(":N: %s %s %s", n.Token.String(), strconv.Itoa(int(n.Type)), n.Data)
@@ -0,0 +1,560 @@
+package zonefile
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+//maxIncludeDepth is the maximum nesting of $INCLUDE entries in a master file.
+const maxIncludeDepth = 8
+
+//aliasTypes are the object types for which a name object translated from a CNAME record is valid.
+//A CNAME record aliases all types, thus these are all types produced from a master file.
+var aliasTypes = []object.Type{object.OTIP6Addr, object.OTIP4Addr, object.OTRedirection,
+	object.OTCertInfo, object.OTServiceInfo, object.OTRegistrant}
+
+//tlsaUsages maps the certificate usage of a TLSA record to the RAINS certificate usage.
+var tlsaUsages = map[string]object.CertificateUsage{
+	"2": object.CUTrustAnchor,
+	"3": object.CUEndEntity,
+}
+
+//tlsaHashAlgos maps the matching type of a TLSA record to the RAINS hash algorithm.
+var tlsaHashAlgos = map[string]algorithmTypes.Hash{
+	"0": algorithmTypes.NoHashAlgo,
+	"1": algorithmTypes.Sha256,
+	"2": algorithmTypes.Sha512,
+}
+
+//UnmappableRecord is a record of a DNS master file which cannot be expressed in RAINS.
+type UnmappableRecord struct {
+	File   string
+	Line   int
+	Record string
+	Reason string
+}
+
+func (r UnmappableRecord) String() string {
+	return fmt.Sprintf("%s: %s: %s", location(r.File, r.Line), r.Reason, r.Record)
+}
+
+//DecodeMasterFile converts the DNS master file data of zone to a zone in context. Relative names
+//are completed with zone until an $ORIGIN entry changes the origin. Files referenced by $INCLUDE
+//entries are looked up relative to dir. TTLs are ignored as the validity of RAINS sections is
+//determined by their signatures. It returns the zone and all records which cannot be expressed in
+//RAINS or an error if data is malformed.
+func (p IO) DecodeMasterFile(data []byte, zone, context, dir string) (*section.Zone,
+	[]UnmappableRecord, error) {
+	mp := newMasterFileParser(zone, context)
+	if err := mp.parse(data, "", dir, zone, 0); err != nil {
+		return nil, nil, err
+	}
+	return mp.result(), mp.unmappable, nil
+}
+
+//LoadMasterFile converts the DNS master file at path of zone to a zone in context. See
+//DecodeMasterFile for details.
+func (p IO) LoadMasterFile(path, zone, context string) (*section.Zone, []UnmappableRecord, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	mp := newMasterFileParser(zone, context)
+	if err := mp.parse(data, path, filepath.Dir(path), zone, 0); err != nil {
+		return nil, nil, err
+	}
+	return mp.result(), mp.unmappable, nil
+}
+
+//masterEntry is an entry of a master file. It spans several lines if it contains parentheses.
+type masterEntry struct {
+	line int
+	//blankOwner is true if the entry starts with white space and thus belongs to the previous owner.
+	blankOwner bool
+	tokens     []string
+	//quoted[i] is true if tokens[i] is a character string in quotes.
+	quoted []bool
+	//object is the zonefile encoding of an object in a structured comment. The only token of such
+	//an entry is the owner.
+	object string
+}
+
+//String returns the entry with all tokens separated by a single space.
+func (e masterEntry) String() string {
+	if e.object != "" {
+		return fmt.Sprintf("%s %s %s", rainsComment, e.tokens[0], e.object)
+	}
+	tokens := make([]string, len(e.tokens))
+	for i, t := range e.tokens {
+		if e.quoted[i] {
+			t = strconv.Quote(t)
+		}
+		tokens[i] = t
+	}
+	return strings.Join(tokens, " ")
+}
+
+//masterEntries splits data into entries. Comments are removed except structured comments containing
+//an object without DNS equivalent. Escape sequences in quoted strings are resolved.
+func masterEntries(data []byte) ([]masterEntry, error) {
+	var entries []masterEntry
+	var entry masterEntry
+	depth := 0
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if depth == 0 {
+			entry = masterEntry{line: i + 1,
+				blankOwner: strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")}
+			if fields := strings.Fields(strings.TrimPrefix(line, rainsComment)); strings.HasPrefix(line,
+				rainsComment) && len(fields) > 1 {
+				entry.tokens, entry.quoted = fields[:1], []bool{false}
+				entry.object = strings.Join(fields[1:], " ")
+				entries = append(entries, entry)
+				continue
+			}
+		}
+		for pos := 0; pos < len(line); {
+			switch c := line[pos]; c {
+			case ';':
+				pos = len(line)
+			case ' ', '\t':
+				pos++
+			case '(':
+				depth++
+				pos++
+			case ')':
+				if depth == 0 {
+					return nil, fmt.Errorf("line %d: unbalanced parentheses", i+1)
+				}
+				depth--
+				pos++
+			case '"':
+				text, end, err := quotedString(line, pos)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", i+1, err)
+				}
+				entry.tokens = append(entry.tokens, text)
+				entry.quoted = append(entry.quoted, true)
+				pos = end
+			default:
+				end := pos
+				for end < len(line) && !strings.ContainsRune(" \t;()\"", rune(line[end])) {
+					if line[end] == '\\' {
+						end++
+					}
+					end++
+				}
+				if end > len(line) {
+					end = len(line)
+				}
+				entry.tokens = append(entry.tokens, line[pos:end])
+				entry.quoted = append(entry.quoted, false)
+				pos = end
+			}
+		}
+		if depth == 0 && len(entry.tokens) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("line %d: unbalanced parentheses", entry.line)
+	}
+	return entries, nil
+}
+
+//quotedString returns the content of the quoted string starting at start in line and the position
+//after the closing quote.
+func quotedString(line string, start int) (string, int, error) {
+	var text []byte
+	for pos := start + 1; pos < len(line); pos++ {
+		switch line[pos] {
+		case '"':
+			return string(text), pos + 1, nil
+		case '\\':
+			if pos+3 < len(line) && isDigits(line[pos+1:pos+4]) {
+				value, _ := strconv.Atoi(line[pos+1 : pos+4])
+				if value > 255 {
+					return "", 0, errors.New("invalid escape sequence in quoted string")
+				}
+				text = append(text, byte(value))
+				pos += 3
+			} else if pos+1 < len(line) {
+				text = append(text, line[pos+1])
+				pos++
+			}
+		default:
+			text = append(text, line[pos])
+		}
+	}
+	return "", 0, errors.New("unterminated quoted string")
+}
+
+//masterFileParser collects the assertions of a zone from one or several master files.
+type masterFileParser struct {
+	zone       string
+	context    string
+	assertions map[string]*section.Assertion
+	//names contains the subject names of assertions in the order of their first occurrence.
+	names      []string
+	unmappable []UnmappableRecord
+}
+
+func newMasterFileParser(zone, context string) *masterFileParser {
+	if !strings.HasSuffix(zone, ".") {
+		zone += "."
+	}
+	return &masterFileParser{
+		zone:       zone,
+		context:    context,
+		assertions: make(map[string]*section.Assertion),
+	}
+}
+
+//parse adds the records of the master file data stored at file to the zone. Relative names in data
+//are completed with origin.
+func (mp *masterFileParser) parse(data []byte, file, dir, origin string, depth int) error {
+	entries, err := masterEntries(data)
+	if err != nil {
+		if file == "" {
+			return err
+		}
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	origin = absoluteName(origin, ".")
+	owner := ""
+	for _, e := range entries {
+		if e.object != "" {
+			if err := mp.addEncodedObject(e, file, origin); err != nil {
+				return fmt.Errorf("%s: %v", location(file, e.line), err)
+			}
+			continue
+		}
+		if !e.blankOwner && strings.HasPrefix(e.tokens[0], "$") {
+			if origin, err = mp.control(e, file, dir, origin, depth); err != nil {
+				return fmt.Errorf("%s: %v", location(file, e.line), err)
+			}
+			continue
+		}
+		tokens := e.tokens
+		if !e.blankOwner {
+			owner = absoluteName(tokens[0], origin)
+			tokens = tokens[1:]
+		} else if owner == "" {
+			return fmt.Errorf("%s: entry without owner", location(file, e.line))
+		}
+		class := "IN"
+		for i := 0; i < 2 && len(tokens) > 1; i++ {
+			if isTTL(tokens[0]) {
+				tokens = tokens[1:]
+			} else if isClass(tokens[0]) {
+				class = strings.ToUpper(tokens[0])
+				tokens = tokens[1:]
+			}
+		}
+		if len(tokens) == 0 {
+			return fmt.Errorf("%s: record type is missing", location(file, e.line))
+		}
+		unmappable := func(reason string) {
+			mp.unmappable = append(mp.unmappable,
+				UnmappableRecord{File: file, Line: e.line, Record: e.String(), Reason: reason})
+		}
+		subjectName, ok := relativeName(owner, mp.zone)
+		if class != "IN" {
+			unmappable("class " + class + " is not supported")
+			continue
+		}
+		if !ok {
+			unmappable("owner is not in zone " + mp.zone)
+			continue
+		}
+		rrType := strings.ToUpper(tokens[0])
+		obj, reason, err := masterObject(rrType, tokens[1:], origin)
+		if err != nil {
+			return fmt.Errorf("%s: %s record: %v", location(file, e.line), rrType, err)
+		}
+		if reason != "" {
+			unmappable(reason)
+			if obj.Value == nil {
+				continue
+			}
+		}
+		mp.add(subjectName, obj)
+	}
+	return nil
+}
+
+//control processes the control entry e and returns the origin for the following entries.
+func (mp *masterFileParser) control(e masterEntry, file, dir, origin string, depth int) (string,
+	error) {
+	switch strings.ToUpper(e.tokens[0]) {
+	case "$ORIGIN":
+		if len(e.tokens) != 2 {
+			return "", errors.New("$ORIGIN requires a domain name")
+		}
+		return absoluteName(e.tokens[1], origin), nil
+	case "$TTL":
+		if len(e.tokens) != 2 || !isTTL(e.tokens[1]) {
+			return "", errors.New("$TTL requires a time value")
+		}
+		//TTLs are ignored, the validity of a RAINS section is determined by its signatures.
+		return origin, nil
+	case "$INCLUDE":
+		if len(e.tokens) < 2 || len(e.tokens) > 3 {
+			return "", errors.New("$INCLUDE requires a file name and an optional origin")
+		}
+		if depth >= maxIncludeDepth {
+			return "", errors.New("$INCLUDE is nested too deeply")
+		}
+		path := e.tokens[1]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		includeOrigin := origin
+		if len(e.tokens) == 3 {
+			includeOrigin = absoluteName(e.tokens[2], origin)
+		}
+		//The origin of the including file is not changed by the included file.
+		return origin, mp.parse(data, path, filepath.Dir(path), includeOrigin, depth+1)
+	}
+	mp.unmappable = append(mp.unmappable, UnmappableRecord{File: file, Line: e.line,
+		Record: e.String(), Reason: "control entry is not supported"})
+	return origin, nil
+}
+
+//masterObject returns the RAINS object corresponding to a DNS record of rrType with the given
+//rdata. If the record cannot be expressed in RAINS, it returns the reason instead. If only a part of
+//the record is expressed, it returns the object together with the reason why the rest is dropped.
+//It returns an error if rdata is malformed.
+func masterObject(rrType string, rdata []string, origin string) (object.Object, string, error) {
+	switch rrType {
+	case "A", "AAAA":
+		if len(rdata) != 1 {
+			return object.Object{}, "", errors.New("requires an IP address")
+		}
+		ip := net.ParseIP(rdata[0])
+		if ip == nil || (ip.To4() != nil) != (rrType == "A") {
+			return object.Object{}, "", fmt.Errorf("invalid address %s", rdata[0])
+		}
+		if rrType == "A" {
+			return object.Object{Type: object.OTIP4Addr, Value: ip.String()}, "", nil
+		}
+		return object.Object{Type: object.OTIP6Addr, Value: ip.String()}, "", nil
+	case "CNAME":
+		if len(rdata) != 1 {
+			return object.Object{}, "", errors.New("requires a domain name")
+		}
+		return object.Object{Type: object.OTName, Value: object.Name{
+			Name: absoluteName(rdata[0], origin), Types: aliasTypes}}, "", nil
+	case "NS":
+		if len(rdata) != 1 {
+			return object.Object{}, "", errors.New("requires a domain name")
+		}
+		return object.Object{Type: object.OTRedirection, Value: absoluteName(rdata[0], origin)}, "",
+			nil
+	case "SRV":
+		if len(rdata) != 4 {
+			return object.Object{}, "", errors.New("requires priority, weight, port and target")
+		}
+		weight, err := strconv.ParseUint(rdata[1], 10, 16)
+		if err != nil {
+			return object.Object{}, "", fmt.Errorf("invalid weight %s", rdata[1])
+		}
+		srv, err := DecodeSrv(absoluteName(rdata[3], origin), rdata[2], rdata[0])
+		if err != nil {
+			return object.Object{}, "", err
+		}
+		//RAINS has no weights, all targets of the same priority are equally likely.
+		reason := ""
+		if weight != 0 {
+			reason = fmt.Sprintf("weight %d is dropped as RAINS has no weights", weight)
+		}
+		return object.Object{Type: object.OTServiceInfo, Value: srv}, reason, nil
+	case "TLSA":
+		if len(rdata) < 4 {
+			return object.Object{}, "", errors.New("requires usage, selector, matching type and data")
+		}
+		usage, ok := tlsaUsages[rdata[0]]
+		if !ok {
+			return object.Object{}, "certificate usage " + rdata[0] + " is not supported", nil
+		}
+		if rdata[1] != "0" {
+			return object.Object{}, "only the full certificate can be expressed (selector 0)", nil
+		}
+		hashAlgo, ok := tlsaHashAlgos[rdata[2]]
+		if !ok {
+			return object.Object{}, "matching type " + rdata[2] + " is not supported", nil
+		}
+		cert, err := DecodeCertificate(object.PTTLS, usage, hashAlgo, strings.Join(rdata[3:], ""))
+		if err != nil {
+			return object.Object{}, "", err
+		}
+		return object.Object{Type: object.OTCertInfo, Value: cert}, "", nil
+	case "TXT":
+		if len(rdata) == 0 {
+			return object.Object{}, "", errors.New("requires at least one character string")
+		}
+		//RAINS has no generic text object, the registrant information is the closest match. The
+		//mapping is lossy as the boundaries between the character strings are not preserved.
+		text := strings.Join(rdata, " ")
+		if !isFreeText(text) {
+			return object.Object{}, "text cannot be expressed as free text in a zonefile", nil
+		}
+		reason := ""
+		if len(rdata) > 1 {
+			reason = "character strings are joined with a space"
+		}
+		return object.Object{Type: object.OTRegistrant, Value: text}, reason, nil
+	case "SOA":
+		return object.Object{}, "RAINS has no start of authority record", nil
+	}
+	return object.Object{}, "record type " + rrType + " has no corresponding RAINS object", nil
+}
+
+//addEncodedObject decodes the object of the structured comment e and adds it to the assertion of
+//its owner.
+func (mp *masterFileParser) addEncodedObject(e masterEntry, file, origin string) error {
+	subjectName, ok := relativeName(absoluteName(e.tokens[0], origin), mp.zone)
+	if !ok {
+		mp.unmappable = append(mp.unmappable, UnmappableRecord{File: file, Line: e.line,
+			Record: e.String(), Reason: "owner is not in zone " + mp.zone})
+		return nil
+	}
+	sections, err := IO{}.Decode([]byte(fmt.Sprintf("%s %s %s %s [ %s ]", TypeAssertion,
+		subjectName, mp.zone, mp.context, e.object)))
+	if err != nil {
+		return fmt.Errorf("malformed object in structured comment: %s", e.object)
+	}
+	if a, ok := sections[0].(*section.Assertion); ok {
+		for _, obj := range a.Content {
+			mp.add(subjectName, obj)
+		}
+	}
+	return nil
+}
+
+//add appends obj to the assertion about subjectName.
+func (mp *masterFileParser) add(subjectName string, obj object.Object) {
+	a, ok := mp.assertions[subjectName]
+	if !ok {
+		a = &section.Assertion{SubjectName: subjectName}
+		mp.assertions[subjectName] = a
+		mp.names = append(mp.names, subjectName)
+	}
+	a.Content = append(a.Content, obj)
+}
+
+//result returns the zone containing all collected assertions.
+func (mp *masterFileParser) result() *section.Zone {
+	zone := &section.Zone{SubjectZone: mp.zone, Context: mp.context}
+	for _, name := range mp.names {
+		zone.Content = append(zone.Content, mp.assertions[name])
+	}
+	return zone
+}
+
+//location returns the position of line in file for error messages.
+func location(file string, line int) string {
+	if file == "" {
+		return fmt.Sprintf("line %d", line)
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+//absoluteName returns the fully qualified domain name of name relative to origin.
+func absoluteName(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return name
+	case origin == ".":
+		return name + "."
+	}
+	return name + "." + origin
+}
+
+//relativeName returns the subject name of the fully qualified domain name name in zone and true. It
+//returns false if name is not in zone.
+func relativeName(name, zone string) (string, bool) {
+	name, zone = strings.ToLower(name), strings.ToLower(zone)
+	switch {
+	case name == zone:
+		return "@", true
+	case zone == ".":
+		return strings.TrimSuffix(name, "."), true
+	case strings.HasSuffix(name, "."+zone):
+		return strings.TrimSuffix(name, "."+zone), true
+	}
+	return "", false
+}
+
+//isTTL returns true if s is a TTL in seconds or in the unit notation of BIND, e.g. 1h30m.
+func isTTL(s string) bool {
+	if s == "" {
+		return false
+	}
+	digits := false
+	for _, c := range strings.ToLower(s) {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case strings.ContainsRune("smhdw", c) && digits:
+			digits = false
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+//isClass returns true if s is a DNS class.
+func isClass(s string) bool {
+	s = strings.ToUpper(s)
+	switch s {
+	case "IN", "CS", "CH", "HS":
+		return true
+	}
+	return strings.HasPrefix(s, "CLASS") && len(s) > len("CLASS") && isDigits(s[len("CLASS"):])
+}
+
+//isFreeText returns true if text is encoded unchanged as free text in a zonefile, i.e. it consists
+//of words separated by single spaces none of which is a keyword of the zonefile format and it
+//contains no comment.
+func isFreeText(text string) bool {
+	words := strings.Fields(text)
+	if len(words) == 0 || strings.Join(words, " ") != text || strings.Contains(text, ";") {
+		return false
+	}
+	for _, w := range words {
+		switch w {
+		case "[", "]", "(", ")", "<", ">":
+			return false
+		}
+		if len(w) > 1 && strings.HasPrefix(w, ":") && strings.HasSuffix(w, ":") {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,200 @@
+package zonefile
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	log "github.com/inconshreveable/log15"
+
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+const (
+	//masterFileTTL is the TTL of all records in an exported master file. RAINS has no TTLs, the
+	//validity of a section is determined by its signatures.
+	masterFileTTL = 3600
+	//rainsComment starts a structured comment containing an object without DNS equivalent.
+	rainsComment = ";rains:"
+	//maxCharacterString is the maximum length of a character string in a TXT record.
+	maxCharacterString = 255
+)
+
+//EncodeMasterFile returns the zones and assertions of sections as a DNS master file. Objects
+//without DNS equivalent are encoded as structured comments which are restored by DecodeMasterFile.
+//Shards, pshards and signatures are omitted. A start of authority record is synthesized for each
+//zone.
+func (p IO) EncodeMasterFile(sections []section.WithSigForward) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$TTL %d\n", masterFileTTL)
+	for _, s := range sections {
+		switch s := s.(type) {
+		case *section.Zone:
+			fmt.Fprintf(&b, "\n; RAINS zone %s in context %s\n$ORIGIN %s\n", s.SubjectZone, s.Context,
+				absoluteName(s.SubjectZone, "."))
+			b.WriteString(encodeSOA(s))
+			for _, a := range s.Content {
+				encodeMasterFileAssertion(&b, a, a.SubjectName)
+			}
+		case *section.Assertion:
+			encodeMasterFileAssertion(&b, s, s.FQDN())
+		default:
+			log.Debug("Section has no representation in a master file", "type", fmt.Sprintf("%T", s))
+		}
+	}
+	return b.String()
+}
+
+//encodeSOA returns a start of authority record for zone. RAINS has no such record, thus all fields
+//are synthesized. The primary name server is the first redirection of the zone apex.
+func encodeSOA(zone *section.Zone) string {
+	mname := absoluteName(zone.SubjectZone, ".")
+	for _, a := range zone.Content {
+		if a.SubjectName != "@" {
+			continue
+		}
+		for _, o := range a.Content {
+			if target, ok := o.Value.(string); ok && o.Type == object.OTRedirection &&
+				strings.HasSuffix(target, ".") {
+				mname = target
+				break
+			}
+		}
+	}
+	return fmt.Sprintf("@ IN SOA %s %s %d 3600 600 86400 %d\n", mname,
+		absoluteName("hostmaster", absoluteName(zone.SubjectZone, ".")), uint32(time.Now().Unix()),
+		masterFileTTL)
+}
+
+//encodeMasterFileAssertion writes the objects of a as records or structured comments of owner to b.
+func encodeMasterFileAssertion(b *strings.Builder, a *section.Assertion, owner string) {
+	nofRecords := 0
+	for _, o := range a.Content {
+		if _, _, ok := masterRecord(o); ok {
+			nofRecords++
+		}
+	}
+	for _, o := range a.Content {
+		rrType, rdata, ok := masterRecord(o)
+		//A CNAME record cannot coexist with other records of the same owner.
+		if ok && !(rrType == "CNAME" && nofRecords > 1) {
+			fmt.Fprintf(b, "%s IN %s %s\n", owner, rrType, rdata)
+		} else if encoding := encodeObjects([]object.Object{o}, ""); encoding != "" {
+			fmt.Fprintf(b, "%s %s %s\n", rainsComment, owner, strings.Join(strings.Fields(encoding), " "))
+		}
+	}
+}
+
+//masterRecord returns the type and rdata of the DNS record corresponding to o and true. It returns
+//false if o cannot be expressed in DNS without losing information.
+func masterRecord(o object.Object) (string, string, bool) {
+	switch v := o.Value.(type) {
+	case string:
+		switch o.Type {
+		case object.OTIP4Addr:
+			if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+				return "A", v, true
+			}
+		case object.OTIP6Addr:
+			if ip := net.ParseIP(v); ip != nil && ip.To4() == nil {
+				return "AAAA", v, true
+			}
+		case object.OTRedirection:
+			if strings.HasSuffix(v, ".") {
+				return "NS", v, true
+			}
+		case object.OTRegistrant:
+			if len(v) <= maxCharacterString && isFreeText(v) {
+				return "TXT", quoteCharacterString(v), true
+			}
+		}
+	case object.Name:
+		//A CNAME record aliases all types.
+		if strings.HasSuffix(v.Name, ".") && sameTypes(v.Types, aliasTypes) {
+			return "CNAME", v.Name, true
+		}
+	case object.ServiceInfo:
+		//RAINS has no weights, all targets of the same priority are equally likely.
+		if strings.HasSuffix(v.Name, ".") && v.Priority <= 0xffff {
+			return "SRV", fmt.Sprintf("%d 0 %d %s", v.Priority, v.Port, v.Name), true
+		}
+	case object.Certificate:
+		if v.Type != object.PTTLS {
+			break
+		}
+		usage, ok := tlsaUsageOf(v.Usage)
+		matching, ok2 := tlsaMatchingTypeOf(v)
+		if ok && ok2 && len(v.Data) > 0 {
+			//The selector is 0 as RAINS certificates contain or hash the full certificate.
+			return "TLSA", fmt.Sprintf("%s 0 %s %s", usage, matching, hex.EncodeToString(v.Data)),
+				true
+		}
+	}
+	return "", "", false
+}
+
+//tlsaUsageOf returns the certificate usage of a TLSA record corresponding to usage and true. It
+//returns false if there is none.
+func tlsaUsageOf(usage object.CertificateUsage) (string, bool) {
+	for k, v := range tlsaUsages {
+		if v == usage {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+//tlsaMatchingTypeOf returns the matching type of a TLSA record corresponding to the hash algorithm
+//of cert and true. It returns false if there is none.
+func tlsaMatchingTypeOf(cert object.Certificate) (string, bool) {
+	for k, v := range tlsaHashAlgos {
+		if v == cert.HashAlgo {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+//quoteCharacterString returns s as a quoted character string of a master file.
+func quoteCharacterString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+//sameTypes returns true if a and b contain the same object types.
+func sameTypes(a, b []object.Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, t := range a {
+		if !containsType(b, t) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsType(types []object.Type, t object.Type) bool {
+	for _, typ := range types {
+		if typ == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package zonefile
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+func TestEncodeMasterFile(t *testing.T) {
+	var tests = []struct {
+		input object.Object
+		want  string
+	}{
+		{object.Object{Type: object.OTIP4Addr, Value: "192.0.2.1"}, "www IN A 192.0.2.1"},
+		{object.Object{Type: object.OTIP6Addr, Value: "2001:db8::1"}, "www IN AAAA 2001:db8::1"},
+		{object.Object{Type: object.OTRedirection, Value: "ns.example.com."},
+			"www IN NS ns.example.com."},
+		{object.Object{Type: object.OTRedirection, Value: "ns"}, ";rains: www :redir: ns"},
+		{object.Object{Type: object.OTName, Value: object.Name{Name: "a.example.com.",
+			Types: aliasTypes}}, "www IN CNAME a.example.com."},
+		{object.Object{Type: object.OTName, Value: object.Name{Name: "a.example.com.",
+			Types: []object.Type{object.OTIP4Addr}}}, ";rains: www :name: a.example.com. [ :ip4: ]"},
+		{object.Object{Type: object.OTServiceInfo, Value: object.ServiceInfo{Name: "ns.example.com.",
+			Port: 5022, Priority: 1}}, "www IN SRV 1 0 5022 ns.example.com."},
+		{object.CertificateObject(), "www IN TLSA 3 0 1 6365727444617461"},
+		{object.Object{Type: object.OTRegistrant, Value: `say "hi"`}, `www IN TXT "say \"hi\""`},
+		{object.Object{Type: object.OTRegistrar, Value: "registrar text"},
+			";rains: www :regr: registrar text"},
+		{object.Object{Type: object.OTScionAddr4, Value: "1-ff00:0:111,[192.0.2.1]"},
+			";rains: www :scionip4: 1-ff00:0:111,[192.0.2.1]"},
+	}
+	for i, test := range tests {
+		a := &section.Assertion{SubjectName: "www", Content: []object.Object{test.input}}
+		zone := &section.Zone{SubjectZone: "example.com.", Context: ".",
+			Content: []*section.Assertion{a}}
+		encoding := IO{}.EncodeMasterFile([]section.WithSigForward{zone})
+		if !strings.Contains(encoding, test.want+"\n") {
+			t.Errorf("%d: Encoding wrong. expected line=%s actual=%s", i, test.want, encoding)
+		}
+	}
+}
+
+func TestEncodeMasterFileCNAMEWithOtherRecords(t *testing.T) {
+	a := &section.Assertion{SubjectName: "www", SubjectZone: "example.com.", Context: ".",
+		Content: []object.Object{
+			{Type: object.OTName, Value: object.Name{Name: "a.example.com.", Types: aliasTypes}},
+			{Type: object.OTIP4Addr, Value: "192.0.2.1"},
+		}}
+	encoding := IO{}.EncodeMasterFile([]section.WithSigForward{a})
+	if strings.Contains(encoding, "CNAME") || !strings.Contains(encoding,
+		";rains: www.example.com. :name: a.example.com.") {
+		t.Errorf("CNAME record must not coexist with other records. actual=%s", encoding)
+	}
+}
+
+func TestEncodeMasterFileRoundTrip(t *testing.T) {
+	sections, err := IO{}.LoadZonefile("test/zonefile.txt")
+	if err != nil {
+		t.Fatalf("Was not able to load zonefile: %v", err)
+	}
+	zone := sections[0].(*section.Zone)
+	encoding := IO{}.EncodeMasterFile([]section.WithSigForward{zone})
+	decoded, unmappable, err := IO{}.DecodeMasterFile([]byte(encoding), zone.SubjectZone,
+		zone.Context, "")
+	if err != nil {
+		t.Fatalf("Was not able to decode exported master file: %v\n%s", err, encoding)
+	}
+	//Only the synthesized start of authority record has no RAINS equivalent.
+	if len(unmappable) != 1 || !strings.Contains(unmappable[0].Record, "SOA") {
+		t.Errorf("Wrong unmappable records. expected=[SOA] actual=%v", unmappable)
+	}
+	if want, got := objectsByName(zone), objectsByName(decoded); !reflect.DeepEqual(want, got) {
+		t.Errorf("Round trip lost information. expected=%v actual=%v", want, got)
+	}
+}
+
+//objectsByName returns the objects of all assertions of zone grouped by subject name. IP addresses
+//are normalized.
+func objectsByName(zone *section.Zone) map[string][]object.Object {
+	result := make(map[string][]object.Object)
+	for _, a := range zone.Content {
+		for _, o := range a.Content {
+			if v, ok := o.Value.(string); ok && (o.Type == object.OTIP4Addr ||
+				o.Type == object.OTIP6Addr) {
+				o.Value = net.ParseIP(v).String()
+			}
+			result[a.SubjectName] = append(result[a.SubjectName], o)
+		}
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+package zonefile
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
+	"github.com/netsec-ethz/rains/internal/pkg/object"
+	"github.com/netsec-ethz/rains/internal/pkg/section"
+)
+
+func TestLoadMasterFile(t *testing.T) {
+	certData, _ := hex.DecodeString("e28b1bd3a73882b198dfe4f0fa954ce28b1bd3a73882b198dfe4f0fa954c")
+	want := &section.Zone{SubjectZone: "example.com.", Context: ".", Content: []*section.Assertion{
+		{SubjectName: "@", Content: []object.Object{
+			{Type: object.OTRedirection, Value: "ns1.example.com."},
+			{Type: object.OTRedirection, Value: "ns2.example.net."},
+			{Type: object.OTIP4Addr, Value: "192.0.2.1"},
+			{Type: object.OTIP6Addr, Value: "2001:db8::1"},
+			{Type: object.OTRegistrant, Value: "v=spf1 -all"},
+		}},
+		{SubjectName: "ns1", Content: []object.Object{{Type: object.OTIP4Addr, Value: "192.0.2.53"}}},
+		{SubjectName: "www", Content: []object.Object{{Type: object.OTName,
+			Value: object.Name{Name: "example.com.", Types: aliasTypes}}}},
+		{SubjectName: "_rains._tcp", Content: []object.Object{{Type: object.OTServiceInfo,
+			Value: object.ServiceInfo{Name: "ns1.example.com.", Port: 5022, Priority: 0}}}},
+		{SubjectName: "_443._tcp.www", Content: []object.Object{{Type: object.OTCertInfo,
+			Value: object.Certificate{Type: object.PTTLS, Usage: object.CUEndEntity,
+				HashAlgo: algorithmTypes.Sha256, Data: certData}}}},
+		{SubjectName: "sub", Content: []object.Object{
+			{Type: object.OTRedirection, Value: "ns.sub.example.com."}}},
+		{SubjectName: "ns.sub", Content: []object.Object{
+			{Type: object.OTIP4Addr, Value: "192.0.2.100"}}},
+		{SubjectName: "host.lab", Content: []object.Object{
+			{Type: object.OTIP4Addr, Value: "192.0.2.10"}}},
+		{SubjectName: "txt.sub", Content: []object.Object{
+			{Type: object.OTRegistrant, Value: "joined strings"}}},
+	}}
+	wantUnmappable := []struct {
+		file string
+		line int
+	}{
+		{"test/example.com.zone", 4},
+		{"test/example.com.zone", 15},
+		{"test/example.com.zone", 18},
+		{"test/example.com.zone", 22},
+		{"test/included.zone", 3},
+		{"test/example.com.zone", 27},
+		{"test/example.com.zone", 28},
+	}
+	zone, unmappable, err := IO{}.LoadMasterFile("test/example.com.zone", "example.com.", ".")
+	if err != nil {
+		t.Fatalf("Was not able to load master file: %v", err)
+	}
+	if !reflect.DeepEqual(zone, want) {
+		t.Errorf("Wrong zone. expected=%s actual=%s", IO{}.EncodeSection(want), IO{}.EncodeSection(zone))
+	}
+	if len(unmappable) != len(wantUnmappable) {
+		t.Fatalf("Wrong number of unmappable records. expected=%d actual=%v", len(wantUnmappable),
+			unmappable)
+	}
+	for i, u := range unmappable {
+		if u.File != wantUnmappable[i].file || u.Line != wantUnmappable[i].line {
+			t.Errorf("Wrong unmappable record. expected=%s:%d actual=%s", wantUnmappable[i].file,
+				wantUnmappable[i].line, u)
+		}
+	}
+}
+
+func TestDecodeMasterFileErrors(t *testing.T) {
+	var tests = []string{
+		"www A 192.0.2.1 (",
+		"www A 192.0.2.1 )",
+		"www TXT \"unterminated",
+		"www A 2001:db8::1",
+		"www AAAA 192.0.2.1",
+		"www SRV 0 5 port target",
+		"www TLSA 3 0 1 nothex",
+		"  A 192.0.2.1",
+		"$ORIGIN",
+		"$TTL forever",
+		"$INCLUDE missing.zone",
+	}
+	for i, test := range tests {
+		if _, _, err := (IO{}).DecodeMasterFile([]byte(test), "example.com.", ".", "test"); err == nil {
+			t.Errorf("%d: Malformed master file was accepted: %s", i, test)
+		}
+	}
+}
+
+func TestIsFreeText(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  bool
+	}{
+		{"v=spf1 -all", true},
+		{"two  spaces", false},
+		{"comment ; here", false},
+		{"keyword :ip4:", false},
+		{"bracket ]", false},
+		{"", false},
+	}
+	for i, test := range tests {
+		if got := isFreeText(test.input); got != test.want {
+			t.Errorf("%d: isFreeText(%q) expected=%t actual=%t", i, test.input, test.want, got)
+		}
+	}
+}
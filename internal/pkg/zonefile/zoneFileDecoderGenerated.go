@@ -13,6 +13,7 @@ import (
 	"errors"
 	"fmt"
 	log "github.com/inconshreveable/log15"
+	"github.com/netsec-ethz/rains/internal/pkg/address"
 	"github.com/netsec-ethz/rains/internal/pkg/algorithmTypes"
 	"github.com/netsec-ethz/rains/internal/pkg/datastructures/bitarray"
 	"github.com/netsec-ethz/rains/internal/pkg/keys"
@@ -109,28 +110,31 @@ func DecodeValidity(validSince, validUntil string) (int64, int64, error) {
 //Result gets stored in this variable
 var output []section.WithSigForward
 
-//line zonefileParser.y:119
+//line zonefileParser.y:120
 type ZFPSymType struct {
-	yys          int
-	str          string
-	assertion    *section.Assertion
-	assertions   []*section.Assertion
-	shard        *section.Shard
-	pshard       *section.Pshard
-	zone         *section.Zone
-	sections     []section.WithSigForward
-	objects      []object.Object
-	object       object.Object
-	objectTypes  []object.Type
-	objectType   object.Type
-	signatures   []signature.Sig
-	signature    signature.Sig
-	shardRange   []string
-	publicKey    keys.PublicKey
-	protocolType object.ProtocolType
-	certUsage    object.CertificateUsage
-	hashType     algorithmTypes.Hash
-	bfAlgo       section.BloomFilterAlgo
+	yys            int
+	str            string
+	assertion      *section.Assertion
+	assertions     []*section.Assertion
+	shard          *section.Shard
+	pshard         *section.Pshard
+	zone           *section.Zone
+	addrAssertion  *section.AddressAssertion
+	addrAssertions []*section.AddressAssertion
+	addrZone       *section.AddressZone
+	sections       []section.WithSigForward
+	objects        []object.Object
+	object         object.Object
+	objectTypes    []object.Type
+	objectType     object.Type
+	signatures     []signature.Sig
+	signature      signature.Sig
+	shardRange     []string
+	publicKey      keys.PublicKey
+	protocolType   object.ProtocolType
+	certUsage      object.CertificateUsage
+	hashType       algorithmTypes.Hash
+	bfAlgo         section.BloomFilterAlgo
 }
 
 const ID = 57346
@@ -138,45 +142,47 @@ const assertionType = 57347
 const shardType = 57348
 const pshardType = 57349
 const zoneType = 57350
-const nameType = 57351
-const ip4Type = 57352
-const ip6Type = 57353
-const scionip4Type = 57354
-const scionip6Type = 57355
-const redirType = 57356
-const delegType = 57357
-const namesetType = 57358
-const certType = 57359
-const srvType = 57360
-const regrType = 57361
-const regtType = 57362
-const infraType = 57363
-const extraType = 57364
-const nextType = 57365
-const sigType = 57366
-const ed25519Type = 57367
-const unspecified = 57368
-const tls = 57369
-const trustAnchor = 57370
-const endEntity = 57371
-const noHash = 57372
-const sha256 = 57373
-const sha384 = 57374
-const sha512 = 57375
-const shake256 = 57376
-const fnv64 = 57377
-const fnv128 = 57378
-const bloomKM12 = 57379
-const bloomKM16 = 57380
-const bloomKM20 = 57381
-const bloomKM24 = 57382
-const rains = 57383
-const rangeBegin = 57384
-const rangeEnd = 57385
-const lBracket = 57386
-const rBracket = 57387
-const lParenthesis = 57388
-const rParenthesis = 57389
+const addrAssertionType = 57351
+const addrZoneType = 57352
+const nameType = 57353
+const ip4Type = 57354
+const ip6Type = 57355
+const scionip4Type = 57356
+const scionip6Type = 57357
+const redirType = 57358
+const delegType = 57359
+const namesetType = 57360
+const certType = 57361
+const srvType = 57362
+const regrType = 57363
+const regtType = 57364
+const infraType = 57365
+const extraType = 57366
+const nextType = 57367
+const sigType = 57368
+const ed25519Type = 57369
+const unspecified = 57370
+const tls = 57371
+const trustAnchor = 57372
+const endEntity = 57373
+const noHash = 57374
+const sha256 = 57375
+const sha384 = 57376
+const sha512 = 57377
+const shake256 = 57378
+const fnv64 = 57379
+const fnv128 = 57380
+const bloomKM12 = 57381
+const bloomKM16 = 57382
+const bloomKM20 = 57383
+const bloomKM24 = 57384
+const rains = 57385
+const rangeBegin = 57386
+const rangeEnd = 57387
+const lBracket = 57388
+const rBracket = 57389
+const lParenthesis = 57390
+const rParenthesis = 57391
 
 var ZFPToknames = [...]string{
 	"$end",
@@ -187,6 +193,8 @@ var ZFPToknames = [...]string{
 	"shardType",
 	"pshardType",
 	"zoneType",
+	"addrAssertionType",
+	"addrZoneType",
 	"nameType",
 	"ip4Type",
 	"ip6Type",
@@ -233,8 +241,7 @@ const ZFPEofCode = 1
 const ZFPErrCode = 2
 const ZFPInitialStackSize = 16
 
-//line zonefileParser.y:701
-
+//line zonefileParser.y:776
 /*  Lexer  */
 
 // The parser expects the lexer to return 0 on EOF.
@@ -277,6 +284,10 @@ func (l *ZFPLex) Lex(lval *ZFPSymType) int {
 		return pshardType
 	case TypeZone:
 		return zoneType
+	case TypeAddressAssertion:
+		return addrAssertionType
+	case TypeAddressZone:
+		return addrZoneType
 	case TypeName:
 		return nameType
 	case TypeIP6:
@@ -411,128 +422,142 @@ var ZFPExca = [...]int{
 
 const ZFPPrivate = 57344
 
-const ZFPLast = 216
+const ZFPLast = 271
 
 var ZFPAct = [...]int{
 
-	129, 3, 37, 38, 85, 11, 130, 131, 132, 133,
-	134, 135, 136, 137, 138, 139, 140, 141, 142, 143,
-	144, 54, 56, 55, 57, 58, 59, 60, 61, 62,
-	63, 64, 65, 66, 67, 68, 16, 27, 109, 97,
-	96, 77, 163, 74, 75, 159, 11, 29, 95, 100,
-	98, 103, 104, 105, 106, 71, 27, 158, 113, 114,
-	33, 147, 148, 149, 150, 151, 152, 153, 70, 91,
-	92, 54, 56, 55, 57, 58, 59, 60, 61, 62,
-	63, 64, 65, 66, 67, 68, 125, 28, 101, 99,
-	122, 123, 124, 72, 88, 89, 94, 93, 84, 119,
-	73, 36, 25, 15, 167, 166, 165, 76, 162, 126,
-	161, 157, 17, 18, 19, 11, 12, 13, 14, 156,
-	155, 154, 160, 77, 145, 1, 127, 34, 118, 164,
-	130, 131, 132, 133, 134, 135, 136, 137, 138, 139,
-	140, 141, 142, 143, 144, 54, 56, 55, 57, 58,
-	59, 60, 61, 62, 63, 64, 65, 66, 67, 68,
-	117, 116, 111, 115, 110, 108, 86, 90, 83, 82,
-	81, 80, 79, 78, 69, 35, 32, 31, 30, 23,
-	22, 21, 20, 102, 121, 146, 112, 87, 26, 24,
-	128, 53, 52, 51, 50, 49, 48, 47, 46, 45,
-	44, 43, 42, 40, 41, 39, 7, 107, 120, 9,
-	5, 8, 4, 2, 10, 6,
+	151, 51, 3, 7, 50, 101, 152, 153, 154, 155,
+	156, 157, 158, 159, 160, 161, 162, 163, 164, 165,
+	166, 67, 69, 68, 70, 71, 72, 73, 74, 75,
+	76, 77, 78, 79, 80, 81, 15, 19, 15, 22,
+	44, 84, 185, 39, 128, 116, 114, 113, 88, 112,
+	90, 37, 93, 89, 87, 91, 111, 180, 37, 67,
+	69, 68, 70, 71, 72, 73, 74, 75, 76, 77,
+	78, 79, 80, 81, 46, 147, 132, 133, 181, 110,
+	144, 85, 43, 107, 108, 38, 117, 115, 109, 83,
+	93, 141, 142, 143, 125, 146, 67, 69, 68, 70,
+	71, 72, 73, 74, 75, 76, 77, 78, 79, 80,
+	81, 119, 120, 121, 122, 35, 100, 138, 169, 170,
+	171, 172, 173, 174, 175, 49, 145, 93, 104, 105,
+	148, 86, 124, 21, 15, 16, 17, 18, 19, 20,
+	93, 189, 182, 188, 23, 24, 25, 26, 27, 187,
+	47, 186, 67, 69, 68, 70, 71, 72, 73, 74,
+	75, 76, 77, 78, 79, 80, 81, 152, 153, 154,
+	155, 156, 157, 158, 159, 160, 161, 162, 163, 164,
+	165, 166, 1, 184, 183, 179, 178, 177, 92, 67,
+	69, 68, 70, 71, 72, 73, 74, 75, 76, 77,
+	78, 79, 80, 81, 176, 167, 149, 137, 136, 135,
+	130, 134, 129, 127, 102, 106, 99, 98, 97, 96,
+	95, 94, 82, 48, 45, 42, 41, 40, 33, 32,
+	31, 30, 29, 28, 118, 140, 168, 131, 103, 36,
+	34, 150, 66, 65, 64, 63, 62, 61, 60, 59,
+	58, 57, 56, 55, 53, 54, 52, 13, 126, 14,
+	8, 9, 123, 139, 11, 5, 10, 4, 2, 12,
+	6,
 }
 var ZFPPact = [...]int{
 
-	-1000, -1000, 110, -1000, -1000, -1000, -1000, -10, -10, -10,
-	-10, 178, 177, 176, 175, -1000, 32, -1000, -1000, -1000,
-	43, 174, 173, 172, 13, -1000, 171, 76, 136, 170,
-	51, 51, -1, -1000, -1000, -1000, 3, 62, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, 169, 168, 167, 166, 165, 164,
-	73, 162, 68, 163, 162, 162, 72, 71, 23, -4,
-	-5, 46, 45, 14, -1000, 161, -1000, -1000, -6, -1000,
-	-1000, -1000, -1000, -1000, 160, 158, -1000, 30, -1000, -1000,
-	159, 158, 158, 157, 156, 124, 136, -1000, -1000, -1000,
-	-1000, -1000, 56, -1000, -1000, -1000, -1000, 41, 122, 121,
-	120, -1000, 31, -1000, -1000, 117, 116, 115, 107, 12,
-	0, 106, -1000, -1000, -1000, -1000, -1000, 104, -3, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, 102, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, -1000, 101, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, -1000, 100, -1000,
+	-1000, -1000, 129, -1000, -1000, -1000, -1000, -1000, -1000, -9,
+	-9, -9, -9, -9, -9, 229, 228, 227, 226, 225,
+	224, -1000, 32, -1000, -1000, -1000, -1000, -1000, 39, 223,
+	222, 221, 36, 220, 25, -1000, 219, 98, 178, 218,
+	37, 37, 8, 178, 7, 4, -1000, -1000, -1000, 12,
+	141, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, 217, 216, 215,
+	214, 213, 212, 89, 210, 100, 211, 210, 210, 61,
+	52, 29, 3, 1, 42, 41, 72, -1000, 85, 178,
+	-1000, 209, -1000, -1000, -2, -1000, -1000, -1000, -1000, -1000,
+	208, 206, -1000, 46, -1000, -1000, 207, 206, 206, 205,
+	204, 203, 178, -1000, -1000, -1000, -1000, -1000, 55, -1000,
+	-1000, -1000, -1000, 33, -1000, 48, 28, 202, 156, 201,
+	-1000, 86, -1000, -1000, 200, 183, 182, 181, 10, 31,
+	180, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 179,
+	-5, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 145, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 139,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 137, -1000,
 }
 var ZFPPgo = [...]int{
 
-	0, 215, 214, 213, 212, 211, 68, 210, 209, 208,
-	207, 1, 206, 2, 3, 205, 204, 203, 202, 201,
-	200, 199, 198, 197, 196, 195, 194, 193, 192, 191,
-	190, 0, 103, 189, 102, 188, 4, 187, 186, 185,
-	184, 183, 125,
+	0, 270, 269, 268, 267, 266, 89, 265, 264, 263,
+	262, 2, 261, 260, 259, 258, 3, 257, 4, 1,
+	256, 255, 254, 253, 252, 251, 250, 249, 248, 247,
+	246, 245, 244, 243, 242, 241, 0, 133, 240, 115,
+	239, 5, 238, 237, 236, 235, 234, 182,
 }
 var ZFPR1 = [...]int{
 
-	0, 42, 3, 3, 3, 3, 3, 1, 1, 2,
-	10, 10, 4, 4, 5, 6, 6, 6, 6, 9,
-	9, 7, 7, 8, 40, 40, 40, 41, 41, 41,
-	41, 11, 11, 12, 12, 13, 13, 14, 14, 14,
-	14, 14, 14, 14, 14, 14, 14, 14, 14, 14,
-	14, 14, 15, 30, 30, 31, 31, 31, 31, 31,
-	31, 31, 31, 31, 31, 31, 31, 31, 31, 31,
-	17, 16, 19, 18, 20, 21, 22, 23, 24, 25,
-	26, 27, 28, 29, 37, 37, 38, 38, 39, 39,
-	39, 39, 39, 39, 39, 36, 36, 32, 33, 33,
-	34, 34, 35,
+	0, 47, 3, 3, 3, 3, 3, 3, 3, 1,
+	1, 2, 10, 10, 4, 4, 5, 6, 6, 6,
+	6, 9, 9, 7, 7, 8, 45, 45, 45, 46,
+	46, 46, 46, 11, 11, 12, 12, 13, 13, 14,
+	15, 15, 16, 16, 17, 17, 18, 18, 19, 19,
+	19, 19, 19, 19, 19, 19, 19, 19, 19, 19,
+	19, 19, 19, 20, 35, 35, 36, 36, 36, 36,
+	36, 36, 36, 36, 36, 36, 36, 36, 36, 36,
+	36, 22, 21, 24, 23, 25, 26, 27, 28, 29,
+	30, 31, 32, 33, 34, 42, 42, 43, 43, 44,
+	44, 44, 44, 44, 44, 44, 41, 41, 37, 38,
+	38, 39, 39, 40,
 }
 var ZFPR2 = [...]int{
 
-	0, 1, 0, 2, 2, 2, 2, 1, 2, 6,
-	0, 2, 1, 2, 7, 2, 2, 2, 2, 0,
-	2, 1, 2, 7, 1, 1, 1, 1, 1, 1,
-	1, 1, 2, 5, 7, 1, 2, 1, 1, 1,
+	0, 1, 0, 2, 2, 2, 2, 2, 2, 1,
+	2, 6, 0, 2, 1, 2, 7, 2, 2, 2,
+	2, 0, 2, 1, 2, 7, 1, 1, 1, 1,
+	1, 1, 1, 1, 2, 5, 7, 1, 2, 6,
+	0, 2, 1, 2, 5, 6, 1, 2, 1, 1,
 	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
-	1, 1, 5, 1, 2, 1, 1, 1, 1, 1,
+	1, 1, 1, 5, 1, 2, 1, 1, 1, 1,
 	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
-	2, 2, 2, 2, 2, 4, 2, 5, 4, 2,
-	2, 4, 4, 6, 1, 1, 1, 1, 1, 1,
-	1, 1, 1, 1, 1, 1, 2, 3, 1, 2,
-	1, 2, 6,
+	1, 2, 2, 2, 2, 2, 4, 2, 5, 4,
+	2, 2, 4, 4, 6, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 2, 3, 1,
+	2, 1, 2, 6,
 }
 var ZFPChk = [...]int{
 
-	-1000, -42, -3, -11, -4, -7, -1, -12, -5, -8,
-	-2, 5, 6, 7, 8, -32, 46, -32, -32, -32,
-	4, 4, 4, 4, -33, -34, -35, 24, 44, 4,
-	4, 4, 4, 47, -34, 4, 25, -13, -14, -15,
-	-17, -16, -18, -19, -20, -21, -22, -23, -24, -25,
-	-26, -27, -28, -29, 9, 11, 10, 12, 13, 14,
-	15, 16, 17, 18, 19, 20, 21, 22, 23, 4,
-	-6, 4, 42, -6, 44, 41, 45, -14, 4, 4,
-	4, 4, 4, 4, 25, -36, 4, -37, 26, 27,
-	4, -36, -36, 25, 25, 25, 44, 44, 4, 43,
-	4, 43, -41, 37, 38, 39, 40, -10, 4, 44,
-	4, 4, -38, 28, 29, 4, 4, 4, 4, -13,
-	-9, -40, 34, 35, 36, 45, -11, 4, -30, -31,
-	9, 10, 11, 12, 13, 14, 15, 16, 17, 18,
-	19, 20, 21, 22, 23, 4, -39, 30, 31, 32,
-	33, 34, 35, 36, 4, 4, 4, 4, 45, 45,
-	-11, 4, 4, 45, -31, 4, 4, 4,
+	-1000, -47, -3, -11, -4, -7, -1, -16, -13, -12,
+	-5, -8, -2, -17, -14, 5, 6, 7, 8, 9,
+	10, -37, 48, -37, -37, -37, -37, -37, 4, 4,
+	4, 4, 4, 4, -38, -39, -40, 26, 46, 4,
+	4, 4, 4, 46, 4, 4, 49, -39, 4, 27,
+	-18, -19, -20, -22, -21, -23, -24, -25, -26, -27,
+	-28, -29, -30, -31, -32, -33, -34, 11, 13, 12,
+	14, 15, 16, 17, 18, 19, 20, 21, 22, 23,
+	24, 25, 4, -6, 4, 44, -6, 46, -18, 46,
+	46, 43, 47, -19, 4, 4, 4, 4, 4, 4,
+	27, -41, 4, -42, 28, 29, 4, -41, -41, 27,
+	27, 27, 46, 46, 4, 45, 4, 45, -46, 39,
+	40, 41, 42, -10, 47, -18, -15, 4, 46, 4,
+	4, -43, 30, 31, 4, 4, 4, 4, -18, -9,
+	-45, 36, 37, 38, 47, -11, 47, 47, -16, 4,
+	-35, -36, 11, 12, 13, 14, 15, 16, 17, 18,
+	19, 20, 21, 22, 23, 24, 25, 4, -44, 32,
+	33, 34, 35, 36, 37, 38, 4, 4, 4, 4,
+	47, 47, -11, 4, 4, 47, -36, 4, 4, 4,
 }
 var ZFPDef = [...]int{
 
-	2, -2, 1, 3, 4, 5, 6, 31, 12, 21,
-	7, 0, 0, 0, 0, 32, 0, 13, 22, 8,
-	0, 0, 0, 0, 0, 98, 100, 0, 0, 0,
-	0, 0, 0, 97, 99, 101, 0, 0, 35, 37,
-	38, 39, 40, 41, 42, 43, 44, 45, 46, 47,
-	48, 49, 50, 51, 0, 0, 0, 0, 0, 0,
+	2, -2, 1, 3, 4, 5, 6, 7, 8, 33,
+	14, 23, 9, 42, 37, 0, 0, 0, 0, 0,
+	0, 34, 0, 15, 24, 10, 43, 38, 0, 0,
+	0, 0, 0, 0, 0, 109, 111, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 108, 110, 112, 0,
+	0, 46, 48, 49, 50, 51, 52, 53, 54, 55,
+	56, 57, 58, 59, 60, 61, 62, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 10, 0, 33, 36, 0, 70,
-	71, 73, 72, 74, 0, 76, 95, 0, 84, 85,
-	0, 79, 80, 0, 0, 0, 0, 19, 15, 17,
-	16, 18, 0, 27, 28, 29, 30, 0, 0, 0,
-	0, 96, 0, 86, 87, 0, 0, 0, 0, 0,
-	0, 0, 24, 25, 26, 9, 11, 0, 0, 53,
-	55, 56, 57, 58, 59, 60, 61, 62, 63, 64,
-	65, 66, 67, 68, 69, 75, 0, 88, 89, 90,
-	91, 92, 93, 94, 78, 81, 82, 0, 34, 14,
-	20, 23, 102, 52, 54, 77, 0, 83,
+	0, 0, 0, 0, 0, 0, 0, 12, 0, 0,
+	40, 0, 35, 47, 0, 81, 82, 84, 83, 85,
+	0, 87, 106, 0, 95, 96, 0, 90, 91, 0,
+	0, 0, 0, 21, 17, 19, 18, 20, 0, 29,
+	30, 31, 32, 0, 44, 0, 0, 0, 0, 0,
+	107, 0, 97, 98, 0, 0, 0, 0, 0, 0,
+	0, 26, 27, 28, 11, 13, 45, 39, 41, 0,
+	0, 64, 66, 67, 68, 69, 70, 71, 72, 73,
+	74, 75, 76, 77, 78, 79, 80, 86, 0, 99,
+	100, 101, 102, 103, 104, 105, 89, 92, 93, 0,
+	36, 16, 22, 25, 113, 63, 65, 88, 0, 94,
 }
 var ZFPTok1 = [...]int{
 
@@ -544,7 +569,7 @@ var ZFPTok2 = [...]int{
 	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
 	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
 	32, 33, 34, 35, 36, 37, 38, 39, 40, 41,
-	42, 43, 44, 45, 46, 47,
+	42, 43, 44, 45, 46, 47, 48, 49,
 }
 var ZFPTok3 = [...]int{
 	0,
@@ -893,50 +918,62 @@ ZFPdefault:
 
 	case 1:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:190
+//line zonefileParser.y:197
 		{
 			output = ZFPDollar[1].sections
 		}
 	case 2:
 		ZFPDollar = ZFPS[ZFPpt-0 : ZFPpt+1]
-//line zonefileParser.y:195
+//line zonefileParser.y:202
 		{
 			ZFPVAL.sections = nil
 		}
 	case 3:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:199
+//line zonefileParser.y:206
 		{
 			ZFPVAL.sections = append(ZFPDollar[1].sections, ZFPDollar[2].assertion)
 		}
 	case 4:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:203
+//line zonefileParser.y:210
 		{
 			ZFPVAL.sections = append(ZFPDollar[1].sections, ZFPDollar[2].shard)
 		}
 	case 5:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:207
+//line zonefileParser.y:214
 		{
 			ZFPVAL.sections = append(ZFPDollar[1].sections, ZFPDollar[2].pshard)
 		}
 	case 6:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:211
+//line zonefileParser.y:218
 		{
 			ZFPVAL.sections = append(ZFPDollar[1].sections, ZFPDollar[2].zone)
 		}
+	case 7:
+		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
+//line zonefileParser.y:222
+		{
+			ZFPVAL.sections = append(ZFPDollar[1].sections, ZFPDollar[2].addrAssertion)
+		}
 	case 8:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:217
+//line zonefileParser.y:226
+		{
+			ZFPVAL.sections = append(ZFPDollar[1].sections, ZFPDollar[2].addrZone)
+		}
+	case 10:
+		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
+//line zonefileParser.y:232
 		{
 			AddSigs(ZFPDollar[1].zone, ZFPDollar[2].signatures)
 			ZFPVAL.zone = ZFPDollar[1].zone
 		}
-	case 9:
+	case 11:
 		ZFPDollar = ZFPS[ZFPpt-6 : ZFPpt+1]
-//line zonefileParser.y:223
+//line zonefileParser.y:238
 		{
 			ZFPVAL.zone = &section.Zone{
 				SubjectZone: ZFPDollar[2].str,
@@ -944,28 +981,28 @@ ZFPdefault:
 				Content:     ZFPDollar[5].assertions,
 			}
 		}
-	case 10:
+	case 12:
 		ZFPDollar = ZFPS[ZFPpt-0 : ZFPpt+1]
-//line zonefileParser.y:232
+//line zonefileParser.y:247
 		{
 			ZFPVAL.assertions = nil
 		}
-	case 11:
+	case 13:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:236
+//line zonefileParser.y:251
 		{
 			ZFPVAL.assertions = append(ZFPDollar[1].assertions, ZFPDollar[2].assertion)
 		}
-	case 13:
+	case 15:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:242
+//line zonefileParser.y:257
 		{
 			AddSigs(ZFPDollar[1].shard, ZFPDollar[2].signatures)
 			ZFPVAL.shard = ZFPDollar[1].shard
 		}
-	case 14:
+	case 16:
 		ZFPDollar = ZFPS[ZFPpt-7 : ZFPpt+1]
-//line zonefileParser.y:248
+//line zonefileParser.y:263
 		{
 			ZFPVAL.shard = &section.Shard{
 				SubjectZone: ZFPDollar[2].str,
@@ -975,52 +1012,52 @@ ZFPdefault:
 				Content:     ZFPDollar[6].assertions,
 			}
 		}
-	case 15:
+	case 17:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:259
+//line zonefileParser.y:274
 		{
 			ZFPVAL.shardRange = []string{ZFPDollar[1].str, ZFPDollar[2].str}
 		}
-	case 16:
+	case 18:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:263
+//line zonefileParser.y:278
 		{
 			ZFPVAL.shardRange = []string{"<", ZFPDollar[2].str}
 		}
-	case 17:
+	case 19:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:267
+//line zonefileParser.y:282
 		{
 			ZFPVAL.shardRange = []string{ZFPDollar[1].str, ">"}
 		}
-	case 18:
+	case 20:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:271
+//line zonefileParser.y:286
 		{
 			ZFPVAL.shardRange = []string{"<", ">"}
 		}
-	case 19:
+	case 21:
 		ZFPDollar = ZFPS[ZFPpt-0 : ZFPpt+1]
-//line zonefileParser.y:276
+//line zonefileParser.y:291
 		{
 			ZFPVAL.assertions = nil
 		}
-	case 20:
+	case 22:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:280
+//line zonefileParser.y:295
 		{
 			ZFPVAL.assertions = append(ZFPDollar[1].assertions, ZFPDollar[2].assertion)
 		}
-	case 22:
+	case 24:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:286
+//line zonefileParser.y:301
 		{
 			AddSigs(ZFPDollar[1].pshard, ZFPDollar[2].signatures)
 			ZFPVAL.pshard = ZFPDollar[1].pshard
 		}
-	case 23:
+	case 25:
 		ZFPDollar = ZFPS[ZFPpt-7 : ZFPpt+1]
-//line zonefileParser.y:292
+//line zonefileParser.y:307
 		{
 			decodedFilter, err := hex.DecodeString(ZFPDollar[7].str)
 			if err != nil {
@@ -1038,67 +1075,67 @@ ZFPdefault:
 				},
 			}
 		}
-	case 24:
+	case 26:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:311
+//line zonefileParser.y:326
 		{
 			ZFPVAL.hashType = algorithmTypes.Shake256
 		}
-	case 25:
+	case 27:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:315
+//line zonefileParser.y:330
 		{
 			ZFPVAL.hashType = algorithmTypes.Fnv64
 		}
-	case 26:
+	case 28:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:319
+//line zonefileParser.y:334
 		{
 			ZFPVAL.hashType = algorithmTypes.Fnv128
 		}
-	case 27:
+	case 29:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:324
+//line zonefileParser.y:339
 		{
 			ZFPVAL.bfAlgo = section.BloomKM12
 		}
-	case 28:
+	case 30:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:328
+//line zonefileParser.y:343
 		{
 			ZFPVAL.bfAlgo = section.BloomKM16
 		}
-	case 29:
+	case 31:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:332
+//line zonefileParser.y:347
 		{
 			ZFPVAL.bfAlgo = section.BloomKM20
 		}
-	case 30:
+	case 32:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:336
+//line zonefileParser.y:351
 		{
 			ZFPVAL.bfAlgo = section.BloomKM24
 		}
-	case 32:
+	case 34:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:342
+//line zonefileParser.y:357
 		{
 			AddSigs(ZFPDollar[1].assertion, ZFPDollar[2].signatures)
 			ZFPVAL.assertion = ZFPDollar[1].assertion
 		}
-	case 33:
+	case 35:
 		ZFPDollar = ZFPS[ZFPpt-5 : ZFPpt+1]
-//line zonefileParser.y:348
+//line zonefileParser.y:363
 		{
 			ZFPVAL.assertion = &section.Assertion{
 				SubjectName: ZFPDollar[2].str,
 				Content:     ZFPDollar[4].objects,
 			}
 		}
-	case 34:
+	case 36:
 		ZFPDollar = ZFPS[ZFPpt-7 : ZFPpt+1]
-//line zonefileParser.y:355
+//line zonefileParser.y:370
 		{
 			ZFPVAL.assertion = &section.Assertion{
 				SubjectName: ZFPDollar[2].str,
@@ -1107,21 +1144,88 @@ ZFPdefault:
 				Content:     ZFPDollar[6].objects,
 			}
 		}
-	case 35:
+	case 38:
+		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
+//line zonefileParser.y:381
+		{
+			AddSigs(ZFPDollar[1].addrZone, ZFPDollar[2].signatures)
+			ZFPVAL.addrZone = ZFPDollar[1].addrZone
+		}
+	case 39:
+		ZFPDollar = ZFPS[ZFPpt-6 : ZFPpt+1]
+//line zonefileParser.y:387
+		{
+			subjectAddr, err := address.Parse(ZFPDollar[2].str)
+			if err != nil {
+				log.Error("semantic error:", "ParseAddress", err)
+			}
+			ZFPVAL.addrZone = &section.AddressZone{
+				SubjectAddr: subjectAddr,
+				Context:     ZFPDollar[3].str,
+				Content:     ZFPDollar[5].addrAssertions,
+			}
+		}
+	case 40:
+		ZFPDollar = ZFPS[ZFPpt-0 : ZFPpt+1]
+//line zonefileParser.y:400
+		{
+			ZFPVAL.addrAssertions = nil
+		}
+	case 41:
+		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
+//line zonefileParser.y:404
+		{
+			ZFPVAL.addrAssertions = append(ZFPDollar[1].addrAssertions, ZFPDollar[2].addrAssertion)
+		}
+	case 43:
+		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
+//line zonefileParser.y:410
+		{
+			AddSigs(ZFPDollar[1].addrAssertion, ZFPDollar[2].signatures)
+			ZFPVAL.addrAssertion = ZFPDollar[1].addrAssertion
+		}
+	case 44:
+		ZFPDollar = ZFPS[ZFPpt-5 : ZFPpt+1]
+//line zonefileParser.y:416
+		{
+			subjectAddr, err := address.Parse(ZFPDollar[2].str)
+			if err != nil {
+				log.Error("semantic error:", "ParseAddress", err)
+			}
+			ZFPVAL.addrAssertion = &section.AddressAssertion{
+				SubjectAddr: subjectAddr,
+				Content:     ZFPDollar[4].objects,
+			}
+		}
+	case 45:
+		ZFPDollar = ZFPS[ZFPpt-6 : ZFPpt+1]
+//line zonefileParser.y:427
+		{
+			subjectAddr, err := address.Parse(ZFPDollar[2].str)
+			if err != nil {
+				log.Error("semantic error:", "ParseAddress", err)
+			}
+			ZFPVAL.addrAssertion = &section.AddressAssertion{
+				SubjectAddr: subjectAddr,
+				Context:     ZFPDollar[3].str,
+				Content:     ZFPDollar[5].objects,
+			}
+		}
+	case 46:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:365
+//line zonefileParser.y:440
 		{
 			ZFPVAL.objects = []object.Object{ZFPDollar[1].object}
 		}
-	case 36:
+	case 47:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:369
+//line zonefileParser.y:444
 		{
 			ZFPVAL.objects = append(ZFPDollar[1].objects, ZFPDollar[2].object)
 		}
-	case 52:
+	case 63:
 		ZFPDollar = ZFPS[ZFPpt-5 : ZFPpt+1]
-//line zonefileParser.y:390
+//line zonefileParser.y:465
 		{
 			ZFPVAL.object = object.Object{
 				Type: object.OTName,
@@ -1131,156 +1235,156 @@ ZFPdefault:
 				},
 			}
 		}
-	case 53:
+	case 64:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:401
+//line zonefileParser.y:476
 		{
 			ZFPVAL.objectTypes = []object.Type{ZFPDollar[1].objectType}
 		}
-	case 54:
+	case 65:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:405
+//line zonefileParser.y:480
 		{
 			ZFPVAL.objectTypes = append(ZFPDollar[1].objectTypes, ZFPDollar[2].objectType)
 		}
-	case 55:
+	case 66:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:410
+//line zonefileParser.y:485
 		{
 			ZFPVAL.objectType = object.OTName
 		}
-	case 56:
+	case 67:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:414
+//line zonefileParser.y:489
 		{
 			ZFPVAL.objectType = object.OTIP4Addr
 		}
-	case 57:
+	case 68:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:418
+//line zonefileParser.y:493
 		{
 			ZFPVAL.objectType = object.OTIP6Addr
 		}
-	case 58:
+	case 69:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:422
+//line zonefileParser.y:497
 		{
 			ZFPVAL.objectType = object.OTScionAddr4
 		}
-	case 59:
+	case 70:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:426
+//line zonefileParser.y:501
 		{
 			ZFPVAL.objectType = object.OTScionAddr6
 		}
-	case 60:
+	case 71:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:430
+//line zonefileParser.y:505
 		{
 			ZFPVAL.objectType = object.OTRedirection
 		}
-	case 61:
+	case 72:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:434
+//line zonefileParser.y:509
 		{
 			ZFPVAL.objectType = object.OTDelegation
 		}
-	case 62:
+	case 73:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:438
+//line zonefileParser.y:513
 		{
 			ZFPVAL.objectType = object.OTNameset
 		}
-	case 63:
+	case 74:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:442
+//line zonefileParser.y:517
 		{
 			ZFPVAL.objectType = object.OTCertInfo
 		}
-	case 64:
+	case 75:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:446
+//line zonefileParser.y:521
 		{
 			ZFPVAL.objectType = object.OTServiceInfo
 		}
-	case 65:
+	case 76:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:450
+//line zonefileParser.y:525
 		{
 			ZFPVAL.objectType = object.OTRegistrar
 		}
-	case 66:
+	case 77:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:454
+//line zonefileParser.y:529
 		{
 			ZFPVAL.objectType = object.OTRegistrant
 		}
-	case 67:
+	case 78:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:458
+//line zonefileParser.y:533
 		{
 			ZFPVAL.objectType = object.OTInfraKey
 		}
-	case 68:
+	case 79:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:462
+//line zonefileParser.y:537
 		{
 			ZFPVAL.objectType = object.OTExtraKey
 		}
-	case 69:
+	case 80:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:466
+//line zonefileParser.y:541
 		{
 			ZFPVAL.objectType = object.OTNextKey
 		}
-	case 70:
+	case 81:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:470
+//line zonefileParser.y:545
 		{
 			ZFPVAL.object = object.Object{
 				Type:  object.OTIP6Addr,
 				Value: ZFPDollar[2].str,
 			}
 		}
-	case 71:
+	case 82:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:477
+//line zonefileParser.y:552
 		{
 			ZFPVAL.object = object.Object{
 				Type:  object.OTIP4Addr,
 				Value: ZFPDollar[2].str,
 			}
 		}
-	case 72:
+	case 83:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:484
+//line zonefileParser.y:559
 		{
 			ZFPVAL.object = object.Object{
 				Type:  object.OTScionAddr6,
 				Value: ZFPDollar[2].str,
 			}
 		}
-	case 73:
+	case 84:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:491
+//line zonefileParser.y:566
 		{
 			ZFPVAL.object = object.Object{
 				Type:  object.OTScionAddr4,
 				Value: ZFPDollar[2].str,
 			}
 		}
-	case 74:
+	case 85:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:498
+//line zonefileParser.y:573
 		{
 			ZFPVAL.object = object.Object{
 				Type:  object.OTRedirection,
 				Value: ZFPDollar[2].str,
 			}
 		}
-	case 75:
+	case 86:
 		ZFPDollar = ZFPS[ZFPpt-4 : ZFPpt+1]
-//line zonefileParser.y:506
+//line zonefileParser.y:581
 		{
 			pkey, err := DecodeEd25519PublicKeyData(ZFPDollar[4].str, ZFPDollar[3].str)
 			if err != nil {
@@ -1291,18 +1395,18 @@ ZFPdefault:
 				Value: pkey,
 			}
 		}
-	case 76:
+	case 87:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:518
+//line zonefileParser.y:593
 		{
 			ZFPVAL.object = object.Object{
 				Type:  object.OTNameset,
 				Value: ZFPDollar[2].str,
 			}
 		}
-	case 77:
+	case 88:
 		ZFPDollar = ZFPS[ZFPpt-5 : ZFPpt+1]
-//line zonefileParser.y:526
+//line zonefileParser.y:601
 		{
 			cert, err := DecodeCertificate(ZFPDollar[2].protocolType, ZFPDollar[3].certUsage, ZFPDollar[4].hashType, ZFPDollar[5].str)
 			if err != nil {
@@ -1313,9 +1417,9 @@ ZFPdefault:
 				Value: cert,
 			}
 		}
-	case 78:
+	case 89:
 		ZFPDollar = ZFPS[ZFPpt-4 : ZFPpt+1]
-//line zonefileParser.y:538
+//line zonefileParser.y:613
 		{
 			srv, err := DecodeSrv(ZFPDollar[2].str, ZFPDollar[3].str, ZFPDollar[4].str)
 			if err != nil {
@@ -1326,27 +1430,27 @@ ZFPdefault:
 				Value: srv,
 			}
 		}
-	case 79:
+	case 90:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:550
+//line zonefileParser.y:625
 		{
 			ZFPVAL.object = object.Object{
 				Type:  object.OTRegistrar,
 				Value: ZFPDollar[2].str,
 			}
 		}
-	case 80:
+	case 91:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:558
+//line zonefileParser.y:633
 		{
 			ZFPVAL.object = object.Object{
 				Type:  object.OTRegistrant,
 				Value: ZFPDollar[2].str,
 			}
 		}
-	case 81:
+	case 92:
 		ZFPDollar = ZFPS[ZFPpt-4 : ZFPpt+1]
-//line zonefileParser.y:566
+//line zonefileParser.y:641
 		{
 			pkey, err := DecodeEd25519PublicKeyData(ZFPDollar[4].str, ZFPDollar[3].str)
 			if err != nil {
@@ -1357,9 +1461,9 @@ ZFPdefault:
 				Value: pkey,
 			}
 		}
-	case 82:
+	case 93:
 		ZFPDollar = ZFPS[ZFPpt-4 : ZFPpt+1]
-//line zonefileParser.y:578
+//line zonefileParser.y:653
 		{ //TODO CFE as of now there is only the rains key space. There will
 			//be additional rules in case there are new key spaces
 			pkey, err := DecodeEd25519PublicKeyData(ZFPDollar[4].str, ZFPDollar[3].str)
@@ -1371,9 +1475,9 @@ ZFPdefault:
 				Value: pkey,
 			}
 		}
-	case 83:
+	case 94:
 		ZFPDollar = ZFPS[ZFPpt-6 : ZFPpt+1]
-//line zonefileParser.y:591
+//line zonefileParser.y:666
 		{
 			pkey, err := DecodeEd25519PublicKeyData(ZFPDollar[4].str, ZFPDollar[3].str)
 			if err != nil {
@@ -1388,99 +1492,99 @@ ZFPdefault:
 				Value: pkey,
 			}
 		}
-	case 84:
+	case 95:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:607
+//line zonefileParser.y:682
 		{
 			ZFPVAL.protocolType = object.PTUnspecified
 		}
-	case 85:
+	case 96:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:611
+//line zonefileParser.y:686
 		{
 			ZFPVAL.protocolType = object.PTTLS
 		}
-	case 86:
+	case 97:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:616
+//line zonefileParser.y:691
 		{
 			ZFPVAL.certUsage = object.CUTrustAnchor
 		}
-	case 87:
+	case 98:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:620
+//line zonefileParser.y:695
 		{
 			ZFPVAL.certUsage = object.CUEndEntity
 		}
-	case 88:
+	case 99:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:625
+//line zonefileParser.y:700
 		{
 			ZFPVAL.hashType = algorithmTypes.NoHashAlgo
 		}
-	case 89:
+	case 100:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:629
+//line zonefileParser.y:704
 		{
 			ZFPVAL.hashType = algorithmTypes.Sha256
 		}
-	case 90:
+	case 101:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:633
+//line zonefileParser.y:708
 		{
 			ZFPVAL.hashType = algorithmTypes.Sha384
 		}
-	case 91:
+	case 102:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:637
+//line zonefileParser.y:712
 		{
 			ZFPVAL.hashType = algorithmTypes.Sha512
 		}
-	case 92:
+	case 103:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:641
+//line zonefileParser.y:716
 		{
 			ZFPVAL.hashType = algorithmTypes.Shake256
 		}
-	case 93:
+	case 104:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:645
+//line zonefileParser.y:720
 		{
 			ZFPVAL.hashType = algorithmTypes.Fnv64
 		}
-	case 94:
+	case 105:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:649
+//line zonefileParser.y:724
 		{
 			ZFPVAL.hashType = algorithmTypes.Fnv128
 		}
-	case 96:
+	case 107:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:655
+//line zonefileParser.y:730
 		{
 			ZFPVAL.str = ZFPDollar[1].str + " " + ZFPDollar[2].str
 		}
-	case 97:
+	case 108:
 		ZFPDollar = ZFPS[ZFPpt-3 : ZFPpt+1]
-//line zonefileParser.y:660
+//line zonefileParser.y:735
 		{
 			ZFPVAL.signatures = ZFPDollar[2].signatures
 		}
-	case 98:
+	case 109:
 		ZFPDollar = ZFPS[ZFPpt-1 : ZFPpt+1]
-//line zonefileParser.y:665
+//line zonefileParser.y:740
 		{
 			ZFPVAL.signatures = []signature.Sig{ZFPDollar[1].signature}
 		}
-	case 99:
+	case 110:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:669
+//line zonefileParser.y:744
 		{
 			ZFPVAL.signatures = append(ZFPDollar[1].signatures, ZFPDollar[2].signature)
 		}
-	case 101:
+	case 112:
 		ZFPDollar = ZFPS[ZFPpt-2 : ZFPpt+1]
-//line zonefileParser.y:675
+//line zonefileParser.y:750
 		{
 			sigData, err := hex.DecodeString(ZFPDollar[2].str)
 			if err != nil {
@@ -1489,9 +1593,9 @@ ZFPdefault:
 			ZFPDollar[1].signature.Data = sigData
 			ZFPVAL.signature = ZFPDollar[1].signature
 		}
-	case 102:
+	case 113:
 		ZFPDollar = ZFPS[ZFPpt-6 : ZFPpt+1]
-//line zonefileParser.y:685
+//line zonefileParser.y:760
 		{
 			publicKeyID, err := DecodePublicKeyID(ZFPDollar[4].str)
 			if err != nil {
@@ -146,6 +146,55 @@ func encodeAssertion(a *section.Assertion, context, zone, indent string, addZone
 	return fmt.Sprintf("%s %s ]%s", assertion, encodeObjects(a.Content, ""), signature)
 }
 
+//encodeAddressZone returns z in zonefile format.
+func encodeAddressZone(z *section.AddressZone) string {
+	zone := fmt.Sprintf("%s %s %s [\n", TypeAddressZone, z.SubjectAddr, z.Context)
+	for _, a := range z.Content {
+		zone += encodeAddressAssertion(a, indent4, false) + "\n"
+	}
+	if z.Signatures != nil {
+		var sigs []string
+		for _, sig := range z.Signatures {
+			sigs = append(sigs, encodeEd25519Signature(sig))
+		}
+		if len(sigs) == 1 {
+			return fmt.Sprintf("%s] ( %s )\n", zone, sigs[0])
+		}
+		return fmt.Sprintf("%s] ( \n%s%s\n  )\n", zone, indent4, strings.Join(sigs, "\n"+indent4))
+	}
+	return fmt.Sprintf("%s]\n", zone)
+}
+
+//encodeAddressAssertion returns a in zonefile format. If addContext is true, the context is also
+//present.
+func encodeAddressAssertion(a *section.AddressAssertion, indent string, addContext bool) string {
+	var assertion string
+	if addContext {
+		assertion = fmt.Sprintf("%s%s %s %s [", indent, TypeAddressAssertion, a.SubjectAddr,
+			a.Context)
+	} else {
+		assertion = fmt.Sprintf("%s%s %s [", indent, TypeAddressAssertion, a.SubjectAddr)
+	}
+	signature := ""
+	if a.Signatures != nil {
+		var sigs []string
+		for _, sig := range a.Signatures {
+			sigs = append(sigs, encodeEd25519Signature(sig))
+		}
+		if len(sigs) == 1 {
+			signature = fmt.Sprintf(" ( %s )\n", sigs[0])
+		} else {
+			signature = fmt.Sprintf(" ( \n%s%s\n%s)\n", indent+indent4,
+				strings.Join(sigs, "\n"+indent+indent4), indent)
+		}
+	}
+	if len(a.Content) > 1 {
+		return fmt.Sprintf("%s\n%s\n%s]%s", assertion, encodeObjects(a.Content, indent+indent4),
+			indent, signature)
+	}
+	return fmt.Sprintf("%s %s ]%s", assertion, encodeObjects(a.Content, ""), signature)
+}
+
 //encodeObjects returns o in zonefile format.
 func encodeObjects(o []object.Object, indent string) string {
 	var objects []string
@@ -15,43 +15,45 @@ import (
 )
 
 const (
-	TypeAssertion     = ":A:"
-	TypeShard         = ":S:"
-	TypePshard        = ":P:"
-	TypeZone          = ":Z:"
-	TypeSignature     = ":sig:"
-	TypeName          = ":name:"
-	TypeIP6           = ":ip6:"
-	TypeIP4           = ":ip4:"
-	TypeScionIP6      = ":scionip6:"
-	TypeScionIP4      = ":scionip4:"
-	TypeRedirection   = ":redir:"
-	TypeDelegation    = ":deleg:"
-	TypeNameSet       = ":nameset:"
-	TypeCertificate   = ":cert:"
-	TypeServiceInfo   = ":srv:"
-	TypeRegistrar     = ":regr:"
-	TypeRegistrant    = ":regt:"
-	TypeInfraKey      = ":infra:"
-	TypeExternalKey   = ":extra:"
-	TypeNextKey       = ":next:"
-	TypeEd25519       = ":ed25519:"
-	TypeUnspecified   = ":unspecified:"
-	TypePTTLS         = ":tls:"
-	TypeCUTrustAnchor = ":trustAnchor:"
-	TypeCUEndEntity   = ":endEntity:"
-	TypeNoHash        = ":noHash:"
-	TypeSha256        = ":sha256:"
-	TypeSha384        = ":sha384:"
-	TypeSha512        = ":sha512:"
-	TypeShake256      = ":shake256:"
-	TypeFnv64         = ":fnv64:"
-	TypeFnv128        = ":fnv128:"
-	TypeKM12          = ":bloomKM12:"
-	TypeKM16          = ":bloomKM16:"
-	TypeKM20          = ":bloomKM20:"
-	TypeKM24          = ":bloomKM24:"
-	TypeKSRains       = ":rains:"
+	TypeAssertion        = ":A:"
+	TypeShard            = ":S:"
+	TypePshard           = ":P:"
+	TypeZone             = ":Z:"
+	TypeAddressAssertion = ":AA:"
+	TypeAddressZone      = ":AZ:"
+	TypeSignature        = ":sig:"
+	TypeName             = ":name:"
+	TypeIP6              = ":ip6:"
+	TypeIP4              = ":ip4:"
+	TypeScionIP6         = ":scionip6:"
+	TypeScionIP4         = ":scionip4:"
+	TypeRedirection      = ":redir:"
+	TypeDelegation       = ":deleg:"
+	TypeNameSet          = ":nameset:"
+	TypeCertificate      = ":cert:"
+	TypeServiceInfo      = ":srv:"
+	TypeRegistrar        = ":regr:"
+	TypeRegistrant       = ":regt:"
+	TypeInfraKey         = ":infra:"
+	TypeExternalKey      = ":extra:"
+	TypeNextKey          = ":next:"
+	TypeEd25519          = ":ed25519:"
+	TypeUnspecified      = ":unspecified:"
+	TypePTTLS            = ":tls:"
+	TypeCUTrustAnchor    = ":trustAnchor:"
+	TypeCUEndEntity      = ":endEntity:"
+	TypeNoHash           = ":noHash:"
+	TypeSha256           = ":sha256:"
+	TypeSha384           = ":sha384:"
+	TypeSha512           = ":sha512:"
+	TypeShake256         = ":shake256:"
+	TypeFnv64            = ":fnv64:"
+	TypeFnv128           = ":fnv128:"
+	TypeKM12             = ":bloomKM12:"
+	TypeKM16             = ":bloomKM16:"
+	TypeKM20             = ":bloomKM20:"
+	TypeKM24             = ":bloomKM24:"
+	TypeKSRains          = ":rains:"
 
 	indent4  = "    "
 	indent8  = indent4 + indent4
@@ -75,6 +77,17 @@ type ZoneFileIO interface {
 	//in case of failure.
 	LoadZonefile(path string) ([]section.WithSigForward, error)
 
+	//DecodeMasterFile takes as input a byte string of a DNS master file of zone. It returns the
+	//records converted to a zone in context, all records which cannot be expressed in RAINS, or an
+	//error in case of failure. Files included by the master file are looked up relative to dir.
+	DecodeMasterFile(data []byte, zone, context, dir string) (*section.Zone, []UnmappableRecord,
+		error)
+
+	//LoadMasterFile takes as input a path to a DNS master file of zone. It returns the records
+	//converted to a zone in context, all records which cannot be expressed in RAINS, or an error in
+	//case of failure.
+	LoadMasterFile(path, zone, context string) (*section.Zone, []UnmappableRecord, error)
+
 	//Encode returns the given sections represented in zone file format if it is an assertion,
 	//shard, or zone. In all other cases it returns the sections in a displayable format similar to
 	//the zonefile format
@@ -89,6 +102,10 @@ type ZoneFileIO interface {
 	//assertion, shard, pshard, or zone. In all other cases it stores the sections in a displayable
 	//format similar to the zone file format
 	EncodeAndStore(path string, section []section.Section) error
+
+	//EncodeMasterFile returns the zones and assertions of the given sections represented as a DNS
+	//master file. Objects without DNS equivalent are represented as structured comments.
+	EncodeMasterFile(sections []section.WithSigForward) string
 }
 
 //Parser can be used to parse and encode RAINS zone files
@@ -151,8 +168,14 @@ func (p IO) EncodeSection(s section.Section) string {
 		encoding = encodePshard(s, s.Context, s.SubjectZone, "")
 	case *section.Zone:
 		encoding = encodeZone(s)
+	case *section.AddressAssertion:
+		encoding = encodeAddressAssertion(s, "", true)
+	case *section.AddressZone:
+		encoding = encodeAddressZone(s)
 	case *query.Name:
 		encoding = encodeQuery(s)
+	case *query.Address:
+		encoding = encodeAddressQuery(s)
 	case *section.Notification:
 		encoding = encodeNotification(s)
 	default:
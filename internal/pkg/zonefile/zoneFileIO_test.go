@@ -73,6 +73,41 @@ func TestEncodeDecodeZone(t *testing.T) {
 	}
 }
 
+func TestEncodeDecodeAddressSections(t *testing.T) {
+	var tests = []string{
+		":AA: 192.0.2.1/32 . [ :name: host.example.com [ :ip4: ] ]",
+		":AA: 1-ff00:0:110,[2001:db8::/48] . [ :regr: Registrar ]",
+		`:AZ: 192.0.2.0/24 . [
+    :AA: 192.0.2.1/32 [ :name: host.example.com [ :ip4: ] ]
+    :AA: 192.0.2.2/32 [ :redir: ns.example.com ]
+]`,
+	}
+	for i, test := range tests {
+		sections := decode(t, []byte(test))
+		if len(sections) != 1 {
+			t.Fatalf("%d: Wrong number of decoded sections. expected=1 actual=%d", i, len(sections))
+		}
+		if !sections[0].IsConsistent() {
+			t.Errorf("%d: Decoded section is not consistent: %v", i, sections[0])
+		}
+		encoding := IO{}.EncodeSection(sections[0])
+		if strings.Join(strings.Fields(encoding), " ") != strings.Join(strings.Fields(test), " ") {
+			t.Errorf("%d: Wrong encoding. expected=%s actual=%s", i, test, encoding)
+		}
+	}
+	for i, test := range []string{
+		":AA: 192.0.2 . [ :name: host.example.com [ :ip4: ] ]",
+		":AZ: 192.0.2.0/24 . [ :AA: 198.51.100.1 [ :name: host.example.com [ :ip4: ] ] ]",
+		":AZ: 192.0.2.0/24 . [ :AA: 192.0.2.1 . [ :name: host.example.com [ :ip4: ] ] ]",
+		":AA: 192.0.2.1 . [ :ip4: 192.0.2.1 ]",
+	} {
+		sections, err := IO{}.Decode([]byte(test))
+		if err == nil && len(sections) == 1 && sections[0].IsConsistent() {
+			t.Errorf("%d: Malformed address section was accepted: %s", i, test)
+		}
+	}
+}
+
 func decode(t *testing.T, input []byte) []section.WithSigForward {
         zfParser := IO{}
         sections, err := zfParser.Decode(input)
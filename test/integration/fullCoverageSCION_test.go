@@ -53,7 +53,7 @@ func TestFullCoverageSCION(t *testing.T) {
 		panic(err.Error())
 	}
 	cachingResolver.SetResolver(resolver)
-	go cachingResolver.Start(false, "resolver")
+	go cachingResolver.Start("resolver")
 	time.Sleep(1000 * time.Millisecond)
 	log.Info("caching server successfully started")
 
@@ -91,7 +91,7 @@ func TestFullCoverageSCION(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Was not able to create client resolver: %v", err)
 	}
-	go cachingResolver2.Start(false, "resolver2")
+	go cachingResolver2.Start("resolver2")
 	time.Sleep(500 * time.Millisecond)
 	log.Info("caching server successfully started")
 	log.Info("begin sending queries which should be cached by pre load")
@@ -283,7 +283,7 @@ func startSCIONAuthServer(t *testing.T, name string, rootServers []net.Addr) *ra
 		panic(err.Error())
 	}
 	server.SetResolver(resolver)
-	go server.Start(false, "nameServer"+name)
+	go server.Start("nameServer" + name)
 
 	if len(resolver.RootNameServers) > 0 && resolver.RootNameServers[0] == nil {
 		log.Error(fmt.Sprintf("Started name server %s with nil root name server: %v",
@@ -54,7 +54,7 @@ func TestFullCoverage(t *testing.T) {
 		panic(err.Error())
 	}
 	cachingResolver.SetResolver(resolver)
-	go cachingResolver.Start(false, "resolver")
+	go cachingResolver.Start("resolver")
 	time.Sleep(1000 * time.Millisecond)
 	log.Info("caching server successfully started")
 
@@ -93,7 +93,7 @@ func TestFullCoverage(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Was not able to create client resolver: %v", err)
 	}
-	go cachingResolver2.Start(false, "resolver")
+	go cachingResolver2.Start("resolver")
 	time.Sleep(500 * time.Millisecond)
 	log.Info("caching server successfully started")
 	log.Info("begin sending queries which should be cached by pre load")
@@ -101,7 +101,7 @@ func TestFullCoverage(t *testing.T) {
 		sendQueryVerifyResponse(t, *query, cachingResolver2.Addr(), answers[i])
 	}
 	log.Warn("Done sending queries for cached entries that are preloaded")
-	cachingResolver2.Shutdown()
+	cachingResolver2.GracefulShutdown(time.Second)
 }
 
 func TestFullCoverageCLITools(t *testing.T) {
@@ -298,7 +298,7 @@ func startAuthServer(t *testing.T, name string, rootServers []net.Addr) *rainsd.
 		panic(err.Error())
 	}
 	server.SetResolver(resolver)
-	go server.Start(false, "nameServer"+name)
+	go server.Start("nameServer" + name)
 	time.Sleep(250 * time.Millisecond)
 	config, err := publisher.LoadConfig("testdata/conf/publisher" + name + ".conf")
 	if err != nil {
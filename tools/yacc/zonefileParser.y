@@ -18,6 +18,7 @@ import (
     "strconv"
     "strings"
     log "github.com/inconshreveable/log15"
+    "github.com/netsec-ethz/rains/internal/pkg/address"
     "github.com/netsec-ethz/rains/internal/pkg/signature"
     "github.com/netsec-ethz/rains/internal/pkg/section"
     "github.com/netsec-ethz/rains/internal/pkg/object"
@@ -123,6 +124,9 @@ var output []section.WithSigForward
     shard           *section.Shard
     pshard          *section.Pshard
     zone            *section.Zone
+    addrAssertion   *section.AddressAssertion
+    addrAssertions  []*section.AddressAssertion
+    addrZone        *section.AddressZone
     sections        []section.WithSigForward
     objects         []object.Object
     object          object.Object
@@ -147,6 +151,9 @@ var output []section.WithSigForward
 %type <pshard>          pshard pshardBody
 %type <assertions>      shardContent zoneContent
 %type <assertion>       assertion assertionBody
+%type <addrZone>        addrZone addrZoneBody
+%type <addrAssertions>  addrZoneContent
+%type <addrAssertion>   addrAssertion addrAssertionBody
 %type <objects>         objects
 %type <object>          object name ip4 ip6 scionip4 scionip6 redir deleg nameset 
 %type <object>          cert srv regr regt infra extra next
@@ -163,7 +170,7 @@ var output []section.WithSigForward
 // Terminals
 %token <str> ID
 // Section types
-%token assertionType shardType pshardType zoneType
+%token assertionType shardType pshardType zoneType addrAssertionType addrZoneType
 // Object types
 %token nameType ip4Type ip6Type scionip4Type scionip6Type redirType delegType namesetType certType
 %token srvType regrType regtType infraType extraType nextType
@@ -211,6 +218,14 @@ sections        : /* empty */
                 {
                     $$ = append($1, $2)
                 }
+                | sections addrAssertion
+                {
+                    $$ = append($1, $2)
+                }
+                | sections addrZone
+                {
+                    $$ = append($1, $2)
+                }
 
 zone            : zoneBody
                 | zoneBody annotation
@@ -361,6 +376,66 @@ assertionBody   : assertionType ID lBracket objects rBracket
                     }
                 }
 
+addrZone        : addrZoneBody
+                | addrZoneBody annotation
+                {
+                    AddSigs($1,$2)
+                    $$ = $1
+                }
+
+addrZoneBody    : addrZoneType ID ID lBracket addrZoneContent rBracket
+                {
+                    subjectAddr, err := address.Parse($2)
+                    if err != nil {
+                        log.Error("semantic error:", "ParseAddress", err)
+                    }
+                    $$ = &section.AddressZone{
+                        SubjectAddr: subjectAddr,
+                        Context: $3,
+                        Content: $5,
+                    }
+                }
+
+addrZoneContent : /* empty */
+                {
+                    $$ = nil
+                }
+                | addrZoneContent addrAssertion
+                {
+                    $$ = append($1, $2)
+                }
+
+addrAssertion   : addrAssertionBody
+                | addrAssertionBody annotation
+                {
+                    AddSigs($1,$2)
+                    $$ = $1
+                }
+
+addrAssertionBody : addrAssertionType ID lBracket objects rBracket
+                {
+                    subjectAddr, err := address.Parse($2)
+                    if err != nil {
+                        log.Error("semantic error:", "ParseAddress", err)
+                    }
+                    $$ = &section.AddressAssertion{
+                        SubjectAddr: subjectAddr,
+                        Content: $4,
+                    }
+                }
+                | addrAssertionType ID ID lBracket objects rBracket
+                {
+                    subjectAddr, err := address.Parse($2)
+                    if err != nil {
+                        log.Error("semantic error:", "ParseAddress", err)
+                    }
+                    $$ = &section.AddressAssertion{
+                        SubjectAddr: subjectAddr,
+                        Context: $3,
+                        Content: $5,
+                    }
+                }
+
 objects         : object
                 {
                     $$ = []object.Object{$1}
@@ -740,6 +815,10 @@ func (l *ZFPLex) Lex(lval *ZFPSymType) int {
         return pshardType
     case zonefile.TypeZone :
         return zoneType
+    case zonefile.TypeAddressAssertion :
+        return addrAssertionType
+    case zonefile.TypeAddressZone :
+        return addrZoneType
     case zonefile.TypeName :
 		return nameType
 	case zonefile.TypeIP6 :